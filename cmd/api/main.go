@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"log"
+	"os"
 	"os/signal"
 	"path/filepath"
 	"syscall"
@@ -18,6 +19,13 @@ import (
 	"github.com/open-apime/apime/internal/config"
 	"github.com/open-apime/apime/internal/dashboard"
 	"github.com/open-apime/apime/internal/logger"
+	"github.com/open-apime/apime/internal/pkg/linkpreview"
+	"github.com/open-apime/apime/internal/pkg/mediaurl"
+	"github.com/open-apime/apime/internal/pkg/ownership"
+	ownership_memory "github.com/open-apime/apime/internal/pkg/ownership/memory"
+	ownership_redis "github.com/open-apime/apime/internal/pkg/ownership/redis"
+	"github.com/open-apime/apime/internal/pkg/queue"
+	queue_redis "github.com/open-apime/apime/internal/pkg/queue/redis"
 	"github.com/open-apime/apime/internal/pkg/sentryx"
 	"github.com/open-apime/apime/internal/server"
 	"github.com/open-apime/apime/internal/service/api_token"
@@ -46,6 +54,14 @@ func (a *instanceCheckerAdapter) HasWebhook(ctx context.Context, instanceID stri
 	return inst.WebhookURL != ""
 }
 
+func (a *instanceCheckerAdapter) MediaPolicy(ctx context.Context, instanceID string) model.MediaPolicy {
+	inst, err := a.repo.GetByID(ctx, instanceID)
+	if err != nil || inst.MediaPolicy == "" {
+		return model.MediaPolicyEager
+	}
+	return inst.MediaPolicy
+}
+
 func main() {
 	cfg := config.Load()
 
@@ -55,6 +71,13 @@ func main() {
 	}
 	defer logr.Sync()
 
+	if cfg.WhatsApp.UsesDefaultSessionKey() {
+		if cfg.App.Env == "production" {
+			log.Fatal("WHATSAPP_SESSION_KEY_ENC está com o valor padrão: defina uma chave própria (ou WHATSAPP_SESSION_KEYS / WHATSAPP_SESSION_KEK_FILE) antes de rodar em produção")
+		}
+		logr.Warn("sessões cifradas com a chave padrão de WHATSAPP_SESSION_KEY_ENC; não use assim em produção")
+	}
+
 	sentryEnv := cfg.Sentry.Environment
 	if sentryEnv == "" {
 		sentryEnv = cfg.App.Env
@@ -88,13 +111,34 @@ func main() {
 	if err != nil {
 		log.Fatalf("storage: %v", err)
 	}
+	// Before any session starts: inbound events already feed these caches.
+	message.SetCache(repos.Cache)
+	message.SetGovernorConfig(cfg.Governor)
+	instancehandler.SetPictureCache(repos.Cache)
 
 	pgConnString := ""
 	if cfg.Storage.Driver == "postgres" {
 		pgConnString = cfg.DB.DSN()
 	}
 
-	sessionManager := whatsmeow.NewManager(logr, cfg.WhatsApp.SessionKeyEnc, cfg.Storage.Driver, sessionDir, pgConnString, repos.Instance, repos.HistorySync, repos.Message, repos.EventLog)
+	sessionManager := whatsmeow.NewManager(logr, repos.SessionKeys, cfg.Storage.Driver, sessionDir, pgConnString, repos.Instance, repos.HistorySync, repos.Message, repos.EventLog)
+
+	nodeID := cfg.Cluster.NodeID
+	if nodeID == "" {
+		nodeID, _ = os.Hostname()
+	}
+	var owners ownership.Ownership = ownership_memory.New(nodeID)
+	if repos.RedisClient != nil {
+		if cfg.Cluster.AdvertiseAddr == "" {
+			logr.Warn("CLUSTER_ADVERTISE_ADDR vazio: outras réplicas não conseguirão encaminhar requisições para esta")
+		}
+		leases := ownership_redis.NewLeases(repos.RedisClient.RDB(), ownership.Node{ID: nodeID, Addr: cfg.Cluster.AdvertiseAddr},
+			time.Duration(cfg.Cluster.LeaseTTLSeconds)*time.Second, logr)
+		leases.SetMaxOwned(cfg.Cluster.MaxInstances)
+		owners = leases
+		sessionManager.SetOwnership(owners)
+		logr.Info("coordenação de instâncias entre réplicas habilitada", zap.String("node_id", nodeID))
+	}
 
 	instanceService := instance.NewServiceWithSessionMessagesAndEventLogs(repos.Instance, repos.Message, repos.EventLog, sessionManager)
 
@@ -121,14 +165,44 @@ func main() {
 	}
 	logr.Info("media storage inicializado", zap.String("dir", mediaDir), zap.Duration("ttl", mediaTTL))
 
-	mediaHandler := handler.NewMediaHandler(mediaStorage)
+	mediaLimits := media.Limits{
+		Image:    int64(cfg.Media.MaxImageMB) << 20,
+		Video:    int64(cfg.Media.MaxVideoMB) << 20,
+		Audio:    int64(cfg.Media.MaxAudioMB) << 20,
+		Document: int64(cfg.Media.MaxDocumentMB) << 20,
+		Sticker:  int64(cfg.Media.MaxStickerMB) << 20,
+	}
+
+	mediaURLSecret := cfg.Storage.MediaURLSecret
+	if mediaURLSecret == "" {
+		mediaURLSecret = cfg.JWT.Secret
+	}
+	mediaSigner := mediaurl.NewSigner(mediaURLSecret, time.Duration(cfg.Storage.MediaURLTTLSeconds)*time.Second)
+	mediaHandler := handler.NewMediaHandlerWithAccess(mediaStorage, mediaSigner, instanceService)
 
 	logr.Debug("inicializando serviço de mensagens")
 	messageService := message.NewServiceWithSession(repos.Message, sessionManager, repos.Instance, repos.Contact, repos.EventLog, repos.OutboxQueue, repos.WebhookQueue, cfg.WhatsApp, logr)
+	messageService.SetInstanceLocker(repos.InstanceLock)
+	messageService.SetMediaStore(mediaStorage, repos.MediaRef)
+	messageService.SetMediaLimits(mediaLimits)
+	if cfg.LinkPreview.Enabled {
+		messageService.SetLinkPreviewer(linkpreview.NewFetcher(linkpreview.Options{
+			Timeout:       time.Duration(cfg.LinkPreview.TimeoutSeconds) * time.Second,
+			MaxPageBytes:  int64(cfg.LinkPreview.MaxPageKB) << 10,
+			MaxImageBytes: int64(cfg.LinkPreview.MaxImageKB) << 10,
+			UserAgent:     cfg.LinkPreview.UserAgent,
+		}))
+	}
+	go messageService.StartMediaRefPruning(context.Background(), time.Duration(cfg.Media.RefRetentionDays)*24*time.Hour)
 
 	logr.Info("inicializando sistema de webhooks")
 	instanceWebhookChecker := &instanceCheckerAdapter{repo: repos.Instance}
 	eventHandler := webhook.NewEventHandler(repos.WebhookQueue, logr, mediaStorage, repos.Message, cfg.App.BaseURL, instanceWebhookChecker, messageService)
+	eventHandler.SetMediaSigner(mediaSigner)
+	eventHandler.SetMediaLimits(mediaLimits)
+	eventHandler.SetMediaRefs(repos.MediaRef, instanceWebhookChecker)
+	eventHandler.SetMediaRetryListener(messageService)
+	eventHandler.SetBlocklistListener(messageService)
 	sessionManager.SetEventHandler(eventHandler)
 	logr.Info("event handler configurado")
 
@@ -138,30 +212,60 @@ func main() {
 
 	webhookDelivery := delivery.NewDelivery(logr, 3)
 	webhookPool := webhook.NewPool(repos.WebhookQueue, repos.Instance, webhookDelivery, logr, cfg.Webhook.Workers)
+	webhookPool.SetOrdering(cfg.Webhook.Ordering)
 	go webhookPool.Start(context.Background())
-	logr.Info("webhook pool iniciada", zap.Int("workers", cfg.Webhook.Workers))
+	logr.Info("webhook pool iniciada", zap.Int("workers", cfg.Webhook.Workers), zap.String("ordering", cfg.Webhook.Ordering))
+
+	owners.SetCandidates(func(ctx context.Context) ([]string, error) {
+		list, _, err := instanceService.List(ctx, "", 0, 0)
+		if err != nil {
+			return nil, err
+		}
+		ids := make([]string, 0, len(list))
+		for _, inst := range list {
+			if inst.WhatsAppJID != "" {
+				ids = append(ids, inst.ID)
+			}
+		}
+		return ids, nil
+	})
+	owners.OnAcquired(func(instanceID string) {
+		sessionManager.RestoreAllSessions(context.Background(), []string{instanceID})
+	})
+	// Started before the boot restore so this replica is counted in the
+	// cluster when the others compute their share.
+	owners.Start(context.Background())
 
 	logr.Info("restaurando sessões...")
 	instances, _, err := instanceService.List(context.Background(), "", 0, 0)
 	if err == nil {
-		var allInstanceIDs []string
+		ids := make([]string, 0, len(instances))
 		for _, inst := range instances {
-			allInstanceIDs = append(allInstanceIDs, inst.ID)
-		}
-		if len(allInstanceIDs) > 0 {
-			logr.Info("tentando restaurar sessões",
-				zap.Int("total", len(allInstanceIDs)),
-			)
-			sessionManager.RestoreAllSessions(context.Background(), allInstanceIDs)
-		} else {
-			logr.Info("nenhuma instância encontrada para restaurar")
+			ids = append(ids, inst.ID)
 		}
+		go func() {
+			// With several replicas each one only restores its share; the rest
+			// is adopted by the failover loop of the other replicas.
+			claimed := owners.ClaimShare(context.Background(), ids)
+			if len(claimed) == 0 {
+				logr.Info("nenhuma instância encontrada para restaurar")
+				return
+			}
+			logr.Info("tentando restaurar sessões", zap.Int("total", len(claimed)))
+			sessionManager.RestoreAllSessions(context.Background(), claimed)
+		}()
 	} else {
 		logr.Warn("erro ao listar instâncias para restauração", zap.Error(err))
 	}
 
 	logr.Debug("inicializando serviços")
 	outboxWorker := message.NewOutboxWorker(messageService, repos.OutboxQueue, logr, cfg.App.OutboxWorkers)
+	if repos.RedisClient != nil {
+		rdb := repos.RedisClient.RDB()
+		outboxWorker.SetForwarding(owners, func(nodeID string) queue.Queue {
+			return queue_redis.NewQueue(rdb, "message:outbox:node:"+nodeID)
+		})
+	}
 	outboxWorker.Start(context.Background())
 	logr.Info("outbox worker iniciado", zap.Int("workers", cfg.App.OutboxWorkers))
 	apiTokenService := api_token.NewService(repos.APIToken)
@@ -169,13 +273,27 @@ func main() {
 	authService := auth.NewService(cfg.JWT.Secret, cfg.JWT.ExpHours, repos.User)
 	logr.Debug("serviços inicializados")
 
-	instanceHandler := instancehandler.NewHandlerWithSession(instanceService, logr, sessionManager)
-	messageHandler := handler.NewMessageHandler(messageService)
+	instanceHandler := instancehandler.NewHandlerWithSessionAndHealth(instanceService, logr, sessionManager, messageService)
+	messageHandler := handler.NewMessageHandlerWithMediaLimits(messageService, mediaLimits, int64(cfg.Media.UploadMemoryMB)<<20)
 	whatsAppHandler := whatsapphandler.NewHandler(sessionManager, messageService)
 	authHandler := handler.NewAuthHandler(authService)
 	apiTokenHandler := handler.NewAPITokenHandler(apiTokenService)
 	userHandler := handler.NewUserHandler(userService)
-	healthHandler := handler.NewHealthHandler()
+	antibanHandler := handler.NewAntibanHandler(messageService, userService)
+	readiness := handler.ReadinessOptions{
+		DB:              repos.DB,
+		InstanceRepo:    repos.Instance,
+		Sessions:        sessionManager,
+		WebhookQueue:    repos.WebhookQueue,
+		WebhookQueueMax: cfg.Readiness.WebhookQueueMax,
+		MediaDir:        mediaDir,
+		Timeout:         time.Duration(cfg.Readiness.TimeoutSeconds) * time.Second,
+		UserService:     userService,
+	}
+	if repos.RedisClient != nil {
+		readiness.Redis = repos.RedisClient
+	}
+	healthHandler := handler.NewHealthHandlerWithReadiness(readiness)
 
 	rateLimitOpts := middleware.RateLimitOption{
 		Enabled:  cfg.RateLimit.Enabled,
@@ -199,9 +317,15 @@ func main() {
 		InstanceRepo:    repos.Instance,
 		HealthHandler:   healthHandler,
 		UserHandler:     userHandler,
+		AntibanHandler:  antibanHandler,
 		MediaHandler:    mediaHandler,
+		SchemaHandler:   handler.NewWebhookSchemaHandler(),
 		WebhookPool:     webhookPool,
 		RateLimit:       rateLimitOpts,
+		InstanceOwner: middleware.InstanceOwnerOption{
+			Ownership: owners,
+			Logger:    logr,
+		},
 	})
 
 	if cfg.Dashboard.Enabled {
@@ -211,6 +335,9 @@ func main() {
 			UserService:     userService,
 			APITokenService: apiTokenService,
 			SessionManager:  sessionManager,
+			Ownership:       owners,
+			Antiban:         messageService,
+			Health:          messageService,
 			JWTSecret:       cfg.JWT.Secret,
 			DocsDirectory:   ".",
 			BaseURL:         cfg.App.BaseURL,
@@ -258,6 +385,9 @@ func main() {
 	webhookPool.Stop()
 	logr.Info("webhook pool encerrada")
 
+	owners.Stop()
+	logr.Info("leases de instâncias liberados")
+
 	outboxWorker.Stop()
 	logr.Info("outbox worker encerrado")
 
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"time"
+
+	"github.com/open-apime/apime/internal/config"
+	"github.com/open-apime/apime/internal/logger"
+	"github.com/open-apime/apime/internal/pkg/crypto"
+	"github.com/open-apime/apime/internal/storage"
+	"github.com/open-apime/apime/internal/storage/model"
+)
+
+// runRotateKeys re-seals every stored session blob and webhook secret with the primary
+// key of the ring. Run it after putting a new key first in WHATSAPP_SESSION_KEYS (or the KEK
+// file) while keeping the old ones; once it reports nothing left, the old keys can be removed.
+func runRotateKeys(args []string) {
+	fs := flag.NewFlagSet("rotate-keys", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "Só contar o que seria recifrado")
+	_ = fs.Parse(args)
+
+	cfg := config.Load()
+	if cfg.WhatsApp.UsesDefaultSessionKey() {
+		log.Fatal("rotate-keys: a chave primária é a padrão; configure WHATSAPP_SESSION_KEYS ou WHATSAPP_SESSION_KEK_FILE com a nova chave primeiro")
+	}
+	logr, err := logger.New(cfg.App.Env, cfg.Log.Level)
+	if err != nil {
+		log.Fatalf("rotate-keys: logger: %v", err)
+	}
+	defer logr.Sync()
+
+	repos, err := storage.NewRepositories(cfg, logr)
+	if err != nil {
+		log.Fatalf("rotate-keys: storage: %v", err)
+	}
+	keys := repos.SessionKeys
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	// The sealed view: values are rotated as stored and written back with a compare-and-swap on
+	// the old ciphertext, so nothing else of the row is rewritten and a value changed meanwhile
+	// (a webhook secret rotated through the API) is left alone.
+	instances, _, err := repos.SealedInstance.List(ctx, "", 0, 0)
+	if err != nil {
+		log.Fatalf("rotate-keys: listar instâncias: %v", err)
+	}
+
+	var blobs, secrets, failed, changed int
+	for _, listed := range instances {
+		// List leaves the session blob out.
+		inst, err := repos.SealedInstance.GetByID(ctx, listed.ID)
+		if err != nil {
+			log.Printf("rotate-keys: instância %s: ler: %v", listed.ID, err)
+			failed++
+			continue
+		}
+		if len(inst.SessionBlob) > 0 && keys.NeedsRotation(inst.SessionBlob) {
+			rotated, err := keys.Rotate(inst.SessionBlob)
+			switch {
+			case err != nil:
+				log.Printf("rotate-keys: instância %s: session blob: %v", inst.ID, err)
+				failed++
+			case *dryRun:
+				blobs++
+			default:
+				ok, err := repos.SealedInstance.UpdateSessionBlob(ctx, inst.ID, inst.SessionBlob, rotated)
+				switch {
+				case err != nil:
+					log.Printf("rotate-keys: instância %s: gravar session blob: %v", inst.ID, err)
+					failed++
+				case !ok:
+					log.Printf("rotate-keys: instância %s: session blob alterado durante a rotação", inst.ID)
+					changed++
+				default:
+					blobs++
+				}
+			}
+		}
+
+		old := model.WebhookSecrets{Secret: inst.WebhookSecret, Previous: inst.WebhookPreviousSecret}
+		sealed := old
+		rotatedSecrets := 0
+		for _, secret := range []*string{&sealed.Secret, &sealed.Previous} {
+			if *secret == "" || !keys.StringNeedsRotation(*secret) {
+				continue
+			}
+			rotated, err := rotateString(keys, *secret)
+			if err != nil {
+				log.Printf("rotate-keys: instância %s: segredo do webhook: %v", inst.ID, err)
+				failed++
+				continue
+			}
+			*secret = rotated
+			rotatedSecrets++
+		}
+		if rotatedSecrets == 0 {
+			continue
+		}
+		if *dryRun {
+			secrets += rotatedSecrets
+			continue
+		}
+		ok, err := repos.SealedInstance.UpdateWebhookSecret(ctx, inst.ID, old, sealed)
+		switch {
+		case err != nil:
+			log.Printf("rotate-keys: instância %s: gravar segredo do webhook: %v", inst.ID, err)
+			failed += rotatedSecrets
+		case !ok:
+			log.Printf("rotate-keys: instância %s: segredo do webhook alterado durante a rotação", inst.ID)
+			changed++
+		default:
+			secrets += rotatedSecrets
+		}
+	}
+
+	verb := "recifrados"
+	if *dryRun {
+		verb = "a recifrar"
+	}
+	log.Printf("rotate-keys: chave primária %s; %d session blobs e %d segredos de webhook %s, %d falhas, %d alterados durante a rotação.",
+		keys.PrimaryID(), blobs, secrets, verb, failed, changed)
+	if failed > 0 {
+		log.Fatal("rotate-keys: há valores que nenhuma chave do chaveiro abre; mantenha as chaves antigas até resolvê-los")
+	}
+	if changed > 0 {
+		log.Fatal("rotate-keys: valores mudaram durante a rotação; rode o comando de novo")
+	}
+}
+
+// rotateString re-seals a stored text value with the primary key; values stored in clear are
+// sealed for the first time.
+func rotateString(keys *crypto.KeyRing, value string) (string, error) {
+	plain, err := keys.OpenString(value)
+	if err != nil {
+		return "", err
+	}
+	return keys.SealString(plain)
+}
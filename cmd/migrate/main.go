@@ -19,6 +19,17 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "import-session":
+			runImportSession(os.Args[2:])
+			return
+		case "rotate-keys":
+			runRotateKeys(os.Args[2:])
+			return
+		}
+	}
+
 	migrationsDir := flag.String("migrations", "db/migrations/postgres", "Diretório de migrations PostgreSQL")
 	migrationsSQLiteDir := flag.String("migrations-sqlite", "db/migrations/sqlite", "Diretório de migrations SQLite")
 	seedsDir := flag.String("seeds", "db/seeds/postgres", "Diretório de seeds PostgreSQL")
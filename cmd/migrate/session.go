@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/open-apime/apime/internal/config"
+	"github.com/open-apime/apime/internal/logger"
+	"github.com/open-apime/apime/internal/service/instance"
+	"github.com/open-apime/apime/internal/session/whatsmeow"
+	"github.com/open-apime/apime/internal/storage"
+)
+
+// runImportSession restores a session bundle exported by POST /instances/{id}/export into this
+// deployment's stores, with the API stopped. The device is connected by the API on its next start.
+func runImportSession(args []string) {
+	fs := flag.NewFlagSet("import-session", flag.ExitOnError)
+	file := fs.String("file", "", "Pacote exportado (.session)")
+	passphrase := fs.String("passphrase", os.Getenv("APIME_SESSION_PASSPHRASE"), "Senha do pacote (ou APIME_SESSION_PASSPHRASE)")
+	owner := fs.String("owner", "", "E-mail do usuário dono da instância, quando ela ainda não existe aqui")
+	force := fs.Bool("force", false, "Aceitar pacote exportado sem liberar a origem (keepSource)")
+	_ = fs.Parse(args)
+
+	if *file == "" || *passphrase == "" {
+		log.Fatal("import-session: informe -file e -passphrase")
+	}
+	data, err := os.ReadFile(*file)
+	if err != nil {
+		log.Fatalf("import-session: ler pacote: %v", err)
+	}
+
+	cfg := config.Load()
+	logr, err := logger.New(cfg.App.Env, cfg.Log.Level)
+	if err != nil {
+		log.Fatalf("import-session: logger: %v", err)
+	}
+	defer logr.Sync()
+
+	repos, err := storage.NewRepositories(cfg, logr)
+	if err != nil {
+		log.Fatalf("import-session: storage: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	ownerID := ""
+	if *owner != "" {
+		user, err := repos.User.GetByEmail(ctx, *owner)
+		if err != nil {
+			log.Fatalf("import-session: usuário %s: %v", *owner, err)
+		}
+		ownerID = user.ID
+	}
+
+	pgConnString := ""
+	if cfg.Storage.Driver == "postgres" {
+		pgConnString = cfg.DB.DSN()
+	}
+	sessionManager := whatsmeow.NewManager(logr, repos.SessionKeys, cfg.Storage.Driver, filepath.Join(cfg.Storage.DataDir, "sessions"),
+		pgConnString, repos.Instance, repos.HistorySync, repos.Message, repos.EventLog)
+	instanceService := instance.NewServiceWithSessionMessagesAndEventLogs(repos.Instance, repos.Message, repos.EventLog, sessionManager)
+
+	result, err := instanceService.Import(ctx, instance.ImportInput{
+		Bundle:      data,
+		Passphrase:  *passphrase,
+		OwnerUserID: ownerID,
+		UserRole:    "admin",
+		Force:       *force,
+		Offline:     true,
+	})
+	if err != nil {
+		log.Fatalf("import-session: %v", err)
+	}
+	log.Printf("import-session: instância %s (%s) importada com o número %s; será conectada no próximo início da API.",
+		result.Instance.ID, result.Instance.Name, result.Instance.WhatsAppJID)
+}
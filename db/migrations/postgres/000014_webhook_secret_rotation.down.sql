@@ -0,0 +1,2 @@
+ALTER TABLE instances DROP COLUMN IF EXISTS webhook_previous_secret_expires_at;
+ALTER TABLE instances DROP COLUMN IF EXISTS webhook_previous_secret;
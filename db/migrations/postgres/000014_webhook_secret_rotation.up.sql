@@ -0,0 +1,3 @@
+-- Previous webhook secret, still signed alongside the current one until it expires
+ALTER TABLE instances ADD COLUMN IF NOT EXISTS webhook_previous_secret TEXT;
+ALTER TABLE instances ADD COLUMN IF NOT EXISTS webhook_previous_secret_expires_at TIMESTAMPTZ;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS media_refs;
+ALTER TABLE instances DROP COLUMN IF EXISTS media_policy;
@@ -0,0 +1,20 @@
+-- What happens to inbound media: eager (download on arrival), lazy (download on request) or none
+ALTER TABLE instances ADD COLUMN IF NOT EXISTS media_policy TEXT NOT NULL DEFAULT 'eager';
+
+-- Enough of each inbound media message to download it later, or to ask the sender to re-upload it
+CREATE TABLE IF NOT EXISTS media_refs (
+    instance_id UUID NOT NULL REFERENCES instances(id) ON DELETE CASCADE,
+    message_id TEXT NOT NULL,
+    chat_jid TEXT NOT NULL,
+    sender_jid TEXT NOT NULL,
+    from_me BOOLEAN NOT NULL DEFAULT false,
+    kind TEXT NOT NULL,
+    mimetype TEXT NOT NULL DEFAULT '',
+    message BYTEA NOT NULL,
+    media_id TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (instance_id, message_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_media_refs_created_at ON media_refs(created_at);
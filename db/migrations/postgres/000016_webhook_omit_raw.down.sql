@@ -0,0 +1 @@
+ALTER TABLE instances DROP COLUMN IF EXISTS webhook_omit_raw;
@@ -0,0 +1,2 @@
+-- Leave the whatsmeow "raw" event out of the webhook payloads
+ALTER TABLE instances ADD COLUMN IF NOT EXISTS webhook_omit_raw BOOLEAN NOT NULL DEFAULT FALSE;
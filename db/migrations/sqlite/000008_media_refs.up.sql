@@ -0,0 +1,21 @@
+-- What happens to inbound media: eager (download on arrival), lazy (download on request) or none
+ALTER TABLE instances ADD COLUMN media_policy TEXT NOT NULL DEFAULT 'eager';
+
+-- Enough of each inbound media message to download it later, or to ask the sender to re-upload it
+CREATE TABLE IF NOT EXISTS media_refs (
+    instance_id TEXT NOT NULL,
+    message_id TEXT NOT NULL,
+    chat_jid TEXT NOT NULL,
+    sender_jid TEXT NOT NULL,
+    from_me INTEGER NOT NULL DEFAULT 0,
+    kind TEXT NOT NULL,
+    mimetype TEXT NOT NULL DEFAULT '',
+    message BLOB NOT NULL,
+    media_id TEXT NOT NULL DEFAULT '',
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL,
+    PRIMARY KEY (instance_id, message_id),
+    FOREIGN KEY (instance_id) REFERENCES instances(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_media_refs_created_at ON media_refs(created_at);
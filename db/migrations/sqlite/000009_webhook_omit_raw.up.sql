@@ -0,0 +1,2 @@
+-- Leave the whatsmeow "raw" event out of the webhook payloads
+ALTER TABLE instances ADD COLUMN webhook_omit_raw INTEGER NOT NULL DEFAULT 0;
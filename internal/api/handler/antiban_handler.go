@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/open-apime/apime/internal/api/middleware"
+	"github.com/open-apime/apime/internal/pkg/response"
+	messageSvc "github.com/open-apime/apime/internal/service/message"
+	userSvc "github.com/open-apime/apime/internal/service/user"
+)
+
+// AntibanHandler exposes the reach-out locks and the JID resolution cache to
+// operators (admin only).
+type AntibanHandler struct {
+	service     *messageSvc.Service
+	userService *userSvc.Service
+}
+
+func NewAntibanHandler(service *messageSvc.Service, userService *userSvc.Service) *AntibanHandler {
+	return &AntibanHandler{service: service, userService: userService}
+}
+
+func (h *AntibanHandler) Register(r *gin.RouterGroup) {
+	admin := r.Group("/admin")
+	admin.Use(middleware.RequireAdmin(h.userService))
+
+	admin.GET("/reachout-locks", h.listReachoutLocks)
+	admin.DELETE("/reachout-locks/:instanceId/:contact", h.clearReachoutLock)
+	admin.GET("/jid-cache", h.listJIDCache)
+	admin.DELETE("/jid-cache", h.purgeJIDCache)
+	admin.GET("/jid-cache/:phone", h.lookupJID)
+	admin.DELETE("/jid-cache/:phone", h.purgeJID)
+}
+
+func (h *AntibanHandler) listReachoutLocks(c *gin.Context) {
+	locks, err := h.service.ListReachoutLocks(c.Request.Context(), c.Query("instanceId"), c.Query("contact"))
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+	response.Success(c, http.StatusOK, locks)
+}
+
+func (h *AntibanHandler) clearReachoutLock(c *gin.Context) {
+	removed, err := h.service.ClearReachoutLock(c.Request.Context(), c.Param("instanceId"), c.Param("contact"))
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	if !removed {
+		response.ErrorWithMessage(c, http.StatusNotFound, "bloqueio não encontrado")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func jidCacheKind(c *gin.Context) (string, bool) {
+	kind := c.Query("type")
+	switch kind {
+	case "", "positive", "negative":
+		return kind, true
+	}
+	response.ErrorWithMessage(c, http.StatusBadRequest, "type deve ser positive ou negative")
+	return "", false
+}
+
+func (h *AntibanHandler) listJIDCache(c *gin.Context) {
+	kind, ok := jidCacheKind(c)
+	if !ok {
+		return
+	}
+	entries, err := h.service.ListJIDCache(c.Request.Context(), kind)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+	response.Success(c, http.StatusOK, entries)
+}
+
+func (h *AntibanHandler) purgeJIDCache(c *gin.Context) {
+	kind, ok := jidCacheKind(c)
+	if !ok {
+		return
+	}
+	removed, err := h.service.PurgeJIDCache(c.Request.Context(), kind)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+	response.Success(c, http.StatusOK, gin.H{"removed": removed})
+}
+
+func (h *AntibanHandler) lookupJID(c *gin.Context) {
+	lookup, err := h.service.LookupJID(c.Request.Context(), c.Param("phone"))
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	response.Success(c, http.StatusOK, lookup)
+}
+
+func (h *AntibanHandler) purgeJID(c *gin.Context) {
+	removed, err := h.service.PurgeJID(c.Request.Context(), c.Param("phone"))
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	if !removed {
+		response.ErrorWithMessage(c, http.StatusNotFound, "telefone não está no cache")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AntibanHandler) fail(c *gin.Context, err error) {
+	if errors.Is(err, messageSvc.ErrInvalidJID) {
+		response.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	response.Error(c, http.StatusInternalServerError, err)
+}
@@ -1,19 +1,75 @@
 package handler
 
 import (
+	"context"
+	"fmt"
 	"net/http"
+	"os"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/open-apime/apime/internal/api/middleware"
 	"github.com/open-apime/apime/internal/config"
+	"github.com/open-apime/apime/internal/pkg/queue"
+	userSvc "github.com/open-apime/apime/internal/service/user"
+	"github.com/open-apime/apime/internal/storage"
+	"github.com/open-apime/apime/internal/storage/model"
 )
 
-type HealthHandler struct{}
+// ReadinessSessions is the slice of the session manager that /readyz needs.
+type ReadinessSessions interface {
+	ListInstances() []string
+	IsSessionReady(instanceID string) bool
+}
+
+// ReadinessOptions wires the dependencies probed by /readyz. A nil field skips
+// its check, so the zero value keeps /readyz equivalent to /healthz.
+type ReadinessOptions struct {
+	DB              storage.Pinger
+	Redis           storage.Pinger
+	InstanceRepo    storage.InstanceRepository
+	Sessions        ReadinessSessions
+	WebhookQueue    queue.Queue
+	WebhookQueueMax int
+	MediaDir        string
+	Timeout         time.Duration
+	// UserService gates the detailed report, which lists instance IDs and
+	// dependency errors; without it only the public status is served.
+	UserService *userSvc.Service
+}
+
+type HealthHandler struct {
+	ready ReadinessOptions
+}
 
 func NewHealthHandler() *HealthHandler {
 	return &HealthHandler{}
 }
 
+func NewHealthHandlerWithReadiness(opts ReadinessOptions) *HealthHandler {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 3 * time.Second
+	}
+	return &HealthHandler{ready: opts}
+}
+
+type readinessCheck struct {
+	Status    string `json:"status"`
+	LatencyMs int64  `json:"latencyMs"`
+	Error     string `json:"error,omitempty"`
+	Detail    any    `json:"detail,omitempty"`
+}
+
+type sessionSummary struct {
+	Total          int            `json:"total"`
+	ByStatus       map[string]int `json:"byStatus"`
+	InMemory       int            `json:"inMemory"`
+	Ready          int            `json:"ready"`
+	ActiveNotReady []string       `json:"activeNotReady,omitempty"`
+}
+
 func (h *HealthHandler) Register(r *gin.RouterGroup) {
 	r.Match([]string{"GET", "HEAD"}, "/", func(c *gin.Context) {
 		if c.Request.Method == http.MethodHead {
@@ -37,4 +93,166 @@ func (h *HealthHandler) Register(r *gin.RouterGroup) {
 			"version": config.Version,
 		})
 	})
+
+	r.Match([]string{"GET", "HEAD"}, "/readyz", h.readyz)
+}
+
+// RegisterProtected mounts the detailed readiness report under the admin
+// group. It must be called on an authenticated group.
+func (h *HealthHandler) RegisterProtected(r *gin.RouterGroup) {
+	if h.ready.UserService == nil {
+		return
+	}
+	admin := r.Group("/admin")
+	admin.Use(middleware.RequireAdmin(h.ready.UserService))
+	admin.GET("/readyz", h.readyzDetails)
+}
+
+// readyz is public, so it answers with the overall status only: per-check
+// errors and instance IDs stay behind /admin/readyz.
+func (h *HealthHandler) readyz(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.ready.Timeout)
+	defer cancel()
+
+	status, overall, _ := h.runChecks(ctx)
+	if c.Request.Method == http.MethodHead {
+		c.Status(status)
+		return
+	}
+	c.JSON(status, gin.H{"status": overall})
+}
+
+func (h *HealthHandler) readyzDetails(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.ready.Timeout)
+	defer cancel()
+
+	status, overall, results := h.runChecks(ctx)
+	body := gin.H{
+		"status":  overall,
+		"version": config.Version,
+		"checks":  results,
+	}
+	if summary, err := h.sessionSummary(ctx); err == nil && summary != nil {
+		body["sessions"] = summary
+	} else if err != nil {
+		body["sessions"] = gin.H{"error": err.Error()}
+	}
+	c.JSON(status, body)
+}
+
+// runChecks runs every configured check in parallel under a shared timeout.
+// Any failing dependency turns the status into 503; the session summary is
+// informational only, since a phone being offline is not the replica's fault.
+func (h *HealthHandler) runChecks(ctx context.Context) (int, string, map[string]readinessCheck) {
+	checks := map[string]func(context.Context) (any, error){}
+	if h.ready.DB != nil {
+		checks["database"] = func(ctx context.Context) (any, error) {
+			return nil, h.ready.DB.Ping(ctx)
+		}
+	}
+	if h.ready.Redis != nil {
+		checks["redis"] = func(ctx context.Context) (any, error) {
+			return nil, h.ready.Redis.Ping(ctx)
+		}
+	}
+	if h.ready.MediaDir != "" {
+		checks["media"] = func(context.Context) (any, error) {
+			return gin.H{"dir": h.ready.MediaDir}, probeWritable(h.ready.MediaDir)
+		}
+	}
+	if h.ready.WebhookQueue != nil {
+		checks["webhookQueue"] = h.checkWebhookQueue
+	}
+
+	results := make(map[string]readinessCheck, len(checks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, fn := range checks {
+		wg.Add(1)
+		go func(name string, fn func(context.Context) (any, error)) {
+			defer wg.Done()
+			start := time.Now()
+			detail, err := fn(ctx)
+			res := readinessCheck{Status: "ok", LatencyMs: time.Since(start).Milliseconds(), Detail: detail}
+			if err != nil {
+				res.Status = "fail"
+				res.Error = err.Error()
+			}
+			mu.Lock()
+			results[name] = res
+			mu.Unlock()
+		}(name, fn)
+	}
+	wg.Wait()
+
+	ready := true
+	for _, res := range results {
+		if res.Status != "ok" {
+			ready = false
+			break
+		}
+	}
+
+	if !ready {
+		return http.StatusServiceUnavailable, "fail", results
+	}
+	return http.StatusOK, "ok", results
+}
+
+func (h *HealthHandler) checkWebhookQueue(ctx context.Context) (any, error) {
+	size, err := h.ready.WebhookQueue.Size(ctx)
+	if err != nil {
+		return nil, err
+	}
+	detail := gin.H{"size": size, "max": h.ready.WebhookQueueMax}
+	if h.ready.WebhookQueueMax > 0 && size > int64(h.ready.WebhookQueueMax) {
+		return detail, fmt.Errorf("fila de webhooks acima do limite (%d > %d)", size, h.ready.WebhookQueueMax)
+	}
+	return detail, nil
+}
+
+func (h *HealthHandler) sessionSummary(ctx context.Context) (*sessionSummary, error) {
+	if h.ready.InstanceRepo == nil && h.ready.Sessions == nil {
+		return nil, nil
+	}
+
+	summary := &sessionSummary{ByStatus: map[string]int{}}
+	readySet := map[string]bool{}
+	if h.ready.Sessions != nil {
+		ids := h.ready.Sessions.ListInstances()
+		summary.InMemory = len(ids)
+		for _, id := range ids {
+			if h.ready.Sessions.IsSessionReady(id) {
+				readySet[id] = true
+				summary.Ready++
+			}
+		}
+	}
+
+	if h.ready.InstanceRepo != nil {
+		instances, total, err := h.ready.InstanceRepo.List(ctx, "", 0, 0)
+		if err != nil {
+			return nil, err
+		}
+		summary.Total = total
+		for _, inst := range instances {
+			summary.ByStatus[string(inst.Status)]++
+			if inst.Status == model.InstanceStatusActive && !readySet[inst.ID] {
+				summary.ActiveNotReady = append(summary.ActiveNotReady, inst.ID)
+			}
+		}
+	}
+	return summary, nil
+}
+
+// probeWritable creates and removes a file, which is the only reliable way to
+// catch a read-only remount or a full disk.
+func probeWritable(dir string) error {
+	f, err := os.CreateTemp(dir, ".readyz-*")
+	if err != nil {
+		return fmt.Errorf("diretório sem escrita: %w", err)
+	}
+	name := f.Name()
+	_ = f.Close()
+	return os.Remove(name)
 }
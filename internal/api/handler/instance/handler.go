@@ -11,12 +11,14 @@ import (
 
 	"github.com/open-apime/apime/internal/pkg/response"
 	instanceSvc "github.com/open-apime/apime/internal/service/instance"
+	"github.com/open-apime/apime/internal/storage/model"
 )
 
 type Handler struct {
 	service        *instanceSvc.Service
 	log            *zap.Logger
 	sessionManager SessionManager
+	health         HealthReporter
 }
 
 type SessionManager interface {
@@ -35,6 +37,15 @@ func NewHandlerWithSession(service *instanceSvc.Service, log *zap.Logger, sessio
 	}
 }
 
+func NewHandlerWithSessionAndHealth(service *instanceSvc.Service, log *zap.Logger, sessionManager SessionManager, health HealthReporter) *Handler {
+	return &Handler{
+		service:        service,
+		log:            log,
+		sessionManager: sessionManager,
+		health:         health,
+	}
+}
+
 func (h *Handler) Register(r *gin.RouterGroup) {
 	r.GET("/instances", h.list)
 	r.GET("/instances/:id", h.get)
@@ -42,6 +53,7 @@ func (h *Handler) Register(r *gin.RouterGroup) {
 	r.PUT("/instances/:id", h.update)
 	r.DELETE("/instances/:id", h.delete)
 	r.POST("/instances/:id/token/rotate", h.rotateToken)
+	r.POST("/instances/:id/webhook-secret/rotate", h.rotateWebhookSecret)
 	r.GET("/instances/:id/qr", h.getQR)
 	r.POST("/instances/:id/disconnect", h.disconnect)
 	r.GET("/instances/:id/info", h.getInstanceInfo)
@@ -49,12 +61,21 @@ func (h *Handler) Register(r *gin.RouterGroup) {
 	r.GET("/instances/:id/business/:jid", h.getBusinessProfile)
 	r.GET("/instances/:id/profile/:jid/picture", h.getProfilePicture)
 	r.GET("/instances/:id/events", h.listEvents)
+	r.POST("/instances/:id/export", h.export)
+	r.POST("/instances/:id/export/confirm", h.confirmExport)
+	r.POST("/instances/:id/export/cancel", h.cancelExport)
+	r.POST("/instances/import", h.importBundle)
+	if h.health != nil {
+		r.GET("/instances/:id/health", h.getHealth)
+	}
 }
 
 type createInstanceRequest struct {
 	Name          string `json:"name" binding:"required,min=2"`
 	WebhookURL    string `json:"webhook_url"`
 	WebhookSecret string `json:"webhook_secret"`
+	MediaPolicy   string `json:"media_policy"`
+	OmitRaw       bool   `json:"webhook_omit_raw"`
 }
 
 // Pointers to tell an omitted field (preserve current value) from one sent empty (clear it).
@@ -63,6 +84,8 @@ type updateInstanceRequest struct {
 	Name          string  `json:"name" binding:"required,min=2"`
 	WebhookURL    *string `json:"webhook_url"`
 	WebhookSecret *string `json:"webhook_secret"`
+	MediaPolicy   *string `json:"media_policy"`
+	OmitRaw       *bool   `json:"webhook_omit_raw"`
 }
 
 func (h *Handler) create(c *gin.Context) {
@@ -82,6 +105,8 @@ func (h *Handler) create(c *gin.Context) {
 		Name:          req.Name,
 		WebhookURL:    req.WebhookURL,
 		WebhookSecret: req.WebhookSecret,
+		MediaPolicy:   model.MediaPolicy(req.MediaPolicy),
+		OmitRaw:       req.OmitRaw,
 		OwnerUserID:   userID,
 	})
 	if err != nil {
@@ -151,10 +176,18 @@ func (h *Handler) update(c *gin.Context) {
 		callerID = "admin"
 	}
 
+	var mediaPolicy *model.MediaPolicy
+	if req.MediaPolicy != nil {
+		policy := model.MediaPolicy(*req.MediaPolicy)
+		mediaPolicy = &policy
+	}
+
 	inst, err := h.service.UpdateByUser(c.Request.Context(), id, instanceSvc.UpdateInput{
 		Name:          req.Name,
 		WebhookURL:    req.WebhookURL,
 		WebhookSecret: req.WebhookSecret,
+		MediaPolicy:   mediaPolicy,
+		OmitRaw:       req.OmitRaw,
 		OwnerUserID:   callerID,
 	})
 	if err != nil {
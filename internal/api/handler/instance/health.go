@@ -0,0 +1,38 @@
+package instance
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/open-apime/apime/internal/pkg/response"
+	"github.com/open-apime/apime/internal/storage/model"
+)
+
+// HealthReporter computes the number health report of an instance.
+type HealthReporter interface {
+	InstanceHealth(ctx context.Context, instanceID string) (model.InstanceHealth, error)
+}
+
+func (h *Handler) getHealth(c *gin.Context) {
+	id := c.Param("id")
+	if c.GetString("authType") == "instance_token" {
+		if c.GetString("instanceID") != id {
+			response.ErrorWithMessage(c, http.StatusForbidden, "token inválido para esta instância")
+			return
+		}
+	} else if _, err := h.service.GetByUser(c.Request.Context(), id, c.GetString("userID"), c.GetString("userRole")); err != nil {
+		response.Error(c, http.StatusNotFound, err)
+		return
+	}
+
+	report, err := h.health.InstanceHealth(c.Request.Context(), id)
+	if err != nil {
+		h.log.Error("erro ao calcular saúde da instância", zap.String("instance_id", id), zap.Error(err))
+		response.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+	response.Success(c, http.StatusOK, report)
+}
@@ -1,29 +1,51 @@
 package instance
 
 import (
+	"context"
 	"sync"
 	"time"
+
+	"github.com/open-apime/apime/internal/pkg/cache"
+	cache_memory "github.com/open-apime/apime/internal/pkg/cache/memory"
 )
 
 // pictureNegTTL is how long a JID rejected by the server for picture queries
 // stays cached before being retried.
 const pictureNegTTL = 6 * time.Hour
 
-var pictureNegCache sync.Map // jid string -> expiry time.Time
+const pictureNegPrefix = "picture_neg:"
 
-func pictureNegHit(jid string) bool {
-	v, ok := pictureNegCache.Load(jid)
-	if !ok {
-		return false
-	}
-	exp, _ := v.(time.Time)
-	if time.Now().After(exp) {
-		pictureNegCache.Delete(jid)
-		return false
+var (
+	pictureCacheMu sync.RWMutex
+	pictureCache   cache.Cache = cache_memory.New()
+)
+
+// SetPictureCache shares the negative cache between replicas (Redis) so a
+// rejected JID is not re-queried by each one.
+func SetPictureCache(c cache.Cache) {
+	if c == nil {
+		return
 	}
-	return true
+	pictureCacheMu.Lock()
+	pictureCache = c
+	pictureCacheMu.Unlock()
+}
+
+func getPictureCache() cache.Cache {
+	pictureCacheMu.RLock()
+	defer pictureCacheMu.RUnlock()
+	return pictureCache
+}
+
+func pictureNegHit(jid string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_, ok, err := getPictureCache().Get(ctx, pictureNegPrefix+jid)
+	return err == nil && ok
 }
 
 func pictureNegStore(jid string) {
-	pictureNegCache.Store(jid, time.Now().Add(pictureNegTTL))
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_ = getPictureCache().Set(ctx, pictureNegPrefix+jid, "1", pictureNegTTL)
 }
@@ -12,6 +12,7 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/open-apime/apime/internal/pkg/response"
+	messageSvc "github.com/open-apime/apime/internal/service/message"
 	"github.com/open-apime/apime/internal/storage/model"
 )
 
@@ -79,6 +80,15 @@ func (h *Handler) getInstanceInfo(c *gin.Context) {
 	if isLoggedIn && client.Store != nil && client.Store.ID != nil {
 		instanceJID := client.Store.ID.String()
 		responseData["instanceJID"] = instanceJID
+		responseData["pushName"] = client.Store.PushName
+		if client.Store.BusinessName != "" {
+			responseData["businessName"] = client.Store.BusinessName
+		}
+
+		if info, ok := h.ownUserInfo(c.Request.Context(), instanceID, client); ok {
+			responseData["about"] = info.About
+			responseData["isBusiness"] = info.IsBusiness
+		}
 
 		profilePic, err := client.GetProfilePictureInfo(c.Request.Context(), *client.Store.ID, nil)
 		if err == nil && profilePic != nil {
@@ -256,3 +266,26 @@ func (h *Handler) getProfilePicture(c *gin.Context) {
 	// No error, but the lib may return nil when there is no picture.
 	response.Success(c, http.StatusOK, pictureInfo)
 }
+
+// ownUserInfo returns the about and business flag of the instance's account, from the shared
+// cache or a USync query. A failed query is not cached.
+func (h *Handler) ownUserInfo(ctx context.Context, instanceID string, client *whatsmeow.Client) (messageSvc.OwnInfo, bool) {
+	ownJID := client.Store.ID.ToNonAD()
+	if info, ok := messageSvc.CachedOwnInfo(ownJID); ok {
+		return info, true
+	}
+	userInfo, err := client.GetUserInfo(ctx, []types.JID{ownJID})
+	if err != nil {
+		h.log.Debug("não foi possível obter o recado da instância",
+			zap.String("instance_id", instanceID),
+			zap.Error(err))
+		return messageSvc.OwnInfo{}, false
+	}
+	found, ok := userInfo[ownJID]
+	if !ok {
+		return messageSvc.OwnInfo{}, false
+	}
+	info := messageSvc.OwnInfo{About: found.Status, IsBusiness: found.VerifiedName != nil}
+	messageSvc.StoreOwnInfo(ownJID, info)
+	return info, true
+}
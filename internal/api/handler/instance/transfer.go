@@ -0,0 +1,160 @@
+package instance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/open-apime/apime/internal/pkg/response"
+	"github.com/open-apime/apime/internal/pkg/sessionbundle"
+	instanceSvc "github.com/open-apime/apime/internal/service/instance"
+	"github.com/open-apime/apime/internal/storage"
+	"github.com/open-apime/apime/internal/storage/model"
+)
+
+// maxBundleSize bounds the uploaded bundle; a device store with its contacts is a few MB at most.
+const maxBundleSize = 64 << 20
+
+type exportInstanceRequest struct {
+	Passphrase string `json:"passphrase" binding:"required"`
+	KeepSource bool   `json:"keepSource"`
+}
+
+func (h *Handler) export(c *gin.Context) {
+	id := c.Param("id")
+	if c.GetString("authType") == "instance_token" {
+		response.ErrorWithMessage(c, http.StatusForbidden, "endpoint disponível apenas com token de usuário")
+		return
+	}
+	var req exportInstanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err)
+		return
+	}
+
+	data, err := h.service.ExportByUser(c.Request.Context(), id, c.GetString("userID"), c.GetString("userRole"), instanceSvc.ExportInput{
+		Passphrase: req.Passphrase,
+		KeepSource: req.KeepSource,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			response.Error(c, http.StatusNotFound, err)
+		case errors.Is(err, sessionbundle.ErrWeakPassphrase):
+			response.Error(c, http.StatusBadRequest, err)
+		default:
+			h.log.Error("erro ao exportar instância", zap.String("instance_id", id), zap.Error(err))
+			response.Error(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	h.log.Info("instância exportada", zap.String("instance_id", id), zap.Bool("keep_source", req.KeepSource))
+	filename := fmt.Sprintf("apime-%s-%s.session", id, time.Now().UTC().Format("20060102-150405"))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "application/octet-stream", data)
+}
+
+func (h *Handler) confirmExport(c *gin.Context) {
+	h.finishExport(c, "confirmar", h.service.ConfirmExportByUser)
+}
+
+func (h *Handler) cancelExport(c *gin.Context) {
+	h.finishExport(c, "cancelar", h.service.CancelExportByUser)
+}
+
+func (h *Handler) finishExport(c *gin.Context, action string, finish func(ctx context.Context, id, userID, userRole string) (model.Instance, error)) {
+	id := c.Param("id")
+	if c.GetString("authType") == "instance_token" {
+		response.ErrorWithMessage(c, http.StatusForbidden, "endpoint disponível apenas com token de usuário")
+		return
+	}
+
+	inst, err := finish(c.Request.Context(), id, c.GetString("userID"), c.GetString("userRole"))
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			response.Error(c, http.StatusNotFound, err)
+		case errors.Is(err, instanceSvc.ErrNoHandover):
+			response.Error(c, http.StatusConflict, err)
+		default:
+			h.log.Error("erro ao "+action+" exportação", zap.String("instance_id", id), zap.Error(err))
+			response.Error(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	h.log.Info("exportação finalizada", zap.String("instance_id", id), zap.String("action", action))
+	response.Success(c, http.StatusOK, inst)
+}
+
+func (h *Handler) importBundle(c *gin.Context) {
+	if c.GetString("authType") == "instance_token" {
+		response.ErrorWithMessage(c, http.StatusForbidden, "endpoint disponível apenas com token de usuário")
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "arquivo não fornecido")
+		return
+	}
+	if file.Size > maxBundleSize {
+		response.ErrorWithMessage(c, http.StatusRequestEntityTooLarge, "pacote de sessão muito grande")
+		return
+	}
+	src, err := file.Open()
+	if err != nil {
+		response.ErrorWithMessage(c, http.StatusInternalServerError, "erro ao abrir arquivo")
+		return
+	}
+	defer src.Close()
+	data, err := io.ReadAll(io.LimitReader(src, maxBundleSize))
+	if err != nil {
+		response.ErrorWithMessage(c, http.StatusInternalServerError, "erro ao ler arquivo")
+		return
+	}
+	force, _ := strconv.ParseBool(c.PostForm("force"))
+
+	result, err := h.service.Import(c.Request.Context(), instanceSvc.ImportInput{
+		Bundle:      data,
+		Passphrase:  c.PostForm("passphrase"),
+		OwnerUserID: c.GetString("userID"),
+		UserRole:    c.GetString("userRole"),
+		Force:       force,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sessionbundle.ErrInvalidBundle), errors.Is(err, sessionbundle.ErrUnsupportedVersion):
+			response.Error(c, http.StatusBadRequest, err)
+		case errors.Is(err, storage.ErrNotFound):
+			response.Error(c, http.StatusNotFound, err)
+		case errors.Is(err, instanceSvc.ErrDeviceInUse), errors.Is(err, instanceSvc.ErrSessionActive),
+			errors.Is(err, instanceSvc.ErrNotHandover), errors.Is(err, sessionbundle.ErrDeviceExists):
+			response.Error(c, http.StatusConflict, err)
+		default:
+			h.log.Error("erro ao importar instância", zap.Error(err))
+			response.Error(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	if result.ConnectError != "" {
+		h.log.Warn("instância importada, mas a conexão falhou",
+			zap.String("instance_id", result.Instance.ID),
+			zap.String("error", result.ConnectError))
+	}
+	response.Success(c, http.StatusCreated, gin.H{
+		"instance":     result.Instance,
+		"connected":    result.Connected,
+		"connectError": result.ConnectError,
+	})
+}
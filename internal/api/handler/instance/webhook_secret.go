@@ -0,0 +1,56 @@
+package instance
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/open-apime/apime/internal/pkg/response"
+	instanceSvc "github.com/open-apime/apime/internal/service/instance"
+	"github.com/open-apime/apime/internal/storage"
+)
+
+type rotateWebhookSecretRequest struct {
+	Secret     string `json:"secret"`
+	GraceHours *int   `json:"graceHours"`
+}
+
+func (h *Handler) rotateWebhookSecret(c *gin.Context) {
+	id := c.Param("id")
+	if c.GetString("authType") == "instance_token" {
+		response.ErrorWithMessage(c, http.StatusForbidden, "endpoint disponível apenas com token de usuário")
+		return
+	}
+	// The body is optional: without it a secret is generated and the previous one lasts 24h.
+	var req rotateWebhookSecretRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		response.Error(c, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := h.service.RotateWebhookSecretByUser(c.Request.Context(), id, c.GetString("userID"), c.GetString("userRole"), instanceSvc.RotateWebhookSecretInput{
+		Secret:     req.Secret,
+		GraceHours: req.GraceHours,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			response.Error(c, http.StatusNotFound, err)
+		case errors.Is(err, instanceSvc.ErrInvalidGracePeriod):
+			response.Error(c, http.StatusBadRequest, err)
+		default:
+			h.log.Error("erro ao rotacionar segredo do webhook", zap.String("instance_id", id), zap.Error(err))
+			response.Error(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	h.log.Info("segredo do webhook rotacionado", zap.String("instance_id", id))
+	response.Success(c, http.StatusOK, gin.H{
+		"secret":                  result.Secret,
+		"previousSecretExpiresAt": result.PreviousSecretExpiresAt,
+	})
+}
@@ -1,16 +1,28 @@
 package handler
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/open-apime/apime/internal/pkg/mediaurl"
 	"github.com/open-apime/apime/internal/storage/media"
+	"github.com/open-apime/apime/internal/storage/model"
 )
 
 // MediaHandler handles media requests
 type MediaHandler struct {
-	storage *media.Storage
+	storage   *media.Storage
+	signer    *mediaurl.Signer
+	instances InstanceAccess
+}
+
+// InstanceAccess resolves an instance for a user, failing unless they own it or are admin.
+type InstanceAccess interface {
+	GetByUser(ctx context.Context, id string, userID string, userRole string) (model.Instance, error)
 }
 
 // NewMediaHandler creates a new media handler
@@ -20,7 +32,37 @@ func NewMediaHandler(storage *media.Storage) *MediaHandler {
 	}
 }
 
-// GetMedia serves a media file by ID
+// NewMediaHandlerWithSigner also accepts the signed URLs sent in webhook payloads.
+func NewMediaHandlerWithSigner(storage *media.Storage, signer *mediaurl.Signer) *MediaHandler {
+	return &MediaHandler{
+		storage: storage,
+		signer:  signer,
+	}
+}
+
+// NewMediaHandlerWithAccess also serves user JWTs and API tokens of the instance owner (or an admin).
+func NewMediaHandlerWithAccess(storage *media.Storage, signer *mediaurl.Signer, instances InstanceAccess) *MediaHandler {
+	return &MediaHandler{
+		storage:   storage,
+		signer:    signer,
+		instances: instances,
+	}
+}
+
+// Authorize lets signed URLs through, as the webhook consumer has no token to send along with
+// them, and hands every other request to auth. GetMedia checks whichever was used.
+func (h *MediaHandler) Authorize(auth gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Query("sig") != "" || c.Query("expires") != "" {
+			c.Next()
+			return
+		}
+		auth(c)
+	}
+}
+
+// GetMedia serves a media file by ID, with a signed URL, the instance token or a user token of
+// the owner
 // GET /api/media/:instanceId/:mediaId
 func (h *MediaHandler) GetMedia(c *gin.Context) {
 	instanceID := c.Param("instanceId")
@@ -31,25 +73,58 @@ func (h *MediaHandler) GetMedia(c *gin.Context) {
 		return
 	}
 
-	if !h.storage.Exists(instanceID, mediaID) {
-		c.JSON(http.StatusNotFound, gin.H{"error": "mídia não encontrada ou expirada"})
+	if c.Query("sig") != "" || c.Query("expires") != "" {
+		if h.signer == nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "links assinados de mídia desabilitados"})
+			return
+		}
+		if err := h.signer.Verify(instanceID, mediaID, c.Query("expires"), c.Query("sig"), time.Now()); err != nil {
+			if errors.Is(err, mediaurl.ErrExpired) {
+				c.JSON(http.StatusGone, gin.H{"error": "link da mídia expirado"})
+				return
+			}
+			c.JSON(http.StatusForbidden, gin.H{"error": "assinatura do link inválida"})
+			return
+		}
+	} else if !h.allowed(c, instanceID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "token inválido para esta instância"})
 		return
 	}
 
-	data, err := h.storage.Get(c.Request.Context(), instanceID, mediaID)
+	f, err := h.storage.Open(instanceID, mediaID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		c.JSON(http.StatusNotFound, gin.H{"error": "mídia não encontrada ou expirada"})
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "mídia não encontrada ou expirada"})
 		return
 	}
 
-	contentType := getContentTypeFromFilename(mediaID)
-
-	c.Header("Content-Type", contentType)
-	c.Header("Cache-Control", "public, max-age=3600")
-	c.Header("Access-Control-Allow-Origin", "*")
+	c.Header("Content-Type", getContentTypeFromFilename(mediaID))
+	c.Header("Cache-Control", "private, max-age=3600")
 	c.Header("Cross-Origin-Resource-Policy", "cross-origin")
 
-	c.Data(http.StatusOK, contentType, data)
+	// ServeContent answers Range and conditional requests, so players can seek in large videos.
+	http.ServeContent(c.Writer, c.Request, mediaID, info.ModTime(), f)
+}
+
+// allowed checks a token-authenticated request: the instance token of this instance, or a user JWT
+// or API token of whoever may see the instance, the same rule as the /instances routes.
+func (h *MediaHandler) allowed(c *gin.Context, instanceID string) bool {
+	switch c.GetString("authType") {
+	case "instance_token":
+		return c.GetString("instanceID") == instanceID
+	case "user_jwt", "api_token":
+		if h.instances == nil {
+			return false
+		}
+		_, err := h.instances.GetByUser(c.Request.Context(), instanceID, c.GetString("userID"), c.GetString("userRole"))
+		return err == nil
+	}
+	return false
 }
 
 // getContentTypeFromFilename returns the content-type based on the file extension
@@ -3,7 +3,10 @@ package handler
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"math"
+	"mime/multipart"
 	"net/http"
 	"strconv"
 
@@ -11,6 +14,7 @@ import (
 
 	"github.com/open-apime/apime/internal/pkg/response"
 	messageSvc "github.com/open-apime/apime/internal/service/message"
+	"github.com/open-apime/apime/internal/storage/media"
 )
 
 // postFormBool reads a boolean flag from multipart form data (absent = false).
@@ -32,12 +36,106 @@ func parseMentionedJids(c *gin.Context) []string {
 	return jids
 }
 
+// respondBudgetExceeded answers 429 with Retry-After, so the caller can schedule the send instead
+// of retrying blindly (which would only hit the same budget).
+func respondBudgetExceeded(c *gin.Context, err error) {
+	_ = c.Error(err)
+	body := gin.H{"error": err.Error()}
+	var budgetErr *messageSvc.BudgetError
+	if errors.As(err, &budgetErr) {
+		seconds := int(math.Ceil(budgetErr.RetryAfter.Seconds()))
+		c.Header("Retry-After", strconv.Itoa(seconds))
+		body["reason"] = budgetErr.Reason
+		body["retryAfterSeconds"] = seconds
+	}
+	c.JSON(http.StatusTooManyRequests, body)
+}
+
+// multipartOverhead leaves room for the other form fields and part headers when the body is capped
+// at the media limit.
+const multipartOverhead = 1 << 20
+
 type MessageHandler struct {
-	service *messageSvc.Service
+	service      *messageSvc.Service
+	limits       media.Limits
+	uploadMemory int64
 }
 
 func NewMessageHandler(service *messageSvc.Service) *MessageHandler {
-	return &MessageHandler{service: service}
+	return &MessageHandler{service: service, uploadMemory: 32 << 20}
+}
+
+// NewMessageHandlerWithMediaLimits caps uploads per media kind and keeps at most uploadMemory
+// bytes of a multipart body in memory; the rest is spooled to temp files by net/http.
+func NewMessageHandlerWithMediaLimits(service *messageSvc.Service, limits media.Limits, uploadMemory int64) *MessageHandler {
+	return &MessageHandler{service: service, limits: limits, uploadMemory: uploadMemory}
+}
+
+// parseUpload reads the multipart form up front, with the body capped by the largest media
+// limit, so an oversized upload is refused before it fills the disk.
+func (h *MessageHandler) parseUpload(c *gin.Context) bool {
+	if largest := h.limits.Largest(); largest > 0 {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, largest+multipartOverhead)
+	}
+	if err := c.Request.ParseMultipartForm(h.uploadMemory); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			response.ErrorWithMessage(c, http.StatusRequestEntityTooLarge, "arquivo maior que o limite permitido")
+			return false
+		}
+		response.ErrorWithMessage(c, http.StatusBadRequest, "formulário multipart inválido")
+		return false
+	}
+	return true
+}
+
+// openUpload opens the "file" field of a parsed form without reading it: the service streams it
+// to WhatsApp. The caller closes the file.
+func (h *MessageHandler) openUpload(c *gin.Context, kind string) (multipart.File, *multipart.FileHeader, bool) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "arquivo não fornecido")
+		return nil, nil, false
+	}
+	if !h.limits.Allows(kind, file.Size) {
+		response.ErrorWithMessage(c, http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("arquivo maior que o limite de %d MB para %s", h.limits.Max(kind)>>20, kind))
+		return nil, nil, false
+	}
+	src, err := file.Open()
+	if err != nil {
+		response.ErrorWithMessage(c, http.StatusInternalServerError, "erro ao abrir arquivo")
+		return nil, nil, false
+	}
+	return src, file, true
+}
+
+// maxThumbnailSize bounds the caller-provided preview; the one sent to WhatsApp is re-encoded to a
+// few KB anyway.
+const maxThumbnailSize = 1 << 20
+
+// readThumbnail reads the optional "thumbnail" file of the form.
+func readThumbnail(c *gin.Context) ([]byte, bool) {
+	file, err := c.FormFile("thumbnail")
+	if err != nil {
+		return nil, true
+	}
+	if file.Size > maxThumbnailSize {
+		response.ErrorWithMessage(c, http.StatusRequestEntityTooLarge, "miniatura maior que 1 MB")
+		return nil, false
+	}
+	src, err := file.Open()
+	if err != nil {
+		response.ErrorWithMessage(c, http.StatusInternalServerError, "erro ao abrir miniatura")
+		return nil, false
+	}
+	defer src.Close()
+	data, err := io.ReadAll(io.LimitReader(src, maxThumbnailSize))
+	if err != nil {
+		response.ErrorWithMessage(c, http.StatusInternalServerError, "erro ao ler miniatura")
+		return nil, false
+	}
+	return data, true
 }
 
 func (h *MessageHandler) Register(r *gin.RouterGroup) {
@@ -48,7 +146,10 @@ func (h *MessageHandler) Register(r *gin.RouterGroup) {
 	r.POST("/instances/:id/messages/document", h.sendDocument)
 	r.POST("/instances/:id/messages/contact", h.sendContact)
 	r.POST("/instances/:id/messages/location", h.sendLocation)
+	r.POST("/instances/:id/messages/newsletter/text", h.publishNewsletterText)
+	r.POST("/instances/:id/messages/newsletter/media", h.publishNewsletterMedia)
 	r.GET("/instances/:id/messages", h.list)
+	r.GET("/instances/:id/messages/:messageId/media", h.getMedia)
 }
 
 type messageRequest struct {
@@ -95,6 +196,17 @@ type sendTextRequest struct {
 	MentionedJids     []string `json:"mentionedJids"`
 	MarkReadMessageID string   `json:"markReadMessageId"`
 	MarkReadSender    string   `json:"markReadSender"`
+	// LinkPreview fetches the preview of the first link in the text; Preview supplies it instead.
+	LinkPreview bool                `json:"linkPreview"`
+	Preview     *linkPreviewRequest `json:"preview"`
+}
+
+type linkPreviewRequest struct {
+	URL         string `json:"url"`
+	Title       string `json:"title" binding:"required"`
+	Description string `json:"description"`
+	// Thumbnail is the image, base64-encoded in the JSON.
+	Thumbnail []byte `json:"thumbnail"`
 }
 
 func (h *MessageHandler) sendText(c *gin.Context) {
@@ -113,6 +225,20 @@ func (h *MessageHandler) sendText(c *gin.Context) {
 		return
 	}
 
+	var preview *messageSvc.LinkPreview
+	if req.Preview != nil {
+		if len(req.Preview.Thumbnail) > maxThumbnailSize {
+			response.ErrorWithMessage(c, http.StatusRequestEntityTooLarge, "miniatura maior que 1 MB")
+			return
+		}
+		preview = &messageSvc.LinkPreview{
+			URL:         req.Preview.URL,
+			Title:       req.Preview.Title,
+			Description: req.Preview.Description,
+			Thumbnail:   req.Preview.Thumbnail,
+		}
+	}
+
 	// Pass the raw JID/phone so the service can resolve it dynamically via IsOnWhatsApp.
 
 	msg, err := h.service.Send(c.Request.Context(), messageSvc.SendInput{
@@ -120,6 +246,8 @@ func (h *MessageHandler) sendText(c *gin.Context) {
 		To:                req.To,
 		Type:              "text",
 		Text:              req.Text,
+		LinkPreview:       req.LinkPreview,
+		Preview:           preview,
 		Quoted:            req.Quoted,
 		Participant:       req.QuotedParticipant,
 		QuotedText:        req.QuotedText,
@@ -135,8 +263,10 @@ func (h *MessageHandler) sendText(c *gin.Context) {
 			response.Error(c, http.StatusBadRequest, err)
 		} else if errors.Is(err, messageSvc.ErrSessionUnavailable) || errors.Is(err, messageSvc.ErrRecipientLookupUnavailable) {
 			response.ErrorWithMessage(c, http.StatusServiceUnavailable, "sessão não pronta, tente novamente")
-		} else if errors.Is(err, messageSvc.ErrContactReachoutLocked) {
+		} else if errors.Is(err, messageSvc.ErrContactReachoutLocked) || errors.Is(err, messageSvc.ErrContactBlocked) {
 			response.Error(c, http.StatusUnprocessableEntity, err)
+		} else if errors.Is(err, messageSvc.ErrSendBudgetExceeded) {
+			respondBudgetExceeded(c, err)
 		} else {
 			response.Error(c, http.StatusInternalServerError, err)
 		}
@@ -156,6 +286,9 @@ func (h *MessageHandler) sendMedia(c *gin.Context) {
 		response.ErrorWithMessage(c, http.StatusForbidden, "token inválido para esta instância")
 		return
 	}
+	if !h.parseUpload(c) {
+		return
+	}
 	to := c.PostForm("to")
 	mediaType := c.PostForm("type") // "image" or "video"
 	caption := c.PostForm("caption")
@@ -170,32 +303,31 @@ func (h *MessageHandler) sendMedia(c *gin.Context) {
 		return
 	}
 
-	file, err := c.FormFile("file")
-	if err != nil {
-		response.ErrorWithMessage(c, http.StatusBadRequest, "arquivo não fornecido")
+	thumb, ok := readThumbnail(c)
+	if !ok {
 		return
 	}
+	width, _ := strconv.Atoi(c.PostForm("width"))
+	height, _ := strconv.Atoi(c.PostForm("height"))
 
-	src, err := file.Open()
-	if err != nil {
-		response.ErrorWithMessage(c, http.StatusInternalServerError, "erro ao abrir arquivo")
+	src, file, ok := h.openUpload(c, mediaType)
+	if !ok {
 		return
 	}
 	defer src.Close()
 
-	fileData, err := io.ReadAll(src)
-	if err != nil {
-		response.ErrorWithMessage(c, http.StatusInternalServerError, "erro ao ler arquivo")
-		return
-	}
-
 	msg, err := h.service.Send(c.Request.Context(), messageSvc.SendInput{
 		InstanceID:        instanceID,
 		To:                to,
 		Type:              mediaType,
-		MediaData:         fileData,
+		Media:             src,
+		MediaSize:         file.Size,
 		MediaType:         file.Header.Get("Content-Type"),
 		Caption:           caption,
+		Thumbnail:         thumb,
+		Width:             width,
+		Height:            height,
+		ViewOnce:          postFormBool(c, "viewOnce"),
 		Quoted:            c.PostForm("quoted"),
 		Participant:       c.PostForm("quotedParticipant"),
 		QuotedText:        c.PostForm("quotedText"),
@@ -211,8 +343,10 @@ func (h *MessageHandler) sendMedia(c *gin.Context) {
 			response.Error(c, http.StatusBadRequest, err)
 		} else if errors.Is(err, messageSvc.ErrSessionUnavailable) || errors.Is(err, messageSvc.ErrRecipientLookupUnavailable) {
 			response.ErrorWithMessage(c, http.StatusServiceUnavailable, "sessão não pronta, tente novamente")
-		} else if errors.Is(err, messageSvc.ErrContactReachoutLocked) {
+		} else if errors.Is(err, messageSvc.ErrContactReachoutLocked) || errors.Is(err, messageSvc.ErrContactBlocked) {
 			response.Error(c, http.StatusUnprocessableEntity, err)
+		} else if errors.Is(err, messageSvc.ErrSendBudgetExceeded) {
+			respondBudgetExceeded(c, err)
 		} else {
 			response.Error(c, http.StatusInternalServerError, err)
 		}
@@ -232,6 +366,9 @@ func (h *MessageHandler) sendAudio(c *gin.Context) {
 		response.ErrorWithMessage(c, http.StatusForbidden, "token inválido para esta instância")
 		return
 	}
+	if !h.parseUpload(c) {
+		return
+	}
 	to := c.PostForm("to")
 
 	if to == "" {
@@ -239,25 +376,12 @@ func (h *MessageHandler) sendAudio(c *gin.Context) {
 		return
 	}
 
-	file, err := c.FormFile("file")
-	if err != nil {
-		response.ErrorWithMessage(c, http.StatusBadRequest, "arquivo não fornecido")
-		return
-	}
-
-	src, err := file.Open()
-	if err != nil {
-		response.ErrorWithMessage(c, http.StatusInternalServerError, "erro ao abrir arquivo")
+	src, file, ok := h.openUpload(c, "audio")
+	if !ok {
 		return
 	}
 	defer src.Close()
 
-	fileData, err := io.ReadAll(src)
-	if err != nil {
-		response.ErrorWithMessage(c, http.StatusInternalServerError, "erro ao ler arquivo")
-		return
-	}
-
 	secondsStr := c.PostForm("seconds")
 	seconds, _ := strconv.Atoi(secondsStr)
 
@@ -270,10 +394,12 @@ func (h *MessageHandler) sendAudio(c *gin.Context) {
 		InstanceID:        instanceID,
 		To:                to,
 		Type:              "audio",
-		MediaData:         fileData,
+		Media:             src,
+		MediaSize:         file.Size,
 		MediaType:         mediaType,
 		Seconds:           seconds,
 		PTT:               ptt,
+		ViewOnce:          postFormBool(c, "viewOnce"),
 		Quoted:            c.PostForm("quoted"),
 		Participant:       c.PostForm("quotedParticipant"),
 		QuotedText:        c.PostForm("quotedText"),
@@ -285,12 +411,16 @@ func (h *MessageHandler) sendAudio(c *gin.Context) {
 	if err != nil {
 		if errors.Is(err, messageSvc.ErrInstanceNotConnected) {
 			response.ErrorWithMessage(c, http.StatusBadRequest, "instância não conectada")
+		} else if errors.Is(err, messageSvc.ErrUnsupportedPTTCodec) {
+			response.Error(c, http.StatusBadRequest, err)
 		} else if errors.Is(err, messageSvc.ErrInvalidJID) {
 			response.Error(c, http.StatusBadRequest, err)
 		} else if errors.Is(err, messageSvc.ErrSessionUnavailable) || errors.Is(err, messageSvc.ErrRecipientLookupUnavailable) {
 			response.ErrorWithMessage(c, http.StatusServiceUnavailable, "sessão não pronta, tente novamente")
-		} else if errors.Is(err, messageSvc.ErrContactReachoutLocked) {
+		} else if errors.Is(err, messageSvc.ErrContactReachoutLocked) || errors.Is(err, messageSvc.ErrContactBlocked) {
 			response.Error(c, http.StatusUnprocessableEntity, err)
+		} else if errors.Is(err, messageSvc.ErrSendBudgetExceeded) {
+			respondBudgetExceeded(c, err)
 		} else {
 			response.Error(c, http.StatusInternalServerError, err)
 		}
@@ -310,6 +440,9 @@ func (h *MessageHandler) sendDocument(c *gin.Context) {
 		response.ErrorWithMessage(c, http.StatusForbidden, "token inválido para esta instância")
 		return
 	}
+	if !h.parseUpload(c) {
+		return
+	}
 	to := c.PostForm("to")
 	fileName := c.PostForm("filename")
 	caption := c.PostForm("caption")
@@ -319,37 +452,31 @@ func (h *MessageHandler) sendDocument(c *gin.Context) {
 		return
 	}
 
-	file, err := c.FormFile("file")
-	if err != nil {
-		response.ErrorWithMessage(c, http.StatusBadRequest, "arquivo não fornecido")
+	thumb, ok := readThumbnail(c)
+	if !ok {
 		return
 	}
 
-	if fileName == "" {
-		fileName = file.Filename
-	}
-
-	src, err := file.Open()
-	if err != nil {
-		response.ErrorWithMessage(c, http.StatusInternalServerError, "erro ao abrir arquivo")
+	src, file, ok := h.openUpload(c, "document")
+	if !ok {
 		return
 	}
 	defer src.Close()
 
-	fileData, err := io.ReadAll(src)
-	if err != nil {
-		response.ErrorWithMessage(c, http.StatusInternalServerError, "erro ao ler arquivo")
-		return
+	if fileName == "" {
+		fileName = file.Filename
 	}
 
 	msg, err := h.service.Send(c.Request.Context(), messageSvc.SendInput{
 		InstanceID:        instanceID,
 		To:                to,
 		Type:              "document",
-		MediaData:         fileData,
+		Media:             src,
+		MediaSize:         file.Size,
 		MediaType:         file.Header.Get("Content-Type"),
 		FileName:          fileName,
 		Caption:           caption,
+		Thumbnail:         thumb,
 		Quoted:            c.PostForm("quoted"),
 		Participant:       c.PostForm("quotedParticipant"),
 		QuotedText:        c.PostForm("quotedText"),
@@ -365,8 +492,10 @@ func (h *MessageHandler) sendDocument(c *gin.Context) {
 			response.Error(c, http.StatusBadRequest, err)
 		} else if errors.Is(err, messageSvc.ErrSessionUnavailable) || errors.Is(err, messageSvc.ErrRecipientLookupUnavailable) {
 			response.ErrorWithMessage(c, http.StatusServiceUnavailable, "sessão não pronta, tente novamente")
-		} else if errors.Is(err, messageSvc.ErrContactReachoutLocked) {
+		} else if errors.Is(err, messageSvc.ErrContactReachoutLocked) || errors.Is(err, messageSvc.ErrContactBlocked) {
 			response.Error(c, http.StatusUnprocessableEntity, err)
+		} else if errors.Is(err, messageSvc.ErrSendBudgetExceeded) {
+			respondBudgetExceeded(c, err)
 		} else {
 			response.Error(c, http.StatusInternalServerError, err)
 		}
@@ -426,8 +555,10 @@ func (h *MessageHandler) sendContact(c *gin.Context) {
 			response.Error(c, http.StatusBadRequest, err)
 		} else if errors.Is(err, messageSvc.ErrSessionUnavailable) || errors.Is(err, messageSvc.ErrRecipientLookupUnavailable) {
 			response.ErrorWithMessage(c, http.StatusServiceUnavailable, "sessão não pronta, tente novamente")
-		} else if errors.Is(err, messageSvc.ErrContactReachoutLocked) {
+		} else if errors.Is(err, messageSvc.ErrContactReachoutLocked) || errors.Is(err, messageSvc.ErrContactBlocked) {
 			response.Error(c, http.StatusUnprocessableEntity, err)
+		} else if errors.Is(err, messageSvc.ErrSendBudgetExceeded) {
+			respondBudgetExceeded(c, err)
 		} else {
 			response.Error(c, http.StatusInternalServerError, err)
 		}
@@ -489,8 +620,10 @@ func (h *MessageHandler) sendLocation(c *gin.Context) {
 			response.Error(c, http.StatusBadRequest, err)
 		} else if errors.Is(err, messageSvc.ErrSessionUnavailable) || errors.Is(err, messageSvc.ErrRecipientLookupUnavailable) {
 			response.ErrorWithMessage(c, http.StatusServiceUnavailable, "sessão não pronta, tente novamente")
-		} else if errors.Is(err, messageSvc.ErrContactReachoutLocked) {
+		} else if errors.Is(err, messageSvc.ErrContactReachoutLocked) || errors.Is(err, messageSvc.ErrContactBlocked) {
 			response.Error(c, http.StatusUnprocessableEntity, err)
+		} else if errors.Is(err, messageSvc.ErrSendBudgetExceeded) {
+			respondBudgetExceeded(c, err)
 		} else {
 			response.Error(c, http.StatusInternalServerError, err)
 		}
@@ -500,6 +633,106 @@ func (h *MessageHandler) sendLocation(c *gin.Context) {
 	response.Success(c, http.StatusOK, msg)
 }
 
+type publishNewsletterTextRequest struct {
+	To   string `json:"to" binding:"required"`
+	Text string `json:"text" binding:"required"`
+}
+
+func (h *MessageHandler) publishNewsletterText(c *gin.Context) {
+	instanceID := c.Param("id")
+	if c.GetString("authType") != "instance_token" {
+		response.ErrorWithMessage(c, http.StatusForbidden, "endpoint disponível apenas com token de instância")
+		return
+	}
+	if c.GetString("instanceID") != instanceID {
+		response.ErrorWithMessage(c, http.StatusForbidden, "token inválido para esta instância")
+		return
+	}
+	var req publishNewsletterTextRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err)
+		return
+	}
+
+	msg, err := h.service.PublishNewsletter(c.Request.Context(), messageSvc.NewsletterPostInput{
+		InstanceID:    instanceID,
+		NewsletterJID: req.To,
+		Type:          "text",
+		Text:          req.Text,
+	})
+	if err != nil {
+		respondNewsletterError(c, err)
+		return
+	}
+	response.Success(c, http.StatusOK, msg)
+}
+
+// publishNewsletterMedia takes the same form as the media, audio and document sends: to, type
+// (image, video, audio or document), file, caption and filename.
+func (h *MessageHandler) publishNewsletterMedia(c *gin.Context) {
+	instanceID := c.Param("id")
+	if c.GetString("authType") != "instance_token" {
+		response.ErrorWithMessage(c, http.StatusForbidden, "endpoint disponível apenas com token de instância")
+		return
+	}
+	if c.GetString("instanceID") != instanceID {
+		response.ErrorWithMessage(c, http.StatusForbidden, "token inválido para esta instância")
+		return
+	}
+	if !h.parseUpload(c) {
+		return
+	}
+	to := c.PostForm("to")
+	mediaType := c.PostForm("type")
+	if to == "" {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "campo 'to' é obrigatório")
+		return
+	}
+	switch mediaType {
+	case "image", "video", "audio", "document":
+	default:
+		response.ErrorWithMessage(c, http.StatusBadRequest, "tipo deve ser 'image', 'video', 'audio' ou 'document'")
+		return
+	}
+
+	src, file, ok := h.openUpload(c, mediaType)
+	if !ok {
+		return
+	}
+	defer src.Close()
+
+	fileName := c.PostForm("filename")
+	if fileName == "" && mediaType == "document" {
+		fileName = file.Filename
+	}
+
+	msg, err := h.service.PublishNewsletter(c.Request.Context(), messageSvc.NewsletterPostInput{
+		InstanceID:    instanceID,
+		NewsletterJID: to,
+		Type:          mediaType,
+		Media:         src,
+		MediaSize:     file.Size,
+		MediaType:     file.Header.Get("Content-Type"),
+		Caption:       c.PostForm("caption"),
+		FileName:      fileName,
+	})
+	if err != nil {
+		respondNewsletterError(c, err)
+		return
+	}
+	response.Success(c, http.StatusOK, msg)
+}
+
+func respondNewsletterError(c *gin.Context, err error) {
+	if errors.Is(err, messageSvc.ErrInstanceNotConnected) {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "instância não conectada")
+	} else if errors.Is(err, messageSvc.ErrNotNewsletter) || errors.Is(err, messageSvc.ErrInvalidPayload) {
+		response.Error(c, http.StatusBadRequest, err)
+	} else {
+		response.Error(c, http.StatusInternalServerError, err)
+	}
+}
+
 func (h *MessageHandler) list(c *gin.Context) {
 	instanceID := c.Param("id")
 	if c.GetString("authType") != "instance_token" {
@@ -517,3 +750,48 @@ func (h *MessageHandler) list(c *gin.Context) {
 	}
 	response.Success(c, http.StatusOK, list)
 }
+
+// getMedia serves the media of a received message, downloading it from WhatsApp on the first
+// request (lazy media policy) or after the stored copy expired.
+func (h *MessageHandler) getMedia(c *gin.Context) {
+	instanceID := c.Param("id")
+	if c.GetString("authType") != "instance_token" {
+		response.ErrorWithMessage(c, http.StatusForbidden, "endpoint disponível apenas com token de instância")
+		return
+	}
+	if c.GetString("instanceID") != instanceID {
+		response.ErrorWithMessage(c, http.StatusForbidden, "token inválido para esta instância")
+		return
+	}
+
+	f, ref, err := h.service.OpenMedia(c.Request.Context(), instanceID, c.Param("messageId"))
+	if err != nil {
+		switch {
+		case errors.Is(err, messageSvc.ErrMediaNotFound):
+			response.Error(c, http.StatusNotFound, err)
+		case errors.Is(err, messageSvc.ErrMediaUnavailable):
+			response.Error(c, http.StatusGone, err)
+		case errors.Is(err, messageSvc.ErrMediaTooLarge):
+			response.Error(c, http.StatusRequestEntityTooLarge, err)
+		case errors.Is(err, messageSvc.ErrInstanceNotConnected):
+			response.Error(c, http.StatusServiceUnavailable, err)
+		default:
+			response.Error(c, http.StatusBadGateway, err)
+		}
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	contentType := ref.Mimetype
+	if contentType == "" {
+		contentType = getContentTypeFromFilename(ref.MediaID)
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Cache-Control", "private, max-age=3600")
+	http.ServeContent(c.Writer, c.Request, ref.MediaID, info.ModTime(), f)
+}
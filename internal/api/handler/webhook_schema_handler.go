@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/open-apime/apime/internal/webhook/payload"
+)
+
+// WebhookSchemaHandler serves the JSON Schemas of the webhook payloads. Public: they describe the
+// contract, not any instance data, and receivers fetch them from CI to validate their parsers.
+type WebhookSchemaHandler struct{}
+
+func NewWebhookSchemaHandler() *WebhookSchemaHandler {
+	return &WebhookSchemaHandler{}
+}
+
+func (h *WebhookSchemaHandler) Register(r *gin.RouterGroup) {
+	r.GET("/webhook-schemas", h.list)
+	r.GET("/webhook-schemas/:type", h.get)
+}
+
+func (h *WebhookSchemaHandler) list(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"schemaVersion": payload.SchemaVersion,
+		"schemas":       payload.Schemas(),
+	})
+}
+
+func (h *WebhookSchemaHandler) get(c *gin.Context) {
+	schema, ok := payload.Schema(c.Param("type"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "tipo de evento desconhecido"})
+		return
+	}
+	c.JSON(http.StatusOK, schema)
+}
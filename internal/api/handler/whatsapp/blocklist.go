@@ -0,0 +1,77 @@
+package whatsapp
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mau.fi/whatsmeow/types"
+
+	"github.com/open-apime/apime/internal/pkg/response"
+	messageSvc "github.com/open-apime/apime/internal/service/message"
+)
+
+type blocklistRequest struct {
+	// JID is the contact to block or unblock: a phone number or a JID.
+	JID string `json:"jid" binding:"required"`
+}
+
+func (h *Handler) getBlocklist(c *gin.Context) {
+	instanceID, ok := h.requireInstanceToken(c)
+	if !ok {
+		return
+	}
+	jids, err := h.messageService.Blocklist(c.Request.Context(), instanceID)
+	if err != nil {
+		respondBlocklistError(c, err)
+		return
+	}
+	response.Success(c, http.StatusOK, gin.H{"jids": jidStrings(jids)})
+}
+
+func (h *Handler) blockContact(c *gin.Context) {
+	h.updateBlocklist(c, true)
+}
+
+func (h *Handler) unblockContact(c *gin.Context) {
+	h.updateBlocklist(c, false)
+}
+
+func (h *Handler) updateBlocklist(c *gin.Context, block bool) {
+	instanceID, ok := h.requireInstanceToken(c)
+	if !ok {
+		return
+	}
+	var req blocklistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	jids, err := h.messageService.UpdateBlocklist(c.Request.Context(), instanceID, req.JID, block)
+	if err != nil {
+		respondBlocklistError(c, err)
+		return
+	}
+	response.Success(c, http.StatusOK, gin.H{"jids": jidStrings(jids)})
+}
+
+func respondBlocklistError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, messageSvc.ErrInstanceNotConnected):
+		response.ErrorWithMessage(c, http.StatusBadRequest, "instância não conectada")
+	case errors.Is(err, messageSvc.ErrInvalidJID):
+		response.Error(c, http.StatusBadRequest, err)
+	case errors.Is(err, messageSvc.ErrRecipientLookupUnavailable):
+		response.ErrorWithMessage(c, http.StatusServiceUnavailable, "sessão não pronta, tente novamente")
+	default:
+		response.Error(c, http.StatusInternalServerError, err)
+	}
+}
+
+func jidStrings(jids []types.JID) []string {
+	out := make([]string, 0, len(jids))
+	for _, jid := range jids {
+		out = append(out, jid.String())
+	}
+	return out
+}
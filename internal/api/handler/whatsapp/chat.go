@@ -0,0 +1,286 @@
+package whatsapp
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/appstate"
+	"go.mau.fi/whatsmeow/proto/waCommon"
+	"go.mau.fi/whatsmeow/proto/waSyncAction"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/open-apime/apime/internal/pkg/response"
+)
+
+// The chat operations are app state patches: the change is synced to the phone and every other
+// device of the account, unlike chat-settings, which only writes the local store. The phone
+// applies them as if done there, and the change comes back as a chat_update webhook.
+
+// appStateTimeout bounds a patch: sending it includes fetching the collection back.
+const appStateTimeout = 30 * time.Second
+
+// lastMessageRequest identifies the newest message of the chat. Archive, read state, clear and
+// delete carry it so the other devices apply the change up to that message; without it they apply
+// it to the whole chat.
+type lastMessageRequest struct {
+	ID        string `json:"id"`
+	FromMe    bool   `json:"from_me"`
+	Sender    string `json:"sender"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+func (r *lastMessageRequest) key(chat types.JID) (time.Time, *waCommon.MessageKey) {
+	if r == nil || r.ID == "" {
+		return time.Time{}, nil
+	}
+	key := &waCommon.MessageKey{
+		RemoteJID: proto.String(chat.String()),
+		FromMe:    proto.Bool(r.FromMe),
+		ID:        proto.String(r.ID),
+	}
+	if chat.Server == types.GroupServer && !r.FromMe && r.Sender != "" {
+		key.Participant = proto.String(normalizeJIDString(r.Sender))
+	}
+	var ts time.Time
+	if r.Timestamp > 0 {
+		ts = time.Unix(r.Timestamp, 0)
+	}
+	return ts, key
+}
+
+func normalizeJIDString(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.Contains(s, "@") {
+		s = strings.TrimPrefix(s, "+") + "@s.whatsapp.net"
+	}
+	return s
+}
+
+// chatTarget reads the :chat parameter and the client of the instance, answering the request
+// when either is missing.
+func (h *Handler) chatTarget(c *gin.Context) (*whatsmeow.Client, types.JID, bool) {
+	instanceID, ok := h.requireInstanceToken(c)
+	if !ok {
+		return nil, types.EmptyJID, false
+	}
+	chatJID, err := types.ParseJID(normalizeJIDString(c.Param("chat")))
+	if err != nil {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "chat inválido")
+		return nil, types.EmptyJID, false
+	}
+	client, err := h.sessionManager.GetClient(instanceID)
+	if err != nil {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "instância não conectada")
+		return nil, types.EmptyJID, false
+	}
+	return client, chatJID, true
+}
+
+func sendAppState(c *gin.Context, client *whatsmeow.Client, patch appstate.PatchInfo) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), appStateTimeout)
+	defer cancel()
+	if err := client.SendAppState(ctx, patch); err != nil {
+		response.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+	response.Success(c, http.StatusOK, gin.H{"status": "ok"})
+}
+
+type archiveChatRequest struct {
+	Archived    *bool               `json:"archived" binding:"required"`
+	LastMessage *lastMessageRequest `json:"last_message"`
+}
+
+func (h *Handler) archiveChat(c *gin.Context) {
+	client, chatJID, ok := h.chatTarget(c)
+	if !ok {
+		return
+	}
+	var req archiveChatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	ts, key := req.LastMessage.key(chatJID)
+	sendAppState(c, client, appstate.BuildArchive(chatJID, *req.Archived, ts, key))
+}
+
+type pinChatRequest struct {
+	Pinned *bool `json:"pinned" binding:"required"`
+}
+
+func (h *Handler) pinChat(c *gin.Context) {
+	client, chatJID, ok := h.chatTarget(c)
+	if !ok {
+		return
+	}
+	var req pinChatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	sendAppState(c, client, appstate.BuildPin(chatJID, *req.Pinned))
+}
+
+type muteChatRequest struct {
+	Muted *bool `json:"muted" binding:"required"`
+	// DurationSeconds is how long the mute lasts; 0 mutes forever.
+	DurationSeconds int `json:"duration_seconds" binding:"min=0"`
+}
+
+func (h *Handler) muteChat(c *gin.Context) {
+	client, chatJID, ok := h.chatTarget(c)
+	if !ok {
+		return
+	}
+	var req muteChatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	sendAppState(c, client, appstate.BuildMute(chatJID, *req.Muted, time.Duration(req.DurationSeconds)*time.Second))
+}
+
+type markChatUnreadRequest struct {
+	Unread      *bool               `json:"unread" binding:"required"`
+	LastMessage *lastMessageRequest `json:"last_message"`
+}
+
+func (h *Handler) markChatUnread(c *gin.Context) {
+	client, chatJID, ok := h.chatTarget(c)
+	if !ok {
+		return
+	}
+	var req markChatUnreadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	ts, key := req.LastMessage.key(chatJID)
+	sendAppState(c, client, appstate.BuildMarkChatAsRead(chatJID, !*req.Unread, ts, key))
+}
+
+type clearChatRequest struct {
+	DeleteStarred bool                `json:"delete_starred"`
+	DeleteMedia   bool                `json:"delete_media"`
+	LastMessage   *lastMessageRequest `json:"last_message"`
+}
+
+// clearChat removes the messages and keeps the chat.
+func (h *Handler) clearChat(c *gin.Context) {
+	client, chatJID, ok := h.chatTarget(c)
+	if !ok {
+		return
+	}
+	var req clearChatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	ts, key := req.LastMessage.key(chatJID)
+	sendAppState(c, client, buildClearChat(chatJID, req.DeleteStarred, req.DeleteMedia, ts, key))
+}
+
+// buildClearChat is the patch whatsmeow has no builder for. It is laid out like the
+// appstate.BuildDeleteChat of whatsmeow (regular_high, version 6, "1"/"0" flags in the index) and
+// like the "clear" case of Baileys' chatModificationToAppPatch, whose index is
+// clearChat/<jid>/<delete starred>/<delete media>. whatsmeow decodes the result back as an
+// events.ClearChat.
+func buildClearChat(chatJID types.JID, deleteStarred, deleteMedia bool, ts time.Time, key *waCommon.MessageKey) appstate.PatchInfo {
+	return appstate.PatchInfo{
+		Type: appstate.WAPatchRegularHigh,
+		Mutations: []appstate.MutationInfo{{
+			Index:   []string{appstate.IndexClearChat, chatJID.String(), boolIndex(deleteStarred), boolIndex(deleteMedia)},
+			Version: 6,
+			Value: &waSyncAction.SyncActionValue{
+				ClearChatAction: &waSyncAction.ClearChatAction{
+					MessageRange: messageRange(ts, key),
+				},
+			},
+		}},
+	}
+}
+
+type deleteChatRequest struct {
+	DeleteMedia bool                `json:"delete_media"`
+	LastMessage *lastMessageRequest `json:"last_message"`
+}
+
+func (h *Handler) deleteChat(c *gin.Context) {
+	client, chatJID, ok := h.chatTarget(c)
+	if !ok {
+		return
+	}
+	var req deleteChatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	ts, key := req.LastMessage.key(chatJID)
+	sendAppState(c, client, appstate.BuildDeleteChat(chatJID, ts, key, req.DeleteMedia))
+}
+
+type starMessageRequest struct {
+	Chat      string `json:"chat" binding:"required"`
+	MessageID string `json:"message_id" binding:"required"`
+	Sender    string `json:"sender"`
+	FromMe    bool   `json:"from_me"`
+	Starred   *bool  `json:"starred" binding:"required"`
+}
+
+func (h *Handler) starMessage(c *gin.Context) {
+	instanceID, ok := h.requireInstanceToken(c)
+	if !ok {
+		return
+	}
+	var req starMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	chatJID, err := types.ParseJID(normalizeJIDString(req.Chat))
+	if err != nil {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "chat inválido")
+		return
+	}
+	// In 1:1 chats the sender of a received message is the chat itself.
+	senderJID := chatJID
+	if req.Sender != "" {
+		if senderJID, err = types.ParseJID(normalizeJIDString(req.Sender)); err != nil {
+			response.ErrorWithMessage(c, http.StatusBadRequest, "sender inválido")
+			return
+		}
+	} else if chatJID.Server == types.GroupServer && !req.FromMe {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "campo 'sender' é obrigatório para mensagens recebidas em grupo")
+		return
+	}
+	client, err := h.sessionManager.GetClient(instanceID)
+	if err != nil {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "instância não conectada")
+		return
+	}
+	sendAppState(c, client, appstate.BuildStar(chatJID, senderJID, types.MessageID(req.MessageID), req.FromMe, *req.Starred))
+}
+
+func boolIndex(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+func messageRange(ts time.Time, key *waCommon.MessageKey) *waSyncAction.SyncActionMessageRange {
+	r := &waSyncAction.SyncActionMessageRange{}
+	if !ts.IsZero() {
+		r.LastMessageTimestamp = proto.Int64(ts.Unix())
+	}
+	if key != nil {
+		r.Messages = []*waSyncAction.SyncActionMessage{{Key: key, Timestamp: r.LastMessageTimestamp}}
+	}
+	return r
+}
@@ -0,0 +1,39 @@
+package whatsapp
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow/appstate"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// TestBuildClearChat pins the hand-built patch to the one whatsmeow builds for deleteChat, the
+// closest action it ships: same collection and version, flags as "1"/"0" after the JID.
+func TestBuildClearChat(t *testing.T) {
+	chat := types.NewJID("5511999999999", types.DefaultUserServer)
+	ts := time.Unix(1_700_000_000, 0)
+
+	reference := appstate.BuildDeleteChat(chat, ts, nil, true)
+	patch := buildClearChat(chat, true, false, ts, nil)
+
+	if patch.Type != reference.Type {
+		t.Fatalf("coleção %q; deleteChat usa %q", patch.Type, reference.Type)
+	}
+	if len(patch.Mutations) != 1 {
+		t.Fatalf("%d mutações", len(patch.Mutations))
+	}
+	m := patch.Mutations[0]
+	if m.Version != reference.Mutations[0].Version {
+		t.Fatalf("versão %d; deleteChat usa %d", m.Version, reference.Mutations[0].Version)
+	}
+	want := []string{appstate.IndexClearChat, chat.String(), "1", "0"}
+	if !reflect.DeepEqual(m.Index, want) {
+		t.Fatalf("índice %v; queria %v", m.Index, want)
+	}
+	r := m.Value.GetClearChatAction().GetMessageRange()
+	if r.GetLastMessageTimestamp() != ts.Unix() || len(r.GetMessages()) != 0 {
+		t.Fatalf("intervalo de mensagens %v", r)
+	}
+}
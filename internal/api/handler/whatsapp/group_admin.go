@@ -0,0 +1,351 @@
+package whatsapp
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+
+	"github.com/open-apime/apime/internal/pkg/response"
+	"github.com/open-apime/apime/internal/pkg/thumbnail"
+)
+
+const (
+	// maxGroupPhotoSize bounds the uploaded file; it's cropped square and re-encoded to a JPEG of
+	// groupPhotoSide before going to WhatsApp, which rejects larger pictures.
+	maxGroupPhotoSize = 5 << 20
+	groupPhotoSide    = 640
+)
+
+// disappearingTimers are the durations the clients offer; WhatsApp refuses any other.
+var disappearingTimers = map[int]time.Duration{
+	0:       whatsmeow.DisappearingTimerOff,
+	86400:   whatsmeow.DisappearingTimer24Hours,
+	604800:  whatsmeow.DisappearingTimer7Days,
+	7776000: whatsmeow.DisappearingTimer90Days,
+}
+
+// groupTarget reads a group or community JID from the param and the client of the instance,
+// answering the request when either is missing.
+func (h *Handler) groupTarget(c *gin.Context, param string) (*whatsmeow.Client, types.JID, bool) {
+	instanceID, ok := h.requireInstanceToken(c)
+	if !ok {
+		return nil, types.EmptyJID, false
+	}
+	groupJID, err := parseGroupJID(c.Param(param))
+	if err != nil {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "group inválido")
+		return nil, types.EmptyJID, false
+	}
+	client, err := h.sessionManager.GetClient(instanceID)
+	if err != nil {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "instância não conectada")
+		return nil, types.EmptyJID, false
+	}
+	return client, groupJID, true
+}
+
+func parseGroupJID(s string) (types.JID, error) {
+	s = strings.TrimSpace(s)
+	if !strings.Contains(s, "@") {
+		s = s + "@g.us"
+	}
+	jid, err := types.ParseJID(s)
+	if err == nil && jid.Server != types.GroupServer {
+		err = errors.New("não é um grupo")
+	}
+	return jid, err
+}
+
+func respondGroupResult(c *gin.Context, err error) {
+	if err != nil {
+		response.Error(c, groupErrorStatus(err), err)
+		return
+	}
+	response.Success(c, http.StatusOK, gin.H{"status": "ok"})
+}
+
+type setGroupSubjectRequest struct {
+	Subject string `json:"subject" binding:"required"`
+}
+
+func (h *Handler) setGroupSubject(c *gin.Context) {
+	client, groupJID, ok := h.groupTarget(c, "group")
+	if !ok {
+		return
+	}
+	var req setGroupSubjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	respondGroupResult(c, client.SetGroupName(c.Request.Context(), groupJID, strings.TrimSpace(req.Subject)))
+}
+
+type setGroupDescriptionRequest struct {
+	// Description replaces the current one; empty removes it.
+	Description string `json:"description"`
+}
+
+// setGroupDescription needs the id of the current description: WhatsApp refuses an edit based on
+// an outdated one, so it's read right before.
+func (h *Handler) setGroupDescription(c *gin.Context) {
+	client, groupJID, ok := h.groupTarget(c, "group")
+	if !ok {
+		return
+	}
+	var req setGroupDescriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	info, err := client.GetGroupInfo(c.Request.Context(), groupJID)
+	if err != nil {
+		response.Error(c, groupErrorStatus(err), err)
+		return
+	}
+	respondGroupResult(c, client.SetGroupTopic(c.Request.Context(), groupJID, info.TopicID, "", strings.TrimSpace(req.Description)))
+}
+
+// setGroupPhoto takes the picture as the multipart field "photo" (JPEG, PNG or GIF).
+func (h *Handler) setGroupPhoto(c *gin.Context) {
+	client, groupJID, ok := h.groupTarget(c, "group")
+	if !ok {
+		return
+	}
+	header, err := c.FormFile("photo")
+	if err != nil {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "campo 'photo' é obrigatório")
+		return
+	}
+	if header.Size > maxGroupPhotoSize {
+		response.ErrorWithMessage(c, http.StatusRequestEntityTooLarge, "foto maior que 5 MB")
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	defer file.Close()
+	// Cropped square like the profile picture: WhatsApp shows group pictures in a circle and
+	// crops anything else itself, differently on each client.
+	photo, err := thumbnail.SquareJPEG(file, groupPhotoSide)
+	if err != nil {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "foto inválida: envie JPEG, PNG ou GIF")
+		return
+	}
+	pictureID, err := client.SetGroupPhoto(c.Request.Context(), groupJID, photo.JPEG)
+	if err != nil {
+		response.Error(c, groupErrorStatus(err), err)
+		return
+	}
+	response.Success(c, http.StatusOK, gin.H{"picture_id": pictureID})
+}
+
+func (h *Handler) removeGroupPhoto(c *gin.Context) {
+	client, groupJID, ok := h.groupTarget(c, "group")
+	if !ok {
+		return
+	}
+	_, err := client.SetGroupPhoto(c.Request.Context(), groupJID, nil)
+	respondGroupResult(c, err)
+}
+
+type setGroupSettingsRequest struct {
+	// Announce: only admins send messages.
+	Announce *bool `json:"announce"`
+	// Locked: only admins edit the group info.
+	Locked *bool `json:"locked"`
+	// JoinApproval: joining by link needs an admin's approval.
+	JoinApproval *bool `json:"join_approval"`
+}
+
+// setGroupSettings applies the settings present in the body, in order, and answers with the ones
+// applied. Each is a separate request to WhatsApp, so a failure may leave the earlier ones
+// applied: the error response lists them too.
+func (h *Handler) setGroupSettings(c *gin.Context) {
+	client, groupJID, ok := h.groupTarget(c, "group")
+	if !ok {
+		return
+	}
+	var req setGroupSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	if req.Announce == nil && req.Locked == nil && req.JoinApproval == nil {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "informe announce, locked ou join_approval")
+		return
+	}
+	ctx := c.Request.Context()
+	settings := []struct {
+		name  string
+		value *bool
+		apply func(bool) error
+	}{
+		{"announce", req.Announce, func(v bool) error { return client.SetGroupAnnounce(ctx, groupJID, v) }},
+		{"locked", req.Locked, func(v bool) error { return client.SetGroupLocked(ctx, groupJID, v) }},
+		{"join_approval", req.JoinApproval, func(v bool) error { return client.SetGroupJoinApprovalMode(ctx, groupJID, v) }},
+	}
+	applied := []string{}
+	for _, setting := range settings {
+		if setting.value == nil {
+			continue
+		}
+		if err := setting.apply(*setting.value); err != nil {
+			_ = c.Error(err)
+			c.JSON(groupErrorStatus(err), gin.H{"error": err.Error(), "applied": applied})
+			return
+		}
+		applied = append(applied, setting.name)
+	}
+	response.Success(c, http.StatusOK, gin.H{"applied": applied})
+}
+
+type setGroupEphemeralRequest struct {
+	// Seconds: 0 (off), 86400, 604800 or 7776000.
+	Seconds *int `json:"seconds" binding:"required"`
+}
+
+func (h *Handler) setGroupEphemeral(c *gin.Context) {
+	client, groupJID, ok := h.groupTarget(c, "group")
+	if !ok {
+		return
+	}
+	var req setGroupEphemeralRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	timer, valid := disappearingTimers[*req.Seconds]
+	if !valid {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "seconds deve ser 0, 86400, 604800 ou 7776000")
+		return
+	}
+	respondGroupResult(c, client.SetDisappearingTimer(c.Request.Context(), groupJID, timer, time.Now()))
+}
+
+// resetGroupInviteLink revokes the current link; whoever has it can no longer join.
+func (h *Handler) resetGroupInviteLink(c *gin.Context) {
+	client, groupJID, ok := h.groupTarget(c, "group")
+	if !ok {
+		return
+	}
+	link, err := client.GetGroupInviteLink(c.Request.Context(), groupJID, true)
+	if err != nil {
+		response.Error(c, groupErrorStatus(err), err)
+		return
+	}
+	response.Success(c, http.StatusOK, gin.H{"link": link})
+}
+
+type createCommunityRequest struct {
+	Name string `json:"name" binding:"required"`
+	// Description is set right after creating; WhatsApp doesn't take it in the create request.
+	Description string `json:"description"`
+	// JoinApproval: joining needs an admin's approval.
+	JoinApproval bool `json:"join_approval"`
+}
+
+func (h *Handler) createCommunity(c *gin.Context) {
+	instanceID, ok := h.requireInstanceToken(c)
+	if !ok {
+		return
+	}
+	var req createCommunityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	client, err := h.sessionManager.GetClient(instanceID)
+	if err != nil {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "instância não conectada")
+		return
+	}
+	parent := types.GroupParent{IsParent: true}
+	if req.JoinApproval {
+		parent.DefaultMembershipApprovalMode = "request_required"
+	}
+	info, err := client.CreateGroup(c.Request.Context(), whatsmeow.ReqCreateGroup{
+		Name:        strings.TrimSpace(req.Name),
+		GroupParent: parent,
+	})
+	if err != nil {
+		response.Error(c, groupErrorStatus(err), err)
+		return
+	}
+	if desc := strings.TrimSpace(req.Description); desc != "" {
+		if err := client.SetGroupTopic(c.Request.Context(), info.JID, "", "", desc); err != nil {
+			response.Error(c, groupErrorStatus(err), err)
+			return
+		}
+		info.Topic = desc
+	}
+	response.Success(c, http.StatusOK, info)
+}
+
+func (h *Handler) listCommunityGroups(c *gin.Context) {
+	client, communityJID, ok := h.groupTarget(c, "community")
+	if !ok {
+		return
+	}
+	groups, err := client.GetSubGroups(c.Request.Context(), communityJID)
+	if err != nil {
+		response.Error(c, groupErrorStatus(err), err)
+		return
+	}
+	response.Success(c, http.StatusOK, gin.H{"groups": groups})
+}
+
+func (h *Handler) listCommunityParticipants(c *gin.Context) {
+	client, communityJID, ok := h.groupTarget(c, "community")
+	if !ok {
+		return
+	}
+	participants, err := client.GetLinkedGroupsParticipants(c.Request.Context(), communityJID)
+	if err != nil {
+		response.Error(c, groupErrorStatus(err), err)
+		return
+	}
+	response.Success(c, http.StatusOK, gin.H{"participants": jidStrings(participants)})
+}
+
+type linkCommunityGroupRequest struct {
+	Group string `json:"group" binding:"required"`
+}
+
+func (h *Handler) linkCommunityGroup(c *gin.Context) {
+	client, communityJID, ok := h.groupTarget(c, "community")
+	if !ok {
+		return
+	}
+	var req linkCommunityGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	groupJID, err := parseGroupJID(req.Group)
+	if err != nil {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "group inválido")
+		return
+	}
+	respondGroupResult(c, client.LinkGroup(c.Request.Context(), communityJID, groupJID))
+}
+
+func (h *Handler) unlinkCommunityGroup(c *gin.Context) {
+	client, communityJID, ok := h.groupTarget(c, "community")
+	if !ok {
+		return
+	}
+	groupJID, err := parseGroupJID(c.Param("group"))
+	if err != nil {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "group inválido")
+		return
+	}
+	respondGroupResult(c, client.UnlinkGroup(c.Request.Context(), communityJID, groupJID))
+}
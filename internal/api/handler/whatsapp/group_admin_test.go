@@ -0,0 +1,53 @@
+package whatsapp
+
+import (
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+func TestParseGroupJID(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"120363000000000000@g.us", "120363000000000000@g.us", true},
+		{"120363000000000000", "120363000000000000@g.us", true},
+		{"  120363000000000000  ", "120363000000000000@g.us", true},
+		{"5511999999999@s.whatsapp.net", "", false},
+		{"123456789@lid", "", false},
+		{"120363000000000000@newsletter", "", false},
+	}
+	for _, tc := range cases {
+		jid, err := parseGroupJID(tc.in)
+		if (err == nil) != tc.ok {
+			t.Errorf("%q: erro %v; queria ok=%v", tc.in, err, tc.ok)
+			continue
+		}
+		if tc.ok && (jid.String() != tc.want || jid.Server != types.GroupServer) {
+			t.Errorf("%q: %s; queria %s", tc.in, jid, tc.want)
+		}
+	}
+}
+
+// TestDisappearingTimers: the seconds accepted are exactly the ones the clients offer, and each
+// maps to the whatsmeow duration of the same length.
+func TestDisappearingTimers(t *testing.T) {
+	for seconds, timer := range disappearingTimers {
+		if timer != time.Duration(seconds)*time.Second {
+			t.Errorf("%d segundos mapeado para %s", seconds, timer)
+		}
+	}
+	for _, seconds := range []int{0, 86400, 604800, 7776000} {
+		if _, ok := disappearingTimers[seconds]; !ok {
+			t.Errorf("%d deveria ser aceito", seconds)
+		}
+	}
+	for _, seconds := range []int{-1, 1, 3600, 2592000} {
+		if _, ok := disappearingTimers[seconds]; ok {
+			t.Errorf("%d não deveria ser aceito", seconds)
+		}
+	}
+}
@@ -33,6 +33,7 @@ func (h *Handler) Register(r *gin.RouterGroup) {
 	r.POST("/instances/:id/whatsapp/messages/delete", h.deleteForEveryone)
 	r.POST("/instances/:id/whatsapp/messages/edit", h.editMessage)
 	r.POST("/instances/:id/whatsapp/messages/react", h.sendReaction)
+	r.POST("/instances/:id/whatsapp/messages/forward", h.forwardMessage)
 	r.GET("/instances/:id/whatsapp/contacts", h.listContacts)
 	r.GET("/instances/:id/whatsapp/contacts/:jid", h.getContact)
 	r.GET("/instances/:id/whatsapp/userinfo/:jid", h.getUserInfo)
@@ -40,7 +41,21 @@ func (h *Handler) Register(r *gin.RouterGroup) {
 	r.POST("/instances/:id/whatsapp/privacy", h.setPrivacySetting)
 	r.GET("/instances/:id/whatsapp/chat-settings/:chat", h.getChatSettings)
 	r.POST("/instances/:id/whatsapp/chat-settings/:chat", h.setChatSettings)
+	r.POST("/instances/:id/whatsapp/chats/:chat/archive", h.archiveChat)
+	r.POST("/instances/:id/whatsapp/chats/:chat/pin", h.pinChat)
+	r.POST("/instances/:id/whatsapp/chats/:chat/mute", h.muteChat)
+	r.POST("/instances/:id/whatsapp/chats/:chat/unread", h.markChatUnread)
+	r.POST("/instances/:id/whatsapp/chats/:chat/clear", h.clearChat)
+	r.POST("/instances/:id/whatsapp/chats/:chat/delete", h.deleteChat)
+	r.POST("/instances/:id/whatsapp/messages/star", h.starMessage)
+	r.GET("/instances/:id/whatsapp/blocklist", h.getBlocklist)
+	r.POST("/instances/:id/whatsapp/blocklist/block", h.blockContact)
+	r.POST("/instances/:id/whatsapp/blocklist/unblock", h.unblockContact)
 	r.POST("/instances/:id/whatsapp/status", h.setStatusMessage)
+	r.POST("/instances/:id/whatsapp/profile/name", h.setPushName)
+	r.POST("/instances/:id/whatsapp/profile/picture", h.setProfilePicture)
+	r.DELETE("/instances/:id/whatsapp/profile/picture", h.removeProfilePicture)
+	r.GET("/instances/:id/whatsapp/business-profile", h.getOwnBusinessProfile)
 	r.POST("/instances/:id/whatsapp/disappearing-timer", h.setDefaultDisappearingTimer)
 	r.GET("/instances/:id/whatsapp/qr/contact", h.getContactQRLink)
 	r.POST("/instances/:id/whatsapp/qr/contact/resolve", h.resolveContactQRLink)
@@ -53,7 +68,25 @@ func (h *Handler) Register(r *gin.RouterGroup) {
 	r.POST("/instances/:id/whatsapp/groups/:group/participants", h.updateGroupParticipants)
 	r.GET("/instances/:id/whatsapp/groups/:group/requests", h.listGroupJoinRequests)
 	r.POST("/instances/:id/whatsapp/groups/:group/requests", h.updateGroupJoinRequests)
+	r.POST("/instances/:id/whatsapp/groups/:group/subject", h.setGroupSubject)
+	r.POST("/instances/:id/whatsapp/groups/:group/description", h.setGroupDescription)
+	r.POST("/instances/:id/whatsapp/groups/:group/photo", h.setGroupPhoto)
+	r.DELETE("/instances/:id/whatsapp/groups/:group/photo", h.removeGroupPhoto)
+	r.POST("/instances/:id/whatsapp/groups/:group/settings", h.setGroupSettings)
+	r.POST("/instances/:id/whatsapp/groups/:group/ephemeral", h.setGroupEphemeral)
+	r.POST("/instances/:id/whatsapp/groups/:group/invite-link/reset", h.resetGroupInviteLink)
+	r.POST("/instances/:id/whatsapp/communities", h.createCommunity)
+	r.GET("/instances/:id/whatsapp/communities/:community/groups", h.listCommunityGroups)
+	r.POST("/instances/:id/whatsapp/communities/:community/groups", h.linkCommunityGroup)
+	r.DELETE("/instances/:id/whatsapp/communities/:community/groups/:group", h.unlinkCommunityGroup)
+	r.GET("/instances/:id/whatsapp/communities/:community/participants", h.listCommunityParticipants)
 	r.GET("/instances/:id/whatsapp/status-privacy", h.getStatusPrivacy)
+	r.GET("/instances/:id/whatsapp/newsletters", h.listNewsletters)
+	r.POST("/instances/:id/whatsapp/newsletters", h.createNewsletter)
+	r.GET("/instances/:id/whatsapp/newsletters/:jid", h.getNewsletter)
+	r.POST("/instances/:id/whatsapp/newsletters/:jid/follow", h.followNewsletter)
+	r.POST("/instances/:id/whatsapp/newsletters/:jid/unfollow", h.unfollowNewsletter)
+	r.GET("/instances/:id/whatsapp/newsletters/:jid/messages", h.listNewsletterMessages)
 	r.POST("/instances/:id/whatsapp/newsletters/:jid/live-updates", h.newsletterSubscribeLiveUpdates)
 	r.POST("/instances/:id/whatsapp/newsletters/:jid/mark-viewed", h.newsletterMarkViewed)
 	r.POST("/instances/:id/whatsapp/newsletters/:jid/reaction", h.newsletterSendReaction)
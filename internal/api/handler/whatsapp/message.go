@@ -12,7 +12,6 @@ import (
 	"go.mau.fi/whatsmeow/types"
 	"google.golang.org/protobuf/proto"
 
-	"github.com/open-apime/apime/internal/pkg/instancelock"
 	"github.com/open-apime/apime/internal/pkg/response"
 	messageSvc "github.com/open-apime/apime/internal/service/message"
 )
@@ -48,10 +47,14 @@ func (h *Handler) checkIsWhatsApp(c *gin.Context) {
 		return
 	}
 
-	unlock := instancelock.Acquire(instanceID)
+	ctx, unlock, err := h.messageService.LockInstance(c.Request.Context(), instanceID)
+	if err != nil {
+		response.ErrorWithMessage(c, http.StatusServiceUnavailable, "instância ocupada, tente novamente")
+		return
+	}
 	defer unlock()
 
-	jid, err := h.messageService.ResolveJID(c.Request.Context(), client, phone)
+	jid, err := h.messageService.ResolveJID(ctx, client, phone)
 
 	result := types.IsOnWhatsAppResponse{
 		Query: phone,
@@ -159,11 +162,15 @@ func (h *Handler) deleteForEveryone(c *gin.Context) {
 		return
 	}
 
-	unlock := instancelock.Acquire(instanceID)
+	ctx, unlock, err := h.messageService.LockInstance(c.Request.Context(), instanceID)
+	if err != nil {
+		response.ErrorWithMessage(c, http.StatusServiceUnavailable, "instância ocupada, tente novamente")
+		return
+	}
 	defer unlock()
 
 	// Resolve o destino via ResolveJID (mesmo caminho do texto) — ver nota em sendReaction.
-	chatJID, err := h.messageService.ResolveJID(c.Request.Context(), client, strings.TrimSpace(req.Chat))
+	chatJID, err := h.messageService.ResolveJID(ctx, client, strings.TrimSpace(req.Chat))
 	if err != nil {
 		response.ErrorWithMessage(c, http.StatusBadRequest, "chat inválido")
 		return
@@ -184,10 +191,10 @@ func (h *Handler) deleteForEveryone(c *gin.Context) {
 
 	humanPause()
 
-	resp, err := client.RevokeMessage(c.Request.Context(), chatJID, types.MessageID(req.MessageID))
+	resp, err := client.RevokeMessage(ctx, chatJID, types.MessageID(req.MessageID))
 	if err != nil {
 		msg := client.BuildRevoke(chatJID, senderJID, types.MessageID(req.MessageID))
-		resp, err = client.SendMessage(c.Request.Context(), chatJID, msg)
+		resp, err = client.SendMessage(ctx, chatJID, msg)
 		if err != nil {
 			response.Error(c, http.StatusInternalServerError, err)
 			return
@@ -225,11 +232,15 @@ func (h *Handler) editMessage(c *gin.Context) {
 		return
 	}
 
-	unlock := instancelock.Acquire(instanceID)
+	ctx, unlock, err := h.messageService.LockInstance(c.Request.Context(), instanceID)
+	if err != nil {
+		response.ErrorWithMessage(c, http.StatusServiceUnavailable, "instância ocupada, tente novamente")
+		return
+	}
 	defer unlock()
 
 	// Resolve o destino via ResolveJID (mesmo caminho do texto) — ver nota em sendReaction.
-	chatJID, err := h.messageService.ResolveJID(c.Request.Context(), client, strings.TrimSpace(req.Chat))
+	chatJID, err := h.messageService.ResolveJID(ctx, client, strings.TrimSpace(req.Chat))
 	if err != nil {
 		response.ErrorWithMessage(c, http.StatusBadRequest, "chat inválido")
 		return
@@ -241,7 +252,7 @@ func (h *Handler) editMessage(c *gin.Context) {
 		Conversation: proto.String(req.Text),
 	})
 
-	resp, err := client.SendMessage(c.Request.Context(), chatJID, editMsg)
+	resp, err := client.SendMessage(ctx, chatJID, editMsg)
 	if err != nil {
 		response.Error(c, http.StatusInternalServerError, err)
 		return
@@ -280,14 +291,18 @@ func (h *Handler) sendReaction(c *gin.Context) {
 		return
 	}
 
-	unlock := instancelock.Acquire(instanceID)
+	ctx, unlock, err := h.messageService.LockInstance(c.Request.Context(), instanceID)
+	if err != nil {
+		response.ErrorWithMessage(c, http.StatusServiceUnavailable, "instância ocupada, tente novamente")
+		return
+	}
 	defer unlock()
 
 	// Resolve o destino pelo mesmo caminho do envio de texto (ResolveJID popula o
 	// PN->LID mapping in the library store). Without it SendMessage forces PN->LID and fails with
 	// "no LID found ... from server" when the LID is not cached. Handles @lid/@g.us. Inside the
 	// lock, like the text send, to serialize IsOnWhatsApp per instance.
-	chatJID, err := h.messageService.ResolveJID(c.Request.Context(), client, strings.TrimSpace(req.Chat))
+	chatJID, err := h.messageService.ResolveJID(ctx, client, strings.TrimSpace(req.Chat))
 	if err != nil {
 		response.ErrorWithMessage(c, http.StatusBadRequest, "chat inválido")
 		return
@@ -322,7 +337,7 @@ func (h *Handler) sendReaction(c *gin.Context) {
 
 	humanPause()
 
-	resp, err := client.SendMessage(c.Request.Context(), chatJID, waMessage)
+	resp, err := client.SendMessage(ctx, chatJID, waMessage)
 	if err != nil {
 		response.Error(c, http.StatusInternalServerError, err)
 		return
@@ -334,3 +349,43 @@ func (h *Handler) sendReaction(c *gin.Context) {
 		"id":        resp.ID,
 	})
 }
+
+type forwardMessageRequest struct {
+	MessageID string   `json:"message_id" binding:"required"`
+	To        []string `json:"to" binding:"required,min=1"`
+}
+
+// forwardMessage answers 200 with one result per recipient: each one is sent on its own, so some
+// may fail while others go out.
+func (h *Handler) forwardMessage(c *gin.Context) {
+	instanceID, ok := h.requireInstanceToken(c)
+	if !ok {
+		return
+	}
+
+	var req forwardMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err)
+		return
+	}
+
+	results, err := h.messageService.Forward(c.Request.Context(), messageSvc.ForwardInput{
+		InstanceID: instanceID,
+		MessageID:  strings.TrimSpace(req.MessageID),
+		To:         req.To,
+	})
+	if err != nil {
+		if errors.Is(err, messageSvc.ErrForwardNotFound) {
+			response.Error(c, http.StatusNotFound, err)
+		} else if errors.Is(err, messageSvc.ErrForwardViewOnce) || errors.Is(err, messageSvc.ErrUnsupportedMediaType) {
+			response.Error(c, http.StatusUnprocessableEntity, err)
+		} else if errors.Is(err, messageSvc.ErrForwardLimit) || errors.Is(err, messageSvc.ErrInvalidPayload) {
+			response.Error(c, http.StatusBadRequest, err)
+		} else {
+			response.Error(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{"results": results})
+}
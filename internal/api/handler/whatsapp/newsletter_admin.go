@@ -0,0 +1,185 @@
+package whatsapp
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+
+	"github.com/open-apime/apime/internal/pkg/response"
+	"github.com/open-apime/apime/internal/pkg/thumbnail"
+)
+
+const (
+	defaultNewsletterMessages = 50
+	maxNewsletterMessages     = 100
+)
+
+// newsletterTarget reads the :jid parameter and the client of the instance, answering the request
+// when either is missing.
+func (h *Handler) newsletterTarget(c *gin.Context) (*whatsmeow.Client, types.JID, bool) {
+	instanceID, ok := h.requireInstanceToken(c)
+	if !ok {
+		return nil, types.EmptyJID, false
+	}
+	jid, err := types.ParseJID(strings.TrimSpace(c.Param("jid")))
+	if err != nil || jid.Server != types.NewsletterServer {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "jid inválido")
+		return nil, types.EmptyJID, false
+	}
+	client, err := h.sessionManager.GetClient(instanceID)
+	if err != nil {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "instância não conectada")
+		return nil, types.EmptyJID, false
+	}
+	return client, jid, true
+}
+
+// newsletterPicture crops the picture sent by the caller square and re-encodes it to the JPEG
+// WhatsApp accepts, like group and profile pictures.
+func newsletterPicture(c *gin.Context, picture []byte) ([]byte, bool) {
+	if len(picture) > maxGroupPhotoSize {
+		response.ErrorWithMessage(c, http.StatusRequestEntityTooLarge, "foto maior que 5 MB")
+		return nil, false
+	}
+	photo, err := thumbnail.SquareJPEG(bytes.NewReader(picture), groupPhotoSide)
+	if err != nil {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "foto inválida: envie JPEG, PNG ou GIF")
+		return nil, false
+	}
+	return photo.JPEG, true
+}
+
+type createNewsletterRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	// Picture is an image in base64 (JPEG, PNG or GIF).
+	Picture []byte `json:"picture"`
+}
+
+func (h *Handler) createNewsletter(c *gin.Context) {
+	instanceID, ok := h.requireInstanceToken(c)
+	if !ok {
+		return
+	}
+	var req createNewsletterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	client, err := h.sessionManager.GetClient(instanceID)
+	if err != nil {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "instância não conectada")
+		return
+	}
+	params := whatsmeow.CreateNewsletterParams{
+		Name:        strings.TrimSpace(req.Name),
+		Description: strings.TrimSpace(req.Description),
+	}
+	if len(req.Picture) > 0 {
+		if params.Picture, ok = newsletterPicture(c, req.Picture); !ok {
+			return
+		}
+	}
+	meta, err := client.CreateNewsletter(c.Request.Context(), params)
+	if err != nil {
+		response.Error(c, groupErrorStatus(err), err)
+		return
+	}
+	response.Success(c, http.StatusOK, meta)
+}
+
+func (h *Handler) listNewsletters(c *gin.Context) {
+	instanceID, ok := h.requireInstanceToken(c)
+	if !ok {
+		return
+	}
+	client, err := h.sessionManager.GetClient(instanceID)
+	if err != nil {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "instância não conectada")
+		return
+	}
+	list, err := client.GetSubscribedNewsletters(c.Request.Context())
+	if err != nil {
+		response.Error(c, groupErrorStatus(err), err)
+		return
+	}
+	response.Success(c, http.StatusOK, gin.H{"newsletters": list})
+}
+
+func (h *Handler) getNewsletter(c *gin.Context) {
+	client, jid, ok := h.newsletterTarget(c)
+	if !ok {
+		return
+	}
+	meta, err := client.GetNewsletterInfo(c.Request.Context(), jid)
+	if err != nil {
+		response.Error(c, groupErrorStatus(err), err)
+		return
+	}
+	response.Success(c, http.StatusOK, meta)
+}
+
+func (h *Handler) followNewsletter(c *gin.Context) {
+	client, jid, ok := h.newsletterTarget(c)
+	if !ok {
+		return
+	}
+	respondGroupResult(c, client.FollowNewsletter(c.Request.Context(), jid))
+}
+
+func (h *Handler) unfollowNewsletter(c *gin.Context) {
+	client, jid, ok := h.newsletterTarget(c)
+	if !ok {
+		return
+	}
+	respondGroupResult(c, client.UnfollowNewsletter(c.Request.Context(), jid))
+}
+
+// listNewsletterMessages pages back from the newest post: count (default 50, at most 100) and
+// before, the server_id the page ends before. next_before is the before of the next page, absent
+// on the last one.
+func (h *Handler) listNewsletterMessages(c *gin.Context) {
+	client, jid, ok := h.newsletterTarget(c)
+	if !ok {
+		return
+	}
+	count := defaultNewsletterMessages
+	if v := strings.TrimSpace(c.Query("count")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxNewsletterMessages {
+			response.ErrorWithMessage(c, http.StatusBadRequest, "count deve estar entre 1 e 100")
+			return
+		}
+		count = n
+	}
+	params := &whatsmeow.GetNewsletterMessagesParams{Count: count}
+	if v := strings.TrimSpace(c.Query("before")); v != "" {
+		before, err := strconv.Atoi(v)
+		if err != nil || before < 1 {
+			response.ErrorWithMessage(c, http.StatusBadRequest, "before inválido")
+			return
+		}
+		params.Before = types.MessageServerID(before)
+	}
+	messages, err := client.GetNewsletterMessages(c.Request.Context(), jid, params)
+	if err != nil {
+		response.Error(c, groupErrorStatus(err), err)
+		return
+	}
+	body := gin.H{"messages": messages}
+	if len(messages) == count {
+		oldest := messages[0].MessageServerID
+		for _, m := range messages {
+			if m.MessageServerID < oldest {
+				oldest = m.MessageServerID
+			}
+		}
+		body["next_before"] = oldest
+	}
+	response.Success(c, http.StatusOK, body)
+}
@@ -0,0 +1,124 @@
+package whatsapp
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.mau.fi/whatsmeow"
+)
+
+type fakeSessions struct {
+	client *whatsmeow.Client
+	err    error
+}
+
+func (f fakeSessions) GetClient(string) (*whatsmeow.Client, error) {
+	return f.client, f.err
+}
+
+// newsletterRouter registers the handler behind a stand-in for the auth middleware, with the
+// instance token of "inst".
+func newsletterRouter(sessions SessionManager) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	api := r.Group("/api", func(c *gin.Context) {
+		c.Set("authType", "instance_token")
+		c.Set("instanceID", "inst")
+	})
+	NewHandler(sessions, nil).Register(api)
+	return r
+}
+
+func serve(r *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return w
+}
+
+func TestNewsletterTarget(t *testing.T) {
+	connected := newsletterRouter(fakeSessions{client: &whatsmeow.Client{}})
+	cases := []struct {
+		name   string
+		router *gin.Engine
+		path   string
+		status int
+	}{
+		{"jid de grupo", connected, "/api/instances/inst/whatsapp/newsletters/120363000000000000@g.us/messages", http.StatusBadRequest},
+		{"jid inválido", connected, "/api/instances/inst/whatsapp/newsletters/nada/messages", http.StatusBadRequest},
+		{"outra instância", connected, "/api/instances/outra/whatsapp/newsletters/120363000000000000@newsletter/messages", http.StatusForbidden},
+		{"desconectada", newsletterRouter(fakeSessions{err: errors.New("sem sessão")}), "/api/instances/inst/whatsapp/newsletters/120363000000000000@newsletter/messages", http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		if w := serve(tc.router, http.MethodGet, tc.path, ""); w.Code != tc.status {
+			t.Errorf("%s: status %d; queria %d (%s)", tc.name, w.Code, tc.status, w.Body)
+		}
+	}
+}
+
+// TestNewsletterMessagesParams covers the checks made before anything reaches WhatsApp.
+func TestNewsletterMessagesParams(t *testing.T) {
+	r := newsletterRouter(fakeSessions{client: &whatsmeow.Client{}})
+	base := "/api/instances/inst/whatsapp/newsletters/120363000000000000@newsletter/messages"
+	for _, query := range []string{"?count=0", "?count=101", "?count=abc", "?before=0", "?before=-5", "?before=x"} {
+		if w := serve(r, http.MethodGet, base+query, ""); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d; queria 400", query, w.Code)
+		}
+	}
+}
+
+// TestNewsletterEditRoutesRemoved: editing and deleting a channel went through undocumented query
+// ids and were dropped; the routes must not come back by accident.
+func TestNewsletterEditRoutesRemoved(t *testing.T) {
+	r := newsletterRouter(fakeSessions{client: &whatsmeow.Client{}})
+	path := "/api/instances/inst/whatsapp/newsletters/120363000000000000@newsletter"
+	for _, method := range []string{http.MethodPost, http.MethodDelete} {
+		if w := serve(r, method, path, `{"name":"x"}`); w.Code != http.StatusNotFound {
+			t.Errorf("%s %s: status %d; queria 404", method, path, w.Code)
+		}
+	}
+}
+
+func TestNewsletterPicture(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	picture := func(t *testing.T, data []byte) ([]byte, int) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		out, ok := newsletterPicture(c, data)
+		if ok {
+			return out, 0
+		}
+		return nil, w.Code
+	}
+
+	var src bytes.Buffer
+	if err := png.Encode(&src, image.NewRGBA(image.Rect(0, 0, 300, 200))); err != nil {
+		t.Fatal(err)
+	}
+	out, status := picture(t, src.Bytes())
+	if status != 0 {
+		t.Fatalf("foto válida recusada com %d", status)
+	}
+	img, err := jpeg.Decode(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("saída não é JPEG: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != b.Dy() {
+		t.Fatalf("foto deveria sair quadrada, veio %dx%d", b.Dx(), b.Dy())
+	}
+
+	if _, status := picture(t, []byte("não é imagem")); status != http.StatusBadRequest {
+		t.Fatalf("imagem inválida: status %d; queria 400", status)
+	}
+	if _, status := picture(t, make([]byte, maxGroupPhotoSize+1)); status != http.StatusRequestEntityTooLarge {
+		t.Fatalf("foto grande: status %d; queria 413", status)
+	}
+}
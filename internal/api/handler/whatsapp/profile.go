@@ -0,0 +1,115 @@
+package whatsapp
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/appstate"
+	"go.mau.fi/whatsmeow/types"
+
+	"github.com/open-apime/apime/internal/pkg/response"
+	"github.com/open-apime/apime/internal/pkg/thumbnail"
+)
+
+const maxPushNameLength = 25
+
+// ownTarget returns the client of the instance and the account's own JID, answering the request
+// when the instance isn't logged in.
+func (h *Handler) ownTarget(c *gin.Context) (*whatsmeow.Client, types.JID, bool) {
+	instanceID, ok := h.requireInstanceToken(c)
+	if !ok {
+		return nil, types.EmptyJID, false
+	}
+	client, err := h.sessionManager.GetClient(instanceID)
+	if err != nil || client.Store == nil || client.Store.ID == nil {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "instância não conectada")
+		return nil, types.EmptyJID, false
+	}
+	return client, client.Store.ID.ToNonAD(), true
+}
+
+// setProfilePicture takes the picture as the multipart field "photo" (JPEG, PNG or GIF). It's
+// cropped to its centered square here, so WhatsApp doesn't crop it differently on each client.
+func (h *Handler) setProfilePicture(c *gin.Context) {
+	client, _, ok := h.ownTarget(c)
+	if !ok {
+		return
+	}
+	header, err := c.FormFile("photo")
+	if err != nil {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "campo 'photo' é obrigatório")
+		return
+	}
+	if header.Size > maxGroupPhotoSize {
+		response.ErrorWithMessage(c, http.StatusRequestEntityTooLarge, "foto maior que 5 MB")
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	defer file.Close()
+	photo, err := thumbnail.SquareJPEG(file, groupPhotoSide)
+	if err != nil {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "foto inválida: envie JPEG, PNG ou GIF")
+		return
+	}
+	// Without a target the picture request applies to the account itself.
+	pictureID, err := client.SetGroupPhoto(c.Request.Context(), types.EmptyJID, photo.JPEG)
+	if err != nil {
+		response.Error(c, groupErrorStatus(err), err)
+		return
+	}
+	response.Success(c, http.StatusOK, gin.H{"picture_id": pictureID})
+}
+
+func (h *Handler) removeProfilePicture(c *gin.Context) {
+	client, _, ok := h.ownTarget(c)
+	if !ok {
+		return
+	}
+	_, err := client.SetGroupPhoto(c.Request.Context(), types.EmptyJID, nil)
+	respondGroupResult(c, err)
+}
+
+type setPushNameRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// setPushName changes the name contacts without the number saved see. It's an app state setting:
+// whatsmeow applies it to the device store when the collection is fetched back after the patch.
+func (h *Handler) setPushName(c *gin.Context) {
+	client, _, ok := h.ownTarget(c)
+	if !ok {
+		return
+	}
+	var req setPushNameRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len([]rune(name)) > maxPushNameLength {
+		response.ErrorWithMessage(c, http.StatusBadRequest, "name deve ter entre 1 e 25 caracteres")
+		return
+	}
+	sendAppState(c, client, appstate.BuildSettingPushName(name))
+}
+
+// getOwnBusinessProfile is read only: whatsmeow has no method to edit the business profile or to
+// read the catalog, and the IQs the business app sends for them aren't documented.
+func (h *Handler) getOwnBusinessProfile(c *gin.Context) {
+	client, jid, ok := h.ownTarget(c)
+	if !ok {
+		return
+	}
+	profile, err := client.GetBusinessProfile(c.Request.Context(), jid)
+	if err != nil {
+		response.Error(c, groupErrorStatus(err), err)
+		return
+	}
+	response.Success(c, http.StatusOK, profile)
+}
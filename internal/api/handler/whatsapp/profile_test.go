@@ -0,0 +1,28 @@
+package whatsapp
+
+import (
+	"net/http"
+	"testing"
+
+	"go.mau.fi/whatsmeow"
+)
+
+// TestBusinessEditRoutesRemoved: editing the business profile and reading the catalog went through
+// undocumented IQs and were dropped; reading the business profile stays.
+func TestBusinessEditRoutesRemoved(t *testing.T) {
+	r := newsletterRouter(fakeSessions{client: &whatsmeow.Client{}})
+	base := "/api/instances/inst/whatsapp/"
+	removed := []struct{ method, path string }{
+		{http.MethodPost, base + "business-profile"},
+		{http.MethodGet, base + "catalog"},
+	}
+	for _, route := range removed {
+		if w := serve(r, route.method, route.path, `{"description":"x"}`); w.Code != http.StatusNotFound {
+			t.Errorf("%s %s: status %d; queria 404", route.method, route.path, w.Code)
+		}
+	}
+	// Not logged in: the route answers before reaching WhatsApp.
+	if w := serve(r, http.MethodGet, base+"business-profile", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("GET business-profile: status %d; queria 400", w.Code)
+	}
+}
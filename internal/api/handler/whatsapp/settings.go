@@ -10,6 +10,7 @@ import (
 	"go.mau.fi/whatsmeow/types"
 
 	"github.com/open-apime/apime/internal/pkg/response"
+	messageSvc "github.com/open-apime/apime/internal/service/message"
 )
 
 func (h *Handler) getStatusPrivacy(c *gin.Context) {
@@ -103,6 +104,10 @@ func (h *Handler) setStatusMessage(c *gin.Context) {
 		response.Error(c, http.StatusInternalServerError, err)
 		return
 	}
+	if client.Store != nil && client.Store.ID != nil {
+		// The instance info shows the about from a cache; it must not keep the old one.
+		messageSvc.ForgetOwnInfo(*client.Store.ID)
+	}
 	response.Success(c, http.StatusOK, gin.H{"status": "ok"})
 }
 
@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/open-apime/apime/internal/pkg/ownership"
+)
+
+// HeaderForwardedBy marks a request already forwarded by another replica, so
+// the owner never bounces it again (e.g. during a lease handover).
+const HeaderForwardedBy = "X-ApiMe-Forwarded-By"
+
+// InstanceOwnerOption configures the forwarding of instance routes.
+type InstanceOwnerOption struct {
+	Ownership ownership.Ownership
+	Logger    *zap.Logger
+}
+
+// ownedRoutePrefixes are the routes that act on a single instance: the API
+// and the dashboard pages that reach its session.
+var ownedRoutePrefixes = []string{"/api/instances/:id", "/dashboard/instances/:id"}
+
+func isOwnedRoute(path string) bool {
+	for _, prefix := range ownedRoutePrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// InstanceOwner proxies /instances/:id/* requests to the replica that holds
+// the lease of the instance. Unowned instances run locally: the session
+// manager claims the lease when it actually connects the device.
+func InstanceOwner(opts InstanceOwnerOption) gin.HandlerFunc {
+	if opts.Ownership == nil {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	var proxies sync.Map // addr -> *httputil.ReverseProxy
+
+	return func(c *gin.Context) {
+		instanceID := c.Param("id")
+		if instanceID == "" || !isOwnedRoute(c.FullPath()) || c.GetHeader(HeaderForwardedBy) != "" {
+			c.Next()
+			return
+		}
+		if opts.Ownership.IsLocal(instanceID) {
+			c.Next()
+			return
+		}
+
+		owner, ok, err := opts.Ownership.Owner(c.Request.Context(), instanceID)
+		if err != nil {
+			if opts.Logger != nil {
+				opts.Logger.Warn("ownership: erro ao consultar dono, executando localmente",
+					zap.String("instance_id", instanceID), zap.Error(err))
+			}
+			c.Next()
+			return
+		}
+		self := opts.Ownership.Self()
+		if !ok || owner.ID == self.ID {
+			c.Next()
+			return
+		}
+		if owner.Addr == "" {
+			c.Header("Retry-After", "5")
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+				"error": "instância em transição entre réplicas, tente novamente",
+			})
+			return
+		}
+
+		proxy, err := ownerProxy(&proxies, owner.Addr, opts.Logger)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadGateway, gin.H{"error": "endereço da réplica dona inválido"})
+			return
+		}
+
+		c.Request.Header.Set(HeaderForwardedBy, self.ID)
+		if opts.Logger != nil {
+			opts.Logger.Debug("ownership: encaminhando requisição para a réplica dona",
+				zap.String("instance_id", instanceID),
+				zap.String("owner", owner.ID),
+			)
+		}
+		proxy.ServeHTTP(c.Writer, c.Request)
+		c.Abort()
+	}
+}
+
+func ownerProxy(cache *sync.Map, addr string, log *zap.Logger) (*httputil.ReverseProxy, error) {
+	if p, ok := cache.Load(addr); ok {
+		return p.(*httputil.ReverseProxy), nil
+	}
+	target, err := url.Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		if log != nil {
+			log.Warn("ownership: falha ao encaminhar para a réplica dona", zap.String("addr", addr), zap.Error(err))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadGateway)
+		_, _ = w.Write([]byte(`{"error":"réplica dona da instância indisponível"}`))
+	}
+	p, _ := cache.LoadOrStore(addr, proxy)
+	return p.(*httputil.ReverseProxy), nil
+}
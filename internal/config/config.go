@@ -3,8 +3,11 @@ package config
 import (
 	"fmt"
 	"log"
+	"os"
 
 	"github.com/caarlos0/env/v10"
+
+	"github.com/open-apime/apime/internal/pkg/crypto"
 )
 
 type Config struct {
@@ -14,10 +17,15 @@ type Config struct {
 	JWT         JWTConfig
 	Log         LogConfig
 	Storage     StorageConfig
+	Media       MediaConfig
+	LinkPreview LinkPreviewConfig
 	RateLimit   RateLimitConfig
 	IPRateLimit IPRateLimitConfig
 	WhatsApp    WhatsAppConfig
+	Governor    GovernorConfig
 	Webhook     WebhookConfig
+	Readiness   ReadinessConfig
+	Cluster     ClusterConfig
 	Dashboard   DashboardConfig
 	Sentry      SentryConfig
 }
@@ -26,6 +34,34 @@ type StorageConfig struct {
 	Driver          string `env:"DB_DRIVER" envDefault:"sqlite"`
 	DataDir         string `env:"DATA_DIR" envDefault:"/app/data"`
 	MediaTTLSeconds int    `env:"MEDIA_TTL_SECONDS" envDefault:"7200"`
+	// MediaURLSecret signs the media URLs sent in webhooks; JWT_SECRET is used when empty.
+	MediaURLSecret     string `env:"MEDIA_URL_SECRET" envDefault:""`
+	MediaURLTTLSeconds int    `env:"MEDIA_URL_TTL_SECONDS" envDefault:"7200"`
+}
+
+// MediaConfig caps media size per kind, in MB (0 = no limit). It applies to uploads on the send
+// endpoints and to inbound media downloads. Uploads above UploadMemoryMB are spooled to temp
+// files instead of being held in memory.
+type MediaConfig struct {
+	MaxImageMB     int `env:"MEDIA_MAX_IMAGE_MB" envDefault:"16"`
+	MaxVideoMB     int `env:"MEDIA_MAX_VIDEO_MB" envDefault:"0"`
+	MaxAudioMB     int `env:"MEDIA_MAX_AUDIO_MB" envDefault:"16"`
+	MaxDocumentMB  int `env:"MEDIA_MAX_DOCUMENT_MB" envDefault:"0"`
+	MaxStickerMB   int `env:"MEDIA_MAX_STICKER_MB" envDefault:"1"`
+	UploadMemoryMB int `env:"MEDIA_UPLOAD_MEMORY_MB" envDefault:"8"`
+	// RefRetentionDays is how long received media stays downloadable on demand; 0 keeps it forever.
+	RefRetentionDays int `env:"MEDIA_REF_RETENTION_DAYS" envDefault:"30"`
+}
+
+// LinkPreviewConfig controls the previews fetched for links in outgoing text. Fetching is opt-in
+// per message ("linkPreview": true); with Enabled=false only caller-supplied previews are sent.
+// Private and loopback addresses are never fetched.
+type LinkPreviewConfig struct {
+	Enabled        bool   `env:"LINK_PREVIEW_ENABLED" envDefault:"true"`
+	TimeoutSeconds int    `env:"LINK_PREVIEW_TIMEOUT_SECONDS" envDefault:"5"`
+	MaxPageKB      int    `env:"LINK_PREVIEW_MAX_PAGE_KB" envDefault:"512"`
+	MaxImageKB     int    `env:"LINK_PREVIEW_MAX_IMAGE_KB" envDefault:"2048"`
+	UserAgent      string `env:"LINK_PREVIEW_USER_AGENT" envDefault:"WhatsApp/2"`
 }
 
 type AppConfig struct {
@@ -61,6 +97,8 @@ type RedisConfig struct {
 	Password string `env:"REDIS_PASSWORD" envDefault:""`
 	DB       int    `env:"REDIS_DB" envDefault:"0"`
 	Enabled  bool   `env:"REDIS_ENABLED" envDefault:"false"`
+	// LockTTLSeconds bounds how long a crashed replica keeps an instance locked.
+	LockTTLSeconds int `env:"REDIS_INSTANCE_LOCK_TTL_SECONDS" envDefault:"30"`
 }
 
 type RateLimitConfig struct {
@@ -86,15 +124,95 @@ type LogConfig struct {
 	Level string `env:"LOG_LEVEL" envDefault:"debug"`
 }
 
+// DefaultSessionKey is the built-in WHATSAPP_SESSION_KEY_ENC. It is public, so the API refuses to
+// start with it (or the .env.example placeholder) when APP_ENV=production.
+const DefaultSessionKey = "apime-session-key-change-in-production"
+
+// WhatsAppConfig: session data is sealed with a key ring. SessionKeyFile (a KEK file with
+// "id:secret" lines) takes precedence over SessionKeys ("id:secret,id:secret"), which takes
+// precedence over the single SessionKeyEnc (ID "0"). The first key is the one new data uses.
 type WhatsAppConfig struct {
 	SessionKeyEnc             string `env:"WHATSAPP_SESSION_KEY_ENC" envDefault:"apime-session-key-change-in-production"`
+	SessionKeys               string `env:"WHATSAPP_SESSION_KEYS" envDefault:""`
+	SessionKeyFile            string `env:"WHATSAPP_SESSION_KEK_FILE" envDefault:""`
 	JIDCachePositiveTTLHours  int    `env:"WHATSAPP_JID_CACHE_POSITIVE_TTL_HOURS" envDefault:"24"`
 	JIDCacheNegativeTTLDays   int    `env:"WHATSAPP_JID_CACHE_NEGATIVE_TTL_DAYS" envDefault:"7"`
 	JIDCachePositiveDBTTLDays int    `env:"WHATSAPP_JID_CACHE_POSITIVE_DB_TTL_DAYS" envDefault:"15"`
 }
 
+// UsesDefaultSessionKey reports whether the session data would be sealed with a well-known key:
+// the primary key of the ring, whichever source it comes from, is checked. An unreadable or
+// malformed key file answers false; loading the ring reports that error on its own.
+func (cfg WhatsAppConfig) UsesDefaultSessionKey() bool {
+	secret := cfg.SessionKeyEnc
+	spec := cfg.SessionKeys
+	if cfg.SessionKeyFile != "" {
+		data, err := os.ReadFile(cfg.SessionKeyFile)
+		if err != nil {
+			return false
+		}
+		spec = string(data)
+	}
+	if spec != "" {
+		keys, err := crypto.ParseKeys(spec)
+		if err != nil || len(keys) == 0 {
+			return false
+		}
+		secret = keys[0].Secret
+	}
+	return secret == DefaultSessionKey || secret == "change-me" || secret == ""
+}
+
+// GovernorConfig caps the send volume of each instance. First contact means a
+// contact with no inbound in the last 14 days; ongoing covers replies, groups
+// and newsletters. A zero cap disables it. While an instance is slowed down
+// (after a temporary ban or repeated 463s) every cap is scaled by
+// SlowdownPercent.
+type GovernorConfig struct {
+	Enabled bool `env:"GOVERNOR_ENABLED" envDefault:"true"`
+
+	// Warm-up of newly paired numbers: distinct new contacts per day start at
+	// WarmupStartNewContacts and grow WarmupGrowthPercent a day until WarmupDays.
+	WarmupDays             int `env:"GOVERNOR_WARMUP_DAYS" envDefault:"14"`
+	WarmupStartNewContacts int `env:"GOVERNOR_WARMUP_START_NEW_CONTACTS" envDefault:"20"`
+	WarmupGrowthPercent    int `env:"GOVERNOR_WARMUP_GROWTH_PERCENT" envDefault:"25"`
+
+	FirstContactPerMinute int `env:"GOVERNOR_FIRST_CONTACT_PER_MINUTE" envDefault:"4"`
+	FirstContactPerHour   int `env:"GOVERNOR_FIRST_CONTACT_PER_HOUR" envDefault:"60"`
+	FirstContactPerDay    int `env:"GOVERNOR_FIRST_CONTACT_PER_DAY" envDefault:"300"`
+	OngoingPerMinute      int `env:"GOVERNOR_ONGOING_PER_MINUTE" envDefault:"40"`
+	OngoingPerHour        int `env:"GOVERNOR_ONGOING_PER_HOUR" envDefault:"1000"`
+	OngoingPerDay         int `env:"GOVERNOR_ONGOING_PER_DAY" envDefault:"10000"`
+
+	SlowdownPercent int `env:"GOVERNOR_SLOWDOWN_PERCENT" envDefault:"25"`
+	SlowdownHours   int `env:"GOVERNOR_SLOWDOWN_HOURS" envDefault:"48"`
+	// 463s within a day that trigger the slow-down.
+	ReachoutStrikes int `env:"GOVERNOR_REACHOUT_STRIKES" envDefault:"3"`
+}
+
 type WebhookConfig struct {
 	Workers int `env:"WEBHOOK_WORKERS" envDefault:"4"`
+	// Ordering: none, instance or chat. See webhook.Pool.SetOrdering.
+	Ordering string `env:"WEBHOOK_ORDERING" envDefault:"none"`
+}
+
+// ReadinessConfig tunes /readyz. A webhook backlog above WebhookQueueMax means
+// the workers are not keeping up, so the replica is reported as not ready.
+type ReadinessConfig struct {
+	WebhookQueueMax int `env:"READYZ_WEBHOOK_QUEUE_MAX" envDefault:"5000"`
+	TimeoutSeconds  int `env:"READYZ_TIMEOUT_SECONDS" envDefault:"3"`
+}
+
+// ClusterConfig only matters with REDIS_ENABLED: each instance is then owned by
+// a single replica through a Redis lease. AdvertiseAddr is the base URL the
+// other replicas use to forward requests to this one (e.g. http://10.0.0.5:8080).
+type ClusterConfig struct {
+	NodeID          string `env:"CLUSTER_NODE_ID" envDefault:""`
+	AdvertiseAddr   string `env:"CLUSTER_ADVERTISE_ADDR" envDefault:""`
+	LeaseTTLSeconds int    `env:"CLUSTER_LEASE_TTL_SECONDS" envDefault:"30"`
+	// MaxInstances caps the instances a single replica runs; 0 leaves only
+	// the even split across live replicas.
+	MaxInstances int `env:"CLUSTER_MAX_INSTANCES" envDefault:"0"`
 }
 
 type DashboardConfig struct {
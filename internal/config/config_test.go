@@ -0,0 +1,41 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUsesDefaultSessionKey(t *testing.T) {
+	dir := t.TempDir()
+	weakFile := filepath.Join(dir, "weak.kek")
+	strongFile := filepath.Join(dir, "strong.kek")
+	if err := os.WriteFile(weakFile, []byte("# chaves\n1:change-me\n0:uma-chave-forte-antiga\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(strongFile, []byte("1:uma-chave-forte\n0:"+DefaultSessionKey+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name string
+		cfg  WhatsAppConfig
+		want bool
+	}{
+		{"chave única padrão", WhatsAppConfig{SessionKeyEnc: DefaultSessionKey}, true},
+		{"chave única vazia", WhatsAppConfig{}, true},
+		{"chave única forte", WhatsAppConfig{SessionKeyEnc: "uma-chave-forte"}, false},
+		{"lista com a chave padrão como primária", WhatsAppConfig{SessionKeys: "0:" + DefaultSessionKey}, true},
+		{"lista com primária forte", WhatsAppConfig{SessionKeyEnc: DefaultSessionKey, SessionKeys: "1:uma-chave-forte,0:" + DefaultSessionKey}, false},
+		{"arquivo com primária fraca", WhatsAppConfig{SessionKeyFile: weakFile}, true},
+		{"arquivo com primária forte", WhatsAppConfig{SessionKeyFile: strongFile, SessionKeys: "0:change-me"}, false},
+		{"arquivo inexistente", WhatsAppConfig{SessionKeyFile: filepath.Join(dir, "nao-existe")}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.cfg.UsesDefaultSessionKey(); got != tc.want {
+				t.Fatalf("UsesDefaultSessionKey() = %v, esperado %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/open-apime/apime/internal/api/middleware"
 	"github.com/open-apime/apime/internal/config"
+	"github.com/open-apime/apime/internal/pkg/ownership"
 	"github.com/open-apime/apime/internal/service/api_token"
 	"github.com/open-apime/apime/internal/service/auth"
 	"github.com/open-apime/apime/internal/service/instance"
@@ -33,6 +34,9 @@ type Options struct {
 	UserService     *user.Service
 	APITokenService *api_token.Service
 	SessionManager  SessionManager
+	Ownership       ownership.Ownership
+	Antiban         AntibanService
+	Health          HealthService
 	JWTSecret       string
 	DocsDirectory   string
 	BaseURL         string
@@ -47,6 +51,8 @@ type Handler struct {
 	users          *user.Service
 	tokens         *api_token.Service
 	sessionManager SessionManager
+	antiban        AntibanService
+	health         HealthService
 	logger         *zap.Logger
 	docsDir        string
 	baseURL        string
@@ -79,6 +85,8 @@ func Register(router *gin.Engine, opts Options) error {
 		users:          opts.UserService,
 		tokens:         opts.APITokenService,
 		sessionManager: opts.SessionManager,
+		antiban:        opts.Antiban,
+		health:         opts.Health,
 		logger:         opts.Logger,
 		docsDir:        opts.DocsDirectory,
 		baseURL:        opts.BaseURL,
@@ -98,6 +106,9 @@ func Register(router *gin.Engine, opts Options) error {
 
 	group := router.Group("/dashboard")
 	group.Use(middleware.DashboardAuth(opts.JWTSecret, h.users))
+	// QR, disconnect and diagnostics need the live session, which only the
+	// owning replica has.
+	group.Use(middleware.InstanceOwner(middleware.InstanceOwnerOption{Ownership: opts.Ownership, Logger: opts.Logger}))
 
 	group.GET("", h.overview)
 	group.GET("/instances", func(c *gin.Context) {
@@ -122,6 +133,11 @@ func Register(router *gin.Engine, opts Options) error {
 	adminGroup.GET("/users/:id/tokens", h.listUserTokens)
 	adminGroup.POST("/users/:id/tokens", h.createUserToken)
 	adminGroup.POST("/users/:id/tokens/:tokenID/delete", h.deleteUserToken)
+	if h.antiban != nil {
+		adminGroup.GET("/antiban", h.antibanPage)
+		adminGroup.POST("/antiban/reachout/clear", h.clearReachoutLock)
+		adminGroup.POST("/antiban/jid-cache/purge", h.purgeJIDCache)
+	}
 
 	group.GET("/docs", h.docsPage)
 	group.GET("/docs/openapi", h.downloadOpenAPI)
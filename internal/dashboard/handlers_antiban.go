@@ -0,0 +1,124 @@
+package dashboard
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/open-apime/apime/internal/storage/model"
+)
+
+// AntibanService is the operator view over the reach-out locks and the JID
+// resolution cache (implemented by the message service).
+type AntibanService interface {
+	ListReachoutLocks(ctx context.Context, instanceID, contact string) ([]model.ReachoutLock, error)
+	ClearReachoutLock(ctx context.Context, instanceID, contact string) (bool, error)
+	ListJIDCache(ctx context.Context, kind string) ([]model.JIDCacheEntry, error)
+	LookupJID(ctx context.Context, phone string) (model.JIDLookup, error)
+	PurgeJID(ctx context.Context, phone string) (bool, error)
+	PurgeJIDCache(ctx context.Context, kind string) (int, error)
+}
+
+const antibanPath = "/dashboard/antiban"
+
+func (h *Handler) antibanPage(c *gin.Context) {
+	ctx := c.Request.Context()
+	instanceID := strings.TrimSpace(c.Query("instanceId"))
+	contact := strings.TrimSpace(c.Query("contact"))
+	kind := c.Query("type")
+	if kind != "positive" && kind != "negative" {
+		kind = ""
+	}
+	phone := strings.TrimSpace(c.Query("phone"))
+
+	locks, err := h.antiban.ListReachoutLocks(ctx, instanceID, contact)
+	if err != nil {
+		h.renderError(c, err)
+		return
+	}
+	entries, err := h.antiban.ListJIDCache(ctx, kind)
+	if err != nil {
+		h.renderError(c, err)
+		return
+	}
+
+	var lookup *model.JIDLookup
+	if phone != "" {
+		if res, err := h.antiban.LookupJID(ctx, phone); err == nil {
+			lookup = &res
+		}
+	}
+
+	instanceNames := map[string]string{}
+	if instances, _, err := h.instances.List(ctx, "", 0, 0); err == nil {
+		for _, inst := range instances {
+			instanceNames[inst.ID] = inst.Name
+		}
+	}
+
+	data := map[string]any{
+		"Locks":         locks,
+		"Entries":       entries,
+		"Lookup":        lookup,
+		"InstanceID":    instanceID,
+		"Contact":       contact,
+		"Type":          kind,
+		"Phone":         phone,
+		"InstanceNames": instanceNames,
+	}
+	c.HTML(http.StatusOK, "layout", h.pageData(c, "Anti-ban", "antiban_content", data))
+}
+
+func (h *Handler) clearReachoutLock(c *gin.Context) {
+	instanceID := c.PostForm("instanceId")
+	contact := c.PostForm("contact")
+	removed, err := h.antiban.ClearReachoutLock(c.Request.Context(), instanceID, contact)
+	if err != nil {
+		h.logger.Warn("erro ao remover bloqueio de reach-out", zap.Error(err))
+		redirectWithMessage(c, antibanPath, "error", "Falha ao remover bloqueio.")
+		return
+	}
+	if !removed {
+		redirectWithMessage(c, antibanPath, "error", "Bloqueio não encontrado (pode ter expirado).")
+		return
+	}
+	redirectWithMessage(c, antibanPath, "success", "Bloqueio removido.")
+}
+
+func (h *Handler) purgeJIDCache(c *gin.Context) {
+	ctx := c.Request.Context()
+	if phone := strings.TrimSpace(c.PostForm("phone")); phone != "" {
+		removed, err := h.antiban.PurgeJID(ctx, phone)
+		if err != nil {
+			h.logger.Warn("erro ao remover cache de JID", zap.Error(err))
+			redirectWithMessage(c, antibanPath, "error", "Falha ao remover do cache.")
+			return
+		}
+		if !removed {
+			redirectWithMessage(c, antibanPath, "error", "Telefone não está no cache.")
+			return
+		}
+		values := url.Values{}
+		values.Set("success", "Entrada removida do cache.")
+		values.Set("phone", phone)
+		c.Redirect(http.StatusSeeOther, antibanPath+"?"+values.Encode())
+		return
+	}
+
+	kind := c.PostForm("type")
+	if kind != "positive" && kind != "negative" {
+		kind = ""
+	}
+	removed, err := h.antiban.PurgeJIDCache(ctx, kind)
+	if err != nil {
+		h.logger.Warn("erro ao limpar cache de JID", zap.Error(err))
+		redirectWithMessage(c, antibanPath, "error", "Falha ao limpar o cache.")
+		return
+	}
+	redirectWithMessage(c, antibanPath, "success", fmt.Sprintf("%d entradas removidas do cache.", removed))
+}
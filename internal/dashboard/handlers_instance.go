@@ -260,6 +260,12 @@ func (h *Handler) getInstanceQRStatus(c *gin.Context) {
 	})
 }
 
+// HealthService computes the number health report of an instance (implemented by the message
+// service).
+type HealthService interface {
+	InstanceHealth(ctx context.Context, instanceID string) (model.InstanceHealth, error)
+}
+
 func (h *Handler) instanceDiagnostics(c *gin.Context) {
 	instanceID := c.Param("id")
 	ctx := c.Request.Context()
@@ -278,9 +284,19 @@ func (h *Handler) instanceDiagnostics(c *gin.Context) {
 		diagnostics = h.sessionManager.GetDiagnostics(instanceID)
 	}
 
+	var report *model.InstanceHealth
+	if h.health != nil {
+		if r, err := h.health.InstanceHealth(ctx, instanceID); err != nil {
+			h.logger.Warn("erro ao calcular saúde da instância", zap.String("instance_id", instanceID), zap.Error(err))
+		} else {
+			report = &r
+		}
+	}
+
 	data := map[string]any{
 		"Instance":    inst,
 		"Diagnostics": diagnostics,
+		"Health":      report,
 	}
 
 	page := h.pageData(c, "", "instance_diagnostics_content", data)
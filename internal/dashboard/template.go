@@ -6,6 +6,7 @@ import (
 
 	"go.uber.org/zap"
 
+	"github.com/open-apime/apime/internal/pkg/health"
 	"github.com/open-apime/apime/internal/storage/model"
 )
 
@@ -77,7 +78,7 @@ func templateFuncMap() template.FuncMap {
 				return "status-active"
 			case model.InstanceStatusPending:
 				return "status-pending"
-			case model.InstanceStatusDisconnected:
+			case model.InstanceStatusDisconnected, model.InstanceStatusHandover:
 				return "status-disconnected"
 			default:
 				return "status-error"
@@ -91,12 +92,48 @@ func templateFuncMap() template.FuncMap {
 				return "Aguardando"
 			case model.InstanceStatusDisconnected:
 				return "Desconectado"
+			case model.InstanceStatusHandover:
+				return "Em transferência"
 			case model.InstanceStatusError:
 				return "Erro"
 			default:
 				return string(status)
 			}
 		},
+		"riskLabel": func(risk string) string {
+			switch risk {
+			case health.RiskLow:
+				return "Baixo"
+			case health.RiskMedium:
+				return "Médio"
+			case health.RiskHigh:
+				return "Alto"
+			case health.RiskCritical:
+				return "Crítico"
+			default:
+				return risk
+			}
+		},
+		"riskBadge": func(risk string) string {
+			switch risk {
+			case health.RiskLow:
+				return "status-active"
+			case health.RiskMedium:
+				return "status-pending"
+			default:
+				return "status-error"
+			}
+		},
+		"trendLabel": func(trend string) string {
+			switch trend {
+			case health.TrendImproving:
+				return "Melhorando"
+			case health.TrendWorsening:
+				return "Piorando"
+			default:
+				return "Estável"
+			}
+		},
 		"div": func(a, b any) float64 {
 			toFloat := func(v any) float64 {
 				switch i := v.(type) {
@@ -0,0 +1,105 @@
+// Package audio reads the duration and level envelope of uploaded audio, without decoding it
+// through an external tool. Ogg/Opus (voice notes) and WAV are parsed; other formats are only
+// identified.
+package audio
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"math"
+	"time"
+)
+
+const (
+	CodecOpus   = "opus"
+	CodecVorbis = "vorbis"
+	CodecPCM    = "pcm"
+	CodecMP3    = "mp3"
+	CodecAAC    = "aac"
+)
+
+// WaveformSize is the number of samples of AudioMessage.Waveform.
+const WaveformSize = 64
+
+var (
+	ErrUnknownFormat = errors.New("audio: formato não reconhecido")
+	ErrInvalid       = errors.New("audio: arquivo corrompido")
+)
+
+// Info is what could be read from the file. Codec is set even when the rest failed, so the caller
+// can still tell which codec it got.
+type Info struct {
+	Codec    string
+	Duration time.Duration
+	// Waveform has WaveformSize values from 0 to 100, the range the WhatsApp clients draw. nil
+	// when the file carries no usable level information.
+	Waveform []byte
+}
+
+// Analyze identifies the audio in r and, for Ogg/Opus and WAV, reads its duration and waveform.
+// r is left at the start, ready for the upload.
+func Analyze(r io.ReadSeeker) (Info, error) {
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return Info{}, err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return Info{}, err
+	}
+	defer r.Seek(0, io.SeekStart)
+
+	br := bufio.NewReaderSize(r, 64<<10)
+	head, _ := br.Peek(12)
+	switch {
+	case len(head) >= 4 && string(head[:4]) == "OggS":
+		return parseOgg(br)
+	case len(head) >= 12 && string(head[:4]) == "RIFF" && string(head[8:12]) == "WAVE":
+		return parseWAV(br, size)
+	case len(head) >= 3 && string(head[:3]) == "ID3",
+		len(head) >= 2 && head[0] == 0xFF && head[1]&0xE0 == 0xE0:
+		return Info{Codec: CodecMP3}, nil
+	case len(head) >= 8 && string(head[4:8]) == "ftyp":
+		return Info{Codec: CodecAAC}, nil
+	}
+	return Info{}, ErrUnknownFormat
+}
+
+// scale maps bucket levels to 0–100, the quietest bucket to 0. Flat levels give nil: a constant
+// bitrate stream, or digital silence, has nothing to draw.
+func scale(levels []float64) []byte {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, l := range levels {
+		lo = math.Min(lo, l)
+		hi = math.Max(hi, l)
+	}
+	if hi-lo <= hi*0.01 {
+		return nil
+	}
+	waveform := make([]byte, len(levels))
+	for i, l := range levels {
+		waveform[i] = byte(math.Round((l - lo) / (hi - lo) * 100))
+	}
+	return waveform
+}
+
+// fillGaps gives empty buckets (shorter than the chunk that covers them) the level of the
+// previous one.
+func fillGaps(levels []float64, filled []bool) {
+	for i := range levels {
+		if filled[i] {
+			continue
+		}
+		switch {
+		case i > 0:
+			levels[i] = levels[i-1]
+		default:
+			for j := range levels {
+				if filled[j] {
+					levels[i] = levels[j]
+					break
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+// oggPage builds one page; the CRC isn't checked by the parser, so it's left zero.
+func oggPage(serial uint32, seq uint32, granule int64, flags byte, packets ...[]byte) []byte {
+	var lacing, body []byte
+	for _, p := range packets {
+		n := len(p)
+		for n >= 255 {
+			lacing = append(lacing, 255)
+			n -= 255
+		}
+		lacing = append(lacing, byte(n))
+		body = append(body, p...)
+	}
+	h := make([]byte, 27)
+	copy(h, "OggS")
+	h[5] = flags
+	binary.LittleEndian.PutUint64(h[6:], uint64(granule))
+	binary.LittleEndian.PutUint32(h[14:], serial)
+	binary.LittleEndian.PutUint32(h[18:], seq)
+	h[26] = byte(len(lacing))
+	return append(append(h, lacing...), body...)
+}
+
+// opusFile has 10 s of 20 ms CELT packets: loud first half, near silence in the second.
+func opusFile() []byte {
+	head := make([]byte, 19)
+	copy(head, "OpusHead")
+	head[8] = 1
+	head[9] = 1
+	binary.LittleEndian.PutUint16(head[10:], 312)
+	binary.LittleEndian.PutUint32(head[12:], 48000)
+
+	var out bytes.Buffer
+	out.Write(oggPage(7, 0, 0, 0x02, head))
+	out.Write(oggPage(7, 1, 0, 0, []byte("OpusTags\x00\x00\x00\x00\x00\x00\x00\x00")))
+
+	const perPage = 50
+	granule := int64(0)
+	for page := 0; page < 10; page++ {
+		var packets [][]byte
+		for i := 0; i < perPage; i++ {
+			size := 120
+			if page >= 5 {
+				size = 4
+			}
+			p := make([]byte, size)
+			p[0] = 31 << 3 // CELT fullband, 20 ms, one frame
+			packets = append(packets, p)
+		}
+		granule += perPage * 960
+		out.Write(oggPage(7, uint32(page+2), granule+312, 0, packets...))
+	}
+	return out.Bytes()
+}
+
+func TestAnalyzeOpus(t *testing.T) {
+	info, err := Analyze(bytes.NewReader(opusFile()))
+	if err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+	if info.Codec != CodecOpus {
+		t.Fatalf("codec = %q", info.Codec)
+	}
+	if info.Duration != 10*time.Second {
+		t.Fatalf("duração = %s, esperado 10s", info.Duration)
+	}
+	if len(info.Waveform) != WaveformSize {
+		t.Fatalf("waveform com %d amostras", len(info.Waveform))
+	}
+	if info.Waveform[10] != 100 || info.Waveform[50] != 0 {
+		t.Fatalf("waveform não segue o volume: %v", info.Waveform)
+	}
+}
+
+func TestAnalyzeWAV(t *testing.T) {
+	const rate = 8000
+	samples := make([]int16, 2*rate)
+	for i := range samples[:rate] {
+		samples[i] = int16(20000 * math.Sin(2*math.Pi*440*float64(i)/rate))
+	}
+
+	var data bytes.Buffer
+	binary.Write(&data, binary.LittleEndian, samples)
+	var out bytes.Buffer
+	out.WriteString("RIFF")
+	binary.Write(&out, binary.LittleEndian, uint32(36+data.Len()))
+	out.WriteString("WAVEfmt ")
+	binary.Write(&out, binary.LittleEndian, []uint32{16})
+	binary.Write(&out, binary.LittleEndian, []uint16{wavPCM, 1})
+	binary.Write(&out, binary.LittleEndian, []uint32{rate, rate * 2})
+	binary.Write(&out, binary.LittleEndian, []uint16{2, 16})
+	out.WriteString("data")
+	binary.Write(&out, binary.LittleEndian, uint32(data.Len()))
+	out.Write(data.Bytes())
+
+	info, err := Analyze(bytes.NewReader(out.Bytes()))
+	if err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+	if info.Codec != CodecPCM || info.Duration != 2*time.Second {
+		t.Fatalf("codec %q, duração %s", info.Codec, info.Duration)
+	}
+	if info.Waveform[0] < 90 || info.Waveform[63] != 0 {
+		t.Fatalf("waveform não segue o volume: %v", info.Waveform)
+	}
+}
+
+// TestAnalyzeWAVFmtChunkSize: the fmt length comes from the file, so a huge one must fail instead
+// of being allocated, and a long but real one is parsed with the extra bytes skipped.
+func TestAnalyzeWAVFmtChunkSize(t *testing.T) {
+	wav := func(fmtLength uint32, extra int) []byte {
+		var out bytes.Buffer
+		out.WriteString("RIFF\x00\x00\x00\x00WAVEfmt ")
+		binary.Write(&out, binary.LittleEndian, fmtLength)
+		binary.Write(&out, binary.LittleEndian, []uint16{wavPCM, 1})
+		binary.Write(&out, binary.LittleEndian, []uint32{8000, 16000})
+		binary.Write(&out, binary.LittleEndian, []uint16{2, 16})
+		out.Write(make([]byte, extra))
+		out.WriteString("data")
+		binary.Write(&out, binary.LittleEndian, uint32(16000))
+		out.Write(make([]byte, 16000))
+		return out.Bytes()
+	}
+
+	if _, err := Analyze(bytes.NewReader(wav(0xFFFFFFF0, 0))); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("chunk fmt gigante deveria falhar, veio %v", err)
+	}
+	info, err := Analyze(bytes.NewReader(wav(16+100, 100)))
+	if err != nil {
+		t.Fatalf("chunk fmt longo: %v", err)
+	}
+	if info.Codec != CodecPCM || info.Duration != time.Second {
+		t.Fatalf("codec %q, duração %s", info.Codec, info.Duration)
+	}
+}
+
+func TestAnalyzeOtherCodecs(t *testing.T) {
+	vorbis := oggPage(1, 0, 0, 0x02, []byte("\x01vorbis\x00\x00\x00\x00"))
+	cases := map[string][]byte{
+		CodecVorbis: vorbis,
+		CodecMP3:    []byte("ID3\x04\x00\x00\x00\x00\x00\x00\x00\x00"),
+		CodecAAC:    []byte("\x00\x00\x00\x20ftypM4A \x00\x00\x00\x00"),
+	}
+	for codec, data := range cases {
+		info, err := Analyze(bytes.NewReader(data))
+		if err != nil || info.Codec != codec {
+			t.Errorf("%s: codec %q, erro %v", codec, info.Codec, err)
+		}
+	}
+	if _, err := Analyze(bytes.NewReader([]byte("nada de áudio aqui"))); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("formato desconhecido deveria falhar, veio %v", err)
+	}
+}
@@ -0,0 +1,183 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Opus always runs at 48 kHz in Ogg: granule positions count 48 kHz samples whatever the input
+// rate was.
+const opusRate = 48000
+
+type opusPacket struct {
+	duration time.Duration
+	size     int
+}
+
+// parseOgg reads the pages of the first logical stream. Opus packets aren't decoded: the duration
+// comes from the granule position and the waveform from the packet sizes, which in VBR (what every
+// voice encoder uses) follow the loudness closely, and silence shrinks to a few bytes.
+func parseOgg(r io.Reader) (Info, error) {
+	var (
+		info     Info
+		serial   uint32
+		preSkip  int64
+		granule  int64 = -1
+		packets  []opusPacket
+		packet   []byte
+		index    int
+		header   = make([]byte, 27)
+		segments = make([]byte, 255)
+	)
+
+	for page := 0; ; page++ {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if errors.Is(err, io.EOF) && page > 0 {
+				break
+			}
+			// Truncated upload: keep what was read.
+			if errors.Is(err, io.ErrUnexpectedEOF) && info.Codec != "" {
+				break
+			}
+			return info, fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+		if string(header[:4]) != "OggS" || header[4] != 0 {
+			return info, fmt.Errorf("%w: página Ogg inválida", ErrInvalid)
+		}
+		pageGranule := int64(binary.LittleEndian.Uint64(header[6:14]))
+		pageSerial := binary.LittleEndian.Uint32(header[14:18])
+		lacing := segments[:header[26]]
+		if _, err := io.ReadFull(r, lacing); err != nil {
+			return info, fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+		bodySize := 0
+		for _, l := range lacing {
+			bodySize += int(l)
+		}
+		body := make([]byte, bodySize)
+		if _, err := io.ReadFull(r, body); err != nil {
+			if info.Codec != "" {
+				break
+			}
+			return info, fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+
+		if page == 0 {
+			serial = pageSerial
+		} else if pageSerial != serial {
+			// Another multiplexed stream.
+			continue
+		}
+		// A page that doesn't continue a packet drops any partial one left by a lost page.
+		if header[5]&0x01 == 0 {
+			packet = packet[:0]
+		}
+
+		off := 0
+		for _, l := range lacing {
+			packet = append(packet, body[off:off+int(l)]...)
+			off += int(l)
+			if l == 255 {
+				continue
+			}
+			switch {
+			case index == 0:
+				switch {
+				case bytes.HasPrefix(packet, []byte("OpusHead")) && len(packet) >= 19:
+					info.Codec = CodecOpus
+					preSkip = int64(binary.LittleEndian.Uint16(packet[10:12]))
+				case bytes.HasPrefix(packet, []byte("\x01vorbis")):
+					return Info{Codec: CodecVorbis}, nil
+				default:
+					return info, ErrUnknownFormat
+				}
+			case index == 1:
+				// OpusTags.
+			default:
+				packets = append(packets, opusPacket{duration: opusPacketDuration(packet), size: len(packet)})
+			}
+			index++
+			packet = packet[:0]
+		}
+		if pageGranule >= 0 && index > 2 {
+			granule = pageGranule
+		}
+	}
+
+	var total time.Duration
+	for _, p := range packets {
+		total += p.duration
+	}
+	if granule > preSkip {
+		info.Duration = time.Duration(granule-preSkip) * time.Second / opusRate
+	} else {
+		info.Duration = total
+	}
+	info.Waveform = opusWaveform(packets, total)
+	return info, nil
+}
+
+// opusPacketDuration reads the duration from the TOC byte (RFC 6716, section 3.1).
+func opusPacketDuration(p []byte) time.Duration {
+	if len(p) == 0 {
+		return 0
+	}
+	toc := p[0]
+	config := toc >> 3
+	var frame time.Duration
+	switch {
+	case config < 12: // SILK
+		frame = []time.Duration{10, 20, 40, 60}[config%4] * time.Millisecond
+	case config < 16: // hybrid
+		frame = []time.Duration{10, 20}[config%2] * time.Millisecond
+	default: // CELT
+		frame = []time.Duration{2500, 5000, 10000, 20000}[config%4] * time.Microsecond
+	}
+	switch toc & 0x03 {
+	case 0:
+		return frame
+	case 1, 2:
+		return 2 * frame
+	default:
+		if len(p) < 2 {
+			return 0
+		}
+		return time.Duration(p[1]&0x3F) * frame
+	}
+}
+
+// opusWaveform uses the bitrate of each bucket as its level.
+func opusWaveform(packets []opusPacket, total time.Duration) []byte {
+	if len(packets) < WaveformSize/4 || total <= 0 {
+		return nil
+	}
+	var (
+		bytesIn  = make([]float64, WaveformSize)
+		duration = make([]time.Duration, WaveformSize)
+		filled   = make([]bool, WaveformSize)
+		at       time.Duration
+	)
+	for _, p := range packets {
+		mid := at + p.duration/2
+		at += p.duration
+		b := int(int64(mid) * WaveformSize / int64(total))
+		if b >= WaveformSize {
+			b = WaveformSize - 1
+		}
+		bytesIn[b] += float64(p.size)
+		duration[b] += p.duration
+		filled[b] = true
+	}
+	levels := make([]float64, WaveformSize)
+	for i := range levels {
+		if duration[i] > 0 {
+			levels[i] = bytesIn[i] / duration[i].Seconds()
+		}
+	}
+	fillGaps(levels, filled)
+	return scale(levels)
+}
@@ -0,0 +1,161 @@
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+const (
+	wavPCM        = 1
+	wavFloat      = 3
+	wavExtensible = 0xFFFE
+
+	// wavFmtRead is as much of the fmt chunk as is parsed: the extensible layout takes 40 bytes.
+	// The declared length is untrusted, so the rest is skipped instead of allocated.
+	wavFmtRead = 64
+)
+
+type wavFormat struct {
+	tag        uint16
+	channels   uint16
+	sampleRate uint32
+	blockAlign uint16
+	bits       uint16
+}
+
+// parseWAV reads the fmt and data chunks of an uncompressed WAV. size is the file size, used when
+// the data chunk length is missing (files written while recording).
+func parseWAV(r io.Reader, size int64) (Info, error) {
+	var (
+		header = make([]byte, 12)
+		chunk  = make([]byte, 8)
+		format *wavFormat
+		pos    int64 = 12
+	)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return Info{}, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+
+	for {
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			return Info{}, fmt.Errorf("%w: sem chunk de dados", ErrInvalid)
+		}
+		pos += 8
+		id := string(chunk[:4])
+		length := int64(binary.LittleEndian.Uint32(chunk[4:]))
+
+		if id == "data" {
+			if format == nil {
+				return Info{}, fmt.Errorf("%w: dados antes do formato", ErrInvalid)
+			}
+			if length == 0 || length > size-pos {
+				length = size - pos
+			}
+			return readPCM(r, *format, length)
+		}
+
+		padded := length + length%2
+		if id == "fmt " {
+			if length < 16 {
+				return Info{}, fmt.Errorf("%w: chunk fmt curto", ErrInvalid)
+			}
+			if padded > size-pos {
+				return Info{}, fmt.Errorf("%w: chunk fmt maior que o arquivo", ErrInvalid)
+			}
+			data := make([]byte, min(padded, wavFmtRead))
+			if _, err := io.ReadFull(r, data); err != nil {
+				return Info{}, fmt.Errorf("%w: %v", ErrInvalid, err)
+			}
+			if _, err := io.CopyN(io.Discard, r, padded-int64(len(data))); err != nil {
+				return Info{}, fmt.Errorf("%w: %v", ErrInvalid, err)
+			}
+			format = &wavFormat{
+				tag:        binary.LittleEndian.Uint16(data[0:]),
+				channels:   binary.LittleEndian.Uint16(data[2:]),
+				sampleRate: binary.LittleEndian.Uint32(data[4:]),
+				blockAlign: binary.LittleEndian.Uint16(data[12:]),
+				bits:       binary.LittleEndian.Uint16(data[14:]),
+			}
+			if format.tag == wavExtensible && length >= 26 {
+				// The real format is the first two bytes of the sub-format GUID.
+				format.tag = binary.LittleEndian.Uint16(data[24:])
+			}
+		} else if _, err := io.CopyN(io.Discard, r, padded); err != nil {
+			return Info{}, fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+		pos += padded
+	}
+}
+
+// readPCM reads the duration from the data length and the waveform from the RMS of the first
+// channel.
+func readPCM(r io.Reader, format wavFormat, length int64) (Info, error) {
+	sampleBytes := int(format.bits) / 8
+	switch {
+	case format.tag != wavPCM && format.tag != wavFloat,
+		format.tag == wavPCM && (sampleBytes < 1 || sampleBytes > 4),
+		format.tag == wavFloat && sampleBytes != 4 && sampleBytes != 8:
+		return Info{}, fmt.Errorf("%w: codificação WAV %#x/%d bits não suportada", ErrUnknownFormat, format.tag, format.bits)
+	}
+	if format.channels == 0 || format.sampleRate == 0 || int(format.blockAlign) < sampleBytes {
+		return Info{}, fmt.Errorf("%w: formato WAV inválido", ErrInvalid)
+	}
+
+	info := Info{Codec: CodecPCM}
+	frames := length / int64(format.blockAlign)
+	info.Duration = time.Duration(frames) * time.Second / time.Duration(format.sampleRate)
+	if frames < WaveformSize {
+		return info, nil
+	}
+
+	var (
+		sums   = make([]float64, WaveformSize)
+		counts = make([]int64, WaveformSize)
+		frame  = make([]byte, format.blockAlign)
+	)
+	for i := int64(0); i < frames; i++ {
+		if _, err := io.ReadFull(r, frame); err != nil {
+			// Truncated data: the duration stays the declared one, the waveform is dropped.
+			return info, nil
+		}
+		s := sample(frame[:sampleBytes], format.tag)
+		b := i * WaveformSize / frames
+		sums[b] += s * s
+		counts[b]++
+	}
+	levels := make([]float64, WaveformSize)
+	filled := make([]bool, WaveformSize)
+	for i := range levels {
+		if counts[i] > 0 {
+			levels[i] = math.Sqrt(sums[i] / float64(counts[i]))
+			filled[i] = true
+		}
+	}
+	fillGaps(levels, filled)
+	info.Waveform = scale(levels)
+	return info, nil
+}
+
+// sample returns a sample in [-1, 1]. 8-bit PCM is unsigned, wider PCM is signed little-endian.
+func sample(b []byte, tag uint16) float64 {
+	if tag == wavFloat {
+		if len(b) == 8 {
+			return math.Float64frombits(binary.LittleEndian.Uint64(b))
+		}
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+	}
+	switch len(b) {
+	case 1:
+		return (float64(b[0]) - 128) / 128
+	case 2:
+		return float64(int16(binary.LittleEndian.Uint16(b))) / (1 << 15)
+	case 3:
+		v := int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24) >> 8
+		return float64(v) / (1 << 23)
+	default:
+		return float64(int32(binary.LittleEndian.Uint32(b))) / (1 << 31)
+	}
+}
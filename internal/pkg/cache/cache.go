@@ -0,0 +1,39 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Cache is a string key/value store with per-key TTL. The memory backend is
+// per process; the Redis one is shared by replicas and survives restarts,
+// which is what anti-ban guards need.
+//
+// A zero ttl means no expiry.
+type Cache interface {
+	Get(ctx context.Context, key string) (value string, ok bool, err error)
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+	// SetNX stores only when the key is absent and reports whether it did.
+	SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error)
+	// GetDel returns the value and removes the key atomically.
+	GetDel(ctx context.Context, key string) (value string, ok bool, err error)
+	Delete(ctx context.Context, key string) error
+	// TTL returns the remaining lifetime; ok is false when the key is absent.
+	TTL(ctx context.Context, key string) (ttl time.Duration, ok bool, err error)
+	// AddToSet adds member to the set at key and resets the set's expiry to ttl.
+	AddToSet(ctx context.Context, key, member string, ttl time.Duration) error
+	// PopSet returns the members of the set at key and removes it atomically.
+	PopSet(ctx context.Context, key string) ([]string, error)
+	// Entries reads the value and remaining lifetime of each key in one round
+	// trip. Absent keys are left out of the result.
+	Entries(ctx context.Context, keys []string) (map[string]Entry, error)
+	// Keys lists the live keys starting with prefix. Meant for admin and
+	// cleanup paths, not for the hot path.
+	Keys(ctx context.Context, prefix string) ([]string, error)
+}
+
+// Entry is a live key as read by Entries. A zero TTL means no expiry.
+type Entry struct {
+	Value string
+	TTL   time.Duration
+}
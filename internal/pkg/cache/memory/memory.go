@@ -0,0 +1,188 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/open-apime/apime/internal/pkg/cache"
+)
+
+type entry struct {
+	value     string
+	members   map[string]struct{} // set keys only
+	expiresAt time.Time           // zero = no expiry
+}
+
+func (e entry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
+
+// MemoryCache expires keys lazily on access plus a periodic sweep, so keys
+// that are never read again do not accumulate.
+type MemoryCache struct {
+	mu        sync.Mutex
+	items     map[string]entry
+	sweepOnce sync.Once
+}
+
+func New() *MemoryCache {
+	return &MemoryCache{items: make(map[string]entry)}
+}
+
+func deadline(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(ttl)
+}
+
+func (c *MemoryCache) lookup(key string, now time.Time) (entry, bool) {
+	e, ok := c.items[key]
+	if !ok {
+		return entry{}, false
+	}
+	if e.expired(now) {
+		delete(c.items, key)
+		return entry{}, false
+	}
+	return e, true
+}
+
+func (c *MemoryCache) Get(ctx context.Context, key string) (string, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.lookup(key, time.Now())
+	return e.value, ok, nil
+}
+
+func (c *MemoryCache) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	c.sweepOnce.Do(c.startSweep)
+	c.mu.Lock()
+	c.items[key] = entry{value: value, expiresAt: deadline(ttl)}
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *MemoryCache) SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	c.sweepOnce.Do(c.startSweep)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.lookup(key, time.Now()); ok {
+		return false, nil
+	}
+	c.items[key] = entry{value: value, expiresAt: deadline(ttl)}
+	return true, nil
+}
+
+func (c *MemoryCache) GetDel(ctx context.Context, key string) (string, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.lookup(key, time.Now())
+	delete(c.items, key)
+	return e.value, ok, nil
+}
+
+func (c *MemoryCache) Delete(ctx context.Context, key string) error {
+	c.mu.Lock()
+	delete(c.items, key)
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *MemoryCache) TTL(ctx context.Context, key string) (time.Duration, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	e, ok := c.lookup(key, now)
+	if !ok {
+		return 0, false, nil
+	}
+	if e.expiresAt.IsZero() {
+		return 0, true, nil
+	}
+	return e.expiresAt.Sub(now), true, nil
+}
+
+func (c *MemoryCache) Entries(ctx context.Context, keys []string) (map[string]cache.Entry, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	out := make(map[string]cache.Entry, len(keys))
+	for _, key := range keys {
+		e, ok := c.lookup(key, now)
+		if !ok {
+			continue
+		}
+		entry := cache.Entry{Value: e.value}
+		if !e.expiresAt.IsZero() {
+			entry.TTL = e.expiresAt.Sub(now)
+		}
+		out[key] = entry
+	}
+	return out, nil
+}
+
+func (c *MemoryCache) AddToSet(ctx context.Context, key, member string, ttl time.Duration) error {
+	c.sweepOnce.Do(c.startSweep)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.lookup(key, time.Now())
+	if !ok || e.members == nil {
+		e = entry{members: make(map[string]struct{})}
+	}
+	e.members[member] = struct{}{}
+	e.expiresAt = deadline(ttl)
+	c.items[key] = e
+	return nil
+}
+
+func (c *MemoryCache) PopSet(ctx context.Context, key string) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.lookup(key, time.Now())
+	delete(c.items, key)
+	if !ok {
+		return nil, nil
+	}
+	members := make([]string, 0, len(e.members))
+	for m := range e.members {
+		members = append(members, m)
+	}
+	return members, nil
+}
+
+func (c *MemoryCache) Keys(ctx context.Context, prefix string) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	var keys []string
+	for k, e := range c.items {
+		if e.expired(now) {
+			delete(c.items, k)
+			continue
+		}
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	return keys, nil
+}
+
+func (c *MemoryCache) startSweep() {
+	go func() {
+		ticker := time.NewTicker(10 * time.Minute)
+		defer ticker.Stop()
+		for range ticker.C {
+			now := time.Now()
+			c.mu.Lock()
+			for k, e := range c.items {
+				if e.expired(now) {
+					delete(c.items, k)
+				}
+			}
+			c.mu.Unlock()
+		}
+	}()
+}
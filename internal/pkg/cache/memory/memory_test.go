@@ -0,0 +1,94 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryCacheTTLAndSetNX(t *testing.T) {
+	ctx := context.Background()
+	c := New()
+
+	if ok, _ := c.SetNX(ctx, "a", "1", 20*time.Millisecond); !ok {
+		t.Fatal("primeiro SetNX deveria gravar")
+	}
+	if ok, _ := c.SetNX(ctx, "a", "2", time.Minute); ok {
+		t.Fatal("SetNX não deveria sobrescrever chave viva")
+	}
+	if v, ok, _ := c.Get(ctx, "a"); !ok || v != "1" {
+		t.Fatalf("valor inesperado: %q %v", v, ok)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if _, ok, _ := c.Get(ctx, "a"); ok {
+		t.Fatal("chave expirada não deveria ser retornada")
+	}
+	if ok, _ := c.SetNX(ctx, "a", "3", time.Minute); !ok {
+		t.Fatal("SetNX deveria gravar após expirar")
+	}
+}
+
+func TestMemoryCacheGetDelAndKeys(t *testing.T) {
+	ctx := context.Background()
+	c := New()
+	_ = c.Set(ctx, "p:1", "x", 0)
+	_ = c.Set(ctx, "p:2", "y", time.Minute)
+	_ = c.Set(ctx, "q:1", "z", time.Minute)
+
+	keys, _ := c.Keys(ctx, "p:")
+	if len(keys) != 2 {
+		t.Fatalf("esperava 2 chaves com prefixo, veio %v", keys)
+	}
+	if v, ok, _ := c.GetDel(ctx, "p:1"); !ok || v != "x" {
+		t.Fatalf("GetDel inesperado: %q %v", v, ok)
+	}
+	if _, ok, _ := c.Get(ctx, "p:1"); ok {
+		t.Fatal("GetDel deveria remover a chave")
+	}
+	if ttl, ok, _ := c.TTL(ctx, "p:2"); !ok || ttl <= 0 || ttl > time.Minute {
+		t.Fatalf("TTL inesperado: %v %v", ttl, ok)
+	}
+}
+
+func TestMemoryCacheSetPop(t *testing.T) {
+	ctx := context.Background()
+	c := New()
+
+	_ = c.AddToSet(ctx, "idx", "a", time.Minute)
+	_ = c.AddToSet(ctx, "idx", "b", time.Minute)
+	_ = c.AddToSet(ctx, "idx", "a", time.Minute)
+
+	members, _ := c.PopSet(ctx, "idx")
+	if len(members) != 2 {
+		t.Fatalf("esperava 2 membros, veio %v", members)
+	}
+	if members, _ := c.PopSet(ctx, "idx"); len(members) != 0 {
+		t.Fatalf("PopSet deveria remover o conjunto, veio %v", members)
+	}
+
+	_ = c.AddToSet(ctx, "short", "a", 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	if members, _ := c.PopSet(ctx, "short"); len(members) != 0 {
+		t.Fatalf("conjunto expirado não deveria ser retornado, veio %v", members)
+	}
+}
+
+func TestMemoryCacheEntries(t *testing.T) {
+	ctx := context.Background()
+	c := New()
+
+	_ = c.Set(ctx, "a", "1", time.Minute)
+	_ = c.Set(ctx, "b", "2", 0)
+
+	entries, _ := c.Entries(ctx, []string{"a", "b", "missing"})
+	if len(entries) != 2 {
+		t.Fatalf("esperava 2 entradas, veio %v", entries)
+	}
+	if e := entries["a"]; e.Value != "1" || e.TTL <= 0 || e.TTL > time.Minute {
+		t.Fatalf("entrada inesperada para a: %+v", e)
+	}
+	if e := entries["b"]; e.Value != "2" || e.TTL != 0 {
+		t.Fatalf("chave sem expiração deveria ter TTL zero: %+v", e)
+	}
+}
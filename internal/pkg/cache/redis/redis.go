@@ -0,0 +1,153 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/open-apime/apime/internal/pkg/cache"
+)
+
+// RedisCache namespaces every key under prefix so Keys can SCAN safely.
+type RedisCache struct {
+	client *redis.Client
+	prefix string
+}
+
+func New(client *redis.Client, prefix string) *RedisCache {
+	return &RedisCache{client: client, prefix: prefix}
+}
+
+func (c *RedisCache) Get(ctx context.Context, key string) (string, bool, error) {
+	v, err := c.client.Get(ctx, c.prefix+key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("cache get: %w", err)
+	}
+	return v, true, nil
+}
+
+func (c *RedisCache) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	if err := c.client.Set(ctx, c.prefix+key, value, ttl).Err(); err != nil {
+		return fmt.Errorf("cache set: %w", err)
+	}
+	return nil
+}
+
+func (c *RedisCache) SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	ok, err := c.client.SetNX(ctx, c.prefix+key, value, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("cache setnx: %w", err)
+	}
+	return ok, nil
+}
+
+func (c *RedisCache) GetDel(ctx context.Context, key string) (string, bool, error) {
+	v, err := c.client.GetDel(ctx, c.prefix+key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("cache getdel: %w", err)
+	}
+	return v, true, nil
+}
+
+func (c *RedisCache) Delete(ctx context.Context, key string) error {
+	if err := c.client.Del(ctx, c.prefix+key).Err(); err != nil {
+		return fmt.Errorf("cache delete: %w", err)
+	}
+	return nil
+}
+
+func (c *RedisCache) TTL(ctx context.Context, key string) (time.Duration, bool, error) {
+	d, err := c.client.PTTL(ctx, c.prefix+key).Result()
+	if err != nil {
+		return 0, false, fmt.Errorf("cache ttl: %w", err)
+	}
+	// -2: no key; -1: no expiry (go-redis returns these as raw durations).
+	switch {
+	case d == -2 || d == -2*time.Millisecond:
+		return 0, false, nil
+	case d < 0:
+		return 0, true, nil
+	}
+	return d, true, nil
+}
+
+func (c *RedisCache) Entries(ctx context.Context, keys []string) (map[string]cache.Entry, error) {
+	out := make(map[string]cache.Entry, len(keys))
+	if len(keys) == 0 {
+		return out, nil
+	}
+	values := make([]*redis.StringCmd, len(keys))
+	ttls := make([]*redis.DurationCmd, len(keys))
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			values[i] = pipe.Get(ctx, c.prefix+key)
+			ttls[i] = pipe.PTTL(ctx, c.prefix+key)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("cache entries: %w", err)
+	}
+	for i, key := range keys {
+		value, err := values[i].Result()
+		if err != nil {
+			// redis.Nil: expired between the SCAN and the read.
+			continue
+		}
+		entry := cache.Entry{Value: value}
+		if d := ttls[i].Val(); d > 0 {
+			entry.TTL = d
+		}
+		out[key] = entry
+	}
+	return out, nil
+}
+
+func (c *RedisCache) AddToSet(ctx context.Context, key, member string, ttl time.Duration) error {
+	_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.SAdd(ctx, c.prefix+key, member)
+		if ttl > 0 {
+			pipe.PExpire(ctx, c.prefix+key, ttl)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("cache add to set: %w", err)
+	}
+	return nil
+}
+
+func (c *RedisCache) PopSet(ctx context.Context, key string) ([]string, error) {
+	var members *redis.StringSliceCmd
+	_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		members = pipe.SMembers(ctx, c.prefix+key)
+		pipe.Del(ctx, c.prefix+key)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cache pop set: %w", err)
+	}
+	return members.Val(), nil
+}
+
+func (c *RedisCache) Keys(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	iter := c.client.Scan(ctx, 0, c.prefix+prefix+"*", 500).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, strings.TrimPrefix(iter.Val(), c.prefix))
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("cache keys: %w", err)
+	}
+	return keys, nil
+}
@@ -0,0 +1,299 @@
+package crypto
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Sealed values are envelope-encrypted: each one gets its own random data key, which is wrapped
+// by a key of the ring. Layout:
+//
+//	"APK1" | len(keyID) | keyID | wrapped data key (nonce+key+tag) | nonce | ciphertext
+//
+// Values written before the ring existed carry no header; they were encrypted directly with
+// SHA-256 of the session key and are still opened by trying every key of the ring.
+const (
+	sealMagic       = "APK1"
+	dataKeySize     = 32
+	wrappedKeySize  = 12 + dataKeySize + 16
+	sealedStringTag = "enc:"
+)
+
+var (
+	ErrNoKeys        = errors.New("crypto: nenhuma chave de sessão configurada")
+	ErrUnknownKey    = errors.New("crypto: valor cifrado com uma chave que não está no chaveiro")
+	ErrNoMatchingKey = errors.New("crypto: nenhuma chave do chaveiro decifra o valor")
+)
+
+var keyIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,32}$`)
+
+// Key is a named key of the ring. Secret is hashed with SHA-256 into the AES-256 key, as the
+// single session key always was, so an existing WHATSAPP_SESSION_KEY_ENC keeps opening old values.
+type Key struct {
+	ID     string
+	Secret string
+}
+
+// KeyRing holds the keys able to open stored values. New values are sealed with the primary key
+// (the first one); the others stay only to read what was sealed before a rotation.
+type KeyRing struct {
+	primary string
+	order   []string
+	keys    map[string][32]byte
+}
+
+// NewKeyRing builds a ring whose primary key is keys[0].
+func NewKeyRing(keys []Key) (*KeyRing, error) {
+	if len(keys) == 0 {
+		return nil, ErrNoKeys
+	}
+	r := &KeyRing{primary: keys[0].ID, keys: make(map[string][32]byte, len(keys))}
+	for _, k := range keys {
+		if !keyIDPattern.MatchString(k.ID) {
+			return nil, fmt.Errorf("crypto: ID de chave inválido %q", k.ID)
+		}
+		if k.Secret == "" {
+			return nil, fmt.Errorf("crypto: chave %q vazia", k.ID)
+		}
+		if _, dup := r.keys[k.ID]; dup {
+			return nil, fmt.Errorf("crypto: chave %q repetida", k.ID)
+		}
+		r.keys[k.ID] = sha256.Sum256([]byte(k.Secret))
+		r.order = append(r.order, k.ID)
+	}
+	return r, nil
+}
+
+// ParseKeys reads "id:secret" entries separated by commas or new lines. Blank lines and lines
+// starting with # are ignored, so the same format works for an env var and for a key file.
+func ParseKeys(spec string) ([]Key, error) {
+	var keys []Key
+	scanner := bufio.NewScanner(strings.NewReader(strings.ReplaceAll(spec, ",", "\n")))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		id, secret, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("crypto: entrada de chave sem ID (esperado id:segredo)")
+		}
+		keys = append(keys, Key{ID: strings.TrimSpace(id), Secret: strings.TrimSpace(secret)})
+	}
+	return keys, scanner.Err()
+}
+
+// LoadKeyRing builds the ring from, in order of precedence: the key file (KEK kept out of the
+// environment), the "id:secret" list, or the single legacy key under ID "0".
+func LoadKeyRing(keyFile, keys, legacyKey string) (*KeyRing, error) {
+	switch {
+	case keyFile != "":
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("crypto: ler arquivo de chaves: %w", err)
+		}
+		parsed, err := ParseKeys(string(data))
+		if err != nil {
+			return nil, err
+		}
+		return NewKeyRing(parsed)
+	case keys != "":
+		parsed, err := ParseKeys(keys)
+		if err != nil {
+			return nil, err
+		}
+		return NewKeyRing(parsed)
+	default:
+		return NewKeyRing([]Key{{ID: "0", Secret: legacyKey}})
+	}
+}
+
+// PrimaryID is the ID of the key new values are sealed with.
+func (r *KeyRing) PrimaryID() string {
+	return r.primary
+}
+
+// Seal encrypts plaintext under a fresh data key wrapped by the primary key.
+func (r *KeyRing) Seal(plaintext []byte) ([]byte, error) {
+	kek := r.keys[r.primary]
+	dataKey := make([]byte, dataKeySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, fmt.Errorf("crypto: gerar chave de dados: %w", err)
+	}
+	// The key ID is authenticated with the wrapped key, so the header can't be swapped.
+	wrapped, err := gcmSeal(kek[:], dataKey, []byte(r.primary))
+	if err != nil {
+		return nil, err
+	}
+	body, err := gcmSeal(dataKey, plaintext, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, len(sealMagic)+1+len(r.primary)+len(wrapped)+len(body))
+	out = append(out, sealMagic...)
+	out = append(out, byte(len(r.primary)))
+	out = append(out, r.primary...)
+	out = append(out, wrapped...)
+	return append(out, body...), nil
+}
+
+// Open decrypts a value sealed by any key of the ring, or a legacy value without header.
+func (r *KeyRing) Open(data []byte) ([]byte, error) {
+	id, wrapped, body, ok := splitSealed(data)
+	if !ok {
+		for _, kid := range r.order {
+			key := r.keys[kid]
+			if plain, err := gcmOpen(key[:], data, nil); err == nil {
+				return plain, nil
+			}
+		}
+		return nil, ErrNoMatchingKey
+	}
+	kek, known := r.keys[id]
+	if !known {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, id)
+	}
+	dataKey, err := gcmOpen(kek[:], wrapped, []byte(id))
+	if err != nil {
+		return nil, fmt.Errorf("crypto: desembrulhar chave de dados: %w", err)
+	}
+	return gcmOpen(dataKey, body, nil)
+}
+
+// KeyID reports which key sealed data; legacy values have none.
+func KeyID(data []byte) (string, bool) {
+	id, _, _, ok := splitSealed(data)
+	return id, ok
+}
+
+// NeedsRotation tells whether data should be re-sealed: it is a legacy value or was sealed with a
+// key other than the primary.
+func (r *KeyRing) NeedsRotation(data []byte) bool {
+	id, ok := KeyID(data)
+	return !ok || id != r.primary
+}
+
+// Rotate re-seals data with the primary key.
+func (r *KeyRing) Rotate(data []byte) ([]byte, error) {
+	plain, err := r.Open(data)
+	if err != nil {
+		return nil, err
+	}
+	return r.Seal(plain)
+}
+
+// SealString seals a text value for a text column: "enc:" followed by the base64 of Seal.
+func (r *KeyRing) SealString(plain string) (string, error) {
+	sealed, err := r.Seal([]byte(plain))
+	if err != nil {
+		return "", err
+	}
+	return sealedStringTag + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// OpenString reverses SealString. A value without the "enc:" tag was stored in clear before
+// encryption at rest and is returned as is.
+func (r *KeyRing) OpenString(value string) (string, error) {
+	sealed, ok, err := decodeSealedString(value)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return value, nil
+	}
+	plain, err := r.Open(sealed)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// IsSealedString tells whether value was produced by SealString.
+func IsSealedString(value string) bool {
+	return strings.HasPrefix(value, sealedStringTag)
+}
+
+// StringNeedsRotation is NeedsRotation for a SealString value; clear values are reported too.
+func (r *KeyRing) StringNeedsRotation(value string) bool {
+	sealed, ok, err := decodeSealedString(value)
+	if err != nil || !ok {
+		return true
+	}
+	return r.NeedsRotation(sealed)
+}
+
+func decodeSealedString(value string) ([]byte, bool, error) {
+	if !IsSealedString(value) {
+		return nil, false, nil
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, sealedStringTag))
+	if err != nil {
+		return nil, true, fmt.Errorf("crypto: valor cifrado malformado: %w", err)
+	}
+	return sealed, true, nil
+}
+
+func splitSealed(data []byte) (id string, wrapped, body []byte, ok bool) {
+	if !bytes.HasPrefix(data, []byte(sealMagic)) || len(data) < len(sealMagic)+1 {
+		return "", nil, nil, false
+	}
+	rest := data[len(sealMagic):]
+	n := int(rest[0])
+	rest = rest[1:]
+	if n == 0 || len(rest) < n+wrappedKeySize {
+		return "", nil, nil, false
+	}
+	return string(rest[:n]), rest[n : n+wrappedKeySize], rest[n+wrappedKeySize:], true
+}
+
+func gcmSeal(key, plaintext, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("crypto: read nonce: %w", err)
+	}
+	return gcm.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+func gcmOpen(key, data, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("crypto: ciphertext too short")
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, aad)
+	if err != nil {
+		return nil, fmt.Errorf("crypto: decrypt: %w", err)
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("crypto: new cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("crypto: new GCM: %w", err)
+	}
+	return gcm, nil
+}
@@ -0,0 +1,78 @@
+package crypto
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestKeyRingRotation(t *testing.T) {
+	legacy, err := Encrypt([]byte("blob antigo"), "chave-velha")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old, err := LoadKeyRing("", "", "chave-velha")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealedOld, err := old.Seal([]byte("blob v0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, ok := KeyID(sealedOld); !ok || id != "0" {
+		t.Fatalf("esperava chave 0, veio %q %v", id, ok)
+	}
+
+	keys, err := ParseKeys("2:chave-nova,\n# antiga\n0:chave-velha")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ring, err := NewKeyRing(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string][]byte{"legado": legacy, "v0": sealedOld} {
+		if !ring.NeedsRotation(data) {
+			t.Fatalf("%s deveria precisar de rotação", name)
+		}
+		rotated, err := ring.Rotate(data)
+		if err != nil {
+			t.Fatalf("%s: rotate: %v", name, err)
+		}
+		if id, _ := KeyID(rotated); id != "2" || ring.NeedsRotation(rotated) {
+			t.Fatalf("%s: esperava chave 2 após rotação, veio %q", name, id)
+		}
+		if _, err := old.Open(rotated); !errors.Is(err, ErrUnknownKey) {
+			t.Fatalf("%s: chaveiro antigo não deveria abrir o valor rotacionado: %v", name, err)
+		}
+	}
+
+	secret, err := ring.SealString("segredo-do-webhook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain, err := ring.OpenString(secret); err != nil || plain != "segredo-do-webhook" {
+		t.Fatalf("OpenString: %q %v", plain, err)
+	}
+	if plain, err := ring.OpenString("texto-puro"); err != nil || plain != "texto-puro" {
+		t.Fatalf("valor em claro deveria voltar como está: %q %v", plain, err)
+	}
+}
+
+func TestKeyRingRejectsTamperedHeader(t *testing.T) {
+	ring, err := NewKeyRing([]Key{{ID: "a", Secret: "um"}, {ID: "b", Secret: "dois"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := ring.Seal([]byte("x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed[len(sealMagic)+1] = 'b'
+	if _, err := ring.Open(sealed); err == nil {
+		t.Fatal("trocar o ID da chave no cabeçalho deveria falhar")
+	}
+	if _, err := NewKeyRing([]Key{{ID: "a", Secret: "um"}, {ID: "a", Secret: "dois"}}); err == nil {
+		t.Fatal("IDs repetidos deveriam ser recusados")
+	}
+}
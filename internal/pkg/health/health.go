@@ -0,0 +1,156 @@
+// Package health turns the risk signals of a WhatsApp number into a 0-100 score. It holds only
+// the scoring rules; the signals are counted by the message service.
+package health
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/open-apime/apime/internal/storage/model"
+)
+
+// Signal names, used as counter keys.
+const (
+	SignalTemporaryBan         = "temporary_ban"
+	SignalReachoutTimelock     = "reachout_timelock"
+	SignalReachoutError        = "reachout_error"
+	SignalRetryReceipt         = "retry_receipt"
+	SignalUndecryptable        = "undecryptable"
+	SignalOutboundConversation = "outbound_conversation"
+	SignalInboundConversation  = "inbound_conversation"
+	SignalReconnect            = "reconnect"
+)
+
+// Signals lists every signal name.
+var Signals = []string{
+	SignalTemporaryBan,
+	SignalReachoutTimelock,
+	SignalReachoutError,
+	SignalRetryReceipt,
+	SignalUndecryptable,
+	SignalOutboundConversation,
+	SignalInboundConversation,
+	SignalReconnect,
+}
+
+// WindowDays is the period the score is computed over. The trend compares it with the one before.
+const WindowDays = 7
+
+const (
+	RiskLow      = "low"
+	RiskMedium   = "medium"
+	RiskHigh     = "high"
+	RiskCritical = "critical"
+
+	TrendImproving = "improving"
+	TrendStable    = "stable"
+	TrendWorsening = "worsening"
+)
+
+// Add adds n occurrences of signal to s. Unknown names are ignored.
+func Add(s *model.HealthSignals, signal string, n int) {
+	switch signal {
+	case SignalTemporaryBan:
+		s.TemporaryBans += n
+	case SignalReachoutTimelock:
+		s.ReachoutTimelocks += n
+	case SignalReachoutError:
+		s.ReachoutErrors += n
+	case SignalRetryReceipt:
+		s.RetryReceipts += n
+	case SignalUndecryptable:
+		s.UndecryptableMessages += n
+	case SignalOutboundConversation:
+		s.OutboundConversations += n
+	case SignalInboundConversation:
+		s.InboundConversations += n
+	case SignalReconnect:
+		s.Reconnects += n
+	}
+}
+
+// Sum adds b into a.
+func Sum(a, b model.HealthSignals) model.HealthSignals {
+	return model.HealthSignals{
+		TemporaryBans:         a.TemporaryBans + b.TemporaryBans,
+		ReachoutTimelocks:     a.ReachoutTimelocks + b.ReachoutTimelocks,
+		ReachoutErrors:        a.ReachoutErrors + b.ReachoutErrors,
+		RetryReceipts:         a.RetryReceipts + b.RetryReceipts,
+		UndecryptableMessages: a.UndecryptableMessages + b.UndecryptableMessages,
+		OutboundConversations: a.OutboundConversations + b.OutboundConversations,
+		InboundConversations:  a.InboundConversations + b.InboundConversations,
+		Reconnects:            a.Reconnects + b.Reconnects,
+	}
+}
+
+// Ratio is outbound conversations started per inbound one. With no inbound the outbound count
+// itself is returned, so a number that only prospects still scores as lopsided.
+func Ratio(s model.HealthSignals) float64 {
+	if s.InboundConversations == 0 {
+		return float64(s.OutboundConversations)
+	}
+	return math.Round(float64(s.OutboundConversations)/float64(s.InboundConversations)*100) / 100
+}
+
+// Score rates a WindowDays period of signals from 100 (healthy) down to 0, with the reasons for
+// every point taken off. The weights follow what precedes bans in practice: a temporary ban or a
+// reach-out timelock is WhatsApp already acting on the number; 463s and a lopsided outbound ratio
+// are what gets it there; retries, undecryptable messages and reconnects point to an unstable
+// session, which weighs less.
+func Score(s model.HealthSignals) (int, []string) {
+	score := 100
+	reasons := []string{}
+	penalize := func(points, limit int, reason string) {
+		if points <= 0 {
+			return
+		}
+		if points > limit {
+			points = limit
+		}
+		score -= points
+		reasons = append(reasons, fmt.Sprintf("%s (-%d)", reason, points))
+	}
+
+	penalize(s.TemporaryBans*30, 60, fmt.Sprintf("%d ban(s) temporário(s)", s.TemporaryBans))
+	penalize(s.ReachoutTimelocks*20, 40, fmt.Sprintf("%d bloqueio(s) de reach-out da conta", s.ReachoutTimelocks))
+	penalize(s.ReachoutErrors*2, 25, fmt.Sprintf("%d erro(s) 463 ao escrever para contatos frios", s.ReachoutErrors))
+	penalize(s.RetryReceipts-5, 10, fmt.Sprintf("%d retry receipt(s)", s.RetryReceipts))
+	penalize((s.UndecryptableMessages-5)/2, 10, fmt.Sprintf("%d mensagem(ns) não decriptada(s)", s.UndecryptableMessages))
+	if s.OutboundConversations >= 20 {
+		if ratio := Ratio(s); ratio > 3 {
+			penalize(int((ratio-3)*5), 20, fmt.Sprintf("%.1f conversas iniciadas por conversa recebida", ratio))
+		}
+	}
+	penalize(s.Reconnects-14, 15, fmt.Sprintf("%d reconexões", s.Reconnects))
+
+	if score < 0 {
+		score = 0
+	}
+	return score, reasons
+}
+
+// Risk maps a score to its risk level.
+func Risk(score int) string {
+	switch {
+	case score >= 80:
+		return RiskLow
+	case score >= 60:
+		return RiskMedium
+	case score >= 35:
+		return RiskHigh
+	default:
+		return RiskCritical
+	}
+}
+
+// Trend compares the current score with the previous window's.
+func Trend(current, previous int) string {
+	switch diff := current - previous; {
+	case diff >= 5:
+		return TrendImproving
+	case diff <= -5:
+		return TrendWorsening
+	default:
+		return TrendStable
+	}
+}
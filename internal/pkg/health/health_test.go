@@ -0,0 +1,45 @@
+package health
+
+import (
+	"testing"
+
+	"github.com/open-apime/apime/internal/storage/model"
+)
+
+func TestScoreHealthyNumber(t *testing.T) {
+	score, reasons := Score(model.HealthSignals{
+		OutboundConversations: 30,
+		InboundConversations:  25,
+		RetryReceipts:         3,
+		Reconnects:            7,
+	})
+	if score != 100 || len(reasons) != 0 {
+		t.Fatalf("número saudável deveria ter 100 sem motivos, veio %d %v", score, reasons)
+	}
+	if Risk(score) != RiskLow {
+		t.Fatalf("risco deveria ser baixo, veio %s", Risk(score))
+	}
+}
+
+func TestScorePenalizesBansAndLopsidedOutbound(t *testing.T) {
+	score, reasons := Score(model.HealthSignals{
+		TemporaryBans:         1,
+		ReachoutErrors:        5,
+		OutboundConversations: 100,
+		InboundConversations:  10,
+	})
+	// 30 (ban) + 10 (463) + 20 (ratio 10, capped)
+	if score != 40 {
+		t.Fatalf("score esperado 40, veio %d %v", score, reasons)
+	}
+	if len(reasons) != 3 {
+		t.Fatalf("esperava 3 motivos, veio %v", reasons)
+	}
+	if Risk(score) != RiskHigh {
+		t.Fatalf("risco deveria ser alto, veio %s", Risk(score))
+	}
+
+	if score, _ := Score(model.HealthSignals{TemporaryBans: 5, ReachoutTimelocks: 5}); score != 0 {
+		t.Fatalf("score não pode ficar negativo, veio %d", score)
+	}
+}
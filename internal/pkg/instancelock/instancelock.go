@@ -1,15 +1,40 @@
 package instancelock
 
-import "sync"
+import (
+	"context"
+	"time"
 
-var locks sync.Map // instanceID -> *sync.Mutex
+	"go.uber.org/zap"
+)
 
-// Acquire serializes all WhatsApp-facing actions of a single instance
+// Locker serializes all WhatsApp-facing actions of a single instance
 // (send, react, edit, delete, presence). Different instances stay parallel.
-// Returns the release func; call it with defer.
-func Acquire(instanceID string) func() {
-	m, _ := locks.LoadOrStore(instanceID, &sync.Mutex{})
-	mu := m.(*sync.Mutex)
-	mu.Lock()
-	return mu.Unlock
+// Acquire blocks until the lock is held or ctx is done; call release with defer.
+// The returned context derives from ctx and is cancelled on release or when the
+// lock is lost (a shared lock that could not be renewed), so the work done under
+// the lock must use it.
+type Locker interface {
+	Acquire(ctx context.Context, instanceID string) (held context.Context, release func(), err error)
+}
+
+// slowWait is the wait above which acquiring is logged as a warning: it means
+// another request kept the instance busy for a noticeable time.
+const slowWait = time.Second
+
+// LogWait records how long an Acquire waited, for every backend.
+func LogWait(log *zap.Logger, backend, instanceID string, start time.Time) {
+	if log == nil {
+		return
+	}
+	wait := time.Since(start)
+	fields := []zap.Field{
+		zap.String("instance_id", instanceID),
+		zap.String("backend", backend),
+		zap.Duration("wait", wait),
+	}
+	if wait >= slowWait {
+		log.Warn("instancelock: espera longa pelo lock da instância", fields...)
+		return
+	}
+	log.Debug("instancelock: lock adquirido", fields...)
 }
@@ -0,0 +1,72 @@
+package instancelock
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// MemoryLocker is the single-replica locker. It uses a 1-slot channel per
+// instance instead of a sync.Mutex so waiting can be cancelled by ctx.
+//
+// An entry is kept only while someone holds or waits for it, so instances
+// that come and go don't pile up in the map.
+type MemoryLocker struct {
+	mu    sync.Mutex
+	locks map[string]*memoryEntry
+	log   *zap.Logger
+}
+
+type memoryEntry struct {
+	sem  chan struct{}
+	refs int // holder plus waiters
+}
+
+func NewMemory(log *zap.Logger) *MemoryLocker {
+	return &MemoryLocker{locks: make(map[string]*memoryEntry), log: log}
+}
+
+func (l *MemoryLocker) Acquire(ctx context.Context, instanceID string) (context.Context, func(), error) {
+	start := time.Now()
+	entry := l.ref(instanceID)
+
+	select {
+	case entry.sem <- struct{}{}:
+	case <-ctx.Done():
+		l.unref(instanceID, entry)
+		return nil, nil, ctx.Err()
+	}
+
+	LogWait(l.log, "memory", instanceID, start)
+	held, cancel := context.WithCancel(ctx)
+	var once sync.Once
+	return held, func() {
+		once.Do(func() {
+			cancel()
+			<-entry.sem
+			l.unref(instanceID, entry)
+		})
+	}, nil
+}
+
+func (l *MemoryLocker) ref(instanceID string) *memoryEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry, ok := l.locks[instanceID]
+	if !ok {
+		entry = &memoryEntry{sem: make(chan struct{}, 1)}
+		l.locks[instanceID] = entry
+	}
+	entry.refs++
+	return entry
+}
+
+func (l *MemoryLocker) unref(instanceID string, entry *memoryEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if entry.refs--; entry.refs == 0 {
+		delete(l.locks, instanceID)
+	}
+}
@@ -0,0 +1,62 @@
+package instancelock
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// A waiter must give up when its context ends instead of blocking forever
+// behind a stuck holder, which a plain sync.Mutex could not do.
+func TestMemoryLockerHonorsContext(t *testing.T) {
+	l := NewMemory(nil)
+	_, release, err := l.Acquire(context.Background(), "inst")
+	if err != nil {
+		t.Fatalf("primeiro acquire falhou: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, _, err := l.Acquire(ctx, "inst"); err == nil {
+		t.Fatalf("acquire deveria falhar com o lock ocupado")
+	}
+
+	if _, _, err := l.Acquire(context.Background(), "other"); err != nil {
+		t.Fatalf("instâncias diferentes não deveriam bloquear: %v", err)
+	}
+
+	release()
+	release() // idempotent
+	_, again, err := l.Acquire(context.Background(), "inst")
+	if err != nil {
+		t.Fatalf("acquire após release falhou: %v", err)
+	}
+	again()
+}
+
+// Entries live only while someone holds or waits for the lock: a waiter that
+// gives up and a holder that releases leave nothing behind.
+func TestMemoryLockerDropsIdleEntries(t *testing.T) {
+	l := NewMemory(nil)
+	held, release, err := l.Acquire(context.Background(), "inst")
+	if err != nil {
+		t.Fatalf("acquire falhou: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, _, err := l.Acquire(ctx, "inst"); err == nil {
+		t.Fatalf("acquire deveria falhar com o lock ocupado")
+	}
+	if n := len(l.locks); n != 1 {
+		t.Fatalf("entradas com o lock ocupado: %d; queria 1", n)
+	}
+
+	release()
+	if held.Err() == nil {
+		t.Errorf("contexto do dono deveria ser cancelado no release")
+	}
+	if n := len(l.locks); n != 0 {
+		t.Fatalf("entradas após o release: %d; queria 0", n)
+	}
+}
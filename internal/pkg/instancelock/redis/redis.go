@@ -0,0 +1,145 @@
+package redis
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/open-apime/apime/internal/pkg/instancelock"
+	storage_redis "github.com/open-apime/apime/internal/storage/redis"
+)
+
+const (
+	keyPrefix   = "lock:instance:"
+	minBackoff  = 20 * time.Millisecond
+	maxBackoff  = 250 * time.Millisecond
+	releaseWait = 3 * time.Second
+)
+
+// Locker serializes an instance across replicas with storage/redis.Lock. The
+// TTL only bounds how long a crashed holder blocks others; a live holder keeps
+// renewing it every ttl/3 until release.
+//
+// Waiters of the same replica first queue on a local lock, so only one of them
+// polls Redis at a time.
+type Locker struct {
+	client *storage_redis.Client
+	local  *instancelock.MemoryLocker
+	ttl    time.Duration
+	log    *zap.Logger
+}
+
+func NewLocker(client *storage_redis.Client, ttl time.Duration, log *zap.Logger) *Locker {
+	if ttl <= 0 {
+		ttl = 30 * time.Second
+	}
+	return &Locker{
+		client: client,
+		local:  instancelock.NewMemory(nil),
+		ttl:    ttl,
+		log:    log,
+	}
+}
+
+func (l *Locker) Acquire(ctx context.Context, instanceID string) (context.Context, func(), error) {
+	start := time.Now()
+
+	_, releaseLocal, err := l.local.Acquire(ctx, instanceID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	lock := storage_redis.NewLock(l.client, keyPrefix+instanceID, l.ttl)
+	backoff := minBackoff
+	for {
+		ok, err := lock.Acquire(ctx)
+		if err != nil {
+			releaseLocal()
+			return nil, nil, err
+		}
+		if ok {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			releaseLocal()
+			return nil, nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+
+	instancelock.LogWait(l.log, "redis", instanceID, start)
+
+	held, cancel := context.WithCancel(ctx)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go l.renew(lock, instanceID, cancel, stop, done)
+
+	var once sync.Once
+	return held, func() {
+		once.Do(func() {
+			cancel()
+			l.release(lock, instanceID, stop, done, releaseLocal)
+		})
+	}, nil
+}
+
+func (l *Locker) release(lock *storage_redis.Lock, instanceID string, stop chan struct{}, done <-chan struct{}, releaseLocal func()) {
+	close(stop)
+	<-done
+
+	// Release runs even when the caller's ctx is already cancelled.
+	rctx, cancel := context.WithTimeout(context.Background(), releaseWait)
+	defer cancel()
+	if err := lock.Release(rctx); err != nil && l.log != nil {
+		l.log.Warn("instancelock: erro ao liberar lock no Redis", zap.String("instance_id", instanceID), zap.Error(err))
+	}
+	releaseLocal()
+}
+
+// renew extends the lock until stop. Once the lock is gone (taken over after
+// expiring, or unrenewed for a whole ttl while Redis is unreachable) another
+// replica may hold it, so lost cancels the holder's context.
+func (l *Locker) renew(lock *storage_redis.Lock, instanceID string, lost context.CancelFunc, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+	renewed := time.Now()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			attempt := time.Now()
+			ctx, cancel := context.WithTimeout(context.Background(), l.ttl/3)
+			ok, err := lock.Extend(ctx)
+			cancel()
+			if err != nil {
+				if time.Since(renewed) < l.ttl {
+					if l.log != nil {
+						l.log.Warn("instancelock: falha ao renovar lock", zap.String("instance_id", instanceID), zap.Error(err))
+					}
+					continue
+				}
+				if l.log != nil {
+					l.log.Error("instancelock: lock expirado sem renovação, operação cancelada", zap.String("instance_id", instanceID), zap.Error(err))
+				}
+				lost()
+				return
+			}
+			if !ok {
+				if l.log != nil {
+					l.log.Error("instancelock: lock expirou antes do fim da operação, operação cancelada", zap.String("instance_id", instanceID))
+				}
+				lost()
+				return
+			}
+			renewed = attempt
+		}
+	}
+}
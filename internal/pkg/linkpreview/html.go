@@ -0,0 +1,52 @@
+package linkpreview
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// The preview tags are in <head>; a regular expression over it is enough and avoids pulling an
+// HTML parser in for a handful of attributes.
+var (
+	metaTag   = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	attribute = regexp.MustCompile(`(?s)([a-zA-Z_:-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+	titleTag  = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	headEnd   = regexp.MustCompile(`(?i)</head>`)
+	spaces    = regexp.MustCompile(`\s+`)
+)
+
+// parseHTML returns the meta tags of page keyed by their property (or name), lower case, plus the
+// <title> under "title". The first occurrence of a key wins, as in the clients.
+func parseHTML(page string) map[string]string {
+	if loc := headEnd.FindStringIndex(page); loc != nil {
+		page = page[:loc[0]]
+	}
+	page = strings.ToValidUTF8(page, "")
+
+	meta := make(map[string]string)
+	for _, tag := range metaTag.FindAllString(page, -1) {
+		attrs := make(map[string]string)
+		for _, m := range attribute.FindAllStringSubmatch(tag, -1) {
+			attrs[strings.ToLower(m[1])] = m[2] + m[3] + m[4]
+		}
+		key := strings.ToLower(firstOf(attrs["property"], attrs["name"]))
+		content := clean(attrs["content"])
+		if key == "" || content == "" {
+			continue
+		}
+		if _, ok := meta[key]; !ok {
+			meta[key] = content
+		}
+	}
+	if m := titleTag.FindStringSubmatch(page); m != nil {
+		if title := clean(m[1]); title != "" {
+			meta["title"] = title
+		}
+	}
+	return meta
+}
+
+func clean(s string) string {
+	return strings.TrimSpace(spaces.ReplaceAllString(html.UnescapeString(s), " "))
+}
@@ -0,0 +1,284 @@
+// Package linkpreview fetches the OpenGraph data (title, description, image) of a URL found in a
+// message, the way the WhatsApp clients do before sending a link.
+//
+// The URL comes from an API caller, so the fetcher is built to not be a way into the internal
+// network: only http and https are followed, and every connection — redirects included — is
+// checked at dial time against loopback, private, link-local and other non-public ranges. Checking
+// the resolved address on the socket (instead of resolving the host beforehand) also closes the
+// DNS rebinding window.
+package linkpreview
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrBlockedAddress = errors.New("linkpreview: endereço não permitido")
+	ErrUnsupportedURL = errors.New("linkpreview: URL não suportada")
+	ErrNoPreview      = errors.New("linkpreview: página sem dados para pré-visualização")
+)
+
+// maxRedirects follows the usual shortener → canonical page chains without looping forever.
+const maxRedirects = 5
+
+const (
+	maxTitleRunes       = 200
+	maxDescriptionRunes = 300
+)
+
+// Preview is what a page says about itself. URL is the final one, after redirects. Image is the
+// raw og:image (any format); it's empty when the page has none or it couldn't be fetched.
+type Preview struct {
+	URL         string
+	Title       string
+	Description string
+	ImageURL    string
+	Image       []byte
+}
+
+type Options struct {
+	// Timeout bounds the whole fetch: page and image.
+	Timeout       time.Duration
+	MaxPageBytes  int64
+	MaxImageBytes int64
+	UserAgent     string
+	// AllowPrivate lifts the address check. Only meant for tests against a local server.
+	AllowPrivate bool
+}
+
+type Fetcher struct {
+	client *http.Client
+	opts   Options
+}
+
+func NewFetcher(opts Options) *Fetcher {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Second
+	}
+	if opts.MaxPageBytes <= 0 {
+		opts.MaxPageBytes = 512 << 10
+	}
+	if opts.MaxImageBytes <= 0 {
+		opts.MaxImageBytes = 2 << 20
+	}
+	if opts.UserAgent == "" {
+		// Many sites only serve their OpenGraph tags to known link-preview agents.
+		opts.UserAgent = "WhatsApp/2"
+	}
+
+	dialer := &net.Dialer{Timeout: opts.Timeout}
+	if !opts.AllowPrivate {
+		dialer.Control = checkAddress
+	}
+	transport := &http.Transport{
+		// No proxy: the check has to see the address of the site, not the one of a proxy.
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   opts.Timeout,
+		ResponseHeaderTimeout: opts.Timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+	}
+	client := &http.Client{
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("linkpreview: mais de %d redirecionamentos", maxRedirects)
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return ErrUnsupportedURL
+			}
+			return nil
+		},
+	}
+	return &Fetcher{client: client, opts: opts}
+}
+
+// Fetch reads the page at rawURL and returns its preview. A page without a title (OpenGraph,
+// Twitter card or <title>) returns ErrNoPreview. A missing or broken image isn't an error: the
+// preview just goes without it.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (Preview, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Preview{}, ErrUnsupportedURL
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, f.opts.Timeout)
+	defer cancel()
+
+	resp, err := f.get(ctx, u.String(), "text/html,application/xhtml+xml")
+	if err != nil {
+		return Preview{}, err
+	}
+	defer resp.Body.Close()
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return Preview{}, fmt.Errorf("%w: conteúdo %q", ErrNoPreview, mediaType)
+	}
+	page, err := io.ReadAll(io.LimitReader(resp.Body, f.opts.MaxPageBytes))
+	if err != nil {
+		return Preview{}, fmt.Errorf("linkpreview: %w", err)
+	}
+
+	meta := parseHTML(string(page))
+	preview := Preview{
+		URL:         resp.Request.URL.String(),
+		Title:       truncate(firstOf(meta["og:title"], meta["twitter:title"], meta["title"]), maxTitleRunes),
+		Description: truncate(firstOf(meta["og:description"], meta["twitter:description"], meta["description"]), maxDescriptionRunes),
+	}
+	if preview.Title == "" {
+		return Preview{}, ErrNoPreview
+	}
+
+	image := firstOf(meta["og:image:secure_url"], meta["og:image:url"], meta["og:image"], meta["twitter:image"])
+	if image == "" {
+		return preview, nil
+	}
+	ref, err := resp.Request.URL.Parse(image)
+	if err != nil {
+		return preview, nil
+	}
+	preview.ImageURL = ref.String()
+	preview.Image, _ = f.image(ctx, preview.ImageURL)
+	return preview, nil
+}
+
+func (f *Fetcher) image(ctx context.Context, imageURL string) ([]byte, error) {
+	resp, err := f.get(ctx, imageURL, "image/*")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "image/") {
+		return nil, ErrNoPreview
+	}
+	// One byte past the limit tells a truncated image from one that fits exactly.
+	data, err := io.ReadAll(io.LimitReader(resp.Body, f.opts.MaxImageBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > f.opts.MaxImageBytes {
+		return nil, fmt.Errorf("linkpreview: imagem maior que %d bytes", f.opts.MaxImageBytes)
+	}
+	return data, nil
+}
+
+func (f *Fetcher) get(ctx context.Context, target, accept string) (*http.Response, error) {
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, ErrUnsupportedURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("linkpreview: %w", err)
+	}
+	req.Header.Set("User-Agent", f.opts.UserAgent)
+	req.Header.Set("Accept", accept)
+	resp, err := f.client.Do(req)
+	if err != nil {
+		if errors.Is(err, ErrBlockedAddress) {
+			return nil, ErrBlockedAddress
+		}
+		return nil, fmt.Errorf("linkpreview: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("linkpreview: status %d", resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// reservedPrefixes are the non-public ranges netip has no predicate for.
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"), // NAT64 can point back at IPv4 private ranges
+}
+
+// checkAddress runs on the resolved address right before connecting.
+func checkAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return ErrBlockedAddress
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil || !publicAddr(ip) {
+		return ErrBlockedAddress
+	}
+	return nil
+}
+
+func publicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+	for _, p := range reservedPrefixes {
+		if p.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+var (
+	urlPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"]+`)
+	// Punctuation that usually closes the sentence rather than the URL.
+	urlTrailing = ".,;:!?'\")]}"
+)
+
+// FirstURL returns the first link in text as it was written (matched, what WhatsApp calls
+// MatchedText) and as a fetchable URL. Links starting with "www." get https. Empty when there's no
+// link.
+func FirstURL(text string) (matched, target string) {
+	m := urlPattern.FindString(text)
+	if m == "" {
+		return "", ""
+	}
+	m = strings.TrimRight(m, urlTrailing)
+	// A closing parenthesis belongs to the URL when it has an opening one (Wikipedia links).
+	if strings.Count(m, "(") > strings.Count(m, ")") {
+		if i := strings.Index(text, m); i >= 0 && strings.HasPrefix(text[i+len(m):], ")") {
+			m += ")"
+		}
+	}
+	target = m
+	if strings.HasPrefix(strings.ToLower(m), "www.") {
+		target = "https://" + m
+	}
+	if _, err := url.Parse(target); err != nil {
+		return "", ""
+	}
+	return m, target
+}
+
+func firstOf(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return strings.TrimSpace(string(r[:n-1])) + "…"
+}
@@ -0,0 +1,97 @@
+package linkpreview
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestFirstURL(t *testing.T) {
+	cases := []struct {
+		text, matched, target string
+	}{
+		{"veja https://example.com/a?b=1.", "https://example.com/a?b=1", "https://example.com/a?b=1"},
+		{"acesse www.example.com!", "www.example.com", "https://www.example.com"},
+		{"(https://en.wikipedia.org/wiki/Go_(language))", "https://en.wikipedia.org/wiki/Go_(language)", "https://en.wikipedia.org/wiki/Go_(language)"},
+		{"sem link aqui", "", ""},
+		{"ftp://example.com", "", ""},
+	}
+	for _, tc := range cases {
+		matched, target := FirstURL(tc.text)
+		if matched != tc.matched || target != tc.target {
+			t.Errorf("FirstURL(%q) = %q, %q; esperado %q, %q", tc.text, matched, target, tc.matched, tc.target)
+		}
+	}
+}
+
+func TestFetch(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, `<html><head><title>Fallback</title>
+<meta property="og:title" content="Caf&eacute; &amp; Cia">
+<meta name='description' content='Descrição
+  em duas linhas'>
+<meta content="/img.png" property="og:image" />
+</head><body><meta property="og:title" content="ignorado"></body></html>`)
+	})
+	mux.HandleFunc("/img.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("png"))
+	})
+	mux.HandleFunc("/short", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/page", http.StatusFound)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	f := NewFetcher(Options{AllowPrivate: true})
+	p, err := f.Fetch(context.Background(), srv.URL+"/short")
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if p.Title != "Café & Cia" || p.Description != "Descrição em duas linhas" {
+		t.Errorf("título/descrição inesperados: %q / %q", p.Title, p.Description)
+	}
+	if p.URL != srv.URL+"/page" || p.ImageURL != srv.URL+"/img.png" || string(p.Image) != "png" {
+		t.Errorf("URLs/imagem inesperadas: %+v", p)
+	}
+}
+
+func TestFetchBlocksPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("o fetcher não deveria ter chegado ao servidor local")
+	}))
+	defer srv.Close()
+
+	f := NewFetcher(Options{})
+	if _, err := f.Fetch(context.Background(), srv.URL); !errors.Is(err, ErrBlockedAddress) {
+		t.Fatalf("endereço local deveria ser bloqueado, veio %v", err)
+	}
+	if _, err := f.Fetch(context.Background(), "file:///etc/passwd"); !errors.Is(err, ErrUnsupportedURL) {
+		t.Fatalf("esquema file deveria ser recusado, veio %v", err)
+	}
+}
+
+func TestPublicAddr(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"8.8.8.8":          true,
+		"2606:4700::1111":  true,
+		"127.0.0.1":        false,
+		"10.1.2.3":         false,
+		"169.254.169.254":  false,
+		"100.64.0.1":       false,
+		"0.0.0.0":          false,
+		"::1":              false,
+		"fd00::1":          false,
+		"::ffff:127.0.0.1": false,
+	} {
+		if got := publicAddr(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("publicAddr(%s) = %v, esperado %v", addr, got, want)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+// Package mediaurl issues and checks the expiring download URLs of received media.
+package mediaurl
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrMissingSignature = errors.New("mediaurl: URL sem assinatura")
+	ErrExpired          = errors.New("mediaurl: URL expirada")
+	ErrInvalidSignature = errors.New("mediaurl: assinatura inválida")
+)
+
+// Signer signs "<instanceID>/<mediaID>/<expires>", so a URL opens exactly one file until it
+// expires and can't be edited to point to another one.
+type Signer struct {
+	key []byte
+	ttl time.Duration
+}
+
+// NewSigner derives the signing key from secret. The derivation keeps the key distinct from other
+// uses of the same secret (it falls back to JWT_SECRET when MEDIA_URL_SECRET isn't set).
+func NewSigner(secret string, ttl time.Duration) *Signer {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("apime:media-url"))
+	return &Signer{key: mac.Sum(nil), ttl: ttl}
+}
+
+// TTL is how long issued URLs stay valid.
+func (s *Signer) TTL() time.Duration {
+	return s.ttl
+}
+
+// URL returns the signed download URL of a media file, valid for the signer TTL from now.
+func (s *Signer) URL(baseURL, instanceID, mediaID string, now time.Time) string {
+	expires := strconv.FormatInt(now.Add(s.ttl).Unix(), 10)
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("sig", s.sign(instanceID, mediaID, expires))
+	return fmt.Sprintf("%s/api/media/%s/%s?%s", baseURL, url.PathEscape(instanceID), url.PathEscape(mediaID), query.Encode())
+}
+
+// Verify checks the expires and sig query values of a download URL.
+func (s *Signer) Verify(instanceID, mediaID, expires, sig string, now time.Time) error {
+	if expires == "" || sig == "" {
+		return ErrMissingSignature
+	}
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(sig), []byte(s.sign(instanceID, mediaID, expires))) {
+		return ErrInvalidSignature
+	}
+	if now.Unix() > unix {
+		return ErrExpired
+	}
+	return nil
+}
+
+func (s *Signer) sign(instanceID, mediaID, expires string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(instanceID + "/" + mediaID + "/" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,42 @@
+package mediaurl
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestSignedURL(t *testing.T) {
+	signer := NewSigner("segredo", time.Hour)
+	now := time.Unix(1_700_000_000, 0)
+
+	raw := signer.URL("https://api.exemplo.com", "inst-1", "ABC_1a2b3c4d.jpg", now)
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatalf("URL inválida %q: %v", raw, err)
+	}
+	if u.Path != "/api/media/inst-1/ABC_1a2b3c4d.jpg" {
+		t.Fatalf("caminho inesperado: %s", u.Path)
+	}
+	expires, sig := u.Query().Get("expires"), u.Query().Get("sig")
+
+	if err := signer.Verify("inst-1", "ABC_1a2b3c4d.jpg", expires, sig, now.Add(59*time.Minute)); err != nil {
+		t.Fatalf("URL válida recusada: %v", err)
+	}
+	if err := signer.Verify("inst-1", "ABC_1a2b3c4d.jpg", expires, sig, now.Add(61*time.Minute)); !errors.Is(err, ErrExpired) {
+		t.Fatalf("esperava ErrExpired, veio %v", err)
+	}
+	if err := signer.Verify("inst-2", "ABC_1a2b3c4d.jpg", expires, sig, now); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("assinatura de outra instância aceita: %v", err)
+	}
+	if err := signer.Verify("inst-1", "ABC_1a2b3c4d.jpg", "9999999999", sig, now); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expiração alterada aceita: %v", err)
+	}
+	if err := NewSigner("outro", time.Hour).Verify("inst-1", "ABC_1a2b3c4d.jpg", expires, sig, now); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("assinatura com outro segredo aceita: %v", err)
+	}
+	if err := signer.Verify("inst-1", "ABC_1a2b3c4d.jpg", "", "", now); !errors.Is(err, ErrMissingSignature) {
+		t.Fatalf("esperava ErrMissingSignature, veio %v", err)
+	}
+}
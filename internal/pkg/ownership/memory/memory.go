@@ -0,0 +1,40 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/open-apime/apime/internal/pkg/ownership"
+)
+
+// Single is the single-replica ownership: this process owns every instance.
+type Single struct {
+	self ownership.Node
+}
+
+func New(nodeID string) *Single {
+	return &Single{self: ownership.Node{ID: nodeID}}
+}
+
+func (s *Single) Self() ownership.Node { return s.self }
+
+func (s *Single) Claim(context.Context, string) (bool, error) { return true, nil }
+
+func (s *Single) ClaimShare(_ context.Context, instanceIDs []string) []string { return instanceIDs }
+
+func (s *Single) Owner(context.Context, string) (ownership.Node, bool, error) {
+	return s.self, true, nil
+}
+
+func (s *Single) Release(context.Context, string) error { return nil }
+
+func (s *Single) IsLocal(string) bool { return true }
+
+func (s *Single) OnLost(func(string)) {}
+
+func (s *Single) OnAcquired(func(string)) {}
+
+func (s *Single) SetCandidates(func(context.Context) ([]string, error)) {}
+
+func (s *Single) Start(context.Context) {}
+
+func (s *Single) Stop() {}
@@ -0,0 +1,42 @@
+package ownership
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNotOwner is returned when another replica holds the lease of an instance.
+var ErrNotOwner = errors.New("instância pertence a outra réplica")
+
+// Node identifies a replica and the address other replicas use to reach it.
+type Node struct {
+	ID   string
+	Addr string
+}
+
+// Ownership coordinates which replica runs the WhatsApp session of each
+// instance. Only the lease holder may connect the device; everyone else
+// forwards to it.
+type Ownership interface {
+	Self() Node
+	// Claim takes the lease of an instance, or renews it when this replica
+	// already holds it. It returns false when another replica owns it.
+	Claim(ctx context.Context, instanceID string) (bool, error)
+	// ClaimShare claims this replica's fair share of instanceIDs at boot: the
+	// leases it already holds plus free ones, up to the per-replica limit. It
+	// returns the instances claimed.
+	ClaimShare(ctx context.Context, instanceIDs []string) []string
+	// Owner returns the current lease holder; ok is false when nobody holds it.
+	Owner(ctx context.Context, instanceID string) (owner Node, ok bool, err error)
+	Release(ctx context.Context, instanceID string) error
+	IsLocal(instanceID string) bool
+	// OnLost is called when a renewal finds the lease taken by someone else,
+	// e.g. after a network partition. The session must be dropped right away.
+	OnLost(fn func(instanceID string))
+	// OnAcquired is called when the failover loop claims an orphan instance.
+	OnAcquired(fn func(instanceID string))
+	// SetCandidates provides the instances the failover loop may adopt.
+	SetCandidates(fn func(ctx context.Context) ([]string, error))
+	Start(ctx context.Context)
+	Stop()
+}
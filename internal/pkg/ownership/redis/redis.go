@@ -0,0 +1,400 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"github.com/open-apime/apime/internal/pkg/ownership"
+)
+
+const keyPrefix = "cluster:"
+
+var renewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+    return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+    return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// maxAdoptPerTick caps how many orphans one replica adopts per heartbeat, so
+// the survivors of a crash split the load instead of the fastest one taking
+// everything (and reconnecting dozens of devices at once).
+const maxAdoptPerTick = 5
+
+// nodesKey is a sorted set of live replicas scored by the expiry of their
+// announcement; it is what the fair share is computed from.
+const nodesKey = keyPrefix + "nodes"
+
+// Leases holds one Redis lease per instance (cluster:owner:<id> = nodeID) and
+// publishes this replica's address under cluster:node:<nodeID>. Both expire
+// unless the heartbeat renews them, which is what makes failover automatic.
+type Leases struct {
+	rdb  *redis.Client
+	self ownership.Node
+	ttl  time.Duration
+	log  *zap.Logger
+	// maxOwned caps the leases this replica holds; zero means no cap.
+	maxOwned int
+
+	mu sync.Mutex
+	// owned maps each lease held to its last successful renewal.
+	owned      map[string]time.Time
+	onLost     func(string)
+	onAcquired func(string)
+	candidates func(context.Context) ([]string, error)
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewLeases(rdb *redis.Client, self ownership.Node, ttl time.Duration, log *zap.Logger) *Leases {
+	if ttl <= 0 {
+		ttl = 30 * time.Second
+	}
+	return &Leases{
+		rdb:   rdb,
+		self:  self,
+		ttl:   ttl,
+		log:   log,
+		owned: make(map[string]time.Time),
+	}
+}
+
+// SetMaxOwned caps how many instances this replica runs, on top of the fair
+// share. Call before Start.
+func (l *Leases) SetMaxOwned(n int) {
+	l.maxOwned = n
+}
+
+func ownerKey(instanceID string) string { return keyPrefix + "owner:" + instanceID }
+func nodeKey(nodeID string) string      { return keyPrefix + "node:" + nodeID }
+
+func (l *Leases) Self() ownership.Node { return l.self }
+
+func (l *Leases) Claim(ctx context.Context, instanceID string) (bool, error) {
+	key := ownerKey(instanceID)
+	start := time.Now()
+	ok, err := l.rdb.SetNX(ctx, key, l.self.ID, l.ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("ownership claim: %w", err)
+	}
+	if !ok {
+		renewed, err := renewScript.Run(ctx, l.rdb, []string{key}, l.self.ID, l.ttl.Milliseconds()).Int()
+		if err != nil {
+			return false, fmt.Errorf("ownership claim: %w", err)
+		}
+		if renewed == 0 {
+			return false, nil
+		}
+	}
+
+	l.mu.Lock()
+	l.owned[instanceID] = start
+	l.mu.Unlock()
+	return true, nil
+}
+
+// ClaimShare waits a random slice of the heartbeat first, so replicas booting
+// together announce themselves before any of them counts the cluster. Leases
+// this node still holds from before a restart are kept; free instances are
+// then claimed in random order until the fair share is reached.
+func (l *Leases) ClaimShare(ctx context.Context, instanceIDs []string) []string {
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-time.After(time.Duration(rand.Int63n(int64(l.ttl / 3)))):
+	}
+
+	var claimed, free []string
+	for _, id := range instanceIDs {
+		owner, err := l.rdb.Get(ctx, ownerKey(id)).Result()
+		switch {
+		case err == nil && owner == l.self.ID:
+			if ok, err := l.Claim(ctx, id); err == nil && ok {
+				claimed = append(claimed, id)
+			}
+		case errors.Is(err, redis.Nil):
+			free = append(free, id)
+		}
+	}
+
+	share, ok := l.share(ctx, len(instanceIDs))
+	if !ok {
+		// Without the node count any share is a guess; the free instances are
+		// adopted on a later heartbeat instead.
+		share = 0
+	}
+	rand.Shuffle(len(free), func(i, j int) { free[i], free[j] = free[j], free[i] })
+	for _, id := range free {
+		if len(claimed) >= share {
+			break
+		}
+		if ok, err := l.Claim(ctx, id); err == nil && ok {
+			claimed = append(claimed, id)
+		}
+	}
+	l.log.Info("ownership: instâncias assumidas no boot",
+		zap.Int("claimed", len(claimed)),
+		zap.Int("share", share),
+		zap.Int("total", len(instanceIDs)),
+	)
+	return claimed
+}
+
+// share is how many of total instances this replica should hold: an even
+// split across the live nodes, bounded by maxOwned. It reports false when the
+// nodes cannot be counted: assuming a cluster of one would let this replica
+// take everything.
+func (l *Leases) share(ctx context.Context, total int) (int, bool) {
+	nodes, err := l.rdb.ZCount(ctx, nodesKey, strconv.FormatInt(time.Now().UnixMilli(), 10), "+inf").Result()
+	if err != nil {
+		l.log.Warn("ownership: falha ao contar nós", zap.Error(err))
+		return 0, false
+	}
+	if nodes < 1 {
+		nodes = 1
+	}
+	n := (total + int(nodes) - 1) / int(nodes)
+	if l.maxOwned > 0 && n > l.maxOwned {
+		n = l.maxOwned
+	}
+	return n, true
+}
+
+func (l *Leases) Owner(ctx context.Context, instanceID string) (ownership.Node, bool, error) {
+	id, err := l.rdb.Get(ctx, ownerKey(instanceID)).Result()
+	if errors.Is(err, redis.Nil) {
+		return ownership.Node{}, false, nil
+	}
+	if err != nil {
+		return ownership.Node{}, false, fmt.Errorf("ownership owner: %w", err)
+	}
+	if id == l.self.ID {
+		return l.self, true, nil
+	}
+
+	addr, err := l.rdb.Get(ctx, nodeKey(id)).Result()
+	if errors.Is(err, redis.Nil) {
+		// The owner stopped heartbeating; its lease is about to expire.
+		return ownership.Node{ID: id}, true, nil
+	}
+	if err != nil {
+		return ownership.Node{}, false, fmt.Errorf("ownership owner: %w", err)
+	}
+	return ownership.Node{ID: id, Addr: addr}, true, nil
+}
+
+func (l *Leases) Release(ctx context.Context, instanceID string) error {
+	l.mu.Lock()
+	delete(l.owned, instanceID)
+	l.mu.Unlock()
+
+	if err := releaseScript.Run(ctx, l.rdb, []string{ownerKey(instanceID)}, l.self.ID).Err(); err != nil && !errors.Is(err, redis.Nil) {
+		return fmt.Errorf("ownership release: %w", err)
+	}
+	return nil
+}
+
+func (l *Leases) IsLocal(instanceID string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, ok := l.owned[instanceID]
+	return ok
+}
+
+func (l *Leases) OnLost(fn func(string)) {
+	l.mu.Lock()
+	l.onLost = fn
+	l.mu.Unlock()
+}
+
+func (l *Leases) OnAcquired(fn func(string)) {
+	l.mu.Lock()
+	l.onAcquired = fn
+	l.mu.Unlock()
+}
+
+func (l *Leases) SetCandidates(fn func(context.Context) ([]string, error)) {
+	l.mu.Lock()
+	l.candidates = fn
+	l.mu.Unlock()
+}
+
+// Start announces the node and runs the heartbeat every ttl/3, which leaves
+// two missed beats of slack before a lease expires.
+func (l *Leases) Start(ctx context.Context) {
+	ctx, l.cancel = context.WithCancel(ctx)
+	l.announce(ctx)
+
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		ticker := time.NewTicker(l.ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				l.announce(ctx)
+				l.renewAll(ctx)
+				l.adoptOrphans(ctx)
+			}
+		}
+	}()
+
+	l.log.Info("ownership: heartbeat iniciado",
+		zap.String("node_id", l.self.ID),
+		zap.String("addr", l.self.Addr),
+		zap.Duration("ttl", l.ttl),
+	)
+}
+
+// Stop releases every lease held, so the survivors adopt the instances on the
+// next tick instead of waiting for the TTL.
+func (l *Leases) Stop() {
+	if l.cancel != nil {
+		l.cancel()
+	}
+	l.wg.Wait()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, id := range l.ownedIDs() {
+		if err := l.Release(ctx, id); err != nil {
+			l.log.Warn("ownership: erro ao liberar lease", zap.String("instance_id", id), zap.Error(err))
+		}
+	}
+	_ = l.rdb.Del(ctx, nodeKey(l.self.ID)).Err()
+	_ = l.rdb.ZRem(ctx, nodesKey, l.self.ID).Err()
+}
+
+func (l *Leases) announce(ctx context.Context) {
+	now := time.Now()
+	_, err := l.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, nodeKey(l.self.ID), l.self.Addr, l.ttl)
+		pipe.ZAdd(ctx, nodesKey, redis.Z{Score: float64(now.Add(l.ttl).UnixMilli()), Member: l.self.ID})
+		pipe.ZRemRangeByScore(ctx, nodesKey, "-inf", strconv.FormatInt(now.UnixMilli(), 10))
+		return nil
+	})
+	if err != nil {
+		l.log.Warn("ownership: falha ao anunciar nó", zap.Error(err))
+	}
+}
+
+func (l *Leases) ownedIDs() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	ids := make([]string, 0, len(l.owned))
+	for id := range l.owned {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func (l *Leases) renewAll(ctx context.Context) {
+	for _, id := range l.ownedIDs() {
+		// Taken before the call: the lease runs for ttl from when Redis renews
+		// it, never earlier than this.
+		start := time.Now()
+		renewed, err := renewScript.Run(ctx, l.rdb, []string{ownerKey(id)}, l.self.ID, l.ttl.Milliseconds()).Int()
+		if err == nil && renewed == 1 {
+			l.mu.Lock()
+			if _, ok := l.owned[id]; ok {
+				l.owned[id] = start
+			}
+			l.mu.Unlock()
+			continue
+		}
+		if err != nil {
+			// Redis unreachable: the lease is still ours until ttl passes
+			// since the last renewal. After that it may have expired and been
+			// claimed by another replica, so the session must stop here.
+			l.log.Warn("ownership: falha ao renovar lease", zap.String("instance_id", id), zap.Error(err))
+			l.mu.Lock()
+			last, ok := l.owned[id]
+			l.mu.Unlock()
+			if !ok || time.Since(last) < l.ttl {
+				continue
+			}
+		}
+
+		l.mu.Lock()
+		_, ok := l.owned[id]
+		delete(l.owned, id)
+		onLost := l.onLost
+		l.mu.Unlock()
+		if !ok {
+			// Released meanwhile.
+			continue
+		}
+
+		if err != nil {
+			l.log.Warn("ownership: lease expirado sem renovação", zap.String("instance_id", id))
+		} else {
+			l.log.Warn("ownership: lease perdido para outra réplica", zap.String("instance_id", id))
+		}
+		if onLost != nil {
+			onLost(id)
+		}
+	}
+}
+
+func (l *Leases) adoptOrphans(ctx context.Context) {
+	l.mu.Lock()
+	candidates := l.candidates
+	onAcquired := l.onAcquired
+	l.mu.Unlock()
+	if candidates == nil {
+		return
+	}
+
+	ids, err := candidates(ctx)
+	if err != nil {
+		l.log.Warn("ownership: erro ao listar candidatas", zap.Error(err))
+		return
+	}
+	rand.Shuffle(len(ids), func(i, j int) { ids[i], ids[j] = ids[j], ids[i] })
+
+	share, ok := l.share(ctx, len(ids))
+	if !ok {
+		return
+	}
+	adopted := 0
+	for _, id := range ids {
+		if adopted >= maxAdoptPerTick || len(l.ownedIDs()) >= share {
+			return
+		}
+		if l.IsLocal(id) {
+			continue
+		}
+		exists, err := l.rdb.Exists(ctx, ownerKey(id)).Result()
+		if err != nil || exists == 1 {
+			continue
+		}
+		ok, err := l.Claim(ctx, id)
+		if err != nil || !ok {
+			continue
+		}
+		adopted++
+		l.log.Info("ownership: instância órfã assumida", zap.String("instance_id", id), zap.String("node_id", l.self.ID))
+		if onAcquired != nil {
+			onAcquired(id)
+		}
+	}
+}
@@ -6,21 +6,45 @@ import (
 	"sync"
 	"time"
 
+	"github.com/google/uuid"
+
 	"github.com/open-apime/apime/internal/pkg/queue"
 )
 
+// DefaultVisibilityTimeout is how long a dequeued event stays reserved
+// before it is handed out again for lack of an Ack.
+const DefaultVisibilityTimeout = 2 * time.Minute
+
+type inflight struct {
+	event    queue.Event
+	deadline time.Time
+}
+
 type MemoryQueue struct {
-	events chan queue.Event
-	mu     sync.RWMutex
-	closed bool
+	events     chan queue.Event
+	mu         sync.RWMutex
+	closed     bool
+	visibility time.Duration
+
+	pendingMu sync.Mutex
+	pending   map[string]inflight // receipt -> reserved event
 }
 
 func NewQueue(bufferSize int) *MemoryQueue {
+	return NewQueueWithVisibility(bufferSize, DefaultVisibilityTimeout)
+}
+
+func NewQueueWithVisibility(bufferSize int, visibility time.Duration) *MemoryQueue {
 	if bufferSize <= 0 {
 		bufferSize = 1000
 	}
+	if visibility <= 0 {
+		visibility = DefaultVisibilityTimeout
+	}
 	return &MemoryQueue{
-		events: make(chan queue.Event, bufferSize),
+		events:     make(chan queue.Event, bufferSize),
+		visibility: visibility,
+		pending:    make(map[string]inflight),
 	}
 }
 
@@ -32,6 +56,7 @@ func (q *MemoryQueue) Enqueue(ctx context.Context, event queue.Event) error {
 		return errors.New("queue is closed")
 	}
 
+	event.Receipt = ""
 	select {
 	case q.events <- event:
 		return nil
@@ -43,12 +68,16 @@ func (q *MemoryQueue) Enqueue(ctx context.Context, event queue.Event) error {
 }
 
 func (q *MemoryQueue) Dequeue(ctx context.Context, timeout time.Duration) (*queue.Event, error) {
+	if event := q.reclaim(); event != nil {
+		return event, nil
+	}
+
 	select {
 	case event, ok := <-q.events:
 		if !ok {
 			return nil, errors.New("queue is closed")
 		}
-		return &event, nil
+		return q.reserve(event), nil
 	case <-time.After(timeout):
 		return nil, nil
 	case <-ctx.Done():
@@ -56,8 +85,55 @@ func (q *MemoryQueue) Dequeue(ctx context.Context, timeout time.Duration) (*queu
 	}
 }
 
+func (q *MemoryQueue) Ack(ctx context.Context, event *queue.Event) error {
+	q.pendingMu.Lock()
+	delete(q.pending, event.Receipt)
+	q.pendingMu.Unlock()
+	return nil
+}
+
+func (q *MemoryQueue) Nack(ctx context.Context, event *queue.Event) error {
+	q.pendingMu.Lock()
+	_, ok := q.pending[event.Receipt]
+	delete(q.pending, event.Receipt)
+	q.pendingMu.Unlock()
+	if !ok {
+		// Already reclaimed by the visibility timeout and handed to someone else.
+		return nil
+	}
+
+	retry := *event
+	retry.Attempts++
+	return q.Enqueue(ctx, retry)
+}
+
+func (q *MemoryQueue) Requeue(ctx context.Context, event *queue.Event) error {
+	q.pendingMu.Lock()
+	_, ok := q.pending[event.Receipt]
+	delete(q.pending, event.Receipt)
+	q.pendingMu.Unlock()
+	if !ok {
+		return nil
+	}
+	return q.Enqueue(ctx, *event)
+}
+
+func (q *MemoryQueue) Extend(ctx context.Context, event *queue.Event) error {
+	q.pendingMu.Lock()
+	defer q.pendingMu.Unlock()
+	if in, ok := q.pending[event.Receipt]; ok {
+		in.deadline = time.Now().Add(q.visibility)
+		q.pending[event.Receipt] = in
+	}
+	return nil
+}
+
+// Size counts waiting and reserved events: both are still owed to a consumer.
 func (q *MemoryQueue) Size(ctx context.Context) (int64, error) {
-	return int64(len(q.events)), nil
+	q.pendingMu.Lock()
+	reserved := len(q.pending)
+	q.pendingMu.Unlock()
+	return int64(len(q.events) + reserved), nil
 }
 
 func (q *MemoryQueue) Close() error {
@@ -70,3 +146,29 @@ func (q *MemoryQueue) Close() error {
 	}
 	return nil
 }
+
+func (q *MemoryQueue) reserve(event queue.Event) *queue.Event {
+	event.Receipt = uuid.NewString()
+	q.pendingMu.Lock()
+	q.pending[event.Receipt] = inflight{event: event, deadline: time.Now().Add(q.visibility)}
+	q.pendingMu.Unlock()
+	return &event
+}
+
+// reclaim hands out again one reservation whose consumer never acked it.
+func (q *MemoryQueue) reclaim() *queue.Event {
+	now := time.Now()
+	q.pendingMu.Lock()
+	defer q.pendingMu.Unlock()
+	for receipt, in := range q.pending {
+		if now.Before(in.deadline) {
+			continue
+		}
+		delete(q.pending, receipt)
+		event := in.event
+		event.Receipt = uuid.NewString()
+		q.pending[event.Receipt] = inflight{event: event, deadline: now.Add(q.visibility)}
+		return &event
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/open-apime/apime/internal/pkg/queue"
+)
+
+// An event dequeued but never acked (consumer crashed) must come back once the
+// visibility timeout passes; an acked one must not.
+func TestMemoryQueueRedeliversUnacked(t *testing.T) {
+	ctx := context.Background()
+	q := NewQueueWithVisibility(10, 30*time.Millisecond)
+
+	_ = q.Enqueue(ctx, queue.Event{ID: "a"})
+	_ = q.Enqueue(ctx, queue.Event{ID: "b"})
+
+	first, _ := q.Dequeue(ctx, time.Second)
+	second, _ := q.Dequeue(ctx, time.Second)
+	if first == nil || second == nil {
+		t.Fatalf("esperava dois eventos")
+	}
+	if err := q.Ack(ctx, second); err != nil {
+		t.Fatalf("ack falhou: %v", err)
+	}
+
+	time.Sleep(40 * time.Millisecond)
+	again, _ := q.Dequeue(ctx, 10*time.Millisecond)
+	if again == nil || again.ID != first.ID {
+		t.Fatalf("esperava reentrega de %q, veio %+v", first.ID, again)
+	}
+	if again.Receipt == first.Receipt {
+		t.Fatalf("reentrega deveria ter um receipt novo")
+	}
+
+	if err := q.Nack(ctx, again); err != nil {
+		t.Fatalf("nack falhou: %v", err)
+	}
+	retry, _ := q.Dequeue(ctx, time.Second)
+	if retry == nil || retry.Attempts != 1 {
+		t.Fatalf("nack deveria devolver o evento com Attempts=1, veio %+v", retry)
+	}
+	_ = q.Ack(ctx, retry)
+
+	if n, _ := q.Size(ctx); n != 0 {
+		t.Fatalf("fila deveria estar vazia, size=%d", n)
+	}
+}
+
+// Extending a reserved event keeps it from being handed out again while the
+// consumer is still working on it.
+func TestMemoryQueueExtendPostponesRedelivery(t *testing.T) {
+	ctx := context.Background()
+	q := NewQueueWithVisibility(10, 40*time.Millisecond)
+
+	_ = q.Enqueue(ctx, queue.Event{ID: "a"})
+	event, _ := q.Dequeue(ctx, time.Second)
+	if event == nil {
+		t.Fatalf("esperava um evento")
+	}
+
+	time.Sleep(25 * time.Millisecond)
+	if err := q.Extend(ctx, event); err != nil {
+		t.Fatalf("extend falhou: %v", err)
+	}
+	time.Sleep(25 * time.Millisecond)
+	if again, _ := q.Dequeue(ctx, 5*time.Millisecond); again != nil {
+		t.Fatalf("evento estendido não deveria ser reentregue, veio %+v", again)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if again, _ := q.Dequeue(ctx, 5*time.Millisecond); again == nil || again.ID != "a" {
+		t.Fatalf("esperava reentrega após o novo prazo, veio %+v", again)
+	}
+}
+
+// Requeue puts the event back without spending one of its attempts.
+func TestMemoryQueueRequeueKeepsAttempts(t *testing.T) {
+	ctx := context.Background()
+	q := NewQueue(10)
+
+	_ = q.Enqueue(ctx, queue.Event{ID: "a", Attempts: 2})
+	event, _ := q.Dequeue(ctx, time.Second)
+	if event == nil {
+		t.Fatalf("esperava um evento")
+	}
+	if err := q.Requeue(ctx, event); err != nil {
+		t.Fatalf("requeue falhou: %v", err)
+	}
+
+	again, _ := q.Dequeue(ctx, time.Second)
+	if again == nil || again.ID != "a" || again.Attempts != 2 {
+		t.Fatalf("esperava o evento de volta com Attempts=2, veio %+v", again)
+	}
+	_ = q.Ack(ctx, again)
+	if n, _ := q.Size(ctx); n != 0 {
+		t.Fatalf("fila deveria estar vazia, size=%d", n)
+	}
+}
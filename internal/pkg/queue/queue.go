@@ -11,11 +11,62 @@ type Event struct {
 	Type       string                 `json:"type"`
 	Payload    map[string]interface{} `json:"payload"`
 	CreatedAt  time.Time              `json:"createdAt"`
+	// Sequence increases by one per event of the same instance, so receivers
+	// can spot gaps. Zero when the queue has no Sequencer.
+	Sequence int64 `json:"sequence,omitempty"`
+	// Attempts counts the previous Nacks of this event.
+	Attempts int `json:"attempts,omitempty"`
+	// Receipt identifies this delivery of the event for Ack/Nack. Set by Dequeue.
+	Receipt string `json:"-"`
 }
 
+// Queue is at-least-once: a dequeued event stays reserved until it is acked.
+// If the consumer dies, the event becomes visible again after the queue's
+// visibility timeout and is redelivered, so consumers must tolerate duplicates.
 type Queue interface {
 	Enqueue(ctx context.Context, event Event) error
 	Dequeue(ctx context.Context, timeout time.Duration) (*Event, error)
+	// Ack confirms the event was handled and removes it for good.
+	Ack(ctx context.Context, event *Event) error
+	// Nack gives the event back for an immediate retry, with Attempts+1.
+	Nack(ctx context.Context, event *Event) error
+	// Requeue gives the event back at the end of the queue without counting an
+	// attempt, for a consumer that cannot take it right now.
+	Requeue(ctx context.Context, event *Event) error
+	// Extend restarts the visibility timeout of an event still being handled,
+	// so a slow consumer is not mistaken for a dead one.
+	Extend(ctx context.Context, event *Event) error
 	Size(ctx context.Context) (int64, error)
 	Close() error
 }
+
+// KeepAliveInterval is how often KeepAlive extends an event. It stays well
+// under the default visibility timeout of both backends (2 minutes).
+const KeepAliveInterval = 30 * time.Second
+
+// KeepAlive extends the event every KeepAliveInterval until stop is called.
+// Consumers whose handling can outlast the visibility timeout (a send waiting
+// for the instance lock, a webhook retrying) wrap the handling with it.
+func KeepAlive(q Queue, event *Event) (stop func()) {
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(KeepAliveInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				_ = q.Extend(ctx, event)
+				cancel()
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-finished
+	}
+}
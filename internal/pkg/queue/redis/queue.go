@@ -5,31 +5,115 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/open-apime/apime/internal/pkg/queue"
 	"github.com/redis/go-redis/v9"
 )
 
+const (
+	// DefaultVisibilityTimeout is how long an entry may stay pending (read but
+	// not acked) before another consumer reclaims it.
+	DefaultVisibilityTimeout = 2 * time.Minute
+	groupName                = "apime"
+	maxLen                   = 100000
+	payloadField             = "data"
+)
+
+// RedisQueue is a Redis Stream consumed through a consumer group shared by all
+// replicas. Entries read with XREADGROUP stay in the group's pending list until
+// acked, so an event popped by a replica that crashes is reclaimed with
+// XAUTOCLAIM after the visibility timeout instead of being lost.
 type RedisQueue struct {
-	client *redis.Client
-	key    string
+	client     *redis.Client
+	key        string
+	legacyKey  string
+	consumer   string
+	visibility time.Duration
+
+	initMu      sync.Mutex
+	ready       bool
+	lastReclaim time.Time
+	reclaimMu   sync.Mutex
 }
 
+// NewQueue keeps the old list name as a parameter: the stream lives at
+// "<key>:stream" and leftovers of the former LPUSH/BRPOP list are moved into it.
 func NewQueue(client *redis.Client, key string) *RedisQueue {
+	return NewQueueWithVisibility(client, key, DefaultVisibilityTimeout)
+}
+
+func NewQueueWithVisibility(client *redis.Client, key string, visibility time.Duration) *RedisQueue {
+	if visibility <= 0 {
+		visibility = DefaultVisibilityTimeout
+	}
+	host, _ := os.Hostname()
 	return &RedisQueue{
-		client: client,
-		key:    key,
+		client:     client,
+		key:        key + ":stream",
+		legacyKey:  key,
+		consumer:   host + "-" + uuid.NewString()[:8],
+		visibility: visibility,
+	}
+}
+
+func (q *RedisQueue) init(ctx context.Context) error {
+	q.initMu.Lock()
+	defer q.initMu.Unlock()
+	if q.ready {
+		return nil
+	}
+
+	err := q.client.XGroupCreateMkStream(ctx, q.key, groupName, "0").Err()
+	if err != nil && !strings.Contains(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("queue init: %w", err)
+	}
+	q.migrateLegacyList(ctx)
+	q.ready = true
+	return nil
+}
+
+// migrateLegacyList drains events left in the pre-stream list by an older
+// version, oldest first, so an upgrade does not drop them.
+func (q *RedisQueue) migrateLegacyList(ctx context.Context) {
+	if t, err := q.client.Type(ctx, q.legacyKey).Result(); err != nil || t != "list" {
+		return
+	}
+	for {
+		data, err := q.client.RPop(ctx, q.legacyKey).Result()
+		if err != nil {
+			return
+		}
+		_ = q.client.XAdd(ctx, &redis.XAddArgs{
+			Stream: q.key,
+			MaxLen: maxLen,
+			Approx: true,
+			Values: map[string]interface{}{payloadField: data},
+		}).Err()
 	}
 }
 
 func (q *RedisQueue) Enqueue(ctx context.Context, event queue.Event) error {
+	if err := q.init(ctx); err != nil {
+		return err
+	}
+
 	data, err := json.Marshal(event)
 	if err != nil {
 		return fmt.Errorf("queue enqueue: marshal: %w", err)
 	}
 
-	if err := q.client.LPush(ctx, q.key, data).Err(); err != nil {
+	err = q.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: q.key,
+		MaxLen: maxLen,
+		Approx: true,
+		Values: map[string]interface{}{payloadField: data},
+	}).Err()
+	if err != nil {
 		return fmt.Errorf("queue enqueue: %w", err)
 	}
 
@@ -37,28 +121,173 @@ func (q *RedisQueue) Enqueue(ctx context.Context, event queue.Event) error {
 }
 
 func (q *RedisQueue) Dequeue(ctx context.Context, timeout time.Duration) (*queue.Event, error) {
-	result, err := q.client.BRPop(ctx, timeout, q.key).Result()
+	if err := q.init(ctx); err != nil {
+		return nil, err
+	}
+
+	if event, err := q.reclaim(ctx); event != nil || err != nil {
+		return event, err
+	}
+
+	streams, err := q.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    groupName,
+		Consumer: q.consumer,
+		Streams:  []string{q.key, ">"},
+		Count:    1,
+		Block:    timeout,
+	}).Result()
 	if err != nil {
-		if err == redis.Nil {
+		if errors.Is(err, redis.Nil) {
 			return nil, nil
 		}
 		return nil, fmt.Errorf("queue dequeue: %w", err)
 	}
 
-	if len(result) < 2 {
-		return nil, errors.New("queue dequeue: invalid result")
+	for _, stream := range streams {
+		for _, msg := range stream.Messages {
+			return q.decode(ctx, msg)
+		}
+	}
+	return nil, nil
+}
+
+// reclaim takes over one entry left pending by a dead consumer. It only hits
+// Redis every visibility/4, unless the previous call found something.
+func (q *RedisQueue) reclaim(ctx context.Context) (*queue.Event, error) {
+	q.reclaimMu.Lock()
+	if time.Since(q.lastReclaim) < q.visibility/4 {
+		q.reclaimMu.Unlock()
+		return nil, nil
+	}
+	q.reclaimMu.Unlock()
+
+	msgs, _, err := q.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+		Stream:   q.key,
+		Group:    groupName,
+		Consumer: q.consumer,
+		MinIdle:  q.visibility,
+		Start:    "0-0",
+		Count:    1,
+	}).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("queue reclaim: %w", err)
+	}
+
+	if len(msgs) == 0 {
+		q.reclaimMu.Lock()
+		q.lastReclaim = time.Now()
+		q.reclaimMu.Unlock()
+		return nil, nil
 	}
+	return q.decode(ctx, msgs[0])
+}
+
+func (q *RedisQueue) decode(ctx context.Context, msg redis.XMessage) (*queue.Event, error) {
+	raw, _ := msg.Values[payloadField].(string)
 
 	var event queue.Event
-	if err := json.Unmarshal([]byte(result[1]), &event); err != nil {
+	if err := json.Unmarshal([]byte(raw), &event); err != nil {
+		// A poison entry would be reclaimed forever; drop it.
+		_ = q.remove(ctx, msg.ID)
 		return nil, fmt.Errorf("queue dequeue: unmarshal: %w", err)
 	}
-
+	event.Receipt = msg.ID
 	return &event, nil
 }
 
+func (q *RedisQueue) Ack(ctx context.Context, event *queue.Event) error {
+	if event.Receipt == "" {
+		return nil
+	}
+	if err := q.remove(ctx, event.Receipt); err != nil {
+		return fmt.Errorf("queue ack: %w", err)
+	}
+	return nil
+}
+
+// Nack appends a copy with Attempts+1 and acks the original, in one
+// transaction so the event is never lost nor duplicated by the retry itself.
+func (q *RedisQueue) Nack(ctx context.Context, event *queue.Event) error {
+	retry := *event
+	retry.Attempts++
+	if err := q.reappend(ctx, event.Receipt, retry); err != nil {
+		return fmt.Errorf("queue nack: %w", err)
+	}
+	return nil
+}
+
+func (q *RedisQueue) Requeue(ctx context.Context, event *queue.Event) error {
+	if err := q.reappend(ctx, event.Receipt, *event); err != nil {
+		return fmt.Errorf("queue requeue: %w", err)
+	}
+	return nil
+}
+
+func (q *RedisQueue) reappend(ctx context.Context, receipt string, event queue.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	_, err = q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.XAdd(ctx, &redis.XAddArgs{
+			Stream: q.key,
+			MaxLen: maxLen,
+			Approx: true,
+			Values: map[string]interface{}{payloadField: data},
+		})
+		if receipt != "" {
+			pipe.XAck(ctx, q.key, groupName, receipt)
+			pipe.XDel(ctx, q.key, receipt)
+		}
+		return nil
+	})
+	return err
+}
+
+// extendScript re-claims an entry only while it is still pending for the
+// consumer: one reclaimed by another replica after the visibility timeout
+// stays with that replica instead of being taken back.
+var extendScript = redis.NewScript(`
+local pending = redis.call("XPENDING", KEYS[1], ARGV[1], ARGV[2], ARGV[2], 1, ARGV[3])
+if #pending == 0 then
+    return 0
+end
+redis.call("XCLAIM", KEYS[1], ARGV[1], ARGV[3], 0, ARGV[2], "JUSTID")
+return 1
+`)
+
+// Extend re-claims the entry for the same consumer, which resets its idle
+// time in the pending list and keeps XAUTOCLAIM away from it. An entry this
+// consumer no longer holds (acked, or reclaimed elsewhere) is left alone.
+func (q *RedisQueue) Extend(ctx context.Context, event *queue.Event) error {
+	if event.Receipt == "" {
+		return nil
+	}
+	err := extendScript.Run(ctx, q.client, []string{q.key}, groupName, event.Receipt, q.consumer).Err()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return fmt.Errorf("queue extend: %w", err)
+	}
+	return nil
+}
+
+func (q *RedisQueue) remove(ctx context.Context, id string) error {
+	_, err := q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.XAck(ctx, q.key, groupName, id)
+		pipe.XDel(ctx, q.key, id)
+		return nil
+	})
+	return err
+}
+
+// Size is the stream length: acked entries are deleted, so it counts waiting
+// plus pending events.
 func (q *RedisQueue) Size(ctx context.Context) (int64, error) {
-	return q.client.LLen(ctx, q.key).Result()
+	n, err := q.client.XLen(ctx, q.key).Result()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return n, err
 }
 
 func (q *RedisQueue) Close() error {
@@ -0,0 +1,26 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Sequencer keeps one INCR counter per instance, shared by every replica.
+type Sequencer struct {
+	client *redis.Client
+	prefix string
+}
+
+func NewSequencer(client *redis.Client, prefix string) *Sequencer {
+	return &Sequencer{client: client, prefix: prefix}
+}
+
+func (s *Sequencer) Next(ctx context.Context, instanceID string) (int64, error) {
+	n, err := s.client.Incr(ctx, s.prefix+instanceID).Result()
+	if err != nil {
+		return 0, fmt.Errorf("queue sequence: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,48 @@
+package queue
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// Sequencer hands out the next sequence number of an instance.
+type Sequencer interface {
+	Next(ctx context.Context, instanceID string) (int64, error)
+}
+
+// MemorySequencer counts per process; numbers restart at 1 after a restart.
+type MemorySequencer struct {
+	counters sync.Map // instanceID -> *int64
+}
+
+func NewMemorySequencer() *MemorySequencer {
+	return &MemorySequencer{}
+}
+
+func (s *MemorySequencer) Next(ctx context.Context, instanceID string) (int64, error) {
+	v, _ := s.counters.LoadOrStore(instanceID, new(int64))
+	return atomic.AddInt64(v.(*int64), 1), nil
+}
+
+// SequencedQueue stamps Event.Sequence on the way in and delegates everything
+// else to the wrapped queue.
+type SequencedQueue struct {
+	Queue
+	seq Sequencer
+}
+
+func Sequenced(q Queue, seq Sequencer) *SequencedQueue {
+	return &SequencedQueue{Queue: q, seq: seq}
+}
+
+func (q *SequencedQueue) Enqueue(ctx context.Context, event Event) error {
+	if event.Sequence == 0 && event.InstanceID != "" {
+		n, err := q.seq.Next(ctx, event.InstanceID)
+		if err != nil {
+			return err
+		}
+		event.Sequence = n
+	}
+	return q.Queue.Enqueue(ctx, event)
+}
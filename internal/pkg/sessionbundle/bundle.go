@@ -0,0 +1,113 @@
+// Package sessionbundle moves a paired WhatsApp device between deployments: it dumps the
+// device's rows from the whatsmeow store, packs them with the instance settings into an
+// encrypted bundle and loads them into another store, on either driver.
+package sessionbundle
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/open-apime/apime/internal/pkg/crypto"
+)
+
+const (
+	// Version is bumped when the bundle layout changes incompatibly.
+	Version = 1
+
+	magic = "APIME-SESSION-1\n"
+
+	// MinPassphraseLength keeps the bundle from being sealed with a guessable secret: it carries
+	// the device identity keys, enough to impersonate the number.
+	MinPassphraseLength = 12
+)
+
+var (
+	ErrInvalidBundle      = errors.New("pacote de sessão inválido ou senha incorreta")
+	ErrWeakPassphrase     = fmt.Errorf("a senha do pacote deve ter ao menos %d caracteres", MinPassphraseLength)
+	ErrUnsupportedVersion = errors.New("versão de pacote de sessão não suportada")
+	// ErrDeviceExists is returned on import when the target store already holds a paired device
+	// for the instance, or the bundle's device itself.
+	ErrDeviceExists = errors.New("o store de destino já tem um dispositivo pareado para esta instância")
+)
+
+// Instance is the part of the instance row that travels with the device. The token hash goes
+// along so integrations keep using the same instance token after the move.
+type Instance struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	WebhookURL    string `json:"webhookUrl,omitempty"`
+	WebhookSecret string `json:"webhookSecret,omitempty"`
+	TokenHash     string `json:"tokenHash,omitempty"`
+}
+
+// Bundle is the decrypted content of an export.
+type Bundle struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exportedAt"`
+	// Handover is true when the source dropped the device on export. A bundle taken while the
+	// device stayed connected at the source must not be imported: two deployments on the same
+	// device make WhatsApp disconnect both.
+	Handover  bool     `json:"handover"`
+	Instance  Instance `json:"instance"`
+	DeviceJID string   `json:"deviceJid"`
+	Tables    []Table  `json:"tables"`
+}
+
+// Seal serializes, compresses and encrypts the bundle with the passphrase.
+func Seal(b Bundle, passphrase string) ([]byte, error) {
+	if len(passphrase) < MinPassphraseLength {
+		return nil, ErrWeakPassphrase
+	}
+	b.Version = Version
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(b); err != nil {
+		return nil, fmt.Errorf("sessionbundle: encode: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("sessionbundle: compress: %w", err)
+	}
+
+	encrypted, err := crypto.Encrypt(buf.Bytes(), passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("sessionbundle: %w", err)
+	}
+	return append([]byte(magic), encrypted...), nil
+}
+
+// Open reverses Seal.
+func Open(data []byte, passphrase string) (Bundle, error) {
+	if !bytes.HasPrefix(data, []byte(magic)) {
+		return Bundle{}, ErrInvalidBundle
+	}
+	plain, err := crypto.Decrypt(data[len(magic):], passphrase)
+	if err != nil {
+		return Bundle{}, ErrInvalidBundle
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(plain))
+	if err != nil {
+		return Bundle{}, ErrInvalidBundle
+	}
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		return Bundle{}, ErrInvalidBundle
+	}
+
+	var b Bundle
+	if err := json.Unmarshal(raw, &b); err != nil {
+		return Bundle{}, ErrInvalidBundle
+	}
+	if b.Version != Version {
+		return Bundle{}, ErrUnsupportedVersion
+	}
+	if b.DeviceJID == "" || b.Instance.ID == "" {
+		return Bundle{}, ErrInvalidBundle
+	}
+	return b, nil
+}
@@ -0,0 +1,121 @@
+package sessionbundle
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// A trimmed whatsmeow schema: enough to cover a device-keyed table, a "jid"-keyed one with a
+// boolean, the shared LID map and the cascade from whatsmeow_device.
+const testSchema = `
+CREATE TABLE whatsmeow_device (jid TEXT PRIMARY KEY, registration_id BIGINT NOT NULL, noise_key bytea NOT NULL, push_name TEXT NOT NULL DEFAULT '');
+CREATE TABLE whatsmeow_pre_keys (jid TEXT REFERENCES whatsmeow_device(jid) ON DELETE CASCADE, key_id INTEGER, key bytea NOT NULL, uploaded BOOLEAN NOT NULL, PRIMARY KEY (jid, key_id));
+CREATE TABLE whatsmeow_sessions (our_jid TEXT REFERENCES whatsmeow_device(jid) ON DELETE CASCADE, their_id TEXT, session bytea, PRIMARY KEY (our_jid, their_id));
+CREATE TABLE whatsmeow_lid_map (lid TEXT PRIMARY KEY, pn TEXT UNIQUE NOT NULL);
+`
+
+func openStore(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", "file::memory:?_foreign_keys=on")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec(testSchema); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestDumpSealLoadRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	const device = "5511999999999:12@s.whatsapp.net"
+
+	src := openStore(t)
+	_, err := src.Exec(`
+		INSERT INTO whatsmeow_device VALUES ('` + device + `', 42, x'0102', 'Loja'), ('5511888888888:3@s.whatsapp.net', 7, x'09', '');
+		INSERT INTO whatsmeow_pre_keys VALUES ('` + device + `', 1, x'aa', 1), ('5511888888888:3@s.whatsapp.net', 1, x'bb', 0);
+		INSERT INTO whatsmeow_sessions VALUES ('` + device + `', '5511777777777.0', x'cc');
+		INSERT INTO whatsmeow_lid_map VALUES ('123@lid', '5511777777777');
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tables, err := Dump(ctx, src, DialectSQLite, device)
+	if err != nil {
+		t.Fatalf("dump: %v", err)
+	}
+	sealed, err := Seal(Bundle{Instance: Instance{ID: "inst-1", Name: "Loja"}, DeviceJID: device, Handover: true, Tables: tables}, "senha-bem-longa")
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+	if _, err := Open(sealed, "outra-senha-longa"); !errors.Is(err, ErrInvalidBundle) {
+		t.Fatalf("senha errada deveria falhar com ErrInvalidBundle, veio %v", err)
+	}
+	bundle, err := Open(sealed, "senha-bem-longa")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	dst := openStore(t)
+	// Stale rows of the same device in the destination are replaced, not merged.
+	if _, err := dst.Exec(`INSERT INTO whatsmeow_device VALUES ('` + device + `', 1, x'00', 'antigo'); INSERT INTO whatsmeow_sessions VALUES ('` + device + `', 'velho.0', x'00');`); err != nil {
+		t.Fatal(err)
+	}
+	tx, err := dst.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Load(ctx, tx, DialectSQLite, bundle.DeviceJID, bundle.Tables); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	var regID int64
+	var noise []byte
+	var push string
+	if err := dst.QueryRow(`SELECT registration_id, noise_key, push_name FROM whatsmeow_device WHERE jid = ?`, device).Scan(&regID, &noise, &push); err != nil {
+		t.Fatalf("device não importado: %v", err)
+	}
+	if regID != 42 || !bytes.Equal(noise, []byte{1, 2}) || push != "Loja" {
+		t.Fatalf("device importado diferente: %d %x %q", regID, noise, push)
+	}
+	var uploaded bool
+	if err := dst.QueryRow(`SELECT uploaded FROM whatsmeow_pre_keys WHERE jid = ? AND key_id = 1`, device).Scan(&uploaded); err != nil || !uploaded {
+		t.Fatalf("pre-key não importada: %v %v", uploaded, err)
+	}
+	var sessions, devices, lids int
+	_ = dst.QueryRow(`SELECT COUNT(*) FROM whatsmeow_sessions`).Scan(&sessions)
+	_ = dst.QueryRow(`SELECT COUNT(*) FROM whatsmeow_device`).Scan(&devices)
+	_ = dst.QueryRow(`SELECT COUNT(*) FROM whatsmeow_lid_map`).Scan(&lids)
+	if sessions != 1 || devices != 1 || lids != 1 {
+		t.Fatalf("esperava 1 sessão, 1 device e 1 LID, veio %d %d %d", sessions, devices, lids)
+	}
+}
+
+func TestLoadRejectsRowsOfAnotherDevice(t *testing.T) {
+	ctx := context.Background()
+	dst := openStore(t)
+	tables := []Table{{
+		Name:    "whatsmeow_device",
+		Columns: []string{"jid", "registration_id", "noise_key"},
+		Rows:    [][]Value{{{Kind: "string", Str: "outro@s.whatsapp.net"}, {Kind: "int", Int: 1}, {Kind: "bytes", Bytes: []byte{1}}}},
+	}}
+	tx, err := dst.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if err := Load(ctx, tx, DialectSQLite, "meu@s.whatsapp.net", tables); err == nil {
+		t.Fatal("linhas de outro dispositivo deveriam ser recusadas")
+	}
+}
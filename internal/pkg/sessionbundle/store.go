@@ -0,0 +1,301 @@
+package sessionbundle
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Dialects accepted by Dump and Load.
+const (
+	DialectSQLite   = "sqlite3"
+	DialectPostgres = "postgres"
+)
+
+// deviceTables are the whatsmeow store tables, in foreign-key order, with the column holding
+// the device JID. The schema is the same on both drivers, so rows move as they are. Tables with
+// an empty key are shared by every device of the store (the LID ↔ phone map) and are merged
+// instead of replaced. Tables a given whatsmeow version doesn't have are skipped on export.
+var deviceTables = []struct {
+	name string
+	key  string
+}{
+	{"whatsmeow_device", "jid"},
+	{"whatsmeow_identity_keys", "our_jid"},
+	{"whatsmeow_pre_keys", "jid"},
+	{"whatsmeow_sessions", "our_jid"},
+	{"whatsmeow_sender_keys", "our_jid"},
+	{"whatsmeow_app_state_sync_keys", "jid"},
+	{"whatsmeow_app_state_version", "jid"},
+	{"whatsmeow_app_state_mutation_macs", "jid"},
+	{"whatsmeow_contacts", "our_jid"},
+	{"whatsmeow_chat_settings", "our_jid"},
+	{"whatsmeow_message_secrets", "our_jid"},
+	{"whatsmeow_privacy_tokens", "our_jid"},
+	{"whatsmeow_event_buffer", "our_jid"},
+	{"whatsmeow_retry_buffer", "our_jid"},
+	{"whatsmeow_lid_map", ""},
+}
+
+var identifier = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// Table holds the rows of one store table.
+type Table struct {
+	Name    string    `json:"name"`
+	Columns []string  `json:"columns"`
+	Rows    [][]Value `json:"rows"`
+}
+
+// Value is a typed cell. JSON alone would turn bytes and timestamps into strings and lose which
+// one they were, and the drivers don't scan the same types back (pq returns TEXT as []byte).
+type Value struct {
+	Kind  string     `json:"k"`
+	Bytes []byte     `json:"b,omitempty"`
+	Str   string     `json:"s,omitempty"`
+	Int   int64      `json:"i,omitempty"`
+	Float float64    `json:"f,omitempty"`
+	Bool  bool       `json:"t,omitempty"`
+	Time  *time.Time `json:"d,omitempty"`
+}
+
+func valueOf(v any, dbType string) Value {
+	switch x := v.(type) {
+	case nil:
+		return Value{Kind: "null"}
+	case []byte:
+		if isTextType(dbType) {
+			return Value{Kind: "string", Str: string(x)}
+		}
+		return Value{Kind: "bytes", Bytes: append([]byte(nil), x...)}
+	case string:
+		return Value{Kind: "string", Str: x}
+	case int64:
+		return Value{Kind: "int", Int: x}
+	case float64:
+		return Value{Kind: "float", Float: x}
+	case bool:
+		return Value{Kind: "bool", Bool: x}
+	case time.Time:
+		return Value{Kind: "time", Time: &x}
+	default:
+		return Value{Kind: "string", Str: fmt.Sprint(x)}
+	}
+}
+
+func (v Value) arg() any {
+	switch v.Kind {
+	case "bytes":
+		if v.Bytes == nil {
+			return []byte{}
+		}
+		return v.Bytes
+	case "string":
+		return v.Str
+	case "int":
+		return v.Int
+	case "float":
+		return v.Float
+	case "bool":
+		return v.Bool
+	case "time":
+		if v.Time == nil {
+			return nil
+		}
+		return *v.Time
+	default:
+		return nil
+	}
+}
+
+func isTextType(dbType string) bool {
+	t := strings.ToUpper(dbType)
+	return strings.Contains(t, "TEXT") || strings.Contains(t, "CHAR") || t == "UUID"
+}
+
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func tableExists(ctx context.Context, q querier, dialect, name string) (bool, error) {
+	var exists bool
+	var err error
+	if dialect == DialectPostgres {
+		err = q.QueryRowContext(ctx, `SELECT to_regclass($1) IS NOT NULL`, name).Scan(&exists)
+	} else {
+		var n int
+		err = q.QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, name).Scan(&n)
+		exists = n > 0
+	}
+	return exists, err
+}
+
+func placeholder(dialect string, n int) string {
+	if dialect == DialectPostgres {
+		return "$" + strconv.Itoa(n)
+	}
+	return "?"
+}
+
+// Dump reads every row of the device deviceJID from the whatsmeow store in db.
+func Dump(ctx context.Context, db *sql.DB, dialect, deviceJID string) ([]Table, error) {
+	var tables []Table
+	for _, t := range deviceTables {
+		exists, err := tableExists(ctx, db, dialect, t.name)
+		if err != nil {
+			return nil, fmt.Errorf("sessionbundle: verificar %s: %w", t.name, err)
+		}
+		if !exists {
+			continue
+		}
+
+		query := "SELECT * FROM " + t.name
+		var args []any
+		if t.key != "" {
+			query += " WHERE " + t.key + " = " + placeholder(dialect, 1)
+			args = append(args, deviceJID)
+		}
+		table, err := dumpTable(ctx, db, t.name, query, args...)
+		if err != nil {
+			return nil, err
+		}
+		if t.name == "whatsmeow_device" && len(table.Rows) == 0 {
+			return nil, fmt.Errorf("sessionbundle: dispositivo %s não encontrado no store", deviceJID)
+		}
+		tables = append(tables, table)
+	}
+	return tables, nil
+}
+
+func dumpTable(ctx context.Context, db querier, name, query string, args ...any) (Table, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return Table{}, fmt.Errorf("sessionbundle: ler %s: %w", name, err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return Table{}, fmt.Errorf("sessionbundle: colunas de %s: %w", name, err)
+	}
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return Table{}, fmt.Errorf("sessionbundle: tipos de %s: %w", name, err)
+	}
+
+	table := Table{Name: name, Columns: columns}
+	for rows.Next() {
+		cells := make([]any, len(columns))
+		ptrs := make([]any, len(columns))
+		for i := range cells {
+			ptrs[i] = &cells[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return Table{}, fmt.Errorf("sessionbundle: ler linha de %s: %w", name, err)
+		}
+		row := make([]Value, len(columns))
+		for i, cell := range cells {
+			row[i] = valueOf(cell, types[i].DatabaseTypeName())
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return table, rows.Err()
+}
+
+// Load writes the tables of a bundle into the whatsmeow store behind tx, replacing whatever the
+// store had for deviceJID. Table and column names come from a file, so they are checked against
+// the known tables and every device row must belong to deviceJID.
+func Load(ctx context.Context, tx *sql.Tx, dialect, deviceJID string, tables []Table) error {
+	byName := make(map[string]Table, len(tables))
+	for _, t := range tables {
+		byName[t.Name] = t
+	}
+	if _, ok := byName["whatsmeow_device"]; !ok {
+		return fmt.Errorf("sessionbundle: pacote sem whatsmeow_device")
+	}
+
+	for i := len(deviceTables) - 1; i >= 0; i-- {
+		t := deviceTables[i]
+		if t.key == "" {
+			continue
+		}
+		if _, ok := byName[t.name]; !ok {
+			continue
+		}
+		query := "DELETE FROM " + t.name + " WHERE " + t.key + " = " + placeholder(dialect, 1)
+		if _, err := tx.ExecContext(ctx, query, deviceJID); err != nil {
+			return fmt.Errorf("sessionbundle: limpar %s: %w", t.name, err)
+		}
+	}
+
+	known := 0
+	for _, t := range deviceTables {
+		table, ok := byName[t.name]
+		if !ok {
+			continue
+		}
+		known++
+		exists, err := tableExists(ctx, tx, dialect, t.name)
+		if err != nil {
+			return fmt.Errorf("sessionbundle: verificar %s: %w", t.name, err)
+		}
+		if !exists {
+			return fmt.Errorf("sessionbundle: tabela %s não existe no destino (versão do whatsmeow diferente?)", t.name)
+		}
+		if err := loadTable(ctx, tx, dialect, deviceJID, t.key, table); err != nil {
+			return err
+		}
+	}
+	if known != len(tables) {
+		return fmt.Errorf("sessionbundle: pacote contém tabelas desconhecidas")
+	}
+	return nil
+}
+
+func loadTable(ctx context.Context, tx *sql.Tx, dialect, deviceJID, key string, table Table) error {
+	keyIndex := -1
+	marks := make([]string, len(table.Columns))
+	for i, col := range table.Columns {
+		if !identifier.MatchString(col) {
+			return fmt.Errorf("sessionbundle: coluna inválida %q em %s", col, table.Name)
+		}
+		if col == key {
+			keyIndex = i
+		}
+		marks[i] = placeholder(dialect, i+1)
+	}
+	if key != "" && keyIndex < 0 {
+		return fmt.Errorf("sessionbundle: %s sem a coluna %s", table.Name, key)
+	}
+
+	query := "INSERT INTO " + table.Name + " (" + strings.Join(table.Columns, ", ") + ") VALUES (" + strings.Join(marks, ", ") + ")"
+	if key == "" {
+		query += " ON CONFLICT DO NOTHING"
+	}
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("sessionbundle: preparar %s: %w", table.Name, err)
+	}
+	defer stmt.Close()
+
+	for _, row := range table.Rows {
+		if len(row) != len(table.Columns) {
+			return fmt.Errorf("sessionbundle: linha com %d colunas em %s, esperado %d", len(row), table.Name, len(table.Columns))
+		}
+		if keyIndex >= 0 && (row[keyIndex].Kind != "string" || row[keyIndex].Str != deviceJID) {
+			return fmt.Errorf("sessionbundle: linha de outro dispositivo em %s", table.Name)
+		}
+		args := make([]any, len(row))
+		for i, v := range row {
+			args[i] = v.arg()
+		}
+		if _, err := stmt.ExecContext(ctx, args...); err != nil {
+			return fmt.Errorf("sessionbundle: gravar %s: %w", table.Name, err)
+		}
+	}
+	return nil
+}
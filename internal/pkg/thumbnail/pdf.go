@@ -0,0 +1,76 @@
+package thumbnail
+
+import (
+	"bytes"
+	"errors"
+	"image/jpeg"
+	"io"
+	"regexp"
+	"strconv"
+)
+
+// pdfScanLimit is how much of a PDF is read looking for the preview. The images of the first page
+// are usually written near the start.
+const pdfScanLimit = 8 << 20
+
+var ErrNoPreview = errors.New("thumbnail: PDF sem imagem para pré-visualização")
+
+var (
+	pdfObject = regexp.MustCompile(`\d+\s+\d+\s+obj\b`)
+	pdfImage  = regexp.MustCompile(`/Subtype\s*/Image\b`)
+	pdfDCT    = regexp.MustCompile(`/DCTDecode\b`)
+	pdfLength = regexp.MustCompile(`/Length\s+(\d+)(\s+\d+\s+R)?`)
+	pdfStream = regexp.MustCompile(`stream\r?\n`)
+)
+
+// FromPDF returns the thumbnail of the first JPEG image of a PDF. Rendering a page needs a PDF
+// renderer, which isn't available in pure Go; a JPEG image covers the common case of scanned
+// documents and photos saved as PDF. Text-only PDFs return ErrNoPreview.
+func FromPDF(r io.Reader) (Thumbnail, error) {
+	data, err := io.ReadAll(io.LimitReader(r, pdfScanLimit))
+	if err != nil {
+		return Thumbnail{}, err
+	}
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		return Thumbnail{}, ErrNoPreview
+	}
+
+	objects := pdfObject.FindAllIndex(data, -1)
+	for i, loc := range objects {
+		end := len(data)
+		if i+1 < len(objects) {
+			end = objects[i+1][0]
+		}
+		obj := data[loc[1]:end]
+
+		start := pdfStream.FindIndex(obj)
+		if start == nil {
+			continue
+		}
+		dict := obj[:start[0]]
+		if !pdfImage.Match(dict) || !pdfDCT.Match(dict) {
+			continue
+		}
+		stream := obj[start[1]:]
+		// A direct /Length is exact; an indirect one (or none) falls back to endstream.
+		if m := pdfLength.FindSubmatch(dict); m != nil && m[2] == nil {
+			if n, err := strconv.Atoi(string(m[1])); err == nil && n <= len(stream) {
+				stream = stream[:n]
+			}
+		} else if j := bytes.LastIndex(stream, []byte("endstream")); j >= 0 {
+			stream = bytes.TrimRight(stream[:j], "\r\n")
+		}
+
+		// Icons and logos aren't a preview of the page.
+		cfg, err := jpeg.DecodeConfig(bytes.NewReader(stream))
+		if err != nil || cfg.Width < 64 || cfg.Height < 64 || cfg.Width*cfg.Height > maxPixels {
+			continue
+		}
+		img, err := jpeg.Decode(bytes.NewReader(stream))
+		if err != nil {
+			continue
+		}
+		return fromDecoded(img, MaxSide)
+	}
+	return Thumbnail{}, ErrNoPreview
+}
@@ -0,0 +1,145 @@
+// Package thumbnail builds the small JPEG preview (JPEGThumbnail) the WhatsApp clients show while
+// the media is downloading. Only the standard library decoders are used: JPEG, PNG and GIF.
+package thumbnail
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+
+	// Registered for image.Decode.
+	_ "image/gif"
+	_ "image/png"
+)
+
+// MaxSide is the longest side of a thumbnail. The clients blur and stretch it; a bigger one only
+// makes the message heavier.
+const MaxSide = 100
+
+const quality = 60
+
+// maxPixels keeps a huge image from being decoded in full just for its preview.
+const maxPixels = 40_000_000
+
+var ErrTooLarge = errors.New("thumbnail: imagem grande demais para gerar miniatura")
+
+// Thumbnail is the JPEG preview of an image. Width and Height are the ones of the source image.
+type Thumbnail struct {
+	JPEG        []byte
+	Width       int
+	Height      int
+	ThumbWidth  int
+	ThumbHeight int
+}
+
+// FromImage decodes r and returns its thumbnail.
+func FromImage(r io.Reader) (Thumbnail, error) {
+	return FromImageMax(r, MaxSide)
+}
+
+// FromImageMax is FromImage with another longest side, for the previews that are uploaded instead
+// of inlined in the message (link previews), where the clients show a bigger image.
+func FromImageMax(r io.Reader, maxSide int) (Thumbnail, error) {
+	var buf bytes.Buffer
+	cfg, _, err := image.DecodeConfig(io.TeeReader(r, &buf))
+	if err != nil {
+		return Thumbnail{}, fmt.Errorf("thumbnail: %w", err)
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return Thumbnail{}, ErrTooLarge
+	}
+	img, _, err := image.Decode(io.MultiReader(&buf, r))
+	if err != nil {
+		return Thumbnail{}, fmt.Errorf("thumbnail: %w", err)
+	}
+	return fromDecoded(img, maxSide)
+}
+
+// SquareJPEG crops r to the centered square of its shortest side and scales it down to at most
+// side, the shape WhatsApp expects for a profile picture: it'd otherwise crop it on its own.
+func SquareJPEG(r io.Reader, side int) (Thumbnail, error) {
+	var buf bytes.Buffer
+	cfg, _, err := image.DecodeConfig(io.TeeReader(r, &buf))
+	if err != nil {
+		return Thumbnail{}, fmt.Errorf("thumbnail: %w", err)
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return Thumbnail{}, ErrTooLarge
+	}
+	img, _, err := image.Decode(io.MultiReader(&buf, r))
+	if err != nil {
+		return Thumbnail{}, fmt.Errorf("thumbnail: %w", err)
+	}
+	b := img.Bounds()
+	n := min(b.Dx(), b.Dy())
+	x0, y0 := b.Min.X+(b.Dx()-n)/2, b.Min.Y+(b.Dy()-n)/2
+	// Every decoder of the standard library returns a type with SubImage.
+	if sub, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		img = sub.SubImage(image.Rect(x0, y0, x0+n, y0+n))
+	}
+	return fromDecoded(img, side)
+}
+
+func fromDecoded(img image.Image, maxSide int) (Thumbnail, error) {
+	b := img.Bounds()
+	small := resize(img, maxSide)
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, small, &jpeg.Options{Quality: quality}); err != nil {
+		return Thumbnail{}, fmt.Errorf("thumbnail: %w", err)
+	}
+	return Thumbnail{
+		JPEG:        out.Bytes(),
+		Width:       b.Dx(),
+		Height:      b.Dy(),
+		ThumbWidth:  small.Bounds().Dx(),
+		ThumbHeight: small.Bounds().Dy(),
+	}, nil
+}
+
+// resize scales img down so its longest side is at most maxSide, averaging the source pixels that
+// fall in each target pixel (a box filter: enough for a preview, and no aliasing on big
+// downscales). Transparent areas are flattened over white, since JPEG has no alpha.
+func resize(img image.Image, maxSide int) *image.RGBA {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	tw, th := w, h
+	if w >= h && w > maxSide {
+		tw, th = maxSide, max(1, h*maxSide/w)
+	} else if h > w && h > maxSide {
+		tw, th = max(1, w*maxSide/h), maxSide
+	}
+
+	out := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for ty := 0; ty < th; ty++ {
+		y0, y1 := b.Min.Y+ty*h/th, b.Min.Y+max((ty+1)*h/th, ty*h/th+1)
+		for tx := 0; tx < tw; tx++ {
+			x0, x1 := b.Min.X+tx*w/tw, b.Min.X+max((tx+1)*w/tw, tx*w/tw+1)
+			var r, g, bl, n uint64
+			// At most 8×8 samples per target pixel, so a photo doesn't cost a read of every pixel.
+			for y := y0; y < y1; y += max(1, (y1-y0)/8) {
+				for x := x0; x < x1; x += max(1, (x1-x0)/8) {
+					cr, cg, cb, ca := img.At(x, y).RGBA()
+					// Premultiplied: adding the missing alpha as white puts the pixel over white.
+					white := 0xFFFF - uint64(ca)
+					r += uint64(cr) + white
+					g += uint64(cg) + white
+					bl += uint64(cb) + white
+					n++
+				}
+			}
+			out.SetRGBA(tx, ty, color.RGBA{
+				R: uint8(r / n >> 8),
+				G: uint8(g / n >> 8),
+				B: uint8(bl / n >> 8),
+				A: 0xFF,
+			})
+		}
+	}
+	return out
+}
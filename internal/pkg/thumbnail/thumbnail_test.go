@@ -0,0 +1,115 @@
+package thumbnail
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func TestFromImage(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 400, 200))
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 400; x++ {
+			if x < 200 {
+				src.Set(x, y, color.NRGBA{R: 255, A: 255})
+			} // the right half stays transparent
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+
+	thumb, err := FromImage(&buf)
+	if err != nil {
+		t.Fatalf("FromImage: %v", err)
+	}
+	if thumb.Width != 400 || thumb.Height != 200 || thumb.ThumbWidth != MaxSide || thumb.ThumbHeight != MaxSide/2 {
+		t.Fatalf("dimensões inesperadas: %+v", thumb)
+	}
+	img, err := jpeg.Decode(bytes.NewReader(thumb.JPEG))
+	if err != nil {
+		t.Fatalf("miniatura não é JPEG: %v", err)
+	}
+	if r, g, _, _ := img.At(10, 25).RGBA(); r>>8 < 200 || g>>8 > 60 {
+		t.Errorf("metade vermelha perdida: r=%d g=%d", r>>8, g>>8)
+	}
+	if r, g, b, _ := img.At(90, 25).RGBA(); r>>8 < 200 || g>>8 < 200 || b>>8 < 200 {
+		t.Errorf("transparência deveria virar branco: %d %d %d", r>>8, g>>8, b>>8)
+	}
+}
+
+func TestFromImageSmall(t *testing.T) {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewGray(image.Rect(0, 0, 40, 30)))
+	thumb, err := FromImage(&buf)
+	if err != nil {
+		t.Fatalf("FromImage: %v", err)
+	}
+	if thumb.ThumbWidth != 40 || thumb.ThumbHeight != 30 {
+		t.Fatalf("imagem pequena não deveria ser ampliada: %dx%d", thumb.ThumbWidth, thumb.ThumbHeight)
+	}
+}
+
+func TestFromPDF(t *testing.T) {
+	var photo bytes.Buffer
+	jpeg.Encode(&photo, image.NewGray(image.Rect(0, 0, 300, 400)), nil)
+
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	fmt.Fprintf(&pdf, "4 0 obj\n<< /Type /XObject /Subtype /Image /Width 300 /Height 400 /ColorSpace /DeviceGray /BitsPerComponent 8 /Filter /DCTDecode /Length %d >>\nstream\n", photo.Len())
+	pdf.Write(photo.Bytes())
+	pdf.WriteString("\nendstream\nendobj\n%%EOF\n")
+
+	thumb, err := FromPDF(&pdf)
+	if err != nil {
+		t.Fatalf("FromPDF: %v", err)
+	}
+	if thumb.Width != 300 || thumb.Height != 400 || thumb.ThumbHeight != MaxSide {
+		t.Fatalf("dimensões inesperadas: %+v", thumb)
+	}
+
+	text := bytes.NewBufferString("%PDF-1.4\n1 0 obj\n<< /Length 10 >>\nstream\nBT ET\nendstream\nendobj\n")
+	if _, err := FromPDF(text); !errors.Is(err, ErrNoPreview) {
+		t.Fatalf("PDF sem imagem deveria dar ErrNoPreview, veio %v", err)
+	}
+}
+
+func TestSquareJPEG(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 900, 300))
+	for y := 0; y < 300; y++ {
+		for x := 0; x < 900; x++ {
+			c := color.NRGBA{B: 255, A: 255} // the sides are cropped out
+			if x >= 300 && x < 600 {
+				c = color.NRGBA{R: 255, A: 255}
+			}
+			src.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+
+	thumb, err := SquareJPEG(&buf, 200)
+	if err != nil {
+		t.Fatalf("SquareJPEG: %v", err)
+	}
+	if thumb.ThumbWidth != 200 || thumb.ThumbHeight != 200 {
+		t.Fatalf("deveria ser quadrada: %dx%d", thumb.ThumbWidth, thumb.ThumbHeight)
+	}
+	img, err := jpeg.Decode(bytes.NewReader(thumb.JPEG))
+	if err != nil {
+		t.Fatalf("não é JPEG: %v", err)
+	}
+	for _, x := range []int{5, 100, 194} {
+		if r, _, b, _ := img.At(x, 100).RGBA(); r>>8 < 200 || b>>8 > 60 {
+			t.Errorf("pixel %d fora do centro: r=%d b=%d", x, r>>8, b>>8)
+		}
+	}
+}
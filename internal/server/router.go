@@ -2,6 +2,7 @@ package server
 
 import (
 	"html/template"
+	"strings"
 	"time"
 
 	sentrygin "github.com/getsentry/sentry-go/gin"
@@ -30,10 +31,13 @@ type Options struct {
 	HealthHandler   *handler.HealthHandler
 	UserHandler     *handler.UserHandler
 	MediaHandler    *handler.MediaHandler
+	AntibanHandler  *handler.AntibanHandler
+	SchemaHandler   *handler.WebhookSchemaHandler
 	WebhookPool     *webhook.Pool
 	APITokenService interface{}
 	InstanceRepo    interface{}
 	RateLimit       middleware.RateLimitOption
+	InstanceOwner   middleware.InstanceOwnerOption
 }
 
 func NewRouter(opts Options) *gin.Engine {
@@ -51,53 +55,79 @@ func NewRouter(opts Options) *gin.Engine {
 		router.Use(middleware.SentryReport())
 	}
 	router.Use(middleware.RequestID())
-	router.Use(cors.New(cors.Config{
+	// Media is left out of the open CORS policy: any page could otherwise read it with a token or
+	// a signed URL it got hold of. Embedding (<img>, <video>) needs no CORS and keeps working.
+	router.Use(withoutCORS("/api/media/", cors.New(cors.Config{
 		AllowOrigins: []string{"*"},
 		AllowMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowHeaders: []string{"Origin", "Content-Type", "Authorization", middleware.HeaderRequestID},
 		MaxAge:       12 * time.Hour,
-	}))
+	})))
 
 	api := router.Group("/api")
 
 	opts.HealthHandler.Register(api)
 	opts.AuthHandler.Register(api)
-
-	if opts.MediaHandler != nil {
-		api.GET("/media/:instanceId/:mediaId", opts.MediaHandler.GetMedia)
+	if opts.SchemaHandler != nil {
+		opts.SchemaHandler.Register(api)
 	}
 
-	protected := api.Group("")
-	if opts.RateLimit.Enabled {
-		protected.Use(middleware.RateLimit(opts.RateLimit))
-	}
+	auth := middleware.Auth(opts.AuthSecret)
 	if opts.APITokenService != nil {
 		if apiTokenSvc, ok := opts.APITokenService.(*api_token.Service); ok {
 			var instanceRepo storage.InstanceRepository
 			if repo, ok := opts.InstanceRepo.(storage.InstanceRepository); ok {
 				instanceRepo = repo
 			}
-			protected.Use(middleware.AuthWithOptions(middleware.AuthOption{
+			auth = middleware.AuthWithOptions(middleware.AuthOption{
 				JWTSecret:       opts.AuthSecret,
 				APITokenService: apiTokenSvc,
 				InstanceRepo:    instanceRepo,
-			}))
-		} else {
-			protected.Use(middleware.Auth(opts.AuthSecret))
+			})
 		}
-	} else {
-		protected.Use(middleware.Auth(opts.AuthSecret))
 	}
 
+	if opts.MediaHandler != nil {
+		// Outside the protected group: signed URLs from webhook payloads carry no token.
+		mediaAuth := opts.MediaHandler.Authorize(auth)
+		api.GET("/media/:instanceId/:mediaId", mediaAuth, opts.MediaHandler.GetMedia)
+		api.HEAD("/media/:instanceId/:mediaId", mediaAuth, opts.MediaHandler.GetMedia)
+	}
+
+	protected := api.Group("")
+	if opts.RateLimit.Enabled {
+		protected.Use(middleware.RateLimit(opts.RateLimit))
+	}
+	protected.Use(auth)
+
+	// After auth, so a replica only forwards requests it would have accepted itself.
+	protected.Use(middleware.InstanceOwner(opts.InstanceOwner))
+
 	opts.InstanceHandler.Register(protected)
 	opts.MessageHandler.Register(protected)
 	if opts.WhatsAppHandler != nil {
 		opts.WhatsAppHandler.Register(protected)
 	}
 	opts.APITokenHandler.Register(protected)
+	opts.HealthHandler.RegisterProtected(protected)
 	if opts.UserHandler != nil {
 		opts.UserHandler.Register(protected)
 	}
+	if opts.AntibanHandler != nil {
+		opts.AntibanHandler.Register(protected)
+	}
 
 	return router
 }
+
+// withoutCORS runs corsHandler on every path but the ones under prefix. It is applied to the
+// engine, not to a group, so preflight requests (which match no route) still get it.
+func withoutCORS(prefix string, corsHandler gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if strings.HasPrefix(c.Request.URL.Path, prefix) {
+			c.Next()
+			return
+		}
+		corsHandler(c)
+	}
+}
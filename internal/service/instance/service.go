@@ -10,11 +10,15 @@ import (
 
 	"github.com/google/uuid"
 
+	"github.com/open-apime/apime/internal/pkg/sessionbundle"
 	"github.com/open-apime/apime/internal/storage"
 	"github.com/open-apime/apime/internal/storage/model"
 )
 
-var ErrInvalidName = errors.New("nome da instância inválido")
+var (
+	ErrInvalidName        = errors.New("nome da instância inválido")
+	ErrInvalidMediaPolicy = errors.New("política de mídia inválida: use eager, lazy ou none")
+)
 
 type Service struct {
 	repo         storage.InstanceRepository
@@ -30,6 +34,10 @@ type SessionManager interface {
 	Disconnect(instanceID string) error
 	DeleteSession(instanceID string) error
 	SaveSessionBlob(instanceID string) ([]byte, error)
+	ExportDevice(ctx context.Context, instanceID string, handover bool) (string, []sessionbundle.Table, error)
+	RemoveExportedDevice(ctx context.Context, instanceID, deviceJID string) error
+	ImportDevice(ctx context.Context, instanceID, deviceJID string, tables []sessionbundle.Table) error
+	ConnectSession(ctx context.Context, instanceID string) error
 }
 
 func NewService(repo storage.InstanceRepository) *Service {
@@ -52,6 +60,8 @@ type CreateInput struct {
 	Name          string
 	WebhookURL    string
 	WebhookSecret string
+	MediaPolicy   model.MediaPolicy
+	OmitRaw       bool
 	OwnerUserID   string
 }
 
@@ -60,6 +70,8 @@ type UpdateInput struct {
 	Name          string
 	WebhookURL    *string
 	WebhookSecret *string
+	MediaPolicy   *model.MediaPolicy
+	OmitRaw       *bool
 	OwnerUserID   string
 }
 
@@ -70,6 +82,10 @@ func (s *Service) Create(ctx context.Context, input CreateInput) (model.Instance
 	if strings.TrimSpace(input.WebhookURL) != "" && !strings.HasPrefix(strings.TrimSpace(input.WebhookURL), "http") {
 		return model.Instance{}, errors.New("webhook inválido")
 	}
+	policy, err := parseMediaPolicy(input.MediaPolicy)
+	if err != nil {
+		return model.Instance{}, err
+	}
 
 	plainToken := uuid.NewString()
 	hashBytes := sha256.Sum256([]byte(plainToken))
@@ -82,6 +98,8 @@ func (s *Service) Create(ctx context.Context, input CreateInput) (model.Instance
 		OwnerUserID:    input.OwnerUserID,
 		WebhookURL:     strings.TrimSpace(input.WebhookURL),
 		WebhookSecret:  strings.TrimSpace(input.WebhookSecret),
+		MediaPolicy:    policy,
+		WebhookOmitRaw: input.OmitRaw,
 		TokenHash:      hash,
 		TokenUpdatedAt: &now,
 		Status:         model.InstanceStatusPending,
@@ -150,10 +168,34 @@ func applyUpdate(inst *model.Instance, input UpdateInput) error {
 	if input.WebhookSecret != nil {
 		inst.WebhookSecret = strings.TrimSpace(*input.WebhookSecret)
 	}
+	if input.MediaPolicy != nil {
+		policy, err := parseMediaPolicy(*input.MediaPolicy)
+		if err != nil {
+			return err
+		}
+		inst.MediaPolicy = policy
+	}
+	if input.OmitRaw != nil {
+		inst.WebhookOmitRaw = *input.OmitRaw
+	}
 	inst.Name = strings.TrimSpace(input.Name)
 	return nil
 }
 
+// parseMediaPolicy validates a media policy; empty means eager, the behaviour before policies.
+func parseMediaPolicy(policy model.MediaPolicy) (model.MediaPolicy, error) {
+	switch model.MediaPolicy(strings.ToLower(strings.TrimSpace(string(policy)))) {
+	case "", model.MediaPolicyEager:
+		return model.MediaPolicyEager, nil
+	case model.MediaPolicyLazy:
+		return model.MediaPolicyLazy, nil
+	case model.MediaPolicyNone:
+		return model.MediaPolicyNone, nil
+	default:
+		return "", ErrInvalidMediaPolicy
+	}
+}
+
 func (s *Service) UpdateByUser(ctx context.Context, id string, input UpdateInput) (model.Instance, error) {
 	inst, err := s.repo.GetByID(ctx, id)
 	if err != nil {
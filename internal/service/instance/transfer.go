@@ -0,0 +1,258 @@
+package instance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/open-apime/apime/internal/pkg/sessionbundle"
+	"github.com/open-apime/apime/internal/storage"
+	"github.com/open-apime/apime/internal/storage/model"
+)
+
+var (
+	ErrDeviceInUse   = errors.New("este número já está pareado em outra instância desta implantação")
+	ErrSessionActive = errors.New("a instância de destino já tem um número pareado; desconecte e remova a sessão antes de importar")
+	ErrNotHandover   = errors.New("o pacote foi exportado sem liberar a origem; desligue a instância de origem e use force para importar")
+	ErrNoHandover    = errors.New("a instância não tem transferência pendente")
+)
+
+// ExportInput describes an export. By default the export is a handover: the device leaves this
+// deployment. KeepSource produces a snapshot and keeps the instance connected here.
+type ExportInput struct {
+	Passphrase string
+	KeepSource bool
+}
+
+// Export seals the paired device and settings of the instance into an encrypted bundle.
+func (s *Service) Export(ctx context.Context, id string, input ExportInput) ([]byte, error) {
+	if s.session == nil {
+		return nil, errors.New("session manager não configurado")
+	}
+	if len(input.Passphrase) < sessionbundle.MinPassphraseLength {
+		return nil, sessionbundle.ErrWeakPassphrase
+	}
+	inst, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	handover := !input.KeepSource
+	deviceJID, tables, err := s.session.ExportDevice(ctx, id, handover)
+	if err != nil {
+		return nil, err
+	}
+	bundle := sessionbundle.Bundle{
+		ExportedAt: time.Now().UTC(),
+		Handover:   handover,
+		Instance: sessionbundle.Instance{
+			ID:            inst.ID,
+			Name:          inst.Name,
+			WebhookURL:    inst.WebhookURL,
+			WebhookSecret: inst.WebhookSecret,
+			TokenHash:     inst.TokenHash,
+		},
+		DeviceJID: deviceJID,
+		Tables:    tables,
+	}
+	sealed, err := sessionbundle.Seal(bundle, input.Passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if !handover {
+		return sealed, nil
+	}
+
+	// The device stays in the store until ConfirmExport: a download that never reaches the caller
+	// can be retried, or cancelled with CancelExport, without losing the number.
+	if inst.Status != model.InstanceStatusHandover {
+		inst.Status = model.InstanceStatusHandover
+		if _, err := s.repo.Update(ctx, inst); err != nil {
+			return nil, err
+		}
+	}
+	return sealed, nil
+}
+
+// ConfirmExport finishes a transfer once the bundle is safe with the caller (or imported): the
+// device is removed from this deployment, without logout.
+func (s *Service) ConfirmExport(ctx context.Context, id string) (model.Instance, error) {
+	if s.session == nil {
+		return model.Instance{}, errors.New("session manager não configurado")
+	}
+	inst, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return model.Instance{}, err
+	}
+	if inst.Status != model.InstanceStatusHandover {
+		return model.Instance{}, ErrNoHandover
+	}
+	if err := s.session.RemoveExportedDevice(ctx, id, inst.WhatsAppJID); err != nil {
+		return model.Instance{}, fmt.Errorf("remover dispositivo exportado: %w", err)
+	}
+	inst.WhatsAppJID = ""
+	inst.Status = model.InstanceStatusDisconnected
+	return s.repo.Update(ctx, inst)
+}
+
+// CancelExport gives up a transfer and connects the device here again. Only safe while the
+// bundle has not been imported anywhere.
+func (s *Service) CancelExport(ctx context.Context, id string) (model.Instance, error) {
+	if s.session == nil {
+		return model.Instance{}, errors.New("session manager não configurado")
+	}
+	inst, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return model.Instance{}, err
+	}
+	if inst.Status != model.InstanceStatusHandover {
+		return model.Instance{}, ErrNoHandover
+	}
+	inst.Status = model.InstanceStatusDisconnected
+	inst, err = s.repo.Update(ctx, inst)
+	if err != nil {
+		return model.Instance{}, err
+	}
+	if err := s.session.ConnectSession(ctx, id); err != nil {
+		return inst, fmt.Errorf("reconectar instância: %w", err)
+	}
+	if updated, err := s.repo.GetByID(ctx, id); err == nil {
+		inst = updated
+	}
+	return inst, nil
+}
+
+func (s *Service) ExportByUser(ctx context.Context, id string, userID string, userRole string, input ExportInput) ([]byte, error) {
+	if _, err := s.GetByUser(ctx, id, userID, userRole); err != nil {
+		return nil, err
+	}
+	return s.Export(ctx, id, input)
+}
+
+func (s *Service) ConfirmExportByUser(ctx context.Context, id string, userID string, userRole string) (model.Instance, error) {
+	if _, err := s.GetByUser(ctx, id, userID, userRole); err != nil {
+		return model.Instance{}, err
+	}
+	return s.ConfirmExport(ctx, id)
+}
+
+func (s *Service) CancelExportByUser(ctx context.Context, id string, userID string, userRole string) (model.Instance, error) {
+	if _, err := s.GetByUser(ctx, id, userID, userRole); err != nil {
+		return model.Instance{}, err
+	}
+	return s.CancelExport(ctx, id)
+}
+
+// ImportInput describes an import. Force accepts a bundle exported with KeepSource; the caller
+// is then responsible for having shut the source down. Offline skips the first connection (the
+// CLI runs before the API, which connects the device when it restores sessions on start).
+type ImportInput struct {
+	Bundle      []byte
+	Passphrase  string
+	OwnerUserID string
+	UserRole    string
+	Force       bool
+	Offline     bool
+}
+
+// ImportResult is the imported instance. The device is in the store even when the first
+// connection fails; it is retried on the next restore.
+type ImportResult struct {
+	Instance     model.Instance
+	Connected    bool
+	ConnectError string
+}
+
+// Import restores an exported bundle under the same instance ID, creating the instance when it
+// doesn't exist in this deployment.
+func (s *Service) Import(ctx context.Context, input ImportInput) (ImportResult, error) {
+	if s.session == nil {
+		return ImportResult{}, errors.New("session manager não configurado")
+	}
+	bundle, err := sessionbundle.Open(input.Bundle, input.Passphrase)
+	if err != nil {
+		return ImportResult{}, err
+	}
+	if !bundle.Handover && !input.Force {
+		return ImportResult{}, ErrNotHandover
+	}
+
+	// Not List: it leaves out the rows whose webhook secret doesn't open, and one of them could
+	// be the instance holding the device.
+	linked, err := s.repo.IDsByWhatsAppJID(ctx, bundle.DeviceJID)
+	if err != nil {
+		return ImportResult{}, err
+	}
+	for _, id := range linked {
+		if id != bundle.Instance.ID {
+			return ImportResult{}, ErrDeviceInUse
+		}
+	}
+
+	inst, err := s.repo.GetByID(ctx, bundle.Instance.ID)
+	created := false
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		now := time.Now().UTC()
+		inst, err = s.repo.Create(ctx, model.Instance{
+			ID:             bundle.Instance.ID,
+			Name:           bundle.Instance.Name,
+			OwnerUserID:    input.OwnerUserID,
+			WebhookURL:     bundle.Instance.WebhookURL,
+			WebhookSecret:  bundle.Instance.WebhookSecret,
+			TokenHash:      bundle.Instance.TokenHash,
+			TokenUpdatedAt: &now,
+			Status:         model.InstanceStatusDisconnected,
+		})
+		if err != nil {
+			return ImportResult{}, err
+		}
+		created = true
+	case err != nil:
+		return ImportResult{}, err
+	default:
+		if input.UserRole != "admin" && inst.OwnerUserID != input.OwnerUserID {
+			return ImportResult{}, storage.ErrNotFound
+		}
+		if inst.WhatsAppJID != "" || inst.Status == model.InstanceStatusActive {
+			return ImportResult{}, ErrSessionActive
+		}
+	}
+
+	if err := s.session.ImportDevice(ctx, inst.ID, bundle.DeviceJID, bundle.Tables); err != nil {
+		if created {
+			_ = s.repo.Delete(ctx, inst.ID)
+		}
+		return ImportResult{}, err
+	}
+
+	if !created {
+		inst.Name = bundle.Instance.Name
+		inst.WebhookURL = bundle.Instance.WebhookURL
+		inst.WebhookSecret = bundle.Instance.WebhookSecret
+		if bundle.Instance.TokenHash != "" {
+			inst.TokenHash = bundle.Instance.TokenHash
+		}
+	}
+	inst.WhatsAppJID = bundle.DeviceJID
+	inst.Status = model.InstanceStatusDisconnected
+	inst, err = s.repo.Update(ctx, inst)
+	if err != nil {
+		return ImportResult{}, err
+	}
+
+	result := ImportResult{Instance: inst}
+	if input.Offline {
+		return result, nil
+	}
+	if err := s.session.ConnectSession(ctx, inst.ID); err != nil {
+		result.ConnectError = err.Error()
+		return result, nil
+	}
+	result.Connected = true
+	if updated, err := s.repo.GetByID(ctx, inst.ID); err == nil {
+		result.Instance = updated
+	}
+	return result, nil
+}
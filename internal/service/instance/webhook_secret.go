@@ -0,0 +1,84 @@
+package instance
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/open-apime/apime/internal/storage/model"
+)
+
+const (
+	defaultWebhookSecretGrace = 24 * time.Hour
+	maxWebhookSecretGrace     = 7 * 24 * time.Hour
+)
+
+var ErrInvalidGracePeriod = errors.New("período de transição inválido: use de 0 a 168 horas")
+
+// RotateWebhookSecretInput describes a rotation. An empty Secret generates one. GraceHours is how
+// long the previous secret keeps signing deliveries (nil means 24h, 0 drops it at once).
+type RotateWebhookSecretInput struct {
+	Secret     string
+	GraceHours *int
+}
+
+// RotateWebhookSecretResult carries the new secret, returned only here, and when the previous
+// one stops being sent.
+type RotateWebhookSecretResult struct {
+	Secret                  string
+	PreviousSecretExpiresAt *time.Time
+}
+
+func (s *Service) RotateWebhookSecret(ctx context.Context, id string, input RotateWebhookSecretInput) (RotateWebhookSecretResult, error) {
+	inst, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return RotateWebhookSecretResult{}, err
+	}
+	return s.rotateWebhookSecret(ctx, inst, input)
+}
+
+func (s *Service) RotateWebhookSecretByUser(ctx context.Context, id string, userID string, userRole string, input RotateWebhookSecretInput) (RotateWebhookSecretResult, error) {
+	inst, err := s.GetByUser(ctx, id, userID, userRole)
+	if err != nil {
+		return RotateWebhookSecretResult{}, err
+	}
+	return s.rotateWebhookSecret(ctx, inst, input)
+}
+
+func (s *Service) rotateWebhookSecret(ctx context.Context, inst model.Instance, input RotateWebhookSecretInput) (RotateWebhookSecretResult, error) {
+	grace := defaultWebhookSecretGrace
+	if input.GraceHours != nil {
+		grace = time.Duration(*input.GraceHours) * time.Hour
+		if grace < 0 || grace > maxWebhookSecretGrace {
+			return RotateWebhookSecretResult{}, ErrInvalidGracePeriod
+		}
+	}
+
+	secret := strings.TrimSpace(input.Secret)
+	if secret == "" {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			return RotateWebhookSecretResult{}, err
+		}
+		secret = hex.EncodeToString(buf)
+	}
+
+	// Only the secret being replaced overlaps with the new one: a rotation during the grace period
+	// of another drops the oldest, as receivers are expected to have moved off it by then.
+	inst.WebhookPreviousSecret = ""
+	inst.WebhookPreviousSecretExpiresAt = nil
+	if inst.WebhookSecret != "" && grace > 0 {
+		expiresAt := time.Now().UTC().Add(grace)
+		inst.WebhookPreviousSecret = inst.WebhookSecret
+		inst.WebhookPreviousSecretExpiresAt = &expiresAt
+	}
+	inst.WebhookSecret = secret
+	updated, err := s.repo.Update(ctx, inst)
+	if err != nil {
+		return RotateWebhookSecretResult{}, err
+	}
+	return RotateWebhookSecretResult{Secret: secret, PreviousSecretExpiresAt: updated.WebhookPreviousSecretExpiresAt}, nil
+}
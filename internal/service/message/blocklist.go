@@ -0,0 +1,273 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"go.uber.org/zap"
+
+	"github.com/open-apime/apime/internal/storage/model"
+)
+
+// blockedLoadRetry is how long a blocklist that failed to load is left alone. Send checks before
+// every message, and without it an instance whose query keeps failing would pay the round-trip
+// (and its timeout) on each one.
+const blockedLoadRetry = 30 * time.Second
+
+// blocked is the blocklist cache. It is package state, like the rest of the per-instance send
+// state, so the session can drop it on logout and pairing without a handle on the Service.
+var blocked blocklists
+
+// blocklists caches the blocked JIDs of each instance, so Send can check the recipient without a
+// query per message. A missing entry is loaded on the next check; blocklist events update it.
+type blocklists struct {
+	mu     sync.Mutex
+	byIns  map[string]map[types.JID]bool
+	failed map[string]time.Time
+}
+
+func (b *blocklists) get(instanceID string) (map[types.JID]bool, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	set, ok := b.byIns[instanceID]
+	return set, ok
+}
+
+// needsLoad reports whether the list is missing and no load failed in the last blockedLoadRetry.
+func (b *blocklists) needsLoad(instanceID string, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.byIns[instanceID]; ok {
+		return false
+	}
+	failedAt, ok := b.failed[instanceID]
+	return !ok || now.Sub(failedAt) >= blockedLoadRetry
+}
+
+func (b *blocklists) loadFailed(instanceID string, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failed == nil {
+		b.failed = make(map[string]time.Time)
+	}
+	b.failed[instanceID] = now
+}
+
+func (b *blocklists) set(instanceID string, jids []types.JID) {
+	set := make(map[types.JID]bool, len(jids))
+	for _, jid := range jids {
+		set[jid.ToNonAD()] = true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.byIns == nil {
+		b.byIns = make(map[string]map[types.JID]bool)
+	}
+	b.byIns[instanceID] = set
+	delete(b.failed, instanceID)
+}
+
+func (b *blocklists) forget(instanceID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.byIns, instanceID)
+	delete(b.failed, instanceID)
+}
+
+// ForgetBlocklist drops the cached blocklist of an instance. Called when the session ends or a new
+// device pairs: the list belongs to the account, and the next account may not be the same one.
+func ForgetBlocklist(instanceID string) {
+	blocked.forget(instanceID)
+}
+
+// apply updates a loaded list with the changes of an event. An instance not loaded yet stays
+// that way: the next check loads the whole list, changes included.
+func (b *blocklists) apply(instanceID string, changes []events.BlocklistChange) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	set, ok := b.byIns[instanceID]
+	if !ok {
+		return
+	}
+	for _, change := range changes {
+		switch change.Action {
+		case events.BlocklistChangeActionBlock:
+			set[change.JID.ToNonAD()] = true
+		case events.BlocklistChangeActionUnblock:
+			delete(set, change.JID.ToNonAD())
+		}
+	}
+}
+
+// Blocklist returns the contacts blocked by the instance, read from WhatsApp.
+func (s *Service) Blocklist(ctx context.Context, instanceID string) ([]types.JID, error) {
+	client, err := s.connectedClient(instanceID)
+	if err != nil {
+		return nil, err
+	}
+	list, err := client.GetBlocklist(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar lista de bloqueio: %w", err)
+	}
+	blocked.set(instanceID, list.JIDs)
+	return list.JIDs, nil
+}
+
+// UpdateBlocklist blocks or unblocks a contact (number or JID) and returns the updated list.
+func (s *Service) UpdateBlocklist(ctx context.Context, instanceID, contact string, block bool) ([]types.JID, error) {
+	client, err := s.connectedClient(instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	jid, err := s.ResolveJID(ctx, client, contact)
+	if err != nil {
+		return nil, err
+	}
+	action := events.BlocklistChangeActionUnblock
+	if block {
+		action = events.BlocklistChangeActionBlock
+	}
+	list, err := client.UpdateBlocklist(ctx, jid, action)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao atualizar lista de bloqueio: %w", err)
+	}
+	blocked.set(instanceID, list.JIDs)
+	return list.JIDs, nil
+}
+
+// HandleBlocklist keeps the cache in step with blocks and unblocks made on the phone. A "modify"
+// event only says the list changed, so it's reloaded on the next check.
+func (s *Service) HandleBlocklist(instanceID string, evt *events.Blocklist) {
+	if evt.Action == events.BlocklistActionModify {
+		blocked.forget(instanceID)
+		return
+	}
+	blocked.apply(instanceID, evt.Changes)
+}
+
+// checkBlocked refuses a send to a contact the instance blocked before Send takes the instance
+// lock and waits for the session, so the refusal costs neither. The recipient is taken as written
+// (or from the JID cache) since resolving it needs the session ready; Send checks the resolved JID
+// again once it has it. This is also where the list is loaded, outside the lock.
+func (s *Service) checkBlocked(ctx context.Context, input SendInput) error {
+	client, err := s.connectedClient(input.InstanceID)
+	if err != nil {
+		// Send reports the disconnected instance itself.
+		return nil
+	}
+	s.loadBlocklist(ctx, client, input.InstanceID)
+	jid, ok := recipientAsWritten(input.To)
+	if !ok || !s.isBlocked(ctx, client, input.InstanceID, jid) {
+		return nil
+	}
+	s.refuseBlocked(ctx, input, jid)
+	return ErrContactBlocked
+}
+
+// refuseBlocked logs the refusal and marks an already persisted (queued) message as failed, so the
+// stuck recovery doesn't queue it again.
+func (s *Service) refuseBlocked(ctx context.Context, input SendInput, jid types.JID) {
+	s.log.Info("envio recusado: contato bloqueado pela instância",
+		zap.String("instance_id", input.InstanceID),
+		zap.String("to", jid.String()))
+	if input.MessageID != "" {
+		_ = s.repo.Update(ctx, model.Message{
+			ID:         input.MessageID,
+			InstanceID: input.InstanceID,
+			To:         input.To,
+			Type:       input.Type,
+			Payload:    input.Text,
+			Status:     "failed",
+		})
+	}
+}
+
+// recipientAsWritten is the individual JID of to without any query: a JID as is, or a number
+// through the JID cache, falling back to the digits. Groups and other servers are not blocked.
+func recipientAsWritten(to string) (types.JID, bool) {
+	to = strings.TrimSpace(to)
+	if strings.Contains(to, "@") {
+		jid, err := types.ParseJID(to)
+		if err != nil || (jid.Server != types.DefaultUserServer && jid.Server != types.HiddenUserServer) {
+			return types.EmptyJID, false
+		}
+		return jid.ToNonAD(), true
+	}
+	phone := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, to)
+	if phone == "" {
+		return types.EmptyJID, false
+	}
+	if entry, ok := jidCacheLoad(phone); ok && !entry.jid.IsEmpty() {
+		return entry.jid.ToNonAD(), true
+	}
+	return types.NewJID(phone, types.DefaultUserServer), true
+}
+
+// loadBlocklist reads the list from WhatsApp when it isn't cached. A failure is remembered for
+// blockedLoadRetry and doesn't stop the send.
+func (s *Service) loadBlocklist(ctx context.Context, client *whatsmeow.Client, instanceID string) {
+	if !blocked.needsLoad(instanceID, time.Now()) {
+		return
+	}
+	list, err := client.GetBlocklist(ctx)
+	if err != nil {
+		blocked.loadFailed(instanceID, time.Now())
+		s.log.Debug("não foi possível carregar a lista de bloqueio",
+			zap.String("instance_id", instanceID),
+			zap.Error(err))
+		return
+	}
+	blocked.set(instanceID, list.JIDs)
+}
+
+// isBlocked reports whether the instance blocked jid, from the cached list only: a list not loaded
+// counts as empty. The list may hold the phone number or the LID of the contact, so both are
+// checked.
+func (s *Service) isBlocked(ctx context.Context, client *whatsmeow.Client, instanceID string, jid types.JID) bool {
+	set, _ := blocked.get(instanceID)
+	if len(set) == 0 {
+		return false
+	}
+
+	jid = jid.ToNonAD()
+	if set[jid] {
+		return true
+	}
+	if client.Store == nil || client.Store.LIDs == nil {
+		return false
+	}
+	var alt types.JID
+	var err error
+	switch jid.Server {
+	case types.DefaultUserServer:
+		alt, err = client.Store.LIDs.GetLIDForPN(ctx, jid)
+	case types.HiddenUserServer:
+		alt, err = client.Store.LIDs.GetPNForLID(ctx, jid)
+	}
+	return err == nil && !alt.IsEmpty() && set[alt.ToNonAD()]
+}
+
+// connectedClient returns the client of an instance with a logged in session.
+func (s *Service) connectedClient(instanceID string) (*whatsmeow.Client, error) {
+	if s.sessionMgr == nil {
+		return nil, errors.New("session manager não configurado")
+	}
+	client, err := s.sessionMgr.GetClient(instanceID)
+	if err != nil || client == nil || !client.IsLoggedIn() {
+		return nil, ErrInstanceNotConnected
+	}
+	return client, nil
+}
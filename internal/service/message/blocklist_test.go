@@ -0,0 +1,101 @@
+package message
+
+import (
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+func TestBlocklistSetNormalizesDevices(t *testing.T) {
+	var b blocklists
+	device := types.JID{User: "5511999999999", Server: types.DefaultUserServer, Device: 3}
+	b.set("inst", []types.JID{device})
+
+	set, ok := b.get("inst")
+	if !ok || !set[device.ToNonAD()] {
+		t.Fatalf("JID de aparelho deveria entrar sem o aparelho: %v", set)
+	}
+	if _, ok := b.get("outra"); ok {
+		t.Fatalf("instância sem lista não deveria estar carregada")
+	}
+}
+
+func TestBlocklistApply(t *testing.T) {
+	a := types.NewJID("5511111111111", types.DefaultUserServer)
+	c := types.NewJID("5522222222222", types.DefaultUserServer)
+
+	var b blocklists
+	b.set("inst", []types.JID{a})
+	b.apply("inst", []events.BlocklistChange{
+		{JID: a, Action: events.BlocklistChangeActionUnblock},
+		{JID: c, Action: events.BlocklistChangeActionBlock},
+	})
+	set, _ := b.get("inst")
+	if set[a] || !set[c] {
+		t.Fatalf("mudanças não aplicadas: %v", set)
+	}
+
+	// An instance not loaded stays unloaded: a partial list would pass for the whole one.
+	b.apply("outra", []events.BlocklistChange{{JID: c, Action: events.BlocklistChangeActionBlock}})
+	if _, ok := b.get("outra"); ok {
+		t.Fatalf("apply não deveria criar a lista de uma instância não carregada")
+	}
+}
+
+func TestBlocklistForget(t *testing.T) {
+	var b blocklists
+	b.set("inst", []types.JID{types.NewJID("5511111111111", types.DefaultUserServer)})
+	b.forget("inst")
+	if _, ok := b.get("inst"); ok {
+		t.Fatalf("lista deveria ter sido descartada")
+	}
+	if !b.needsLoad("inst", time.Now()) {
+		t.Fatalf("lista descartada deveria ser carregada de novo")
+	}
+}
+
+func TestBlocklistLoadFailureBackoff(t *testing.T) {
+	var b blocklists
+	now := time.Now()
+	if !b.needsLoad("inst", now) {
+		t.Fatalf("lista ausente deveria ser carregada")
+	}
+	b.loadFailed("inst", now)
+	if b.needsLoad("inst", now.Add(blockedLoadRetry/2)) {
+		t.Fatalf("falha recente não deveria ser repetida a cada envio")
+	}
+	if !b.needsLoad("inst", now.Add(blockedLoadRetry)) {
+		t.Fatalf("passado o intervalo a carga deveria ser tentada de novo")
+	}
+
+	b.set("inst", nil)
+	if b.needsLoad("inst", now) {
+		t.Fatalf("lista carregada (mesmo vazia) não precisa de carga")
+	}
+	b.forget("inst")
+	if !b.needsLoad("inst", now) {
+		t.Fatalf("forget deveria limpar também a falha")
+	}
+}
+
+func TestRecipientAsWritten(t *testing.T) {
+	cases := []struct {
+		to   string
+		want types.JID
+		ok   bool
+	}{
+		{"+55 (11) 99999-0000", types.NewJID("5511999990000", types.DefaultUserServer), true},
+		{"5511999990000@s.whatsapp.net", types.NewJID("5511999990000", types.DefaultUserServer), true},
+		{"123456789@lid", types.NewJID("123456789", types.HiddenUserServer), true},
+		{"120363000000000000@g.us", types.EmptyJID, false},
+		{"sem número", types.EmptyJID, false},
+	}
+	for _, tc := range cases {
+		got, ok := recipientAsWritten(tc.to)
+		if ok != tc.ok || got != tc.want {
+			t.Errorf("%q: %v %v; queria %v %v", tc.to, got, ok, tc.want, tc.ok)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package message
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/open-apime/apime/internal/pkg/cache"
+	cache_memory "github.com/open-apime/apime/internal/pkg/cache/memory"
+)
+
+// The anti-ban guards (463 reach-out lock, JID resolution, inbound tracking, contact auto-save,
+// presence state) live in a shared cache instead of package-level maps: with the Redis backend they
+// survive restarts and hold across replicas, so a lock learned on one replica is honored by all.
+// Key prefixes below keep each guard in its own namespace.
+const (
+	cacheKeyReachout    = "reachout:"
+	cacheKeyJID         = "jid:"
+	cacheKeyInbound     = "inbound:"
+	cacheKeyInboundAt   = "inbound_at:"
+	cacheKeyContactSeen = "contact_seen:"
+	cacheKeyAvailable   = "presence:available:"
+	cacheKeyComposing   = "presence:composing:"
+	// Set of the chats with a composing mark, per instance, so a reconnect
+	// clears them without scanning the cache.
+	cacheKeyComposingIndex = "presence:composing_index:"
+	cacheKeyOwnInfo        = "own_info:"
+
+	cacheOpTimeout = 2 * time.Second
+)
+
+var (
+	sharedCacheMu sync.RWMutex
+	sharedCache   cache.Cache = cache_memory.New()
+)
+
+// SetCache replaces the backend used by the package caches. Called once at startup, before any
+// send, with the Redis cache when Redis is enabled.
+func SetCache(c cache.Cache) {
+	if c == nil {
+		return
+	}
+	sharedCacheMu.Lock()
+	sharedCache = c
+	sharedCacheMu.Unlock()
+}
+
+func getCache() cache.Cache {
+	sharedCacheMu.RLock()
+	defer sharedCacheMu.RUnlock()
+	return sharedCache
+}
+
+// cacheCtx bounds a cache call. The guards run on the send path and must never hang it on a slow
+// Redis: on error each caller falls back to the permissive answer, like a cache miss. The 463
+// block is the exception: it falls back to a local copy (see reachoutBlocked).
+func cacheCtx() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), cacheOpTimeout)
+}
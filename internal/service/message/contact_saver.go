@@ -15,13 +15,10 @@ import (
 
 // autoSaveContact ensures the recipient is in the account's contact list (app state
 // critical_unblock_low) at send time. Best-effort and never blocks the send: a local Store read
-// short-circuits when the contact is already named, an in-memory cache covers the window until the
+// short-circuits when the contact is already named, the shared cache covers the window until the
 // app state echo returns, and a per-instance throttle keeps mutations from going out in bursts.
 
 var (
-	// saveContactSeen marks instance:jid pairs already handled (avoids reprocessing before the app state echo).
-	saveContactSeen sync.Map // key "instanceID:jid" -> expiresAt (time.Time)
-
 	saveContactThrottleMu sync.Mutex
 	saveContactLastByInst = map[string]time.Time{}
 )
@@ -65,17 +62,17 @@ func (s *Service) autoSaveContact(ctx context.Context, instanceID string, client
 		}
 	}
 
-	seenKey := instanceID + ":" + pn.String()
-	if exp, ok := saveContactSeen.Load(seenKey); ok {
-		if t, _ := exp.(time.Time); time.Now().Before(t) {
-			return
-		}
+	// "contact_seen:<instanceID>:<jid>" marks pairs already handled (avoids reprocessing before the
+	// app state echo).
+	seenKey := cacheKeyContactSeen + instanceID + ":" + pn.String()
+	if contactSeen(seenKey) {
+		return
 	}
 
 	// "Already saved" gate: local read. Saved = FullName/FirstName set (PushName only = just known).
 	contact, err := client.Store.Contacts.GetContact(ctx, pn)
 	if err == nil && (contact.FullName != "" || contact.FirstName != "") {
-		saveContactSeen.Store(seenKey, time.Now().Add(saveContactSeenTTL))
+		markContactSeen(seenKey, true)
 		return
 	}
 
@@ -89,13 +86,13 @@ func (s *Service) autoSaveContact(ctx context.Context, instanceID string, client
 	}
 
 	// Mark BEFORE sending (avoids a race between 2 simultaneous sends to the same number).
-	saveContactSeen.Store(seenKey, time.Now().Add(saveContactSeenTTL))
+	markContactSeen(seenKey, true)
 	s.throttleSaveContact(instanceID)
 
 	patch := appstate.BuildContact(pn, fullName, "", lid, false)
 	if err := client.SendAppState(ctx, patch); err != nil {
 		// Not fatal: clear the mark to retry on a future send.
-		saveContactSeen.Delete(seenKey)
+		markContactSeen(seenKey, false)
 		s.log.Warn("auto-save de contato falhou (não crítico)",
 			zap.String("instance_id", instanceID), zap.String("jid", pn.String()), zap.Error(err))
 		return
@@ -104,6 +101,23 @@ func (s *Service) autoSaveContact(ctx context.Context, instanceID string, client
 		zap.String("instance_id", instanceID), zap.String("jid", pn.String()))
 }
 
+func contactSeen(key string) bool {
+	ctx, cancel := cacheCtx()
+	defer cancel()
+	_, ok, err := getCache().Get(ctx, key)
+	return err == nil && ok
+}
+
+func markContactSeen(key string, seen bool) {
+	ctx, cancel := cacheCtx()
+	defer cancel()
+	if seen {
+		_ = getCache().Set(ctx, key, "1", saveContactSeenTTL)
+		return
+	}
+	_ = getCache().Delete(ctx, key)
+}
+
 // throttleSaveContact spaces out app state mutations per instance (only delays, never rejects).
 func (s *Service) throttleSaveContact(instanceID string) {
 	saveContactThrottleMu.Lock()
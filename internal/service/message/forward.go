@@ -0,0 +1,211 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/open-apime/apime/internal/storage/model"
+)
+
+var (
+	ErrForwardNotFound = errors.New("mensagem não encontrada para encaminhar")
+	ErrForwardViewOnce = errors.New("mensagem de visualização única não pode ser encaminhada")
+	// ErrForwardLimit mirrors the clients: 5 chats per forward, and a single one for messages
+	// already forwarded many times.
+	ErrForwardLimit = errors.New("limite de destinatários para encaminhamento excedido")
+)
+
+const (
+	maxForwardTargets = 5
+	// frequentlyForwarded is the forwarding score from which the clients label the message
+	// "forwarded many times" and only allow forwarding it to one chat at a time.
+	frequentlyForwarded = 5
+)
+
+type ForwardInput struct {
+	InstanceID string
+	MessageID  string
+	To         []string
+}
+
+// ForwardResult is the outcome for one recipient: the sent message or the reason it failed.
+type ForwardResult struct {
+	To      string         `json:"to"`
+	Message *model.Message `json:"message,omitempty"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// Forward sends a copy of a stored message, flagged as forwarded, to each recipient. Media is
+// forwarded by reference, as the clients do: nothing is downloaded or uploaded again. Each
+// recipient goes through Send (governor, presence, persistence), so one failing doesn't stop the
+// others; errors about the source message or the recipient count are returned before any send.
+func (s *Service) Forward(ctx context.Context, input ForwardInput) ([]ForwardResult, error) {
+	if input.InstanceID == "" || input.MessageID == "" || len(input.To) == 0 {
+		return nil, ErrInvalidPayload
+	}
+	if len(input.To) > maxForwardTargets {
+		return nil, fmt.Errorf("%w: máximo de %d conversas", ErrForwardLimit, maxForwardTargets)
+	}
+
+	src, err := s.storedMessage(ctx, input.InstanceID, input.MessageID)
+	if err != nil {
+		return nil, err
+	}
+	forwarded, score, err := forwardCopy(src)
+	if err != nil {
+		return nil, err
+	}
+	if score > frequentlyForwarded && len(input.To) > 1 {
+		return nil, fmt.Errorf("%w: mensagem encaminhada com frequência só pode ir para uma conversa por vez", ErrForwardLimit)
+	}
+
+	results := make([]ForwardResult, 0, len(input.To))
+	for _, to := range input.To {
+		// A copy each: Send and whatsmeow may fill fields of the message they're given, which must
+		// not leak into the next recipient's.
+		msg, err := s.Send(ctx, SendInput{
+			InstanceID: input.InstanceID,
+			To:         to,
+			Type:       "forward",
+			Forward:    proto.Clone(forwarded).(*waE2E.Message),
+		})
+		if err != nil {
+			results = append(results, ForwardResult{To: to, Error: err.Error()})
+			continue
+		}
+		results = append(results, ForwardResult{To: to, Message: &msg})
+	}
+	return results, nil
+}
+
+// storedMessage looks for the message in memory first (recent sends and receives, any type),
+// then in the media references (received media, kept for the retention period) and last in the
+// persisted messages, where only text can be rebuilt.
+func (s *Service) storedMessage(ctx context.Context, instanceID, messageID string) (*waE2E.Message, error) {
+	if s.sessionMgr != nil {
+		if msg := s.sessionMgr.StoredMessage(instanceID, messageID); msg != nil {
+			return msg, nil
+		}
+	}
+	if s.mediaRefs != nil {
+		if ref, err := s.mediaRefs.Get(ctx, instanceID, messageID); err == nil {
+			if msg, _, err := decodeMediaRef(ref.Message); err == nil {
+				return msg, nil
+			}
+		}
+	}
+	if stored, err := s.repo.GetByWhatsAppID(ctx, messageID); err == nil && stored.InstanceID == instanceID && stored.Type == "text" {
+		return &waE2E.Message{Conversation: proto.String(stored.Payload)}, nil
+	}
+	return nil, ErrForwardNotFound
+}
+
+// forwardCopy returns the message to send as a forward and its new forwarding score. Quotes and
+// mentions don't travel with a forward; the rest of the content (media keys included) does.
+func forwardCopy(src *waE2E.Message) (*waE2E.Message, uint32, error) {
+	src, err := unwrapForward(src)
+	if err != nil {
+		return nil, 0, err
+	}
+	src = proto.Clone(src).(*waE2E.Message)
+
+	out := &waE2E.Message{}
+	var ctxInfo **waE2E.ContextInfo
+	switch {
+	case src.Conversation != nil:
+		out.ExtendedTextMessage = &waE2E.ExtendedTextMessage{Text: src.Conversation}
+		ctxInfo = &out.ExtendedTextMessage.ContextInfo
+	case src.ExtendedTextMessage != nil:
+		out.ExtendedTextMessage = src.ExtendedTextMessage
+		ctxInfo = &out.ExtendedTextMessage.ContextInfo
+	case src.ImageMessage != nil:
+		out.ImageMessage = src.ImageMessage
+		ctxInfo = &out.ImageMessage.ContextInfo
+	case src.VideoMessage != nil:
+		out.VideoMessage = src.VideoMessage
+		ctxInfo = &out.VideoMessage.ContextInfo
+	case src.PtvMessage != nil:
+		out.PtvMessage = src.PtvMessage
+		ctxInfo = &out.PtvMessage.ContextInfo
+	case src.AudioMessage != nil:
+		out.AudioMessage = src.AudioMessage
+		ctxInfo = &out.AudioMessage.ContextInfo
+	case src.DocumentMessage != nil:
+		out.DocumentMessage = src.DocumentMessage
+		ctxInfo = &out.DocumentMessage.ContextInfo
+	case src.StickerMessage != nil:
+		out.StickerMessage = src.StickerMessage
+		ctxInfo = &out.StickerMessage.ContextInfo
+	case src.ContactMessage != nil:
+		out.ContactMessage = src.ContactMessage
+		ctxInfo = &out.ContactMessage.ContextInfo
+	case src.ContactsArrayMessage != nil:
+		out.ContactsArrayMessage = src.ContactsArrayMessage
+		ctxInfo = &out.ContactsArrayMessage.ContextInfo
+	case src.LocationMessage != nil:
+		out.LocationMessage = src.LocationMessage
+		ctxInfo = &out.LocationMessage.ContextInfo
+	default:
+		return nil, 0, fmt.Errorf("%w: tipo de mensagem não encaminhável", ErrUnsupportedMediaType)
+	}
+
+	score := (*ctxInfo).GetForwardingScore() + 1
+	*ctxInfo = &waE2E.ContextInfo{
+		IsForwarded:     proto.Bool(true),
+		ForwardingScore: proto.Uint32(score),
+	}
+	return out, score, nil
+}
+
+// unwrapForward strips the envelopes a stored message may carry (our own sends synced from the
+// phone, disappearing chats, documents with caption). View-once media is refused, as in the
+// clients: forwarding it would defeat the point.
+func unwrapForward(msg *waE2E.Message) (*waE2E.Message, error) {
+	for {
+		switch {
+		case msg.GetViewOnceMessage() != nil, msg.GetViewOnceMessageV2() != nil, msg.GetViewOnceMessageV2Extension() != nil,
+			msg.GetImageMessage().GetViewOnce(), msg.GetVideoMessage().GetViewOnce(), msg.GetAudioMessage().GetViewOnce():
+			return nil, ErrForwardViewOnce
+		case msg.GetDeviceSentMessage().GetMessage() != nil:
+			msg = msg.GetDeviceSentMessage().GetMessage()
+		case msg.GetEphemeralMessage().GetMessage() != nil:
+			msg = msg.GetEphemeralMessage().GetMessage()
+		case msg.GetDocumentWithCaptionMessage().GetMessage() != nil:
+			msg = msg.GetDocumentWithCaptionMessage().GetMessage()
+		default:
+			return msg, nil
+		}
+	}
+}
+
+// describeForward returns the type and payload a forwarded message is persisted with, in the
+// same format as the regular sends.
+func describeForward(msg *waE2E.Message) (string, string) {
+	switch {
+	case msg.ExtendedTextMessage != nil:
+		return "text", msg.ExtendedTextMessage.GetText()
+	case msg.ImageMessage != nil:
+		return "image", fmt.Sprintf("media:%s", msg.ImageMessage.GetMimetype())
+	case msg.VideoMessage != nil:
+		return "video", fmt.Sprintf("media:%s", msg.VideoMessage.GetMimetype())
+	case msg.PtvMessage != nil:
+		return "video", fmt.Sprintf("media:%s", msg.PtvMessage.GetMimetype())
+	case msg.AudioMessage != nil:
+		return "audio", fmt.Sprintf("audio:%s", msg.AudioMessage.GetMimetype())
+	case msg.DocumentMessage != nil:
+		return "document", fmt.Sprintf("document:%s:%s", msg.DocumentMessage.GetFileName(), msg.DocumentMessage.GetMimetype())
+	case msg.StickerMessage != nil:
+		return "sticker", fmt.Sprintf("media:%s", msg.StickerMessage.GetMimetype())
+	case msg.ContactMessage != nil:
+		return "contact", fmt.Sprintf("contact:%s", msg.ContactMessage.GetDisplayName())
+	case msg.ContactsArrayMessage != nil:
+		return "contact", fmt.Sprintf("contact:%s", msg.ContactsArrayMessage.GetDisplayName())
+	case msg.LocationMessage != nil:
+		return "location", fmt.Sprintf("location:%f,%f", msg.LocationMessage.GetDegreesLatitude(), msg.LocationMessage.GetDegreesLongitude())
+	}
+	return "forward", ""
+}
@@ -0,0 +1,151 @@
+package message
+
+import (
+	"errors"
+	"testing"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestForwardCopy(t *testing.T) {
+	quoted := &waE2E.ContextInfo{
+		StanzaID:        proto.String("QUOTED"),
+		MentionedJID:    []string{"5511999999999@s.whatsapp.net"},
+		ForwardingScore: proto.Uint32(3),
+		IsForwarded:     proto.Bool(true),
+	}
+	cases := []struct {
+		name  string
+		src   *waE2E.Message
+		score uint32
+		check func(*waE2E.Message) bool
+	}{
+		{"texto simples", &waE2E.Message{Conversation: proto.String("oi")}, 1,
+			func(m *waE2E.Message) bool { return m.GetExtendedTextMessage().GetText() == "oi" }},
+		{"texto já encaminhado com citação", &waE2E.Message{ExtendedTextMessage: &waE2E.ExtendedTextMessage{
+			Text: proto.String("oi"), ContextInfo: quoted,
+		}}, 4,
+			func(m *waE2E.Message) bool { return m.GetExtendedTextMessage().GetText() == "oi" }},
+		{"imagem", &waE2E.Message{ImageMessage: &waE2E.ImageMessage{
+			Mimetype: proto.String("image/jpeg"), MediaKey: []byte("key"), ContextInfo: quoted,
+		}}, 4,
+			func(m *waE2E.Message) bool { return string(m.GetImageMessage().GetMediaKey()) == "key" }},
+		{"documento com legenda em conversa temporária", &waE2E.Message{EphemeralMessage: &waE2E.FutureProofMessage{
+			Message: &waE2E.Message{DocumentWithCaptionMessage: &waE2E.FutureProofMessage{
+				Message: &waE2E.Message{DocumentMessage: &waE2E.DocumentMessage{FileName: proto.String("a.pdf")}},
+			}},
+		}}, 1,
+			func(m *waE2E.Message) bool { return m.GetDocumentMessage().GetFileName() == "a.pdf" }},
+		{"envio próprio sincronizado", &waE2E.Message{DeviceSentMessage: &waE2E.DeviceSentMessage{
+			Message: &waE2E.Message{AudioMessage: &waE2E.AudioMessage{PTT: proto.Bool(true)}},
+		}}, 1,
+			func(m *waE2E.Message) bool { return m.GetAudioMessage().GetPTT() }},
+	}
+	for _, tc := range cases {
+		before := proto.Clone(tc.src)
+		out, score, err := forwardCopy(tc.src)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if score != tc.score || !tc.check(out) {
+			t.Errorf("%s: score %d (queria %d), mensagem %v", tc.name, score, tc.score, out)
+			continue
+		}
+		ctxInfo := contextInfoOf(out)
+		if !ctxInfo.GetIsForwarded() || ctxInfo.GetForwardingScore() != tc.score {
+			t.Errorf("%s: context info %v", tc.name, ctxInfo)
+		}
+		if ctxInfo.GetStanzaID() != "" || len(ctxInfo.GetMentionedJID()) != 0 {
+			t.Errorf("%s: citação e menções não deveriam ir junto: %v", tc.name, ctxInfo)
+		}
+		if !proto.Equal(before, tc.src) {
+			t.Errorf("%s: a mensagem de origem foi alterada", tc.name)
+		}
+	}
+}
+
+func contextInfoOf(m *waE2E.Message) *waE2E.ContextInfo {
+	switch {
+	case m.ExtendedTextMessage != nil:
+		return m.ExtendedTextMessage.GetContextInfo()
+	case m.ImageMessage != nil:
+		return m.ImageMessage.GetContextInfo()
+	case m.DocumentMessage != nil:
+		return m.DocumentMessage.GetContextInfo()
+	case m.AudioMessage != nil:
+		return m.AudioMessage.GetContextInfo()
+	}
+	return nil
+}
+
+func TestForwardCopyRefuses(t *testing.T) {
+	image := &waE2E.ImageMessage{Mimetype: proto.String("image/jpeg")}
+	cases := []struct {
+		name string
+		src  *waE2E.Message
+		want error
+	}{
+		{"view once v1", &waE2E.Message{ViewOnceMessage: &waE2E.FutureProofMessage{Message: &waE2E.Message{ImageMessage: image}}}, ErrForwardViewOnce},
+		{"view once v2", &waE2E.Message{ViewOnceMessageV2: &waE2E.FutureProofMessage{Message: &waE2E.Message{ImageMessage: image}}}, ErrForwardViewOnce},
+		{"view once de áudio", &waE2E.Message{ViewOnceMessageV2Extension: &waE2E.FutureProofMessage{Message: &waE2E.Message{AudioMessage: &waE2E.AudioMessage{}}}}, ErrForwardViewOnce},
+		{"mídia marcada view once", &waE2E.Message{VideoMessage: &waE2E.VideoMessage{ViewOnce: proto.Bool(true)}}, ErrForwardViewOnce},
+		{"view once dentro de conversa temporária", &waE2E.Message{EphemeralMessage: &waE2E.FutureProofMessage{
+			Message: &waE2E.Message{ImageMessage: &waE2E.ImageMessage{ViewOnce: proto.Bool(true)}},
+		}}, ErrForwardViewOnce},
+		{"reação", &waE2E.Message{ReactionMessage: &waE2E.ReactionMessage{Text: proto.String("👍")}}, ErrUnsupportedMediaType},
+	}
+	for _, tc := range cases {
+		if _, _, err := forwardCopy(tc.src); !errors.Is(err, tc.want) {
+			t.Errorf("%s: erro %v; queria %v", tc.name, err, tc.want)
+		}
+	}
+}
+
+func TestDescribeForward(t *testing.T) {
+	cases := []struct {
+		msg           *waE2E.Message
+		kind, payload string
+	}{
+		{&waE2E.Message{ExtendedTextMessage: &waE2E.ExtendedTextMessage{Text: proto.String("oi")}}, "text", "oi"},
+		{&waE2E.Message{ImageMessage: &waE2E.ImageMessage{Mimetype: proto.String("image/png")}}, "image", "media:image/png"},
+		{&waE2E.Message{PtvMessage: &waE2E.VideoMessage{Mimetype: proto.String("video/mp4")}}, "video", "media:video/mp4"},
+		{&waE2E.Message{AudioMessage: &waE2E.AudioMessage{Mimetype: proto.String("audio/ogg")}}, "audio", "audio:audio/ogg"},
+		{&waE2E.Message{DocumentMessage: &waE2E.DocumentMessage{FileName: proto.String("a.pdf"), Mimetype: proto.String("application/pdf")}}, "document", "document:a.pdf:application/pdf"},
+		{&waE2E.Message{ContactMessage: &waE2E.ContactMessage{DisplayName: proto.String("Ana")}}, "contact", "contact:Ana"},
+		{&waE2E.Message{LocationMessage: &waE2E.LocationMessage{DegreesLatitude: proto.Float64(-23.5), DegreesLongitude: proto.Float64(-46.6)}}, "location", "location:-23.500000,-46.600000"},
+		{&waE2E.Message{}, "forward", ""},
+	}
+	for _, tc := range cases {
+		if kind, payload := describeForward(tc.msg); kind != tc.kind || payload != tc.payload {
+			t.Errorf("%v: %s %q; queria %s %q", tc.msg, kind, payload, tc.kind, tc.payload)
+		}
+	}
+}
+
+func TestViewOnce(t *testing.T) {
+	image, err := viewOnce(&waE2E.Message{ImageMessage: &waE2E.ImageMessage{JPEGThumbnail: []byte("thumb")}})
+	if err != nil {
+		t.Fatalf("imagem: %v", err)
+	}
+	inner := image.GetViewOnceMessageV2().GetMessage().GetImageMessage()
+	if inner == nil || !inner.GetViewOnce() || inner.JPEGThumbnail != nil {
+		t.Fatalf("imagem deveria ir marcada, sem miniatura, em ViewOnceMessageV2: %v", image)
+	}
+
+	video, err := viewOnce(&waE2E.Message{VideoMessage: &waE2E.VideoMessage{}})
+	if err != nil || !video.GetViewOnceMessageV2().GetMessage().GetVideoMessage().GetViewOnce() {
+		t.Fatalf("vídeo deveria ir em ViewOnceMessageV2: %v %v", video, err)
+	}
+
+	// Audio goes in the extension wrapper, like the clients send it.
+	audio, err := viewOnce(&waE2E.Message{AudioMessage: &waE2E.AudioMessage{PTT: proto.Bool(true)}})
+	if err != nil || audio.GetViewOnceMessageV2() != nil || !audio.GetViewOnceMessageV2Extension().GetMessage().GetAudioMessage().GetViewOnce() {
+		t.Fatalf("áudio deveria ir em ViewOnceMessageV2Extension: %v %v", audio, err)
+	}
+
+	if _, err := viewOnce(&waE2E.Message{Conversation: proto.String("oi")}); !errors.Is(err, ErrInvalidPayload) {
+		t.Fatalf("texto não pode ser visualização única, veio %v", err)
+	}
+}
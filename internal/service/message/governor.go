@@ -0,0 +1,321 @@
+package message
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/open-apime/apime/internal/config"
+)
+
+// The send governor puts a volume budget on top of the per-message protections (cold-start wait,
+// humanized presence, reach-out guard): bans are triggered by how much and to whom a number sends,
+// not only by how each message looks. Its state lives in the shared cache, so budgets hold across
+// restarts and replicas.
+//
+// Counters are plain Get/Set, not atomic increments: Send already holds the per-instance lock
+// (shared through Redis between replicas), so there is never more than one writer per instance.
+
+// ErrSendBudgetExceeded is returned (wrapped in *BudgetError) when a send would exceed a budget.
+var ErrSendBudgetExceeded = errors.New("limite de envio da instância atingido")
+
+// BudgetError tells the caller which budget refused the send and when it frees up.
+type BudgetError struct {
+	Reason     string
+	RetryAfter time.Duration
+}
+
+func (e *BudgetError) Error() string {
+	return fmt.Sprintf("%s: %s; tente novamente em %s", ErrSendBudgetExceeded, e.Reason, e.RetryAfter.Round(time.Second))
+}
+
+func (e *BudgetError) Unwrap() error { return ErrSendBudgetExceeded }
+
+const (
+	cacheKeyGovernor = "governor:"
+
+	budgetFirstContact = "first_contact"
+	budgetOngoing      = "ongoing"
+)
+
+var (
+	governorMu  sync.RWMutex
+	governorCfg config.GovernorConfig
+)
+
+// SetGovernorConfig configures the send governor. Until it is called the governor is disabled.
+func SetGovernorConfig(cfg config.GovernorConfig) {
+	governorMu.Lock()
+	governorCfg = cfg
+	governorMu.Unlock()
+}
+
+func getGovernorConfig() config.GovernorConfig {
+	governorMu.RLock()
+	defer governorMu.RUnlock()
+	return governorCfg
+}
+
+type budgetWindow struct {
+	name   string
+	length time.Duration
+	limit  int
+}
+
+func budgetWindows(cfg config.GovernorConfig, class string) []budgetWindow {
+	if class == budgetFirstContact {
+		return []budgetWindow{
+			{"minuto", time.Minute, cfg.FirstContactPerMinute},
+			{"hora", time.Hour, cfg.FirstContactPerHour},
+			{"dia", 24 * time.Hour, cfg.FirstContactPerDay},
+		}
+	}
+	return []budgetWindow{
+		{"minuto", time.Minute, cfg.OngoingPerMinute},
+		{"hora", time.Hour, cfg.OngoingPerHour},
+		{"dia", 24 * time.Hour, cfg.OngoingPerDay},
+	}
+}
+
+// bucket returns the fixed window containing now and when it ends.
+func bucket(now time.Time, length time.Duration) (int64, time.Time) {
+	n := now.UnixNano() / int64(length)
+	return n, time.Unix(0, (n+1)*int64(length))
+}
+
+// scaleLimit applies the slow-down factor, never below 1 so a slowed instance still trickles.
+func scaleLimit(limit int, percent int) int {
+	if percent <= 0 || percent >= 100 {
+		return limit
+	}
+	scaled := limit * percent / 100
+	if scaled < 1 {
+		return 1
+	}
+	return scaled
+}
+
+// warmupLimit returns the distinct-new-contacts cap for a number paired `age` ago, and false once
+// the warm-up is over.
+func warmupLimit(cfg config.GovernorConfig, age time.Duration) (int, int, bool) {
+	if cfg.WarmupDays <= 0 || cfg.WarmupStartNewContacts <= 0 || age < 0 {
+		return 0, 0, false
+	}
+	day := int(age / (24 * time.Hour))
+	if day >= cfg.WarmupDays {
+		return 0, day, false
+	}
+	limit := float64(cfg.WarmupStartNewContacts) * math.Pow(1+float64(cfg.WarmupGrowthPercent)/100, float64(day))
+	return int(limit), day, true
+}
+
+func cacheInt(value string, ok bool) int {
+	if !ok {
+		return 0
+	}
+	n, _ := strconv.Atoi(value)
+	return n
+}
+
+// budgetReservation is one counter governSend bumped, with the value it had before.
+type budgetReservation struct {
+	key   string
+	count int
+	ends  time.Time
+}
+
+// governSend reserves budget for one send from instanceID to contactKey, or refuses it with a
+// *BudgetError. firstContact is decided by the caller (no recent inbound from an individual
+// contact). Cache failures let the send through: the governor must never take sending down.
+//
+// The returned refund gives the reservation back; Send calls it when the message doesn't go out,
+// still holding the instance lock, so only messages WhatsApp accepted spend budget.
+func governSend(instanceID, contactKey string, firstContact bool) (refund func(), err error) {
+	refund = func() {}
+	cfg := getGovernorConfig()
+	if !cfg.Enabled {
+		return refund, nil
+	}
+	ctx, cancel := cacheCtx()
+	defer cancel()
+	c := getCache()
+	now := time.Now()
+
+	class := budgetOngoing
+	if firstContact {
+		class = budgetFirstContact
+	}
+	percent := 100
+	slowReason, slowed := instanceSlowedDown(instanceID)
+	if slowed {
+		percent = cfg.SlowdownPercent
+	}
+
+	var reserve []budgetReservation
+
+	for _, w := range budgetWindows(cfg, class) {
+		if w.limit <= 0 {
+			continue
+		}
+		n, ends := bucket(now, w.length)
+		key := fmt.Sprintf("%scount:%s:%s:%d:%d", cacheKeyGovernor, instanceID, class, int64(w.length/time.Second), n)
+		value, ok, err := c.Get(ctx, key)
+		if err != nil {
+			return refund, nil
+		}
+		count := cacheInt(value, ok)
+		limit := scaleLimit(w.limit, percent)
+		if count >= limit {
+			return refund, &BudgetError{Reason: budgetReason(class, limit, w.name, slowReason), RetryAfter: ends.Sub(now)}
+		}
+		reserve = append(reserve, budgetReservation{key: key, count: count, ends: ends})
+	}
+
+	// Warm-up counts distinct new contacts per day, not messages: the second message to the same
+	// cold contact doesn't widen the number's reach.
+	if firstContact {
+		if paired, ok := pairedAt(instanceID); ok {
+			if limit, day, warming := warmupLimit(cfg, now.Sub(paired)); warming {
+				n, ends := bucket(now, 24*time.Hour)
+				seenKey := fmt.Sprintf("%snew:%s:%d:%s", cacheKeyGovernor, instanceID, n, contactKey)
+				if _, seen, err := c.Get(ctx, seenKey); err == nil && !seen {
+					countKey := fmt.Sprintf("%snew_count:%s:%d", cacheKeyGovernor, instanceID, n)
+					value, ok, err := c.Get(ctx, countKey)
+					if err != nil {
+						return refund, nil
+					}
+					count := cacheInt(value, ok)
+					limit = scaleLimit(limit, percent)
+					if count >= limit {
+						return refund, &BudgetError{
+							Reason:     fmt.Sprintf("aquecimento do número (dia %d de %d): %d novos contatos por dia", day+1, cfg.WarmupDays, limit),
+							RetryAfter: ends.Sub(now),
+						}
+					}
+					reserve = append(reserve,
+						budgetReservation{key: seenKey, count: 0, ends: ends},
+						budgetReservation{key: countKey, count: count, ends: ends})
+				}
+			}
+		}
+	}
+
+	for _, r := range reserve {
+		_ = c.Set(ctx, r.key, strconv.Itoa(r.count+1), r.ends.Sub(now))
+	}
+	return func() { refundBudget(reserve) }, nil
+}
+
+// refundBudget puts the counters back to their value before the reservation. Setting them, rather
+// than decrementing, is safe for the same reason the reservation is: the caller holds the lock.
+func refundBudget(reserve []budgetReservation) {
+	ctx, cancel := cacheCtx()
+	defer cancel()
+	c := getCache()
+	now := time.Now()
+	for _, r := range reserve {
+		if !now.Before(r.ends) {
+			continue // window over, the counter expired with it
+		}
+		if r.count == 0 {
+			_ = c.Delete(ctx, r.key)
+			continue
+		}
+		_ = c.Set(ctx, r.key, strconv.Itoa(r.count), r.ends.Sub(now))
+	}
+}
+
+func budgetReason(class string, limit int, window, slowReason string) string {
+	label := "conversas em andamento"
+	if class == budgetFirstContact {
+		label = "primeiro contato"
+	}
+	reason := fmt.Sprintf("%s: %d mensagens por %s", label, limit, window)
+	if slowReason != "" {
+		reason += " (limites reduzidos: " + slowReason + ")"
+	}
+	return reason
+}
+
+// instanceSlowedDown reports whether the instance is in the post-incident slow-down and why.
+func instanceSlowedDown(instanceID string) (string, bool) {
+	ctx, cancel := cacheCtx()
+	defer cancel()
+	value, ok, err := getCache().Get(ctx, cacheKeyGovernor+"slowdown:"+instanceID)
+	if err != nil || !ok {
+		return "", false
+	}
+	return value, true
+}
+
+// slowDown puts the instance in slow-down for the configured period after `after`, keeping a
+// longer one already in place.
+func slowDown(instanceID, reason string, after time.Duration) {
+	cfg := getGovernorConfig()
+	ttl := after + time.Duration(cfg.SlowdownHours)*time.Hour
+	if ttl <= 0 {
+		return
+	}
+	ctx, cancel := cacheCtx()
+	defer cancel()
+	c := getCache()
+	key := cacheKeyGovernor + "slowdown:" + instanceID
+	if current, ok, err := c.TTL(ctx, key); err == nil && ok && current > ttl {
+		return
+	}
+	_ = c.Set(ctx, key, reason, ttl)
+}
+
+// NoteTemporaryBan is called when WhatsApp temporarily bans the account: once the ban ends the
+// instance keeps reduced budgets for a while instead of resuming at full volume, which is what
+// usually turns a temporary ban into a permanent one.
+func NoteTemporaryBan(instanceID string, expire time.Duration) {
+	if expire < 0 {
+		expire = 0
+	}
+	slowDown(instanceID, "ban temporário", expire)
+}
+
+// noteReachoutStrike counts a 463 and slows the instance down when they pile up within a day.
+func noteReachoutStrike(instanceID string) {
+	cfg := getGovernorConfig()
+	if cfg.ReachoutStrikes <= 0 {
+		return
+	}
+	ctx, cancel := cacheCtx()
+	defer cancel()
+	c := getCache()
+	now := time.Now()
+	n, ends := bucket(now, 24*time.Hour)
+	key := fmt.Sprintf("%sstrikes:%s:%d", cacheKeyGovernor, instanceID, n)
+	value, ok, err := c.Get(ctx, key)
+	if err != nil {
+		return
+	}
+	count := cacheInt(value, ok) + 1
+	_ = c.Set(ctx, key, strconv.Itoa(count), ends.Sub(now))
+	if count == cfg.ReachoutStrikes {
+		slowDown(instanceID, fmt.Sprintf("%d erros 463 no dia", count), 0)
+	}
+}
+
+// NotePaired records when the instance paired a (new) number, which starts its warm-up.
+func NotePaired(instanceID string) {
+	ctx, cancel := cacheCtx()
+	defer cancel()
+	_ = getCache().Set(ctx, cacheKeyGovernor+"paired:"+instanceID, time.Now().UTC().Format(time.RFC3339), 0)
+}
+
+func pairedAt(instanceID string) (time.Time, bool) {
+	ctx, cancel := cacheCtx()
+	defer cancel()
+	value, ok, err := getCache().Get(ctx, cacheKeyGovernor+"paired:"+instanceID)
+	if err != nil || !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	return t, err == nil
+}
@@ -0,0 +1,97 @@
+package message
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/open-apime/apime/internal/config"
+	cache_memory "github.com/open-apime/apime/internal/pkg/cache/memory"
+)
+
+func TestWarmupLimitGrowsAndEnds(t *testing.T) {
+	cfg := config.GovernorConfig{WarmupDays: 3, WarmupStartNewContacts: 10, WarmupGrowthPercent: 50}
+
+	if limit, _, ok := warmupLimit(cfg, time.Hour); !ok || limit != 10 {
+		t.Fatalf("dia 1 deveria ter limite 10, veio %d %v", limit, ok)
+	}
+	if limit, _, ok := warmupLimit(cfg, 49*time.Hour); !ok || limit != 22 {
+		t.Fatalf("dia 3 deveria ter limite 22, veio %d %v", limit, ok)
+	}
+	if _, _, ok := warmupLimit(cfg, 73*time.Hour); ok {
+		t.Fatal("aquecimento deveria terminar após WarmupDays")
+	}
+}
+
+func TestGovernSendCapsFirstContactAndSlowsDown(t *testing.T) {
+	SetCache(cache_memory.New())
+	SetGovernorConfig(config.GovernorConfig{
+		Enabled:               true,
+		FirstContactPerMinute: 4,
+		OngoingPerMinute:      100,
+		SlowdownPercent:       50,
+		SlowdownHours:         1,
+	})
+	defer SetGovernorConfig(config.GovernorConfig{})
+
+	// Keep the sends inside one minute bucket.
+	if time.Now().Second() == 59 {
+		time.Sleep(time.Second)
+	}
+	for i := 0; i < 4; i++ {
+		if _, err := governSend("inst-g", "c", true); err != nil {
+			t.Fatalf("envio %d dentro do limite recusado: %v", i+1, err)
+		}
+	}
+	_, err := governSend("inst-g", "c", true)
+	var budgetErr *BudgetError
+	if !errors.As(err, &budgetErr) || !errors.Is(err, ErrSendBudgetExceeded) {
+		t.Fatalf("quinto primeiro contato deveria ser recusado, veio %v", err)
+	}
+	if budgetErr.RetryAfter <= 0 || budgetErr.RetryAfter > time.Minute {
+		t.Fatalf("retry-after fora da janela: %v", budgetErr.RetryAfter)
+	}
+	if _, err := governSend("inst-g", "c", false); err != nil {
+		t.Fatalf("conversa em andamento tem orçamento próprio: %v", err)
+	}
+
+	NoteTemporaryBan("inst-slow", 0)
+	for i := 0; i < 2; i++ {
+		if _, err := governSend("inst-slow", "c", true); err != nil {
+			t.Fatalf("envio %d dentro do limite reduzido recusado: %v", i+1, err)
+		}
+	}
+	if _, err := governSend("inst-slow", "c", true); !errors.Is(err, ErrSendBudgetExceeded) {
+		t.Fatalf("instância desacelerada deveria ter metade do limite, veio %v", err)
+	}
+}
+
+// TestGovernSendRefund: a send that never went out gives its budget back, including the warm-up
+// slot of a new contact.
+func TestGovernSendRefund(t *testing.T) {
+	SetCache(cache_memory.New())
+	SetGovernorConfig(config.GovernorConfig{
+		Enabled:                true,
+		FirstContactPerMinute:  1,
+		WarmupDays:             3,
+		WarmupStartNewContacts: 1,
+	})
+	defer SetGovernorConfig(config.GovernorConfig{})
+	NotePaired("inst-r")
+
+	if time.Now().Second() == 59 {
+		time.Sleep(time.Second)
+	}
+	refund, err := governSend("inst-r", "a", true)
+	if err != nil {
+		t.Fatalf("primeiro envio recusado: %v", err)
+	}
+	if _, err := governSend("inst-r", "b", true); !errors.Is(err, ErrSendBudgetExceeded) {
+		t.Fatalf("segundo envio deveria estourar o limite, veio %v", err)
+	}
+
+	refund()
+	if _, err := governSend("inst-r", "b", true); err != nil {
+		t.Fatalf("após o reembolso o envio deveria passar: %v", err)
+	}
+}
@@ -0,0 +1,217 @@
+package message
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+	"go.uber.org/zap"
+
+	"github.com/open-apime/apime/internal/storage/model"
+)
+
+// Operator view over the anti-ban caches. Reads go straight to the shared cache, so with Redis
+// every replica sees (and clears) the same state.
+
+// normalizeContactInput accepts a phone or a JID and returns the device-less key used by the
+// reach-out cache.
+func normalizeContactInput(contact string) string {
+	contact = strings.TrimSpace(contact)
+	if contact == "" {
+		return ""
+	}
+	if !strings.Contains(contact, "@") {
+		contact = digitsOnly(contact) + "@" + types.DefaultUserServer
+	}
+	return normalizeChatKey(contact)
+}
+
+// normalizePhoneInput mirrors the normalization ResolveJID applies before touching the cache.
+func normalizePhoneInput(phone string) string {
+	phone = strings.TrimSuffix(strings.TrimSpace(phone), "@"+types.DefaultUserServer)
+	if strings.Contains(phone, "@") {
+		return phone
+	}
+	return digitsOnly(phone)
+}
+
+func digitsOnly(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// ListReachoutLocks returns the active 463 blocks, optionally filtered by instance and/or contact.
+func (s *Service) ListReachoutLocks(ctx context.Context, instanceID, contact string) ([]model.ReachoutLock, error) {
+	c := getCache()
+	prefix := cacheKeyReachout
+	contactKey := normalizeContactInput(contact)
+	if contactKey != "" {
+		prefix += contactKey + ":"
+	}
+	keys, err := c.Keys(ctx, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("listar bloqueios de reach-out: %w", err)
+	}
+
+	entries, err := c.Entries(ctx, keys)
+	if err != nil {
+		return nil, fmt.Errorf("listar bloqueios de reach-out: %w", err)
+	}
+
+	locks := make([]model.ReachoutLock, 0, len(keys))
+	now := time.Now()
+	for _, key := range keys {
+		rest := strings.TrimPrefix(key, cacheKeyReachout)
+		// The contact JID never has ':' (device-less) while the instance ID is the last segment.
+		idx := strings.LastIndex(rest, ":")
+		if idx < 0 {
+			continue
+		}
+		lock := model.ReachoutLock{Contact: rest[:idx], InstanceID: rest[idx+1:]}
+		if instanceID != "" && lock.InstanceID != instanceID {
+			continue
+		}
+		entry, ok := entries[key]
+		if !ok {
+			continue
+		}
+		lock.ExpiresAt = now.Add(entry.TTL)
+		if blockedAt, err := time.Parse(time.RFC3339, entry.Value); err == nil {
+			lock.BlockedAt = &blockedAt
+		}
+		locks = append(locks, lock)
+	}
+	sort.Slice(locks, func(i, j int) bool { return locks[i].ExpiresAt.After(locks[j].ExpiresAt) })
+	return locks, nil
+}
+
+// ClearReachoutLock lifts the block of one instance towards a contact, as an inbound would.
+func (s *Service) ClearReachoutLock(ctx context.Context, instanceID, contact string) (bool, error) {
+	contactKey := normalizeContactInput(contact)
+	if instanceID == "" || contactKey == "" {
+		return false, fmt.Errorf("%w: instância e contato são obrigatórios", ErrInvalidJID)
+	}
+	key := reachoutKey(instanceID, contactKey)
+	reachoutForget(key)
+	_, ok, err := getCache().GetDel(ctx, key)
+	if err != nil {
+		return false, fmt.Errorf("remover bloqueio de reach-out: %w", err)
+	}
+	if ok {
+		s.log.Info("bloqueio de reach-out removido manualmente",
+			zap.String("instance_id", instanceID), zap.String("contact", contactKey))
+	}
+	return ok, nil
+}
+
+// ListJIDCache lists cached resolutions. kind filters by "positive" or "negative"; empty lists all.
+func (s *Service) ListJIDCache(ctx context.Context, kind string) ([]model.JIDCacheEntry, error) {
+	keys, err := getCache().Keys(ctx, cacheKeyJID)
+	if err != nil {
+		return nil, fmt.Errorf("listar cache de JID: %w", err)
+	}
+	cached, err := getCache().Entries(ctx, keys)
+	if err != nil {
+		return nil, fmt.Errorf("listar cache de JID: %w", err)
+	}
+	now := time.Now()
+	entries := make([]model.JIDCacheEntry, 0, len(keys))
+	for _, key := range keys {
+		c, ok := cached[key]
+		if !ok {
+			continue
+		}
+		entry, ok := jidCacheEntryView(strings.TrimPrefix(key, cacheKeyJID), c.Value, now.Add(c.TTL))
+		if !ok {
+			continue
+		}
+		if (kind == "positive" && entry.Negative) || (kind == "negative" && !entry.Negative) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Phone < entries[j].Phone })
+	return entries, nil
+}
+
+// LookupJID returns the cache entry and the persisted Contact row for a phone.
+func (s *Service) LookupJID(ctx context.Context, phone string) (model.JIDLookup, error) {
+	phone = normalizePhoneInput(phone)
+	if phone == "" {
+		return model.JIDLookup{}, fmt.Errorf("%w: telefone vazio", ErrInvalidJID)
+	}
+	out := model.JIDLookup{Phone: phone}
+	if entry, ok := jidCacheView(phone); ok {
+		out.Cache = &entry
+	}
+	if s.contactRepo != nil {
+		if contact, err := s.contactRepo.GetByPhone(ctx, phone); err == nil {
+			out.Contact = &contact
+		}
+	}
+	return out, nil
+}
+
+// PurgeJID drops the cached resolution of a phone, so the next send resolves it again. The Contact
+// row is kept: its UpdatedAt already bounds how long ResolveJID trusts it.
+func (s *Service) PurgeJID(ctx context.Context, phone string) (bool, error) {
+	phone = normalizePhoneInput(phone)
+	if phone == "" {
+		return false, fmt.Errorf("%w: telefone vazio", ErrInvalidJID)
+	}
+	_, ok, err := getCache().GetDel(ctx, cacheKeyJID+phone)
+	if err != nil {
+		return false, fmt.Errorf("remover cache de JID: %w", err)
+	}
+	return ok, nil
+}
+
+// PurgeJIDCache drops every cached resolution of the given kind ("positive", "negative" or empty
+// for all) and returns how many were removed.
+func (s *Service) PurgeJIDCache(ctx context.Context, kind string) (int, error) {
+	entries, err := s.ListJIDCache(ctx, kind)
+	if err != nil {
+		return 0, err
+	}
+	c := getCache()
+	removed := 0
+	for _, entry := range entries {
+		if err := c.Delete(ctx, cacheKeyJID+entry.Phone); err != nil {
+			return removed, fmt.Errorf("remover cache de JID: %w", err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+func jidCacheView(phone string) (model.JIDCacheEntry, bool) {
+	entry, ok := jidCacheLoad(phone)
+	if !ok {
+		return model.JIDCacheEntry{}, false
+	}
+	return jidCacheModel(phone, entry), true
+}
+
+// jidCacheEntryView builds the view from a value and expiry already read in bulk.
+func jidCacheEntryView(phone, value string, expiresAt time.Time) (model.JIDCacheEntry, bool) {
+	entry, ok := parseJIDCacheValue(value, expiresAt)
+	if !ok {
+		return model.JIDCacheEntry{}, false
+	}
+	return jidCacheModel(phone, entry), true
+}
+
+func jidCacheModel(phone string, entry jidCacheEntry) model.JIDCacheEntry {
+	view := model.JIDCacheEntry{Phone: phone, Negative: entry.jid.IsEmpty(), ExpiresAt: entry.expiresAt}
+	if !view.Negative {
+		view.JID = entry.jid.String()
+	}
+	return view
+}
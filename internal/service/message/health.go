@@ -0,0 +1,193 @@
+package message
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/open-apime/apime/internal/pkg/health"
+	"github.com/open-apime/apime/internal/storage/model"
+)
+
+// Health signals are daily counters in the shared cache, "health:<instanceID>:<signal>:<day>",
+// kept for two score windows so the report can compare the current one with the previous.
+// Reconnects are the exception: they are counted from the "connected" rows of the event log,
+// which already records every connection and survives a cache flush.
+//
+// Counting is Get/Set under a process mutex: signals come from session events and sends, which
+// only happen on the replica that owns the instance, so there is a single writer per instance.
+const (
+	cacheKeyHealth        = "health:"
+	cacheKeyHealthStarted = "health_started:"
+	healthTTL             = (2*health.WindowDays + 1) * 24 * time.Hour
+)
+
+var healthMu sync.Mutex
+
+func healthDay(t time.Time) int64 {
+	n, _ := bucket(t, 24*time.Hour)
+	return n
+}
+
+// NoteHealthSignal counts one occurrence of a health signal (health.Signal*) for the instance.
+func NoteHealthSignal(instanceID, signal string) {
+	ctx, cancel := cacheCtx()
+	defer cancel()
+	c := getCache()
+	key := fmt.Sprintf("%s%s:%s:%d", cacheKeyHealth, instanceID, signal, healthDay(time.Now()))
+
+	healthMu.Lock()
+	defer healthMu.Unlock()
+	value, ok, err := c.Get(ctx, key)
+	if err != nil {
+		return
+	}
+	_ = c.Set(ctx, key, strconv.Itoa(cacheInt(value, ok)+1), healthTTL)
+}
+
+// noteOutboundConversation counts a conversation the instance started with a cold contact, once
+// per contact within inboundTTL (the same horizon that makes a contact cold again).
+func noteOutboundConversation(instanceID, chatJID string) {
+	ctx, cancel := cacheCtx()
+	defer cancel()
+	key := cacheKeyHealthStarted + inboundKey(instanceID, chatJID)
+	if first, err := getCache().SetNX(ctx, key, "1", inboundTTL); err != nil || !first {
+		return
+	}
+	NoteHealthSignal(instanceID, health.SignalOutboundConversation)
+}
+
+// healthCounters loads the per-day signals of the instance, keyed by day number.
+func healthCounters(ctx context.Context, instanceID string) (map[int64]model.HealthSignals, error) {
+	c := getCache()
+	prefix := cacheKeyHealth + instanceID + ":"
+	keys, err := c.Keys(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := c.Entries(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	days := make(map[int64]model.HealthSignals)
+	for _, key := range keys {
+		signal, dayStr, found := strings.Cut(strings.TrimPrefix(key, prefix), ":")
+		if !found {
+			continue
+		}
+		day, err := strconv.ParseInt(dayStr, 10, 64)
+		if err != nil {
+			continue
+		}
+		entry, ok := entries[key]
+		s := days[day]
+		health.Add(&s, signal, cacheInt(entry.Value, ok))
+		days[day] = s
+	}
+	return days, nil
+}
+
+// InstanceHealth computes the health report of the instance's number over the last
+// health.WindowDays days, compared with the window before it. The daily trend carries, for each
+// day of the current window, that day's signals and the score of the window ending on it.
+func (s *Service) InstanceHealth(ctx context.Context, instanceID string) (model.InstanceHealth, error) {
+	days, err := healthCounters(ctx, instanceID)
+	if err != nil {
+		return model.InstanceHealth{}, err
+	}
+
+	now := time.Now()
+	today := healthDay(now)
+	if err := s.countReconnects(ctx, instanceID, days, today-2*health.WindowDays+1); err != nil {
+		return model.InstanceHealth{}, err
+	}
+	window := func(end int64) model.HealthSignals {
+		var total model.HealthSignals
+		for d := end - health.WindowDays + 1; d <= end; d++ {
+			total = health.Sum(total, days[d])
+		}
+		return total
+	}
+
+	current := window(today)
+	previous := window(today - health.WindowDays)
+	score, reasons := health.Score(current)
+	previousScore, _ := health.Score(previous)
+
+	report := model.InstanceHealth{
+		InstanceID:           instanceID,
+		Score:                score,
+		Risk:                 health.Risk(score),
+		PreviousScore:        previousScore,
+		Trend:                health.Trend(score, previousScore),
+		WindowDays:           health.WindowDays,
+		Signals:              current,
+		PreviousSignals:      previous,
+		OutboundInboundRatio: health.Ratio(current),
+		Reasons:              reasons,
+		GeneratedAt:          now.UTC(),
+	}
+	for d := today - health.WindowDays + 1; d <= today; d++ {
+		dayScore, _ := health.Score(window(d))
+		report.Daily = append(report.Daily, model.HealthDay{
+			Date:    time.Unix(0, d*int64(24*time.Hour)).UTC().Format("2006-01-02"),
+			Score:   dayScore,
+			Signals: days[d],
+		})
+	}
+	if reason, slowed := instanceSlowedDown(instanceID); slowed {
+		report.SlowedDown = reason
+	}
+	report.TemporaryBanReasons = s.recentBanReasons(ctx, instanceID, now.Add(-health.WindowDays*24*time.Hour))
+	return report, nil
+}
+
+// countReconnects fills the reconnects of each day since firstDay from the "connected" events of
+// the event log.
+func (s *Service) countReconnects(ctx context.Context, instanceID string, days map[int64]model.HealthSignals, firstDay int64) error {
+	if s.eventLogRepo == nil {
+		return nil
+	}
+	events, err := s.eventLogRepo.ListByTypeSince(ctx, instanceID, "connected", time.Unix(0, firstDay*int64(24*time.Hour)))
+	if err != nil {
+		return fmt.Errorf("contar reconexões: %w", err)
+	}
+	// Counters left in the cache by older versions would count the same connections twice.
+	for day, signals := range days {
+		signals.Reconnects = 0
+		days[day] = signals
+	}
+	for _, evt := range events {
+		day := healthDay(evt.CreatedAt)
+		signals := days[day]
+		health.Add(&signals, health.SignalReconnect, 1)
+		days[day] = signals
+	}
+	return nil
+}
+
+// recentBanReasons reads the reasons of the temporary bans since `since` from the event log.
+func (s *Service) recentBanReasons(ctx context.Context, instanceID string, since time.Time) []string {
+	if s.eventLogRepo == nil {
+		return nil
+	}
+	events, err := s.eventLogRepo.ListByTypeSince(ctx, instanceID, "temporary_ban", since)
+	if err != nil {
+		return nil
+	}
+	var reasons []string
+	for _, evt := range events {
+		var payload struct {
+			Reason string `json:"reason"`
+		}
+		if err := json.Unmarshal([]byte(evt.Payload), &payload); err != nil || payload.Reason == "" {
+			continue
+		}
+		reasons = append(reasons, fmt.Sprintf("%s: %s", evt.CreatedAt.UTC().Format("2006-01-02 15:04"), payload.Reason))
+	}
+	return reasons
+}
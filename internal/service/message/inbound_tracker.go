@@ -1,10 +1,12 @@
 package message
 
 import (
-	"sync"
+	"encoding/json"
 	"time"
 
 	"go.mau.fi/whatsmeow/types"
+
+	"github.com/open-apime/apime/internal/pkg/health"
 )
 
 const inboundTTL = 14 * 24 * time.Hour // 14 days
@@ -23,6 +25,12 @@ func normalizeChatKey(chatJID string) string {
 	return jid.ToNonAD().String()
 }
 
+// isIndividualChat tells 1:1 chats apart from groups, broadcasts and newsletters.
+func isIndividualChat(chatJID string) bool {
+	jid, err := types.ParseJID(chatJID)
+	return err == nil && (jid.Server == types.DefaultUserServer || jid.Server == types.HiddenUserServer)
+}
+
 // inboundEntry tracks the last inbound message per chat for auto MarkRead
 type inboundEntry struct {
 	messageID string
@@ -30,98 +38,92 @@ type inboundEntry struct {
 	trackedAt time.Time
 }
 
-var (
-	inboundTracker sync.Map // key: "instanceID:chatJID" → value: inboundEntry
-	// Timestamp of the last inbound message in the chat, surviving the tracker consumption.
-	//
-	// popLastInbound does LoadAndDelete because markread must happen only once. But the time since
-	// the contact.s last message is still needed after that: it is what lets us discount from the
-	// typing delay the wait the contact already had. Without it, the second message in a sequence
-	// would simulate the full typing again.
-	lastInboundAt sync.Map // key: "instanceID:chatJID" → value: time.Time
-	cleanupOnce   sync.Once
-)
+// inboundRecord is the cached form of inboundEntry.
+type inboundRecord struct {
+	MessageID string    `json:"m"`
+	SenderJID string    `json:"s"`
+	TrackedAt time.Time `json:"t"`
+}
+
+// Two keys per chat, both with inboundTTL:
+//   - "inbound:<instanceID>:<chatJID>" holds the entry consumed once by markread.
+//   - "inbound_at:<instanceID>:<chatJID>" holds the timestamp of the last inbound, surviving the
+//     tracker consumption.
+//
+// popLastInbound does GetDel because markread must happen only once. But the time since the
+// contact's last message is still needed after that: it is what lets us discount from the typing
+// delay the wait the contact already had. Without it, the second message in a sequence would
+// simulate the full typing again.
+func inboundKey(instanceID, chatJID string) string {
+	return instanceID + ":" + normalizeChatKey(chatJID)
+}
 
 // TrackInbound stores the last inbound message ID for a given chat.
 // Called by the event handler when a message is received.
 // The Send function uses this to auto-mark messages as read before sending.
 func TrackInbound(instanceID, chatJID, messageID, senderJID string) {
-	cleanupOnce.Do(startCleanupLoop)
-	key := instanceID + ":" + normalizeChatKey(chatJID)
+	key := inboundKey(instanceID, chatJID)
 	now := time.Now()
-	inboundTracker.Store(key, inboundEntry{
-		messageID: messageID,
-		senderJID: senderJID,
-		trackedAt: now,
-	})
-	lastInboundAt.Store(key, now)
+	data, err := json.Marshal(inboundRecord{MessageID: messageID, SenderJID: senderJID, TrackedAt: now})
+	if err != nil {
+		return
+	}
+	// A chat with no inbound within inboundTTL is a conversation the contact (re)opened.
+	if _, known := timeSinceLastInbound(instanceID, chatJID); !known && isIndividualChat(chatJID) {
+		NoteHealthSignal(instanceID, health.SignalInboundConversation)
+	}
+	ctx, cancel := cacheCtx()
+	defer cancel()
+	c := getCache()
+	_ = c.Set(ctx, cacheKeyInbound+key, string(data), inboundTTL)
+	_ = c.Set(ctx, cacheKeyInboundAt+key, now.Format(time.RFC3339Nano), inboundTTL)
 }
 
 // timeSinceLastInbound reports how long ago the contact last spoke in this chat.
-// Second return is false when there is no record (no known inbound in this run).
+// Second return is false when there is no record (no known inbound within inboundTTL).
 func timeSinceLastInbound(instanceID, chatJID string) (time.Duration, bool) {
-	value, ok := lastInboundAt.Load(instanceID + ":" + normalizeChatKey(chatJID))
-	if !ok {
+	ctx, cancel := cacheCtx()
+	defer cancel()
+	value, ok, err := getCache().Get(ctx, cacheKeyInboundAt+inboundKey(instanceID, chatJID))
+	if err != nil || !ok {
 		return 0, false
 	}
-	at, ok := value.(time.Time)
-	if !ok {
+	at, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
 		return 0, false
 	}
 	return time.Since(at), true
 }
 
-// popLastInbound returns and removes the last inbound message for a given chat.
-// Returns false if no inbound message is tracked or if the entry has expired.
-func popLastInbound(instanceID, chatJID string) (inboundEntry, bool) {
-	key := instanceID + ":" + normalizeChatKey(chatJID)
-	val, ok := inboundTracker.LoadAndDelete(key)
-	if !ok {
+func decodeInbound(value string) (inboundEntry, bool) {
+	var rec inboundRecord
+	if err := json.Unmarshal([]byte(value), &rec); err != nil {
 		return inboundEntry{}, false
 	}
-	entry := val.(inboundEntry)
-	if time.Since(entry.trackedAt) > inboundTTL {
+	if time.Since(rec.TrackedAt) > inboundTTL {
 		return inboundEntry{}, false
 	}
-	return entry, true
+	return inboundEntry{messageID: rec.MessageID, senderJID: rec.SenderJID, trackedAt: rec.TrackedAt}, true
 }
 
-// hasRecentInbound reports whether there is a tracked, still-valid inbound for the chat,
-// WITHOUT removing it (unlike popLastInbound). It serves as proof of an "already open
-// conversation": if the contact messaged us recently, an outgoing message is a reply, not
-// a new conversation.
-func hasRecentInbound(instanceID, chatJID string) bool {
-	key := instanceID + ":" + normalizeChatKey(chatJID)
-	val, ok := inboundTracker.Load(key)
-	if !ok {
-		return false
-	}
-	entry, ok := val.(inboundEntry)
-	if !ok {
-		return false
+// popLastInbound returns and removes the last inbound message for a given chat.
+// Returns false if no inbound message is tracked or if the entry has expired.
+func popLastInbound(instanceID, chatJID string) (inboundEntry, bool) {
+	ctx, cancel := cacheCtx()
+	defer cancel()
+	value, ok, err := getCache().GetDel(ctx, cacheKeyInbound+inboundKey(instanceID, chatJID))
+	if err != nil || !ok {
+		return inboundEntry{}, false
 	}
-	return time.Since(entry.trackedAt) <= inboundTTL
+	return decodeInbound(value)
 }
 
-func startCleanupLoop() {
-	go func() {
-		ticker := time.NewTicker(24 * time.Hour)
-		defer ticker.Stop()
-		for range ticker.C {
-			now := time.Now()
-			inboundTracker.Range(func(key, val any) bool {
-				if now.Sub(val.(inboundEntry).trackedAt) > inboundTTL {
-					inboundTracker.Delete(key)
-				}
-				return true
-			})
-			// Same TTL for the timestamp map: without this cleanup it would grow forever.
-			lastInboundAt.Range(func(key, val any) bool {
-				if at, ok := val.(time.Time); ok && now.Sub(at) > inboundTTL {
-					lastInboundAt.Delete(key)
-				}
-				return true
-			})
-		}
-	}()
+// hasRecentInbound reports whether the contact sent anything in this chat within inboundTTL. It
+// serves as proof of an "already open conversation": if the contact messaged us recently, an
+// outgoing message is a reply, not a new conversation. It reads the inbound_at timestamp, not the
+// tracker entry, because popLastInbound consumes the entry on the first reply and the conversation
+// is still open after that.
+func hasRecentInbound(instanceID, chatJID string) bool {
+	elapsed, ok := timeSinceLastInbound(instanceID, chatJID)
+	return ok && elapsed <= inboundTTL
 }
@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"math/rand"
 	"strings"
-	"sync"
 	"time"
 
 	"go.mau.fi/whatsmeow"
@@ -16,8 +15,6 @@ import (
 	"github.com/open-apime/apime/internal/storage/model"
 )
 
-var jidCache sync.Map
-
 // isTransportDown reports whether the error is the socket being unavailable rather than a verdict
 // about the number. whatsmeow wraps the cause with %w, so errors.Is reaches it through the layers.
 func isTransportDown(err error) bool {
@@ -42,6 +39,55 @@ type jidCacheEntry struct {
 	expiresAt time.Time
 }
 
+// The JID cache maps a phone to its resolved JID under "jid:<phone>". An empty value is a negative
+// entry (not on WhatsApp); the cache TTL carries the expiry.
+func jidCacheLoad(phone string) (jidCacheEntry, bool) {
+	ctx, cancel := cacheCtx()
+	defer cancel()
+	c := getCache()
+	value, ok, err := c.Get(ctx, cacheKeyJID+phone)
+	if err != nil || !ok {
+		return jidCacheEntry{}, false
+	}
+	ttl, ok, err := c.TTL(ctx, cacheKeyJID+phone)
+	if err != nil || !ok {
+		return jidCacheEntry{}, false
+	}
+	return parseJIDCacheValue(value, time.Now().Add(ttl))
+}
+
+func parseJIDCacheValue(value string, expiresAt time.Time) (jidCacheEntry, bool) {
+	entry := jidCacheEntry{jid: types.EmptyJID, expiresAt: expiresAt}
+	if value != "" {
+		jid, err := types.ParseJID(value)
+		if err != nil {
+			return jidCacheEntry{}, false
+		}
+		entry.jid = jid
+	}
+	return entry, true
+}
+
+func jidCacheStore(phone string, entry jidCacheEntry) {
+	ttl := time.Until(entry.expiresAt)
+	if ttl <= 0 {
+		return
+	}
+	value := ""
+	if !entry.jid.IsEmpty() {
+		value = entry.jid.String()
+	}
+	ctx, cancel := cacheCtx()
+	defer cancel()
+	_ = getCache().Set(ctx, cacheKeyJID+phone, value, ttl)
+}
+
+func jidCacheDelete(phone string) {
+	ctx, cancel := cacheCtx()
+	defer cancel()
+	_ = getCache().Delete(ctx, cacheKeyJID+phone)
+}
+
 func (s *Service) ResolveJID(ctx context.Context, client *whatsmeow.Client, phone string) (types.JID, error) {
 	phone = strings.TrimSpace(phone)
 
@@ -76,17 +122,13 @@ func (s *Service) ResolveJID(ctx context.Context, client *whatsmeow.Client, phon
 
 	phone = strings.TrimSuffix(phone, "@s.whatsapp.net")
 
-	if val, ok := jidCache.Load(phone); ok {
-		entry := val.(jidCacheEntry)
-		if time.Now().Before(entry.expiresAt) {
-			if entry.jid.IsEmpty() {
-				s.log.Debug("JID negativo (não está no WhatsApp) resolvido via cache", zap.String("phone", phone))
-				return types.EmptyJID, fmt.Errorf("%w: número não registrado no WhatsApp (cache)", ErrInvalidJID)
-			}
-			s.log.Debug("JID resolvido via cache em memória", zap.String("phone", phone), zap.String("jid", entry.jid.String()))
-			return entry.jid.ToNonAD(), nil
+	if entry, ok := jidCacheLoad(phone); ok {
+		if entry.jid.IsEmpty() {
+			s.log.Debug("JID negativo (não está no WhatsApp) resolvido via cache", zap.String("phone", phone))
+			return types.EmptyJID, fmt.Errorf("%w: número não registrado no WhatsApp (cache)", ErrInvalidJID)
 		}
-		jidCache.Delete(phone)
+		s.log.Debug("JID resolvido via cache", zap.String("phone", phone), zap.String("jid", entry.jid.String()))
+		return entry.jid.ToNonAD(), nil
 	}
 
 	if s.contactRepo != nil {
@@ -95,7 +137,7 @@ func (s *Service) ResolveJID(ctx context.Context, client *whatsmeow.Client, phon
 				negativeTTL := time.Duration(s.cfg.JIDCacheNegativeTTLDays) * 24 * time.Hour
 				if time.Since(contact.UpdatedAt) < negativeTTL {
 					s.log.Debug("JID negativo (não está no WhatsApp) resolvido via banco de dados", zap.String("phone", phone))
-					jidCache.Store(phone, jidCacheEntry{jid: types.EmptyJID, expiresAt: contact.UpdatedAt.Add(negativeTTL)})
+					jidCacheStore(phone, jidCacheEntry{jid: types.EmptyJID, expiresAt: contact.UpdatedAt.Add(negativeTTL)})
 					return types.EmptyJID, fmt.Errorf("%w: número não registrado no WhatsApp (DB cache)", ErrInvalidJID)
 				}
 			} else {
@@ -106,7 +148,7 @@ func (s *Service) ResolveJID(ctx context.Context, client *whatsmeow.Client, phon
 					if time.Since(contact.UpdatedAt) < positiveDBTTL {
 						s.log.Debug("JID resolvido via banco de dados", zap.String("phone", phone), zap.String("jid", jid.String()))
 						positiveTTL := time.Duration(s.cfg.JIDCachePositiveTTLHours) * time.Hour
-						jidCache.Store(phone, jidCacheEntry{jid: jid, expiresAt: time.Now().Add(positiveTTL)})
+						jidCacheStore(phone, jidCacheEntry{jid: jid, expiresAt: time.Now().Add(positiveTTL)})
 						return jid, nil
 					}
 					s.log.Info("JID positivo expirado no banco, revalidando via IsOnWhatsApp", zap.String("phone", phone), zap.String("oldJid", jid.String()), zap.Duration("age", time.Since(contact.UpdatedAt)))
@@ -189,7 +231,7 @@ func (s *Service) ResolveJID(ctx context.Context, client *whatsmeow.Client, phon
 	if resolvedJID.IsEmpty() {
 		negativeTTL := time.Duration(s.cfg.JIDCacheNegativeTTLDays) * 24 * time.Hour
 		s.log.Warn("WhatsApp não encontrado - registrando em cache negativo", zap.String("phone", phone), zap.Duration("ttl", negativeTTL))
-		jidCache.Store(phone, jidCacheEntry{jid: types.EmptyJID, expiresAt: time.Now().Add(negativeTTL)})
+		jidCacheStore(phone, jidCacheEntry{jid: types.EmptyJID, expiresAt: time.Now().Add(negativeTTL)})
 
 		if s.contactRepo != nil {
 			_ = s.contactRepo.Upsert(ctx, model.Contact{
@@ -202,7 +244,7 @@ func (s *Service) ResolveJID(ctx context.Context, client *whatsmeow.Client, phon
 	}
 
 	positiveTTL := time.Duration(s.cfg.JIDCachePositiveTTLHours) * time.Hour
-	jidCache.Store(phone, jidCacheEntry{jid: resolvedJID, expiresAt: time.Now().Add(positiveTTL)})
+	jidCacheStore(phone, jidCacheEntry{jid: resolvedJID, expiresAt: time.Now().Add(positiveTTL)})
 	if s.contactRepo != nil {
 		_ = s.contactRepo.Upsert(ctx, model.Contact{
 			Phone: phone,
@@ -227,18 +269,18 @@ func (s *Service) ConfirmJID(ctx context.Context, jid types.JID) {
 	jidStr := jid.String()
 
 	// Fast path: a valid positive cache entry with the same JID means nothing to do, no DB access.
-	if val, loaded := jidCache.Load(phone); loaded {
-		if entry, ok := val.(jidCacheEntry); ok && !entry.jid.IsEmpty() && entry.jid.String() == jidStr && time.Now().Before(entry.expiresAt) {
+	if entry, loaded := jidCacheLoad(phone); loaded {
+		if !entry.jid.IsEmpty() && entry.jid.String() == jidStr {
 			return
 		}
-		if entry, ok := val.(jidCacheEntry); ok && entry.jid.IsEmpty() {
+		if entry.jid.IsEmpty() {
 			s.log.Info("cache negativo invalidado por evento", zap.String("phone", phone))
 		}
-		jidCache.Delete(phone)
+		jidCacheDelete(phone)
 	}
 
 	positiveTTL := time.Duration(s.cfg.JIDCachePositiveTTLHours) * time.Hour
-	jidCache.Store(phone, jidCacheEntry{jid: jid, expiresAt: time.Now().Add(positiveTTL)})
+	jidCacheStore(phone, jidCacheEntry{jid: jid, expiresAt: time.Now().Add(positiveTTL)})
 
 	if s.contactRepo == nil {
 		return
@@ -0,0 +1,129 @@
+package message
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/open-apime/apime/internal/pkg/linkpreview"
+	"github.com/open-apime/apime/internal/pkg/thumbnail"
+)
+
+// linkThumbnailSide is the longest side of the uploaded link preview image. The inline
+// JPEGThumbnail is the usual small one; clients that download the uploaded image show it large.
+const linkThumbnailSide = 400
+
+// LinkPreview is a preview provided by the caller instead of fetched. URL defaults to the first
+// link of the text; Thumbnail is an image (JPEG, PNG or GIF).
+type LinkPreview struct {
+	URL         string
+	Title       string
+	Description string
+	Thumbnail   []byte
+}
+
+// SetLinkPreviewer enables fetching previews for the messages sent with LinkPreview. Without it
+// only caller-supplied previews are sent.
+func (s *Service) SetLinkPreviewer(f *linkpreview.Fetcher) {
+	s.linkPreviews = f
+}
+
+// fetchLinkPreview fetches the preview asked for with LinkPreview into input.Preview. Send calls
+// it before taking the instance lock: the fetch waits on a third-party site, and holding the lock
+// meanwhile would stall every other send of the instance. A failure leaves no preview.
+func (s *Service) fetchLinkPreview(ctx context.Context, input *SendInput) {
+	if input.Type != "text" || input.Preview != nil || !input.LinkPreview || s.linkPreviews == nil {
+		return
+	}
+	_, target := linkpreview.FirstURL(input.Text)
+	if target == "" {
+		return
+	}
+	preview, err := s.linkPreviews.Fetch(ctx, target)
+	if err != nil {
+		s.log.Debug("pré-visualização de link não gerada",
+			zap.String("instance_id", input.InstanceID),
+			zap.String("url", target),
+			zap.Error(err))
+		return
+	}
+	input.Preview = &LinkPreview{
+		URL:         preview.URL,
+		Title:       preview.Title,
+		Description: preview.Description,
+		Thumbnail:   preview.Image,
+	}
+}
+
+// applyLinkPreview fills msg with the preview of the first link of the text: the caller's one, or
+// the one fetchLinkPreview got. It reports whether a preview was added. Any failure leaves the
+// message as plain text — a preview is never a reason not to send.
+func (s *Service) applyLinkPreview(ctx context.Context, client *whatsmeow.Client, input SendInput, msg *waE2E.ExtendedTextMessage) bool {
+	if input.Preview == nil {
+		return false
+	}
+	matched, target := linkpreview.FirstURL(input.Text)
+	preview := linkpreview.Preview{
+		URL:         input.Preview.URL,
+		Title:       input.Preview.Title,
+		Description: input.Preview.Description,
+		Image:       input.Preview.Thumbnail,
+	}
+	if preview.URL == "" {
+		preview.URL = target
+	}
+	if preview.URL == "" || preview.Title == "" {
+		return false
+	}
+	if matched == "" {
+		matched = preview.URL
+	}
+
+	msg.MatchedText = proto.String(matched)
+	msg.Title = proto.String(preview.Title)
+	if preview.Description != "" {
+		msg.Description = proto.String(preview.Description)
+	}
+	msg.PreviewType = waE2E.ExtendedTextMessage_NONE.Enum()
+	if len(preview.Image) > 0 {
+		s.attachLinkThumbnail(ctx, client, input.InstanceID, preview.Image, msg)
+	}
+	return true
+}
+
+// attachLinkThumbnail sets the inline thumbnail and uploads the larger one. An image that can't be
+// decoded or uploaded is dropped; the title and description still go.
+func (s *Service) attachLinkThumbnail(ctx context.Context, client *whatsmeow.Client, instanceID string, image []byte, msg *waE2E.ExtendedTextMessage) {
+	small, err := thumbnail.FromImage(bytes.NewReader(image))
+	if err != nil {
+		s.log.Debug("imagem da pré-visualização de link ignorada",
+			zap.String("instance_id", instanceID),
+			zap.Error(err))
+		return
+	}
+	msg.JPEGThumbnail = small.JPEG
+
+	large, err := thumbnail.FromImageMax(bytes.NewReader(image), linkThumbnailSide)
+	if err != nil {
+		return
+	}
+	uploaded, err := client.Upload(ctx, large.JPEG, whatsmeow.MediaLinkThumbnail)
+	if err != nil {
+		s.log.Warn("erro ao enviar imagem da pré-visualização de link",
+			zap.String("instance_id", instanceID),
+			zap.Error(err))
+		return
+	}
+	msg.ThumbnailDirectPath = proto.String(uploaded.DirectPath)
+	msg.ThumbnailSHA256 = uploaded.FileSHA256
+	msg.ThumbnailEncSHA256 = uploaded.FileEncSHA256
+	msg.MediaKey = uploaded.MediaKey
+	msg.MediaKeyTimestamp = proto.Int64(time.Now().Unix())
+	msg.ThumbnailWidth = proto.Uint32(uint32(large.ThumbWidth))
+	msg.ThumbnailHeight = proto.Uint32(uint32(large.ThumbHeight))
+}
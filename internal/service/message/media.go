@@ -0,0 +1,312 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/proto/waMmsRetry"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/open-apime/apime/internal/storage"
+	"github.com/open-apime/apime/internal/storage/media"
+	"github.com/open-apime/apime/internal/storage/model"
+)
+
+var (
+	ErrMediaNotFound = errors.New("mídia não encontrada para esta mensagem")
+	// ErrMediaUnavailable: the CDN no longer has the file and the phone didn't upload it again
+	// (offline, media deleted, or no answer in time).
+	ErrMediaUnavailable = errors.New("mídia indisponível: o celular não reenviou o arquivo")
+	// ErrMediaTooLarge: the declared size is above the limit of its kind, so it isn't downloaded.
+	ErrMediaTooLarge = errors.New("mídia acima do limite de tamanho")
+)
+
+// mediaRetryTimeout is how long a download waits for the phone to answer a re-upload request.
+// The phone has to be online; past this the request fails instead of holding the HTTP call.
+const mediaRetryTimeout = 30 * time.Second
+
+// mediaRetries routes the phone's answers to re-upload requests to the downloads waiting on them,
+// keyed by instance and message ID. Concurrent downloads of the same message each wait, and the
+// answer goes to all of them.
+type mediaRetries struct {
+	mu      sync.Mutex
+	waiting map[string][]chan *events.MediaRetry
+}
+
+func (m *mediaRetries) wait(key string) (<-chan *events.MediaRetry, func()) {
+	ch := make(chan *events.MediaRetry, 1)
+	m.mu.Lock()
+	if m.waiting == nil {
+		m.waiting = make(map[string][]chan *events.MediaRetry)
+	}
+	m.waiting[key] = append(m.waiting[key], ch)
+	m.mu.Unlock()
+	return ch, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		waiters := m.waiting[key]
+		for i, w := range waiters {
+			if w == ch {
+				waiters = append(waiters[:i:i], waiters[i+1:]...)
+				break
+			}
+		}
+		if len(waiters) == 0 {
+			delete(m.waiting, key)
+		} else {
+			m.waiting[key] = waiters
+		}
+	}
+}
+
+func (m *mediaRetries) deliver(key string, evt *events.MediaRetry) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	waiters := m.waiting[key]
+	for _, ch := range waiters {
+		select {
+		case ch <- evt:
+		default:
+		}
+	}
+	return len(waiters) > 0
+}
+
+// SetMediaStore enables on-demand download of the media referenced in media_refs (instances with
+// the lazy policy, or eager ones whose file already expired).
+func (s *Service) SetMediaStore(mediaStorage *media.Storage, refs storage.MediaRefRepository) {
+	s.mediaStorage = mediaStorage
+	s.mediaRefs = refs
+}
+
+// SetMediaLimits caps on-demand downloads with the same per-kind limits as eager ones.
+func (s *Service) SetMediaLimits(limits media.Limits) {
+	s.mediaLimits = limits
+}
+
+// OpenMedia returns the media file of a received message, downloading and decrypting it on the
+// first call. Later calls serve the cached file until the media TTL removes it. The caller closes
+// the file.
+func (s *Service) OpenMedia(ctx context.Context, instanceID, messageID string) (*os.File, model.MediaRef, error) {
+	if s.mediaStorage == nil || s.mediaRefs == nil {
+		return nil, model.MediaRef{}, ErrMediaNotFound
+	}
+	ref, err := s.mediaRefs.Get(ctx, instanceID, messageID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, model.MediaRef{}, ErrMediaNotFound
+		}
+		return nil, model.MediaRef{}, err
+	}
+
+	if ref.MediaID != "" {
+		f, err := s.mediaStorage.Open(instanceID, ref.MediaID)
+		if err == nil {
+			return f, ref, nil
+		}
+		if !errors.Is(err, media.ErrNotFound) {
+			return nil, model.MediaRef{}, err
+		}
+	}
+
+	if s.sessionMgr == nil {
+		return nil, model.MediaRef{}, ErrInstanceNotConnected
+	}
+	client, err := s.sessionMgr.GetClient(instanceID)
+	if err != nil || client == nil {
+		return nil, model.MediaRef{}, ErrInstanceNotConnected
+	}
+
+	// Two concurrent first requests both download; they produce the same media ID and the rename
+	// in Keep makes the second one a no-op.
+	mediaID, err := s.downloadMediaRef(ctx, client, &ref)
+	if err != nil {
+		return nil, model.MediaRef{}, err
+	}
+	ref.MediaID = mediaID
+	if err := s.mediaRefs.Upsert(ctx, ref); err != nil {
+		s.log.Warn("erro ao salvar referência de mídia", zap.String("instance_id", instanceID), zap.String("message_id", messageID), zap.Error(err))
+	}
+
+	f, err := s.mediaStorage.Open(instanceID, mediaID)
+	if err != nil {
+		return nil, model.MediaRef{}, err
+	}
+	return f, ref, nil
+}
+
+// downloadMediaRef downloads the media of ref into the storage. Once the CDN drops the file
+// (404/410, usually after a couple of weeks) the phone is asked to upload it again and the
+// download is retried on the new path.
+func (s *Service) downloadMediaRef(ctx context.Context, client *whatsmeow.Client, ref *model.MediaRef) (string, error) {
+	msg, downloadable, err := decodeMediaRef(ref.Message)
+	if err != nil {
+		return "", err
+	}
+	// Checked against the size the sender declared, before anything is fetched.
+	if sized, ok := downloadable.(interface{ GetFileLength() uint64 }); ok && !s.mediaLimits.Allows(ref.Kind, int64(sized.GetFileLength())) {
+		return "", fmt.Errorf("%w: %d bytes, limite de %d para %s", ErrMediaTooLarge, sized.GetFileLength(), s.mediaLimits.Max(ref.Kind), ref.Kind)
+	}
+
+	mediaID, err := s.saveMedia(ctx, client, ref, downloadable)
+	if !errors.Is(err, whatsmeow.ErrMediaDownloadFailedWith404) && !errors.Is(err, whatsmeow.ErrMediaDownloadFailedWith410) {
+		return mediaID, err
+	}
+
+	s.log.Info("mídia expirada no CDN, solicitando reenvio ao celular",
+		zap.String("instance_id", ref.InstanceID),
+		zap.String("message_id", ref.MessageID),
+	)
+	directPath, err := s.requestMediaReupload(ctx, client, ref, downloadable.GetMediaKey())
+	if err != nil {
+		return "", err
+	}
+	setMediaDirectPath(msg, directPath)
+	if ref.Message, err = proto.Marshal(msg); err != nil {
+		return "", fmt.Errorf("serializar mídia: %w", err)
+	}
+	return s.saveMedia(ctx, client, ref, downloadable)
+}
+
+func (s *Service) saveMedia(ctx context.Context, client *whatsmeow.Client, ref *model.MediaRef, downloadable whatsmeow.DownloadableMessage) (string, error) {
+	spool, err := s.mediaStorage.Spool(ref.InstanceID)
+	if err != nil {
+		return "", err
+	}
+	if err := client.DownloadToFile(ctx, downloadable, spool); err != nil {
+		s.mediaStorage.Discard(spool)
+		return "", fmt.Errorf("baixar mídia: %w", err)
+	}
+	return s.mediaStorage.Keep(spool, ref.InstanceID, ref.MessageID, ref.Mimetype)
+}
+
+// requestMediaReupload sends the media retry receipt and waits for the phone's answer, which
+// arrives as an events.MediaRetry routed through HandleMediaRetry.
+func (s *Service) requestMediaReupload(ctx context.Context, client *whatsmeow.Client, ref *model.MediaRef, mediaKey []byte) (string, error) {
+	chat, err := types.ParseJID(ref.ChatJID)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidJID, err)
+	}
+	sender, err := types.ParseJID(ref.SenderJID)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidJID, err)
+	}
+
+	answer, stop := s.mediaRetries.wait(ref.InstanceID + ":" + ref.MessageID)
+	defer stop()
+
+	info := &types.MessageInfo{
+		MessageSource: types.MessageSource{
+			Chat:     chat,
+			Sender:   sender,
+			IsFromMe: ref.FromMe,
+			IsGroup:  chat.Server == types.GroupServer,
+		},
+		ID: ref.MessageID,
+	}
+	if err := client.SendMediaRetryReceipt(ctx, info, mediaKey); err != nil {
+		return "", fmt.Errorf("solicitar reenvio da mídia: %w", err)
+	}
+
+	var evt *events.MediaRetry
+	select {
+	case evt = <-answer:
+	case <-time.After(mediaRetryTimeout):
+		return "", ErrMediaUnavailable
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+
+	notification, err := whatsmeow.DecryptMediaRetryNotification(evt, mediaKey)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrMediaUnavailable, err)
+	}
+	if notification.GetResult() != waMmsRetry.MediaRetryNotification_SUCCESS || notification.GetDirectPath() == "" {
+		return "", fmt.Errorf("%w: %s", ErrMediaUnavailable, notification.GetResult())
+	}
+	return notification.GetDirectPath(), nil
+}
+
+// HandleMediaRetry hands the phone's answer to a re-upload request to the download waiting on it.
+func (s *Service) HandleMediaRetry(instanceID string, evt *events.MediaRetry) {
+	if !s.mediaRetries.deliver(instanceID+":"+string(evt.MessageID), evt) {
+		s.log.Debug("resposta de reenvio de mídia sem download aguardando",
+			zap.String("instance_id", instanceID),
+			zap.String("message_id", string(evt.MessageID)),
+		)
+	}
+}
+
+// StartMediaRefPruning deletes media references older than retention once an hour, until ctx is
+// done. Past the retention the media can't be downloaded through the API anymore.
+func (s *Service) StartMediaRefPruning(ctx context.Context, retention time.Duration) {
+	if s.mediaRefs == nil || retention <= 0 {
+		return
+	}
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		deleted, err := s.mediaRefs.DeleteOlderThan(ctx, time.Now().Add(-retention))
+		if err != nil {
+			s.log.Warn("erro ao remover referências de mídia antigas", zap.Error(err))
+		} else if deleted > 0 {
+			s.log.Info("referências de mídia antigas removidas", zap.Int64("deleted", deleted))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// decodeMediaRef reads the stored message, which holds only the media sub-message.
+func decodeMediaRef(data []byte) (*waE2E.Message, whatsmeow.DownloadableMessage, error) {
+	var msg waE2E.Message
+	if err := proto.Unmarshal(data, &msg); err != nil {
+		return nil, nil, fmt.Errorf("ler referência de mídia: %w", err)
+	}
+	switch {
+	case msg.GetImageMessage() != nil:
+		return &msg, msg.GetImageMessage(), nil
+	case msg.GetVideoMessage() != nil:
+		return &msg, msg.GetVideoMessage(), nil
+	case msg.GetPtvMessage() != nil:
+		return &msg, msg.GetPtvMessage(), nil
+	case msg.GetDocumentMessage() != nil:
+		return &msg, msg.GetDocumentMessage(), nil
+	case msg.GetAudioMessage() != nil:
+		return &msg, msg.GetAudioMessage(), nil
+	case msg.GetStickerMessage() != nil:
+		return &msg, msg.GetStickerMessage(), nil
+	}
+	return nil, nil, ErrMediaNotFound
+}
+
+// setMediaDirectPath points the media at the path of the re-upload. The old URL is dropped: it
+// would take precedence and is the one that already expired.
+func setMediaDirectPath(msg *waE2E.Message, directPath string) {
+	switch {
+	case msg.GetImageMessage() != nil:
+		msg.ImageMessage.DirectPath, msg.ImageMessage.URL = proto.String(directPath), nil
+	case msg.GetVideoMessage() != nil:
+		msg.VideoMessage.DirectPath, msg.VideoMessage.URL = proto.String(directPath), nil
+	case msg.GetPtvMessage() != nil:
+		msg.PtvMessage.DirectPath, msg.PtvMessage.URL = proto.String(directPath), nil
+	case msg.GetDocumentMessage() != nil:
+		msg.DocumentMessage.DirectPath, msg.DocumentMessage.URL = proto.String(directPath), nil
+	case msg.GetAudioMessage() != nil:
+		msg.AudioMessage.DirectPath, msg.AudioMessage.URL = proto.String(directPath), nil
+	case msg.GetStickerMessage() != nil:
+		msg.StickerMessage.DirectPath, msg.StickerMessage.URL = proto.String(directPath), nil
+	}
+}
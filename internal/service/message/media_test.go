@@ -0,0 +1,46 @@
+package message
+
+import (
+	"testing"
+
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// TestMediaRetriesFanOut: two downloads of the same message waiting at once both get the answer,
+// and one giving up doesn't take the other's place.
+func TestMediaRetriesFanOut(t *testing.T) {
+	var m mediaRetries
+	first, stopFirst := m.wait("inst:MSG")
+	second, stopSecond := m.wait("inst:MSG")
+	defer stopSecond()
+
+	evt := &events.MediaRetry{MessageID: "MSG"}
+	if !m.deliver("inst:MSG", evt) {
+		t.Fatalf("resposta deveria ter quem espera")
+	}
+	for i, ch := range []<-chan *events.MediaRetry{first, second} {
+		select {
+		case got := <-ch:
+			if got != evt {
+				t.Fatalf("espera %d recebeu outra resposta", i+1)
+			}
+		default:
+			t.Fatalf("espera %d não recebeu a resposta", i+1)
+		}
+	}
+
+	stopFirst()
+	if !m.deliver("inst:MSG", evt) {
+		t.Fatalf("a segunda espera ainda está ativa")
+	}
+	if got := <-second; got != evt {
+		t.Fatalf("segunda espera recebeu outra resposta")
+	}
+	stopSecond()
+	if m.deliver("inst:MSG", evt) {
+		t.Fatalf("sem ninguém esperando a resposta não deveria ser entregue")
+	}
+	if m.deliver("inst:OUTRA", evt) {
+		t.Fatalf("mensagem sem espera não deveria ser entregue")
+	}
+}
@@ -0,0 +1,206 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+
+	"github.com/google/uuid"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/open-apime/apime/internal/storage/model"
+)
+
+// ErrNotNewsletter: the target of a newsletter post isn't a channel JID (<id>@newsletter).
+var ErrNotNewsletter = errors.New("destino não é um canal (newsletter)")
+
+// NewsletterPostInput is a post published by the instance on a channel it administers. Type is
+// text, image, video, audio or document; Media, MediaSize and MediaType as in SendInput.
+type NewsletterPostInput struct {
+	InstanceID    string
+	NewsletterJID string
+	Type          string
+	Text          string
+	Media         io.ReadSeeker
+	MediaSize     int64
+	MediaType     string
+	Caption       string
+	FileName      string
+}
+
+// PublishNewsletter posts on a channel. Unlike Send there is no recipient lookup, reach-out guard,
+// presence or send budget: a channel post is a broadcast to followers who opted in, and its
+// content goes unencrypted — media is uploaded as plain newsletter media, referenced by the
+// upload handle. Only the channel admins can post; WhatsApp refuses the others.
+func (s *Service) PublishNewsletter(ctx context.Context, input NewsletterPostInput) (model.Message, error) {
+	if input.InstanceID == "" || input.NewsletterJID == "" {
+		return model.Message{}, ErrInvalidPayload
+	}
+	jid, err := types.ParseJID(strings.TrimSpace(input.NewsletterJID))
+	if err != nil || jid.Server != types.NewsletterServer {
+		return model.Message{}, ErrNotNewsletter
+	}
+	client, err := s.connectedClient(input.InstanceID)
+	if err != nil {
+		return model.Message{}, err
+	}
+
+	ctx, unlock, err := s.LockInstance(ctx, input.InstanceID)
+	if err != nil {
+		return model.Message{}, err
+	}
+	defer unlock()
+
+	waMessage, extra, payload, err := s.newsletterMessage(ctx, client, input)
+	if err != nil {
+		return model.Message{}, err
+	}
+
+	msg, err := s.repo.Create(ctx, model.Message{
+		ID:         uuid.NewString(),
+		InstanceID: input.InstanceID,
+		To:         jid.String(),
+		Type:       input.Type,
+		Payload:    payload,
+		Status:     "sending",
+	})
+	if err != nil {
+		return model.Message{}, fmt.Errorf("erro ao salvar mensagem: %w", err)
+	}
+
+	resp, err := client.SendMessage(ctx, jid, waMessage, extra)
+	if err != nil {
+		msg.Status = "failed"
+		_ = s.repo.Update(ctx, msg)
+		return msg, fmt.Errorf("erro ao publicar no canal: %w", err)
+	}
+	s.log.Info("publicação enviada ao canal",
+		zap.String("instance_id", input.InstanceID),
+		zap.String("newsletter", jid.String()),
+		zap.String("server_id", resp.ID))
+
+	msg.Status = "sent"
+	msg.WhatsAppID = resp.ID
+	if err := s.repo.Update(ctx, msg); err != nil {
+		s.log.Warn("erro ao atualizar status enviado no banco", zap.Error(err))
+	}
+	return msg, nil
+}
+
+// newsletterMessage builds the post, uploading the media first. It returns the message, the send
+// options carrying the media handle, and the payload the message is persisted with.
+func (s *Service) newsletterMessage(ctx context.Context, client *whatsmeow.Client, input NewsletterPostInput) (*waE2E.Message, whatsmeow.SendRequestExtra, string, error) {
+	var extra whatsmeow.SendRequestExtra
+	if input.Type == "text" {
+		if input.Text == "" {
+			return nil, extra, "", ErrInvalidPayload
+		}
+		return &waE2E.Message{ExtendedTextMessage: &waE2E.ExtendedTextMessage{Text: proto.String(input.Text)}}, extra, input.Text, nil
+	}
+	if input.Media == nil || input.MediaSize == 0 {
+		return nil, extra, "", ErrInvalidPayload
+	}
+
+	var mediaType whatsmeow.MediaType
+	switch input.Type {
+	case "image":
+		mediaType = whatsmeow.MediaImage
+	case "video":
+		mediaType = whatsmeow.MediaVideo
+	case "audio":
+		mediaType = whatsmeow.MediaAudio
+	case "document":
+		mediaType = whatsmeow.MediaDocument
+	default:
+		return nil, extra, "", ErrUnsupportedMediaType
+	}
+
+	// Same previews as a regular send; the thumbnail travels inline, so it needs no upload.
+	thumb, hasThumb, err := s.mediaThumbnail(SendInput{
+		InstanceID: input.InstanceID,
+		Type:       input.Type,
+		Media:      input.Media,
+		MediaType:  input.MediaType,
+	})
+	if err != nil {
+		return nil, extra, "", err
+	}
+
+	uploaded, err := client.UploadNewsletterReader(ctx, input.Media, mediaType)
+	if err != nil {
+		return nil, extra, "", fmt.Errorf("erro ao fazer upload da mídia: %w", err)
+	}
+	extra.MediaHandle = uploaded.Handle
+
+	var caption *string
+	if input.Caption != "" {
+		caption = proto.String(input.Caption)
+	}
+	msg := &waE2E.Message{}
+	payload := fmt.Sprintf("media:%s", input.MediaType)
+	switch input.Type {
+	case "image":
+		msg.ImageMessage = &waE2E.ImageMessage{
+			URL:        proto.String(uploaded.URL),
+			DirectPath: proto.String(uploaded.DirectPath),
+			FileSHA256: uploaded.FileSHA256,
+			FileLength: proto.Uint64(uploaded.FileLength),
+			Mimetype:   proto.String(input.MediaType),
+			Caption:    caption,
+		}
+		if hasThumb {
+			msg.ImageMessage.JPEGThumbnail = thumb.JPEG
+			msg.ImageMessage.Width = proto.Uint32(uint32(thumb.Width))
+			msg.ImageMessage.Height = proto.Uint32(uint32(thumb.Height))
+		}
+	case "video":
+		msg.VideoMessage = &waE2E.VideoMessage{
+			URL:        proto.String(uploaded.URL),
+			DirectPath: proto.String(uploaded.DirectPath),
+			FileSHA256: uploaded.FileSHA256,
+			FileLength: proto.Uint64(uploaded.FileLength),
+			Mimetype:   proto.String(input.MediaType),
+			Caption:    caption,
+		}
+	case "audio":
+		msg.AudioMessage = &waE2E.AudioMessage{
+			URL:        proto.String(uploaded.URL),
+			DirectPath: proto.String(uploaded.DirectPath),
+			FileSHA256: uploaded.FileSHA256,
+			FileLength: proto.Uint64(uploaded.FileLength),
+			Mimetype:   proto.String(input.MediaType),
+		}
+		payload = fmt.Sprintf("audio:%s", input.MediaType)
+	case "document":
+		fileName := input.FileName
+		if fileName == "" {
+			fileName = "document"
+			if exts, _ := mime.ExtensionsByType(input.MediaType); len(exts) > 0 {
+				fileName += exts[0]
+			}
+		}
+		msg.DocumentMessage = &waE2E.DocumentMessage{
+			URL:        proto.String(uploaded.URL),
+			DirectPath: proto.String(uploaded.DirectPath),
+			FileSHA256: uploaded.FileSHA256,
+			FileLength: proto.Uint64(uploaded.FileLength),
+			Mimetype:   proto.String(input.MediaType),
+			FileName:   proto.String(fileName),
+			Caption:    caption,
+		}
+		if hasThumb {
+			msg.DocumentMessage.JPEGThumbnail = thumb.JPEG
+			msg.DocumentMessage.ThumbnailWidth = proto.Uint32(uint32(thumb.ThumbWidth))
+			msg.DocumentMessage.ThumbnailHeight = proto.Uint32(uint32(thumb.ThumbHeight))
+		}
+		payload = fmt.Sprintf("document:%s:%s", fileName, input.MediaType)
+	}
+	return msg, extra, payload, nil
+}
@@ -0,0 +1,58 @@
+package message
+
+import (
+	"encoding/json"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+// The account's own about and business flag, shown by the instance info endpoint. Reading them is
+// a USync query, the kind WhatsApp watches on contact lookups, and that endpoint is polled by
+// dashboards, so the answer is kept in the shared cache. Changing the about through the API
+// forgets it right away, on every replica; a change made on the phone shows up once it expires.
+const ownInfoTTL = 10 * time.Minute
+
+// OwnInfo is what the USync query returns about the account itself.
+type OwnInfo struct {
+	About      string `json:"about"`
+	IsBusiness bool   `json:"isBusiness"`
+}
+
+// The key is the account JID, not the instance, so pairing another number doesn't reuse the entry.
+func ownInfoKey(jid types.JID) string {
+	return cacheKeyOwnInfo + jid.ToNonAD().String()
+}
+
+// CachedOwnInfo returns the cached info of the account, if any.
+func CachedOwnInfo(jid types.JID) (OwnInfo, bool) {
+	ctx, cancel := cacheCtx()
+	defer cancel()
+	value, ok, err := getCache().Get(ctx, ownInfoKey(jid))
+	if err != nil || !ok {
+		return OwnInfo{}, false
+	}
+	var info OwnInfo
+	if json.Unmarshal([]byte(value), &info) != nil {
+		return OwnInfo{}, false
+	}
+	return info, true
+}
+
+// StoreOwnInfo caches the info of the account for ownInfoTTL.
+func StoreOwnInfo(jid types.JID, info OwnInfo) {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return
+	}
+	ctx, cancel := cacheCtx()
+	defer cancel()
+	_ = getCache().Set(ctx, ownInfoKey(jid), string(data), ownInfoTTL)
+}
+
+// ForgetOwnInfo drops the cached info of the account. Called after the about changes.
+func ForgetOwnInfo(jid types.JID) {
+	ctx, cancel := cacheCtx()
+	defer cancel()
+	_ = getCache().Delete(ctx, ownInfoKey(jid))
+}
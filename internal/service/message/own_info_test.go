@@ -0,0 +1,32 @@
+package message
+
+import (
+	"testing"
+
+	"go.mau.fi/whatsmeow/types"
+
+	cache_memory "github.com/open-apime/apime/internal/pkg/cache/memory"
+)
+
+func TestOwnInfoCache(t *testing.T) {
+	SetCache(cache_memory.New())
+	device := types.JID{User: "5511999999999", Server: types.DefaultUserServer, Device: 7}
+	account := device.ToNonAD()
+
+	if _, ok := CachedOwnInfo(account); ok {
+		t.Fatalf("cache vazio não deveria ter a conta")
+	}
+	StoreOwnInfo(device, OwnInfo{About: "Disponível", IsBusiness: true})
+	info, ok := CachedOwnInfo(account)
+	if !ok || info.About != "Disponível" || !info.IsBusiness {
+		t.Fatalf("a entrada gravada pelo JID do aparelho deveria valer para a conta: %+v %v", info, ok)
+	}
+	if _, ok := CachedOwnInfo(types.NewJID("5511888888888", types.DefaultUserServer)); ok {
+		t.Fatalf("outra conta não deveria reusar a entrada")
+	}
+
+	ForgetOwnInfo(device)
+	if _, ok := CachedOwnInfo(account); ok {
+		t.Fatalf("a entrada deveria ter sido descartada")
+	}
+}
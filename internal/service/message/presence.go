@@ -169,7 +169,7 @@ func calculatePresenceDelay(input SendInput) time.Duration {
 		}
 	case "image", "video":
 		// Base delay + size factor + caption
-		sizeMB := len(input.MediaData) / (1024 * 1024)
+		sizeMB := int(input.MediaSize / (1024 * 1024))
 		base = 2000 + sizeMB*300
 		if base > 8000 {
 			base = 8000
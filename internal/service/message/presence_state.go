@@ -1,19 +1,12 @@
 package message
 
 import (
-	"strings"
-	"sync"
 	"time"
 )
 
 // Tracks what has already been signaled to WhatsApp, so we don't repeat a signal that still holds.
 // Besides the wasted round-trip on every send, an always-online presence is one of the patterns
 // automation detection looks at.
-var (
-	availableAt sync.Map // instanceID -> time.Time of the last `available`
-	composingAt sync.Map // instanceID + "|" + chatJID -> time.Time of the last `composing`
-)
-
 const (
 	// `available` is client state and holds until it's replaced or the connection drops. Drops are
 	// handled by event (see ForgetInstancePresence), so this TTL isn't the correctness guarantee —
@@ -35,49 +28,52 @@ func chatKey(instanceID, chatJID string) string {
 	return instanceID + "|" + chatJID
 }
 
-func expired(m *sync.Map, key string, ttl time.Duration) bool {
-	value, ok := m.Load(key)
-	if !ok {
-		return true
-	}
-	at, ok := value.(time.Time)
-	return !ok || time.Since(at) >= ttl
+// markIfExpired sets the mark only when it's absent (SetNX), so two replicas sending to the same
+// chat at once don't both resend the signal. A cache error answers true: sending a redundant
+// presence is harmless, skipping a needed one is not.
+func markIfExpired(key string, ttl time.Duration) bool {
+	ctx, cancel := cacheCtx()
+	defer cancel()
+	set, err := getCache().SetNX(ctx, key, "1", ttl)
+	return err != nil || set
 }
 
 // needsAvailable reports whether `available` is worth resending for this instance, marking the send.
 func needsAvailable(instanceID string) bool {
-	if !expired(&availableAt, instanceID, availableTTL) {
-		return false
-	}
-	availableAt.Store(instanceID, time.Now())
-	return true
+	return markIfExpired(cacheKeyAvailable+instanceID, availableTTL)
 }
 
 // needsComposing reports whether the typing indicator is worth reopening, marking the opening.
 func needsComposing(instanceID, chatJID string) bool {
-	key := chatKey(instanceID, chatJID)
-	if !expired(&composingAt, key, composingTTL) {
+	if !markIfExpired(cacheKeyComposing+chatKey(instanceID, chatJID), composingTTL) {
 		return false
 	}
-	composingAt.Store(key, time.Now())
+	ctx, cancel := cacheCtx()
+	defer cancel()
+	_ = getCache().AddToSet(ctx, cacheKeyComposingIndex+instanceID, chatJID, composingTTL)
 	return true
 }
 
 // forgetComposing is called when sending `paused`: the indicator is closed, so the next send has to
 // actually reopen it.
 func forgetComposing(instanceID, chatJID string) {
-	composingAt.Delete(chatKey(instanceID, chatJID))
+	ctx, cancel := cacheCtx()
+	defer cancel()
+	_ = getCache().Delete(ctx, cacheKeyComposing+chatKey(instanceID, chatJID))
 }
 
 // ForgetInstancePresence clears the state on connect/disconnect: after reconnecting, nothing
 // signaled on the previous session still holds on the other side.
 func ForgetInstancePresence(instanceID string) {
-	availableAt.Delete(instanceID)
-	prefix := instanceID + "|"
-	composingAt.Range(func(k, _ any) bool {
-		if key, ok := k.(string); ok && strings.HasPrefix(key, prefix) {
-			composingAt.Delete(key)
-		}
-		return true
-	})
+	ctx, cancel := cacheCtx()
+	defer cancel()
+	c := getCache()
+	_ = c.Delete(ctx, cacheKeyAvailable+instanceID)
+	chats, err := c.PopSet(ctx, cacheKeyComposingIndex+instanceID)
+	if err != nil {
+		return
+	}
+	for _, chatJID := range chats {
+		_ = c.Delete(ctx, cacheKeyComposing+chatKey(instanceID, chatJID))
+	}
 }
@@ -103,7 +103,7 @@ func TestPresenceCacheRespectsWindow(t *testing.T) {
 		t.Fatal("dentro da janela não deveria repetir")
 	}
 
-	composingAt.Delete(chatKey("inst-1", "chat-a"))
+	forgetComposing("inst-1", "chat-a")
 	if !needsComposing("inst-1", "chat-a") {
 		t.Fatal("primeira vez precisa abrir o indicador")
 	}
@@ -11,80 +11,91 @@ import (
 // the "cold and refusing" window.
 const reachoutNegTTL = 6 * time.Hour
 
-// reachoutNegCache implements the "smart" 463 block: GLOBAL on write, PER-CONNECTION on release.
+// The reach-out cache implements the "smart" 463 block: GLOBAL on write, PER-CONNECTION on release.
 // Each instance that hits 463 accumulates in the contact's set, so switching connections to retry
 // the same cold contact does not bypass the guard (that pattern is what got a device 403-logged
 // out). An inbound releases only the instance it arrived on, which is correct rather than merely
 // conservative: the tctoken is per-connection, so instance A still lacks it if the contact replied
 // to B.
 //
-// Key = "contactKey" (device-less PN via normalizeChatKey). Value = map[instanceID]expiry.
-var (
-	reachoutNegCache sync.Map // contactKey -> map[string]time.Time
-	reachoutNegMu    sync.Mutex
-	reachoutCleanup  sync.Once
-)
+// One key per (contact, instance): "reachout:<contactKey>:<instanceID>", contactKey being the
+// device-less PN from normalizeChatKey. The per-key TTL replaces the old cleanup loop.
+func reachoutKey(instanceID, contactKey string) string {
+	return cacheKeyReachout + contactKey + ":" + instanceID
+}
+
+// reachoutLocal mirrors the blocks this process stored or read, with their expiry. Unlike the
+// cosmetic caches, this guard must not fail open: a Redis outage would otherwise let the next send
+// hit the same cold contact again, which is the pattern that ends in a 403 logout.
+var reachoutLocal = struct {
+	sync.Mutex
+	until map[string]time.Time
+}{until: make(map[string]time.Time)}
+
+func reachoutRemember(key string, until time.Time) {
+	now := time.Now()
+	reachoutLocal.Lock()
+	defer reachoutLocal.Unlock()
+	for k, t := range reachoutLocal.until {
+		if !now.Before(t) {
+			delete(reachoutLocal.until, k)
+		}
+	}
+	reachoutLocal.until[key] = until
+}
+
+func reachoutForget(key string) {
+	reachoutLocal.Lock()
+	delete(reachoutLocal.until, key)
+	reachoutLocal.Unlock()
+}
 
 // reachoutBlocked reports whether sending from instanceID to contactKey is currently blocked by a
-// recent 463. Expired entries are cleaned lazily.
+// recent 463. When the cache can't be read, the local copy answers.
 func reachoutBlocked(instanceID, contactKey string) bool {
-	v, ok := reachoutNegCache.Load(contactKey)
-	if !ok {
-		return false
+	key := reachoutKey(instanceID, contactKey)
+	ctx, cancel := cacheCtx()
+	defer cancel()
+	value, ok, err := getCache().Get(ctx, key)
+	if err != nil {
+		reachoutLocal.Lock()
+		defer reachoutLocal.Unlock()
+		until, found := reachoutLocal.until[key]
+		return found && time.Now().Before(until)
 	}
-	reachoutNegMu.Lock()
-	defer reachoutNegMu.Unlock()
-	m, _ := v.(map[string]time.Time)
-	if m == nil {
-		return false
-	}
-	exp, ok := m[instanceID]
 	if !ok {
+		// Released, possibly on another replica.
+		reachoutForget(key)
 		return false
 	}
-	if time.Now().After(exp) {
-		delete(m, instanceID)
-		if len(m) == 0 {
-			reachoutNegCache.Delete(contactKey)
-		}
-		return false
+	until := time.Now().Add(reachoutNegTTL)
+	if blockedAt, err := time.Parse(time.RFC3339, value); err == nil {
+		until = blockedAt.Add(reachoutNegTTL)
 	}
+	reachoutRemember(key, until)
 	return true
 }
 
 // reachoutStore records a 463 block for (contactKey, instanceID). Global per contact, so any later
 // send from ANY instance that also hit 463 is barred; switching connections does not evade it.
 func reachoutStore(instanceID, contactKey string) {
-	reachoutCleanup.Do(startReachoutCleanup)
-	reachoutNegMu.Lock()
-	defer reachoutNegMu.Unlock()
-	v, _ := reachoutNegCache.LoadOrStore(contactKey, map[string]time.Time{})
-	m, _ := v.(map[string]time.Time)
-	if m == nil {
-		m = map[string]time.Time{}
-		reachoutNegCache.Store(contactKey, m)
-	}
-	m[instanceID] = time.Now().Add(reachoutNegTTL)
+	key := reachoutKey(instanceID, contactKey)
+	now := time.Now()
+	reachoutRemember(key, now.Add(reachoutNegTTL))
+	ctx, cancel := cacheCtx()
+	defer cancel()
+	_ = getCache().Set(ctx, key, now.UTC().Format(time.RFC3339), reachoutNegTTL)
 }
 
 // reachoutRelease unblocks ONLY the given instance for the contact (called on inbound). The contact
 // talked to this connection → this connection now has/will get the tctoken. Other connections stay
 // blocked until the contact talks to them too.
 func reachoutRelease(instanceID, contactKey string) {
-	v, ok := reachoutNegCache.Load(contactKey)
-	if !ok {
-		return
-	}
-	reachoutNegMu.Lock()
-	defer reachoutNegMu.Unlock()
-	m, _ := v.(map[string]time.Time)
-	if m == nil {
-		return
-	}
-	delete(m, instanceID)
-	if len(m) == 0 {
-		reachoutNegCache.Delete(contactKey)
-	}
+	key := reachoutKey(instanceID, contactKey)
+	reachoutForget(key)
+	ctx, cancel := cacheCtx()
+	defer cancel()
+	_ = getCache().Delete(ctx, key)
 }
 
 // ReleaseReachoutOnInbound is the public entry point called by the webhook layer when an inbound
@@ -93,29 +104,3 @@ func reachoutRelease(instanceID, contactKey string) {
 func ReleaseReachoutOnInbound(instanceID, chatJID string) {
 	reachoutRelease(instanceID, normalizeChatKey(chatJID))
 }
-
-func startReachoutCleanup() {
-	go func() {
-		ticker := time.NewTicker(24 * time.Hour)
-		defer ticker.Stop()
-		for range ticker.C {
-			now := time.Now()
-			reachoutNegCache.Range(func(key, val any) bool {
-				reachoutNegMu.Lock()
-				m, _ := val.(map[string]time.Time)
-				if m != nil {
-					for inst, exp := range m {
-						if now.After(exp) {
-							delete(m, inst)
-						}
-					}
-					if len(m) == 0 {
-						reachoutNegCache.Delete(key)
-					}
-				}
-				reachoutNegMu.Unlock()
-				return true
-			})
-		}
-	}()
-}
@@ -0,0 +1,65 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/open-apime/apime/internal/pkg/cache"
+	cache_memory "github.com/open-apime/apime/internal/pkg/cache/memory"
+)
+
+// downCache is a cache whose backend is unreachable.
+type downCache struct{ cache.Cache }
+
+var errCacheDown = errors.New("cache fora do ar")
+
+func (downCache) Get(context.Context, string) (string, bool, error) { return "", false, errCacheDown }
+func (downCache) Set(context.Context, string, string, time.Duration) error {
+	return errCacheDown
+}
+func (downCache) Delete(context.Context, string) error { return errCacheDown }
+
+// TestReachoutBlockSurvivesCacheOutage: a 463 block must hold while the cache is down, and an
+// inbound must still lift it.
+func TestReachoutBlockSurvivesCacheOutage(t *testing.T) {
+	shared := cache_memory.New()
+	SetCache(shared)
+	defer SetCache(cache_memory.New())
+
+	reachoutStore("inst-463", "5511999990000@s.whatsapp.net")
+	// Read once while the cache works, as another replica would after the store.
+	reachoutStore("inst-463b", "5511999990001@s.whatsapp.net")
+	reachoutForget(reachoutKey("inst-463b", "5511999990001@s.whatsapp.net"))
+	if !reachoutBlocked("inst-463b", "5511999990001@s.whatsapp.net") {
+		t.Fatalf("bloqueio gravado no cache deveria valer")
+	}
+
+	SetCache(downCache{})
+	if !reachoutBlocked("inst-463", "5511999990000@s.whatsapp.net") {
+		t.Fatalf("bloqueio gravado aqui deveria valer com o cache fora do ar")
+	}
+	if !reachoutBlocked("inst-463b", "5511999990001@s.whatsapp.net") {
+		t.Fatalf("bloqueio lido do cache deveria valer com o cache fora do ar")
+	}
+	if reachoutBlocked("inst-463", "5511000000000@s.whatsapp.net") {
+		t.Fatalf("contato sem bloqueio não deveria ficar bloqueado")
+	}
+
+	reachoutRelease("inst-463", "5511999990000@s.whatsapp.net")
+	if reachoutBlocked("inst-463", "5511999990000@s.whatsapp.net") {
+		t.Fatalf("mensagem recebida deveria liberar mesmo com o cache fora do ar")
+	}
+
+	// Back online: a block released elsewhere is dropped from the local copy too.
+	SetCache(shared)
+	_ = shared.Delete(context.Background(), reachoutKey("inst-463b", "5511999990001@s.whatsapp.net"))
+	if reachoutBlocked("inst-463b", "5511999990001@s.whatsapp.net") {
+		t.Fatalf("bloqueio removido no cache não deveria valer")
+	}
+	SetCache(downCache{})
+	if reachoutBlocked("inst-463b", "5511999990001@s.whatsapp.net") {
+		t.Fatalf("a cópia local deveria ter esquecido o bloqueio removido")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"math"
 	"math/rand"
 	"mime"
@@ -17,9 +18,12 @@ import (
 	"go.uber.org/zap"
 	"google.golang.org/protobuf/proto"
 
-	"github.com/open-apime/apime/internal/pkg/instancelock"
+	"github.com/open-apime/apime/internal/pkg/audio"
+	"github.com/open-apime/apime/internal/pkg/health"
+	"github.com/open-apime/apime/internal/pkg/ownership"
 	"github.com/open-apime/apime/internal/pkg/queue"
 	"github.com/open-apime/apime/internal/storage/model"
+	webhookPayload "github.com/open-apime/apime/internal/webhook/payload"
 )
 
 type ContactEntry struct {
@@ -28,16 +32,31 @@ type ContactEntry struct {
 }
 
 type SendInput struct {
-	InstanceID  string
-	To          string
-	Type        string
-	Text        string
-	MediaData   []byte
-	MediaType   string
-	Caption     string
-	FileName    string
-	Seconds     int
-	PTT         bool
+	InstanceID string
+	To         string
+	Type       string
+	Text       string
+	// LinkPreview fetches a preview of the first link of Text; Preview is sent as is instead.
+	LinkPreview bool
+	Preview     *LinkPreview
+	// Media is streamed to the WhatsApp upload (through a temp file for the encryption), so the
+	// file is never held in memory; MediaSize is its length in bytes.
+	Media     io.ReadSeeker
+	MediaSize int64
+	MediaType string
+	Caption   string
+	FileName  string
+	Seconds   int
+	PTT       bool
+	// ViewOnce sends image, video or audio as view-once media.
+	ViewOnce bool
+	// Forward is a prepared forwarded message (Type "forward"), see Forward.
+	Forward *waE2E.Message
+	// Thumbnail is a caller-provided preview (JPEG, PNG or GIF) for videos and documents, with
+	// the video's Width and Height. Images get theirs from the file.
+	Thumbnail   []byte
+	Width       int
+	Height      int
 	MessageID   string
 	Quoted      string
 	Participant string
@@ -98,7 +117,15 @@ func (s *Service) Send(ctx context.Context, input SendInput) (model.Message, err
 		return model.Message{}, ErrInvalidPayload
 	}
 
-	unlock := instancelock.Acquire(input.InstanceID)
+	s.fetchLinkPreview(ctx, &input)
+	if err := s.checkBlocked(ctx, input); err != nil {
+		return model.Message{}, err
+	}
+
+	ctx, unlock, err := s.LockInstance(ctx, input.InstanceID)
+	if err != nil {
+		return model.Message{}, err
+	}
 	defer unlock()
 
 	instance, err := s.instanceRepo.GetByID(ctx, input.InstanceID)
@@ -111,6 +138,10 @@ func (s *Service) Send(ctx context.Context, input SendInput) (model.Message, err
 	}
 
 	client, err := s.sessionMgr.GetClient(input.InstanceID)
+	if errors.Is(err, ownership.ErrNotOwner) {
+		// Another replica runs this session; the instance itself is fine.
+		return model.Message{}, err
+	}
 	if err != nil {
 		ctxUpdate := context.Background()
 		if instToUpdate, fetchErr := s.instanceRepo.GetByID(ctxUpdate, input.InstanceID); fetchErr == nil {
@@ -194,9 +225,6 @@ func (s *Service) Send(ctx context.Context, input SendInput) (model.Message, err
 		return model.Message{}, fmt.Errorf("falha ao resolver destinatário %s: %w", input.To, err)
 	}
 
-	// Ensure the recipient is in the account's contact list before sending (best-effort, non-blocking).
-	s.autoSaveContact(ctx, input.InstanceID, client, toJID, input.DisplayName)
-
 	// Reach-out (463) guard: if this contact already refused via this connection and hasn't
 	// replied, skip the send instead of triggering another 463 that feeds the 403-logout. Only
 	// individual contacts (groups/broadcast/newsletter have no per-contact tctoken).
@@ -221,7 +249,38 @@ func (s *Service) Send(ctx context.Context, input SendInput) (model.Message, err
 			}
 			return model.Message{}, ErrContactReachoutLocked
 		}
+		// The resolved JID may differ from the one checked before the lock (a number the JID cache
+		// didn't know yet); the list is already loaded by then.
+		if s.isBlocked(ctx, client, input.InstanceID, toJID) {
+			s.refuseBlocked(ctx, input, toJID)
+			return model.Message{}, ErrContactBlocked
+		}
+	}
+
+	// Volume budget. After the reach-out guard so a blocked contact doesn't spend budget, and before
+	// anything reaches WhatsApp (contact save, presence). Individual contacts without a recent
+	// inbound are first contacts; groups, broadcasts and newsletters count as ongoing.
+	individual := toJID.Server == types.DefaultUserServer || toJID.Server == types.HiddenUserServer
+	firstContact := individual && !hasRecentInbound(input.InstanceID, toJID.String())
+	refund, err := governSend(input.InstanceID, normalizeChatKey(toJID.String()), firstContact)
+	if err != nil {
+		s.log.Warn("envio recusado pelo governador de volume",
+			zap.String("instance_id", input.InstanceID),
+			zap.Bool("first_contact", firstContact),
+			zap.Error(err))
+		return model.Message{}, err
 	}
+	// Only a message WhatsApp accepted spends budget; every other way out of Send gives it back.
+	// Deferred after unlock, so it runs first, still under the lock.
+	sent := false
+	defer func() {
+		if !sent {
+			refund()
+		}
+	}()
+
+	// Ensure the recipient is in the account's contact list before sending (best-effort, non-blocking).
+	s.autoSaveContact(ctx, input.InstanceID, client, toJID, input.DisplayName)
 
 	// Typing simulation wait. Runs in parallel with message preparation and is awaited right before
 	// sending; with no presence (group, broadcast) there is nothing to wait for.
@@ -425,13 +484,13 @@ func (s *Service) Send(ctx context.Context, input SendInput) (model.Message, err
 		if input.Text == "" {
 			return model.Message{}, ErrInvalidPayload
 		}
+		extended := &waE2E.ExtendedTextMessage{Text: proto.String(input.Text)}
+		hasPreview := s.applyLinkPreview(ctx, client, input, extended)
 		if input.Quoted != "" || len(input.MentionedJids) > 0 {
-			waMessage = &waE2E.Message{
-				ExtendedTextMessage: &waE2E.ExtendedTextMessage{
-					Text:        proto.String(input.Text),
-					ContextInfo: buildContextInfo(input.Quoted, input.Participant, input.MentionedJids),
-				},
-			}
+			extended.ContextInfo = buildContextInfo(input.Quoted, input.Participant, input.MentionedJids)
+		}
+		if hasPreview || extended.ContextInfo != nil {
+			waMessage = &waE2E.Message{ExtendedTextMessage: extended}
 		} else {
 			waMessage = &waE2E.Message{
 				Conversation: proto.String(input.Text),
@@ -441,7 +500,7 @@ func (s *Service) Send(ctx context.Context, input SendInput) (model.Message, err
 		payload = input.Text
 
 	case "image", "video":
-		if len(input.MediaData) == 0 {
+		if input.Media == nil || input.MediaSize == 0 {
 			return model.Message{}, ErrInvalidPayload
 		}
 
@@ -452,7 +511,12 @@ func (s *Service) Send(ctx context.Context, input SendInput) (model.Message, err
 			mediaType = whatsmeow.MediaVideo
 		}
 
-		uploadResp, err := client.Upload(ctx, input.MediaData, mediaType)
+		thumb, hasThumb, err := s.mediaThumbnail(input)
+		if err != nil {
+			return model.Message{}, err
+		}
+
+		uploadResp, err := client.UploadReader(ctx, input.Media, nil, mediaType)
 		if err != nil {
 			return model.Message{}, fmt.Errorf("erro ao fazer upload da mídia: %w", err)
 		}
@@ -467,6 +531,11 @@ func (s *Service) Send(ctx context.Context, input SendInput) (model.Message, err
 				FileLength:    &uploadResp.FileLength,
 				Mimetype:      proto.String(input.MediaType),
 			}
+			if hasThumb {
+				imageMsg.JPEGThumbnail = thumb.JPEG
+				imageMsg.Width = proto.Uint32(uint32(thumb.Width))
+				imageMsg.Height = proto.Uint32(uint32(thumb.Height))
+			}
 			if input.Caption != "" {
 				imageMsg.Caption = proto.String(input.Caption)
 			}
@@ -486,6 +555,13 @@ func (s *Service) Send(ctx context.Context, input SendInput) (model.Message, err
 				FileLength:    &uploadResp.FileLength,
 				Mimetype:      proto.String(input.MediaType),
 			}
+			if hasThumb {
+				videoMsg.JPEGThumbnail = thumb.JPEG
+			}
+			if input.Width > 0 && input.Height > 0 {
+				videoMsg.Width = proto.Uint32(uint32(input.Width))
+				videoMsg.Height = proto.Uint32(uint32(input.Height))
+			}
 			if input.Caption != "" {
 				videoMsg.Caption = proto.String(input.Caption)
 			}
@@ -500,26 +576,45 @@ func (s *Service) Send(ctx context.Context, input SendInput) (model.Message, err
 		payload = fmt.Sprintf("media:%s", input.MediaType)
 
 	case "audio":
-		if len(input.MediaData) == 0 {
+		if input.Media == nil || input.MediaSize == 0 {
 			return model.Message{}, ErrInvalidPayload
 		}
 
-		uploadResp, err := client.Upload(ctx, input.MediaData, whatsmeow.MediaAudio)
+		isPTT := input.PTT
+		info, err := audio.Analyze(input.Media)
+		if err != nil {
+			s.log.Debug("não foi possível analisar o áudio", zap.String("codec", info.Codec), zap.Error(err))
+		}
+		if isPTT && info.Codec != audio.CodecOpus {
+			codec := info.Codec
+			if codec == "" {
+				codec = "desconhecido"
+			}
+			return model.Message{}, fmt.Errorf("%w (recebido: %s)", ErrUnsupportedPTTCodec, codec)
+		}
+		seconds := input.Seconds
+		if info.Duration > 0 {
+			seconds = int(math.Ceil(info.Duration.Seconds()))
+		}
+
+		uploadResp, err := client.UploadReader(ctx, input.Media, nil, whatsmeow.MediaAudio)
 		if err != nil {
 			return model.Message{}, fmt.Errorf("erro ao fazer upload do áudio: %w", err)
 		}
-		isPTT := input.PTT
 
 		var waveform []byte
 		var sidecar []byte
 
 		if isPTT {
-			waveform = generatePTTWaveform(input.Seconds)
+			waveform = info.Waveform
+			if waveform == nil {
+				waveform = generatePTTWaveform(seconds)
+			}
 			sidecar = make([]byte, 16)
 		}
 
 		finalMimeType := input.MediaType
-		if isPTT && strings.Contains(input.MediaType, "audio/ogg") {
+		if isPTT {
 			finalMimeType = "audio/ogg; codecs=opus"
 		}
 
@@ -535,7 +630,7 @@ func (s *Service) Send(ctx context.Context, input SendInput) (model.Message, err
 				Expiration: proto.Uint32(0),
 			},
 			PTT:               proto.Bool(isPTT),
-			Seconds:           proto.Uint32(uint32(input.Seconds)),
+			Seconds:           proto.Uint32(uint32(seconds)),
 			Waveform:          waveform,
 			StreamingSidecar:  sidecar,
 			MediaKeyTimestamp: proto.Int64(time.Now().Unix()),
@@ -553,11 +648,16 @@ func (s *Service) Send(ctx context.Context, input SendInput) (model.Message, err
 		payload = fmt.Sprintf("audio:%s", input.MediaType)
 
 	case "document":
-		if len(input.MediaData) == 0 {
+		if input.Media == nil || input.MediaSize == 0 {
 			return model.Message{}, ErrInvalidPayload
 		}
 
-		uploadResp, err := client.Upload(ctx, input.MediaData, whatsmeow.MediaDocument)
+		thumb, hasThumb, err := s.mediaThumbnail(input)
+		if err != nil {
+			return model.Message{}, err
+		}
+
+		uploadResp, err := client.UploadReader(ctx, input.Media, nil, whatsmeow.MediaDocument)
 		if err != nil {
 			return model.Message{}, fmt.Errorf("erro ao fazer upload do documento: %w", err)
 		}
@@ -582,6 +682,11 @@ func (s *Service) Send(ctx context.Context, input SendInput) (model.Message, err
 			Mimetype:      proto.String(input.MediaType),
 			FileName:      proto.String(fileName),
 		}
+		if hasThumb {
+			docMsg.JPEGThumbnail = thumb.JPEG
+			docMsg.ThumbnailWidth = proto.Uint32(uint32(thumb.ThumbWidth))
+			docMsg.ThumbnailHeight = proto.Uint32(uint32(thumb.ThumbHeight))
+		}
 		if input.Caption != "" {
 			docMsg.Caption = proto.String(input.Caption)
 		}
@@ -646,6 +751,13 @@ func (s *Service) Send(ctx context.Context, input SendInput) (model.Message, err
 		messageType = "contact"
 		payload = fmt.Sprintf("contact:%s", input.DisplayName)
 
+	case "forward":
+		if input.Forward == nil {
+			return model.Message{}, ErrInvalidPayload
+		}
+		waMessage = input.Forward
+		messageType, payload = describeForward(waMessage)
+
 	case "location":
 		locMsg := &waE2E.LocationMessage{
 			DegreesLatitude:  proto.Float64(input.Latitude),
@@ -668,6 +780,13 @@ func (s *Service) Send(ctx context.Context, input SendInput) (model.Message, err
 		return model.Message{}, fmt.Errorf("%w: %s", ErrUnsupportedMediaType, input.Type)
 	}
 
+	if input.ViewOnce {
+		waMessage, err = viewOnce(waMessage)
+		if err != nil {
+			return model.Message{}, err
+		}
+	}
+
 	var msg model.Message
 	if input.MessageID != "" {
 		msg.ID = input.MessageID
@@ -677,12 +796,15 @@ func (s *Service) Send(ctx context.Context, input SendInput) (model.Message, err
 		msg.Payload = payload
 		msg.Status = "sending"
 
-		if err := s.repo.Update(ctx, msg); err != nil {
-			s.log.Warn("erro ao atualizar status da mensagem existente, tentando criar nova", zap.Error(err))
-			msg, err = s.repo.Create(ctx, msg)
-			if err != nil {
-				return model.Message{}, fmt.Errorf("erro ao salvar mensagem: %w", err)
-			}
+		// The outbox is at-least-once: the same message can be redelivered (reclaimed after a
+		// crash, re-enqueued by the recovery pass). Only the worker that moves it out of 'queued'
+		// sends it.
+		claimed, err := s.repo.ClaimQueued(ctx, input.MessageID)
+		if err != nil {
+			return model.Message{}, fmt.Errorf("erro ao reservar mensagem: %w", err)
+		}
+		if !claimed {
+			return model.Message{}, ErrAlreadyProcessed
 		}
 	} else {
 		message := model.Message{
@@ -740,7 +862,7 @@ func (s *Service) Send(ctx context.Context, input SendInput) (model.Message, err
 				zap.String("server_id", resp.ID),
 				zap.Int64("timestamp", resp.Timestamp.Unix()))
 			if s.sessionMgr != nil {
-				s.sessionMgr.CacheOutgoingMessage(resp.ID, waMessage)
+				s.sessionMgr.CacheOutgoingMessage(input.InstanceID, resp.ID, waMessage)
 			}
 			break
 		}
@@ -773,6 +895,8 @@ func (s *Service) Send(ctx context.Context, input SendInput) (model.Message, err
 				zap.String("instance_id", input.InstanceID),
 				zap.String("to", toJID.String()))
 			reachoutStore(input.InstanceID, normalizeChatKey(toJID.String()))
+			noteReachoutStrike(input.InstanceID)
+			NoteHealthSignal(input.InstanceID, health.SignalReachoutError)
 			if s.eventLogRepo != nil {
 				go func() {
 					evtCtx, evtCancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -786,21 +910,26 @@ func (s *Service) Send(ctx context.Context, input SendInput) (model.Message, err
 				}()
 			}
 			if s.webhookQueue != nil {
-				evt := queue.Event{
-					ID:         uuid.NewString(),
-					InstanceID: input.InstanceID,
-					Type:       "contact_reachout_locked",
-					Payload: map[string]interface{}{
-						"type":   "contact_reachout_locked",
-						"reason": "server returned error 463",
-						"detail": "Contato frio sem tctoken; aguardando o contato iniciar conversa",
-						"to":     toJID.String(),
-						"code":   463,
-					},
-					CreatedAt: time.Now(),
-				}
-				if enqErr := s.webhookQueue.Enqueue(ctx, evt); enqErr != nil {
-					s.log.Warn("falha ao enfileirar webhook contact_reachout_locked", zap.Error(enqErr))
+				data, mapErr := webhookPayload.Map(&webhookPayload.ContactReachoutLocked{
+					Base:   webhookPayload.NewBase(webhookPayload.TypeContactReachoutLocked),
+					To:     toJID.String(),
+					Reason: "server returned error 463",
+					Detail: "Contato frio sem tctoken; aguardando o contato iniciar conversa",
+					Code:   463,
+				})
+				if mapErr != nil {
+					s.log.Warn("falha ao montar webhook contact_reachout_locked", zap.String("instance_id", input.InstanceID), zap.Error(mapErr))
+				} else {
+					evt := queue.Event{
+						ID:         uuid.NewString(),
+						InstanceID: input.InstanceID,
+						Type:       webhookPayload.TypeContactReachoutLocked,
+						Payload:    data,
+						CreatedAt:  time.Now(),
+					}
+					if enqErr := s.webhookQueue.Enqueue(ctx, evt); enqErr != nil {
+						s.log.Warn("falha ao enfileirar webhook contact_reachout_locked", zap.Error(enqErr))
+					}
 				}
 			}
 			break
@@ -864,7 +993,7 @@ func (s *Service) Send(ctx context.Context, input SendInput) (model.Message, err
 		// redo IsOnWhatsApp (a discovery query) for an already-known contact — a "prospecting"
 		// footprint that worsens the anti-spam heuristic. We keep the positive warm.
 		if !isTransientErr {
-			jidCache.Delete(input.To)
+			jidCacheDelete(input.To)
 			s.log.Info("Removido do cache de JID devido a erro não-transitório de envio", zap.String("phone", input.To))
 			if s.contactRepo != nil {
 				_ = s.contactRepo.Upsert(ctx, model.Contact{
@@ -889,11 +1018,15 @@ func (s *Service) Send(ctx context.Context, input SendInput) (model.Message, err
 		return msg, fmt.Errorf("erro ao enviar mensagem após %d tentativas: %w", maxRetries, err)
 	}
 
+	sent = true
 	msg.Status = "sent"
 	msg.WhatsAppID = resp.ID
 	if err := s.repo.Update(ctx, msg); err != nil {
 		s.log.Warn("erro ao atualizar status enviado no banco", zap.Error(err))
 	}
+	if firstContact {
+		noteOutboundConversation(input.InstanceID, toJID.String())
+	}
 
 	// `paused` closes the indicator: the cache must forget, otherwise the next send would trust a
 	// "typing" that is no longer open and would send with no signal at all.
@@ -902,3 +1035,23 @@ func (s *Service) Send(ctx context.Context, input SendInput) (model.Message, err
 
 	return msg, nil
 }
+
+// viewOnce wraps image, video and audio as view-once media, the way the clients send it: the media
+// flagged and wrapped in ViewOnceMessageV2 (ViewOnceMessageV2Extension for audio). The thumbnail
+// is dropped, since it would show the content before it's opened.
+func viewOnce(msg *waE2E.Message) (*waE2E.Message, error) {
+	switch {
+	case msg.GetImageMessage() != nil:
+		msg.ImageMessage.ViewOnce = proto.Bool(true)
+		msg.ImageMessage.JPEGThumbnail = nil
+		return &waE2E.Message{ViewOnceMessageV2: &waE2E.FutureProofMessage{Message: msg}}, nil
+	case msg.GetVideoMessage() != nil:
+		msg.VideoMessage.ViewOnce = proto.Bool(true)
+		msg.VideoMessage.JPEGThumbnail = nil
+		return &waE2E.Message{ViewOnceMessageV2: &waE2E.FutureProofMessage{Message: msg}}, nil
+	case msg.GetAudioMessage() != nil:
+		msg.AudioMessage.ViewOnce = proto.Bool(true)
+		return &waE2E.Message{ViewOnceMessageV2Extension: &waE2E.FutureProofMessage{Message: msg}}, nil
+	}
+	return nil, fmt.Errorf("%w: visualização única só vale para imagem, vídeo e áudio", ErrInvalidPayload)
+}
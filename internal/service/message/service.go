@@ -3,6 +3,7 @@ package message
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"go.mau.fi/whatsmeow"
@@ -11,8 +12,11 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/open-apime/apime/internal/config"
+	"github.com/open-apime/apime/internal/pkg/instancelock"
+	"github.com/open-apime/apime/internal/pkg/linkpreview"
 	"github.com/open-apime/apime/internal/pkg/queue"
 	"github.com/open-apime/apime/internal/storage"
+	"github.com/open-apime/apime/internal/storage/media"
 	"github.com/open-apime/apime/internal/storage/model"
 )
 
@@ -29,6 +33,15 @@ var (
 	// (whatsmeow's usync query needs the websocket). The request is fine and the number may well
 	// exist — only the lookup failed, so this is a RETRYABLE 503, not a 500.
 	ErrRecipientLookupUnavailable = errors.New("não foi possível validar o destinatário: conexão indisponível")
+	// ErrUnsupportedPTTCodec: the WhatsApp clients only play voice notes in Ogg/Opus; anything
+	// else arrives as a voice note nobody can listen to.
+	ErrUnsupportedPTTCodec = errors.New("áudio de voz (ptt) precisa ser OGG/Opus")
+	// ErrContactBlocked: the instance blocked the recipient. WhatsApp accepts the send and drops
+	// it silently, so it's refused here instead. Terminal until the contact is unblocked.
+	ErrContactBlocked = errors.New("contato bloqueado por esta instância")
+	// ErrAlreadyProcessed: an outbox message is no longer queued, because another worker took it
+	// or it already went out. Redeliveries of the queue end here instead of sending twice.
+	ErrAlreadyProcessed = errors.New("mensagem já processada")
 )
 
 type Service struct {
@@ -41,6 +54,12 @@ type Service struct {
 	webhookQueue queue.Queue
 	cfg          config.WhatsAppConfig
 	log          *zap.Logger
+	locker       instancelock.Locker
+	mediaStorage *media.Storage
+	mediaRefs    storage.MediaRefRepository
+	mediaLimits  media.Limits
+	mediaRetries mediaRetries
+	linkPreviews *linkpreview.Fetcher
 }
 
 type SessionManager interface {
@@ -49,15 +68,18 @@ type SessionManager interface {
 	HasSession(instanceID string, jid types.JID) (bool, error)
 	GetPreKeyCount(instanceID string) (int, error)
 	GetConnectedAt(instanceID string) time.Time
-	CacheOutgoingMessage(id string, msg *waE2E.Message)
+	CacheOutgoingMessage(instanceID, id string, msg *waE2E.Message)
+	// StoredMessage returns a recent sent or received message kept in memory, or nil.
+	StoredMessage(instanceID, id string) *waE2E.Message
 }
 
 func NewService(repo storage.MessageRepository, q queue.Queue, cfg config.WhatsAppConfig, log *zap.Logger) *Service {
 	return &Service{
-		repo:  repo,
-		queue: q,
-		cfg:   cfg,
-		log:   log,
+		repo:   repo,
+		queue:  q,
+		cfg:    cfg,
+		log:    log,
+		locker: instancelock.NewMemory(log),
 	}
 }
 
@@ -72,9 +94,30 @@ func NewServiceWithSession(repo storage.MessageRepository, sessionMgr SessionMan
 		webhookQueue: webhookQueue,
 		cfg:          cfg,
 		log:          log,
+		locker:       instancelock.NewMemory(log),
 	}
 }
 
+// SetInstanceLocker swaps the default in-process lock for a shared one, so
+// replicas serialize the same instance too.
+func (s *Service) SetInstanceLocker(l instancelock.Locker) {
+	if l != nil {
+		s.locker = l
+	}
+}
+
+// LockInstance serializes WhatsApp-facing actions of one instance. A failure
+// (ctx done, Redis down) is reported as ErrSessionUnavailable, a retryable 503.
+// The returned context is cancelled if the lock is lost midway; use it for the
+// guarded work.
+func (s *Service) LockInstance(ctx context.Context, instanceID string) (context.Context, func(), error) {
+	held, unlock, err := s.locker.Acquire(ctx, instanceID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: lock da instância: %v", ErrSessionUnavailable, err)
+	}
+	return held, unlock, nil
+}
+
 func (s *Service) List(ctx context.Context, instanceID string) ([]model.Message, error) {
 	return s.repo.ListByInstance(ctx, instanceID)
 }
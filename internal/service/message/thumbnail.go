@@ -0,0 +1,49 @@
+package message
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/open-apime/apime/internal/pkg/thumbnail"
+)
+
+// mediaThumbnail returns the preview of an outgoing media: generated from the file for images and
+// for PDFs with a JPEG page, or the caller's one for videos (decoding video isn't possible here)
+// and documents. A preview that can't be made isn't an error: the message goes without it, as
+// before. input.Media is left at the start; failing to rewind it is the only error.
+func (s *Service) mediaThumbnail(input SendInput) (thumbnail.Thumbnail, bool, error) {
+	var (
+		thumb thumbnail.Thumbnail
+		err   error
+	)
+	switch {
+	case input.Type == "image":
+		thumb, err = thumbnail.FromImage(input.Media)
+	case len(input.Thumbnail) > 0:
+		thumb, err = thumbnail.FromImage(bytes.NewReader(input.Thumbnail))
+		// The caller's thumbnail says nothing about the size of the media itself.
+		thumb.Width, thumb.Height = input.Width, input.Height
+	case input.Type == "document" && strings.HasPrefix(input.MediaType, "image/"):
+		thumb, err = thumbnail.FromImage(input.Media)
+	case input.Type == "document" && strings.HasPrefix(input.MediaType, "application/pdf"):
+		thumb, err = thumbnail.FromPDF(input.Media)
+	default:
+		return thumbnail.Thumbnail{}, false, nil
+	}
+	if _, seekErr := input.Media.Seek(0, io.SeekStart); seekErr != nil {
+		return thumbnail.Thumbnail{}, false, fmt.Errorf("erro ao ler mídia: %w", seekErr)
+	}
+	if err != nil {
+		s.log.Debug("miniatura não gerada",
+			zap.String("instance_id", input.InstanceID),
+			zap.String("type", input.Type),
+			zap.String("mimetype", input.MediaType),
+			zap.Error(err))
+		return thumbnail.Thumbnail{}, false, nil
+	}
+	return thumb, true, nil
+}
@@ -2,11 +2,15 @@ package message
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math/rand"
 	"sync"
 	"time"
 
+	"github.com/open-apime/apime/internal/pkg/ownership"
 	"github.com/open-apime/apime/internal/pkg/queue"
+	"github.com/open-apime/apime/internal/storage/model"
 	"go.uber.org/zap"
 )
 
@@ -18,6 +22,11 @@ type OutboxWorker struct {
 	wg         sync.WaitGroup
 	ctx        context.Context
 	cancel     context.CancelFunc
+
+	owners     ownership.Ownership
+	nodeQueue  func(nodeID string) queue.Queue
+	nodeMu     sync.Mutex
+	nodeQueues map[string]queue.Queue
 }
 
 func NewOutboxWorker(service *Service, q queue.Queue, log *zap.Logger, numWorkers int) *OutboxWorker {
@@ -32,13 +41,26 @@ func NewOutboxWorker(service *Service, q queue.Queue, log *zap.Logger, numWorker
 	}
 }
 
+// SetForwarding routes messages of instances owned by another replica to that
+// replica's own queue, built by nodeQueue, instead of back into the shared
+// outbox. The worker also consumes the queue of its own node. Call before Start.
+func (w *OutboxWorker) SetForwarding(owners ownership.Ownership, nodeQueue func(nodeID string) queue.Queue) {
+	w.owners = owners
+	w.nodeQueue = nodeQueue
+	w.nodeQueues = make(map[string]queue.Queue)
+}
+
 func (w *OutboxWorker) Start(ctx context.Context) {
 	w.ctx, w.cancel = context.WithCancel(ctx)
 	w.log.Info("outbox worker: iniciando", zap.Int("workers", w.numWorkers))
 
 	for i := 0; i < w.numWorkers; i++ {
 		w.wg.Add(1)
-		go w.runWorker(i + 1)
+		go w.runWorker(fmt.Sprintf("[outbox-worker %d]", i+1), w.queue)
+	}
+	if w.nodeQueue != nil {
+		w.wg.Add(1)
+		go w.runWorker("[outbox-worker node]", w.queueOf(w.owners.Self().ID))
 	}
 
 	w.wg.Add(1)
@@ -53,9 +75,8 @@ func (w *OutboxWorker) Stop() {
 	w.wg.Wait()
 }
 
-func (w *OutboxWorker) runWorker(id int) {
+func (w *OutboxWorker) runWorker(prefix string, q queue.Queue) {
 	defer w.wg.Done()
-	prefix := fmt.Sprintf("[outbox-worker %d]", id)
 	w.log.Info(prefix + ": iniciado")
 
 	for {
@@ -64,7 +85,7 @@ func (w *OutboxWorker) runWorker(id int) {
 			w.log.Info(prefix + ": parando")
 			return
 		default:
-			event, err := w.queue.Dequeue(w.ctx, 1*time.Second)
+			event, err := q.Dequeue(w.ctx, 1*time.Second)
 			if err != nil {
 				w.log.Error(prefix+": erro ao desenfileirar", zap.Error(err))
 				continue
@@ -74,12 +95,12 @@ func (w *OutboxWorker) runWorker(id int) {
 				continue
 			}
 
-			w.processEvent(prefix, event)
+			w.processEvent(prefix, q, event)
 		}
 	}
 }
 
-func (w *OutboxWorker) processEvent(prefix string, event *queue.Event) {
+func (w *OutboxWorker) processEvent(prefix string, q queue.Queue, event *queue.Event) {
 	w.log.Info(prefix+": processando mensagem da fila",
 		zap.String("id", event.ID),
 		zap.String("instance_id", event.InstanceID))
@@ -95,18 +116,150 @@ func (w *OutboxWorker) processEvent(prefix string, event *queue.Event) {
 		MessageID:  event.ID,
 	}
 
+	// A send can outlast the queue's visibility timeout (instance lock, cold-start stabilization,
+	// typing, retries); keep the entry ours meanwhile so no other replica reclaims it.
+	stopKeepAlive := queue.KeepAlive(q, event)
 	// We use service.Send here because it already has the retry loop and AUTO-TRUST
 	_, err := w.service.Send(w.ctx, input)
+	stopKeepAlive()
+	if errors.Is(err, ErrAlreadyProcessed) {
+		w.log.Debug(prefix+": mensagem já processada, descartando entrega repetida",
+			zap.String("id", event.ID),
+			zap.String("instance_id", event.InstanceID))
+		_ = q.Ack(context.Background(), event)
+		return
+	}
+	if errors.Is(err, ownership.ErrNotOwner) {
+		if w.forward(event) {
+			w.log.Debug(prefix+": instância pertence a outra réplica, mensagem encaminhada",
+				zap.String("id", event.ID),
+				zap.String("instance_id", event.InstanceID))
+			_ = q.Ack(context.Background(), event)
+			return
+		}
+		// Owner unknown (lease in transition): hand the message back after a
+		// pause so the replica that ends up owning it picks it up.
+		w.log.Debug(prefix+": dona da instância indisponível, devolvendo à fila",
+			zap.String("id", event.ID),
+			zap.String("instance_id", event.InstanceID))
+		select {
+		case <-w.ctx.Done():
+		case <-time.After(500 * time.Millisecond):
+		}
+		_ = q.Nack(context.Background(), event)
+		return
+	}
+	if errors.Is(err, ErrSendBudgetExceeded) {
+		// Not a failure: the message stays 'queued' and comes back once the budget frees up, without
+		// holding a worker meanwhile.
+		delay := budgetRetryDelay(err)
+		w.log.Info(prefix+": envio adiado pelo governador de volume",
+			zap.String("id", event.ID),
+			zap.String("instance_id", event.InstanceID),
+			zap.Duration("retry_in", delay),
+			zap.Error(err))
+		w.requeueAfter(q, event, delay)
+		_ = q.Ack(context.Background(), event)
+		return
+	}
 	if err != nil {
 		w.log.Error(prefix+": falha final ao enviar mensagem",
 			zap.String("id", event.ID),
 			zap.Error(err))
 	}
+	// Send persists the final status itself (sent or failed), so the queue
+	// entry is done either way.
+	_ = q.Ack(context.Background(), event)
+}
+
+// budgetRetryDelay is when a message refused by the governor is tried again: right after the
+// budget window ends, plus a few seconds so the new window has surely started.
+func budgetRetryDelay(err error) time.Duration {
+	delay := recoveryInterval
+	var budgetErr *BudgetError
+	if errors.As(err, &budgetErr) && budgetErr.RetryAfter > 0 {
+		delay = budgetErr.RetryAfter
+	}
+	return delay + time.Duration(rand.Int63n(int64(5*time.Second)))
+}
+
+// requeueAfter enqueues the event on q again after delay. The message stays 'queued' in the
+// database, so if this replica stops first the recovery pass takes it once the deferral mark
+// (shared through the cache) expires; until then the pass leaves it alone.
+func (w *OutboxWorker) requeueAfter(q queue.Queue, event *queue.Event, delay time.Duration) {
+	ctx, cancel := cacheCtx()
+	_ = getCache().Set(ctx, deferredKey(event.ID), "1", delay+recoveryMinQueued)
+	cancel()
+
+	retry := *event
+	retry.Receipt = ""
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-timer.C:
+		}
+		if err := q.Enqueue(w.ctx, retry); err != nil {
+			w.log.Warn("outbox worker: falha ao reenfileirar mensagem adiada",
+				zap.String("id", retry.ID),
+				zap.String("instance_id", retry.InstanceID),
+				zap.Error(err))
+		}
+	}()
+}
+
+func deferredKey(messageID string) string { return "outbox:deferred:" + messageID }
+
+// forward enqueues the event on the queue of the replica that owns its
+// instance. It reports false when forwarding is off or the owner is unknown.
+func (w *OutboxWorker) forward(event *queue.Event) bool {
+	if w.nodeQueue == nil {
+		return false
+	}
+	owner, ok, err := w.owners.Owner(w.ctx, event.InstanceID)
+	if err != nil || !ok || owner.ID == w.owners.Self().ID {
+		return false
+	}
+	fwd := *event
+	fwd.Receipt = ""
+	if err := w.queueOf(owner.ID).Enqueue(w.ctx, fwd); err != nil {
+		w.log.Warn("outbox worker: falha ao encaminhar mensagem para a réplica dona",
+			zap.String("id", event.ID),
+			zap.String("owner", owner.ID),
+			zap.Error(err))
+		return false
+	}
+	return true
+}
+
+func (w *OutboxWorker) queueOf(nodeID string) queue.Queue {
+	w.nodeMu.Lock()
+	defer w.nodeMu.Unlock()
+	q, ok := w.nodeQueues[nodeID]
+	if !ok {
+		q = w.nodeQueue(nodeID)
+		w.nodeQueues[nodeID] = q
+	}
+	return q
 }
 
+// recoveryLeaseKey makes the recovery pass run on one replica per tick: every replica sees the same
+// queued rows, and all of them re-enqueueing would multiply the queue entries.
+const (
+	recoveryLeaseKey  = "outbox:recovery"
+	recoveryInterval  = 30 * time.Second
+	recoveryMinQueued = time.Minute
+	recoveryBatch     = 50
+	recoveryMaxBatch  = 1000
+)
+
 func (w *OutboxWorker) runStuckRecovery() {
 	defer w.wg.Done()
-	ticker := time.NewTicker(30 * time.Second)
+	ticker := time.NewTicker(recoveryInterval)
 	defer ticker.Stop()
 
 	for {
@@ -114,18 +267,29 @@ func (w *OutboxWorker) runStuckRecovery() {
 		case <-w.ctx.Done():
 			return
 		case <-ticker.C:
-			messages, err := w.service.repo.GetPendingMessages(w.ctx, 50)
-			if err != nil {
-				w.log.Error("outbox recovery: erro ao buscar mensagens pendentes", zap.Error(err))
+			leaseCtx, cancel := cacheCtx()
+			won, err := getCache().SetNX(leaseCtx, recoveryLeaseKey, "1", recoveryInterval-time.Second)
+			cancel()
+			if err != nil || !won {
 				continue
 			}
 
-			if len(messages) == 0 {
+			messages, deferred, err := w.pendingMessages()
+			if err != nil {
+				w.log.Error("outbox recovery: erro ao buscar mensagens pendentes", zap.Error(err))
 				continue
 			}
 
-			w.log.Info("outbox recovery: recuperando mensagens pendentes do banco", zap.Int("count", len(messages)))
+			recovered := 0
 			for _, msg := range messages {
+				// A message queued a moment ago is most likely still in the queue, and one deferred by
+				// the governor comes back on its own.
+				if time.Since(msg.CreatedAt) < recoveryMinQueued {
+					continue
+				}
+				if deferred[msg.ID] {
+					continue
+				}
 				event := queue.Event{
 					ID:         msg.ID,
 					InstanceID: msg.InstanceID,
@@ -136,8 +300,48 @@ func (w *OutboxWorker) runStuckRecovery() {
 					},
 					CreatedAt: msg.CreatedAt,
 				}
-				_ = w.queue.Enqueue(w.ctx, event)
+				if err := w.queue.Enqueue(w.ctx, event); err == nil {
+					recovered++
+				}
 			}
+			if recovered > 0 {
+				w.log.Info("outbox recovery: recuperando mensagens pendentes do banco", zap.Int("count", recovered))
+			}
+		}
+	}
+}
+
+// pendingMessages reads the oldest queued messages and which of them the governor deferred. The
+// deferred ones are skipped by the pass, so the read widens by as many rows as it found of them:
+// a backlog of deferred messages must not hide one that was really lost.
+func (w *OutboxWorker) pendingMessages() ([]model.Message, map[string]bool, error) {
+	limit := recoveryBatch
+	for {
+		messages, err := w.service.repo.GetPendingMessages(w.ctx, limit)
+		if err != nil {
+			return nil, nil, err
+		}
+		keys := make([]string, len(messages))
+		for i, msg := range messages {
+			keys[i] = deferredKey(msg.ID)
+		}
+		ctx, cancel := cacheCtx()
+		entries, err := getCache().Entries(ctx, keys)
+		cancel()
+		deferred := make(map[string]bool, len(entries))
+		if err == nil {
+			for _, msg := range messages {
+				if _, ok := entries[deferredKey(msg.ID)]; ok {
+					deferred[msg.ID] = true
+				}
+			}
+		}
+		if len(deferred) == 0 || len(messages) < limit || limit >= recoveryMaxBatch {
+			return messages, deferred, nil
+		}
+		limit = min(recoveryBatch+len(deferred), recoveryMaxBatch)
+		if limit <= len(messages) {
+			return messages, deferred, nil
 		}
 	}
 }
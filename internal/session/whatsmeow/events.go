@@ -10,6 +10,8 @@ import (
 	"go.mau.fi/whatsmeow/types/events"
 	"go.uber.org/zap"
 
+	"github.com/open-apime/apime/internal/pkg/health"
+	"github.com/open-apime/apime/internal/service/message"
 	"github.com/open-apime/apime/internal/storage/model"
 )
 
@@ -29,15 +31,25 @@ func (m *Manager) handleEvent(instanceID string, evt any) {
 
 		switch evt.(type) {
 		case *events.Message, *events.Receipt, *events.Presence,
-			*events.UndecryptableMessage, *events.ChatPresence, *events.Contact:
+			*events.UndecryptableMessage, *events.ChatPresence, *events.Contact,
+			*events.NotifyAccountReachoutTimelock, *events.MediaRetry,
+			*events.Archive, *events.Pin, *events.Mute, *events.MarkChatAsRead, *events.ClearChat,
+			*events.DeleteChat, *events.Star, *events.DeleteForMe, *events.Blocklist:
 			// UndecryptableMessage covers view-once (stub without media) and desynced sessions;
 			// ChatPresence is the "typing…" indicator; Contact carries the appstate contact
 			// sync (incl. the WhatsApp @username, 2026). Without forwarding these, the
-			// normalizeEvent that handles them would be dead code.
+			// normalizeEvent that handles them would be dead code. MediaRetry answers the
+			// re-upload requests of on-demand media downloads. The app state chat actions
+			// (archive, pin, mute, read, clear, delete, star) become chat_update, and
+			// Blocklist feeds the send guard and blocklist_update.
 			go handler.Handle(context.Background(), instanceID, instanceJID, client, evt)
 		}
 	}
 
+	if msg, ok := evt.(*events.Message); ok {
+		m.storeReceivedMessage(instanceID, msg)
+	}
+
 	if receipt, ok := evt.(*events.Receipt); ok {
 		m.log.Debug("receipt recebido",
 			zap.String("instance_id", instanceID),
@@ -45,6 +57,7 @@ func (m *Manager) handleEvent(instanceID string, evt any) {
 			zap.Int("message_count", len(receipt.MessageIDs)))
 
 		if receipt.Type == types.ReceiptTypeRetry {
+			message.NoteHealthSignal(instanceID, health.SignalRetryReceipt)
 			m.log.Warn("RECEBIDO RETRY RECEIPT - Possível falha de decriptação no destino. Acionando reset proativo.",
 				zap.String("instance_id", instanceID),
 				zap.Strings("msg_ids", receipt.MessageIDs),
@@ -162,6 +175,7 @@ func (m *Manager) handleEvent(instanceID string, evt any) {
 			}()
 		}
 
+		// Also the reconnect count of the health score, read back from the event log.
 		m.logConnectionEvent(instanceID, "connected", `{"message":"Instância conectada ao WhatsApp"}`)
 
 		if callback != nil {
@@ -172,6 +186,10 @@ func (m *Manager) handleEvent(instanceID string, evt any) {
 			zap.String("instance_id", instanceID),
 			zap.String("user_jid", v.ID.String()),
 		)
+		// A freshly paired number starts its send warm-up now.
+		message.NotePaired(instanceID)
+		// The account may differ from the one paired before; its blocklist is loaded on the next send.
+		message.ForgetBlocklist(instanceID)
 		if callback != nil {
 			callback(instanceID, "active")
 		}
@@ -234,6 +252,8 @@ func (m *Manager) handleEvent(instanceID string, evt any) {
 			zap.String("reason", v.Reason.String()),
 		)
 
+		message.ForgetBlocklist(instanceID)
+
 		if !wasManual {
 			m.logConnectionEvent(instanceID, "logged_out", fmt.Sprintf(`{"message":"Dispositivo removido pelo WhatsApp","reason":"%s"}`, v.Reason.String()))
 		}
@@ -267,6 +287,10 @@ func (m *Manager) handleEvent(instanceID string, evt any) {
 			`{"message":"Ban temporário pelo WhatsApp","reason":"%s","code":%d,"expire":"%s"}`,
 			banReason, int(v.Code), expireStr,
 		))
+		// Fed from the session, not the webhook normalizer, so the governor slows the instance
+		// down even when no webhook is configured.
+		message.NoteHealthSignal(instanceID, health.SignalTemporaryBan)
+		message.NoteTemporaryBan(instanceID, v.Expire)
 
 		// The consumer needs to know about the ban: without this the connection stayed "connected"
 		// on their side while every send failed, and the reason lived only in our log.
@@ -279,6 +303,29 @@ func (m *Manager) handleEvent(instanceID string, evt any) {
 		if callback != nil {
 			callback(instanceID, "error")
 		}
+	case *events.NotifyAccountReachoutTimelock:
+		// Account-wide reach-out restriction (the 463s of the send path are per contact). Handled
+		// here rather than in the webhook normalizer so it counts even without a webhook.
+		if v.IsActive {
+			var remaining time.Duration
+			if !v.TimeEnforcementEnds.IsZero() {
+				remaining = time.Until(v.TimeEnforcementEnds.Time)
+			}
+			m.log.Warn("conta com restrição de reach-out ativa",
+				zap.String("instance_id", instanceID),
+				zap.Duration("remaining", remaining))
+			m.logConnectionEvent(instanceID, "reachout_timelock", fmt.Sprintf(
+				`{"message":"Restrição de reach-out na conta","reason":"account reachout timelock","expire":"%s"}`,
+				remaining.Round(time.Second),
+			))
+			message.NoteHealthSignal(instanceID, health.SignalReachoutTimelock)
+			message.NoteTemporaryBan(instanceID, remaining)
+		}
+	case *events.UndecryptableMessage:
+		// View-once stubs are undecryptable by design; only real decryption failures count.
+		if !v.Info.IsFromMe && !v.Info.IsGroup && v.UnavailableType != events.UnavailableTypeViewOnce {
+			message.NoteHealthSignal(instanceID, health.SignalUndecryptable)
+		}
 	case *events.ConnectFailure:
 		m.log.Error("falha ao conectar instância",
 			zap.String("instance_id", instanceID),
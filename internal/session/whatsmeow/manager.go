@@ -17,6 +17,8 @@ import (
 	"go.uber.org/zap"
 	"google.golang.org/protobuf/proto"
 
+	"github.com/open-apime/apime/internal/pkg/crypto"
+	"github.com/open-apime/apime/internal/pkg/ownership"
 	"github.com/open-apime/apime/internal/pkg/sentryx"
 	"github.com/open-apime/apime/internal/storage"
 )
@@ -69,7 +71,7 @@ type Manager struct {
 	sessionReady       map[string]bool
 	mu                 sync.RWMutex
 	log                *zap.Logger
-	encKey             string
+	keys               *crypto.KeyRing
 	storageDriver      string
 	baseDir            string
 	pgConnString       string
@@ -85,9 +87,11 @@ type Manager struct {
 	messageRepo        storage.MessageRepository
 	sharedContainer    *sqlstore.Container
 	outgoingMsgCache   sync.Map // msgID -> outgoingMsgEntry, for retry of any type (including media)
+	receivedMsgs       sync.Map // instanceID -> *InMemMessageStore, recent inbound messages for forwarding
+	ownership          ownership.Ownership
 }
 
-func NewManager(log *zap.Logger, encKey, storageDriver, baseDir, pgConnString string, instanceRepo storage.InstanceRepository, historySyncRepo storage.HistorySyncRepository, messageRepo storage.MessageRepository, eventLogRepo storage.EventLogRepository) *Manager {
+func NewManager(log *zap.Logger, keys *crypto.KeyRing, storageDriver, baseDir, pgConnString string, instanceRepo storage.InstanceRepository, historySyncRepo storage.HistorySyncRepository, messageRepo storage.MessageRepository, eventLogRepo storage.EventLogRepository) *Manager {
 	// Limit history sync to RECENT data (~3 days). Without a limit (the default), the phone
 	// tries to prepare the ENTIRE history at pairing time, hanging the QR "forever". Requesting
 	// only recent data keeps the sync small and fast (releases the QR immediately) and runs in
@@ -130,7 +134,7 @@ func NewManager(log *zap.Logger, encKey, storageDriver, baseDir, pgConnString st
 		pairingSuccess:     make(map[string]time.Time),
 		sessionReady:       make(map[string]bool),
 		log:                log,
-		encKey:             encKey,
+		keys:               keys,
 		storageDriver:      storageDriver,
 		baseDir:            baseDir,
 		pgConnString:       pgConnString,
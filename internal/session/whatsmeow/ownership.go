@@ -0,0 +1,82 @@
+package whatsmeow
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/open-apime/apime/internal/pkg/ownership"
+)
+
+// SetOwnership enables multi-replica coordination. Without it the manager
+// behaves as a single replica and connects any instance it is asked for.
+func (m *Manager) SetOwnership(o ownership.Ownership) {
+	m.mu.Lock()
+	m.ownership = o
+	m.mu.Unlock()
+	o.OnLost(m.dropLocalSession)
+}
+
+// claimInstance must succeed before a device is connected: two replicas
+// holding the same device would make WhatsApp kick both of them. It is a Redis
+// round trip, so callers must not hold m.mu.
+func (m *Manager) claimInstance(ctx context.Context, instanceID string) error {
+	m.mu.RLock()
+	o := m.ownership
+	m.mu.RUnlock()
+	if o == nil {
+		return nil
+	}
+	ok, err := o.Claim(ctx, instanceID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ownership.ErrNotOwner
+	}
+	return nil
+}
+
+func (m *Manager) releaseInstance(instanceID string) {
+	m.mu.RLock()
+	o := m.ownership
+	m.mu.RUnlock()
+	if o == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := o.Release(ctx, instanceID); err != nil {
+		m.log.Warn("erro ao liberar lease da instância", zap.String("instance_id", instanceID), zap.Error(err))
+	}
+}
+
+// dropLocalSession disconnects the socket without logging out or touching the
+// device store: the session stays valid for the replica that now owns it.
+func (m *Manager) dropLocalSession(instanceID string) {
+	m.closeLocalSession(instanceID)
+	m.log.Warn("sessão liberada localmente, outra réplica assumiu a instância", zap.String("instance_id", instanceID))
+}
+
+func (m *Manager) closeLocalSession(instanceID string) {
+	m.mu.Lock()
+	client := m.clients[instanceID]
+	delete(m.clients, instanceID)
+	delete(m.sessionReady, instanceID)
+	delete(m.connectedAt, instanceID)
+	m.expectedDisconnect[instanceID] = true
+	if cancel, exists := m.qrContexts[instanceID]; exists {
+		cancel()
+		delete(m.qrContexts, instanceID)
+	}
+	if cancel, exists := m.syncWorkers[instanceID]; exists {
+		cancel()
+		delete(m.syncWorkers, instanceID)
+	}
+	m.mu.Unlock()
+
+	if client != nil {
+		client.Disconnect()
+	}
+}
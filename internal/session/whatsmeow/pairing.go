@@ -143,8 +143,11 @@ func (m *Manager) CreateSession(ctx context.Context, instanceID string) (string,
 }
 
 func (m *Manager) createSession(ctx context.Context, instanceID string, forceRecreate bool) (string, error) {
-	m.mu.Lock()
+	if err := m.claimInstance(ctx, instanceID); err != nil {
+		return "", err
+	}
 
+	m.mu.Lock()
 	if existingClient, exists := m.clients[instanceID]; exists {
 		if !forceRecreate {
 			m.mu.Unlock()
@@ -155,7 +158,6 @@ func (m *Manager) createSession(ctx context.Context, instanceID string, forceRec
 		existingClient.Disconnect()
 		delete(m.clients, instanceID)
 	}
-
 	m.mu.Unlock()
 
 	m.log.Info("criando nova sessão WhatsMeow", zap.String("instance_id", instanceID))
@@ -362,6 +364,16 @@ func (m *Manager) monitorQRChannel(instanceID string, client *whatsmeow.Client,
 					}
 				}
 			}
+
+			// A device that never paired has nothing to fail over; without this the
+			// heartbeat would renew its lease forever. A session recreated meanwhile
+			// (GetQR recreating the channel) keeps it.
+			m.mu.RLock()
+			_, recreated := m.clients[instanceID]
+			m.mu.RUnlock()
+			if !recreated {
+				m.releaseInstance(instanceID)
+			}
 		} else {
 			m.log.Info("canal QR encerrado após pareamento bem-sucedido",
 				zap.String("instance_id", instanceID))
@@ -11,20 +11,21 @@ import (
 )
 
 type outgoingMsgEntry struct {
-	msg *waE2E.Message
-	at  time.Time
+	instanceID string
+	msg        *waE2E.Message
+	at         time.Time
 }
 
 const outgoingMsgCacheTTL = 30 * time.Minute
 
 // CacheOutgoingMessage stores the raw protobuf of a sent message to allow
 // reconstruction on retry receipts (including media, which cannot be rebuilt from the database).
-func (m *Manager) CacheOutgoingMessage(id string, msg *waE2E.Message) {
+func (m *Manager) CacheOutgoingMessage(instanceID, id string, msg *waE2E.Message) {
 	if id == "" || msg == nil {
 		return
 	}
 	now := time.Now()
-	m.outgoingMsgCache.Store(id, outgoingMsgEntry{msg: msg, at: now})
+	m.outgoingMsgCache.Store(id, outgoingMsgEntry{instanceID: instanceID, msg: msg, at: now})
 	// Opportunistic cleanup of expired entries.
 	m.outgoingMsgCache.Range(func(k, v any) bool {
 		if e, ok := v.(outgoingMsgEntry); ok && now.Sub(e.at) > outgoingMsgCacheTTL {
@@ -14,7 +14,7 @@ import (
 	"go.mau.fi/whatsmeow/types"
 	"go.uber.org/zap"
 
-	"github.com/open-apime/apime/internal/pkg/crypto"
+	"github.com/open-apime/apime/internal/service/message"
 	"github.com/open-apime/apime/internal/storage/model"
 )
 
@@ -22,7 +22,7 @@ func (m *Manager) RestoreSession(ctx context.Context, instanceID string, encrypt
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	_, err := crypto.Decrypt(encryptedBlob, m.encKey)
+	_, err := m.keys.Open(encryptedBlob)
 	if err != nil {
 		return fmt.Errorf("whatsmeow: descriptografar: %w", err)
 	}
@@ -93,6 +93,8 @@ func (m *Manager) DeleteSession(instanceID string) error {
 	delete(m.sessionReady, instanceID)
 	delete(m.pairingSuccess, instanceID)
 	m.mu.Unlock()
+	m.receivedMsgs.Delete(instanceID)
+	message.ForgetBlocklist(instanceID)
 
 	if client == nil {
 		m.log.Debug("cliente não encontrado em memória, tentando restaurar para logout", zap.String("instance_id", instanceID))
@@ -111,6 +113,7 @@ func (m *Manager) DeleteSession(instanceID string) error {
 	}
 
 	m.logConnectionEvent(instanceID, "manual_disconnect", `{"message":"Desconectado manualmente pelo usuário"}`)
+	defer m.releaseInstance(instanceID)
 
 	if m.instanceRepo != nil {
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -271,9 +274,38 @@ func (m *Manager) GetClient(instanceID string) (*whatsmeow.Client, error) {
 }
 
 func (m *Manager) restoreSessionIfExists(ctx context.Context, instanceID string) (*whatsmeow.Client, error) {
+	m.mu.RLock()
+	client, exists := m.clients[instanceID]
+	m.mu.RUnlock()
+	if exists {
+		return client, nil
+	}
+
+	if m.instanceRepo != nil {
+		if inst, err := m.instanceRepo.GetByID(ctx, instanceID); err == nil && inst.Status == model.InstanceStatusHandover {
+			return nil, errHandoverPending
+		}
+	}
+
+	if err := m.claimInstance(ctx, instanceID); err != nil {
+		return nil, err
+	}
+
 	m.mu.Lock()
-	defer m.mu.Unlock()
+	client, err := m.restoreSessionLocked(ctx, instanceID)
+	_, kept := m.clients[instanceID]
+	m.mu.Unlock()
+	if err != nil && !kept {
+		// Nothing to run here (no stored device, or it failed to log in): holding
+		// the lease would only keep the heartbeat renewing it forever.
+		m.releaseInstance(instanceID)
+	}
+	return client, err
+}
 
+// restoreSessionLocked loads and connects the stored device. Callers hold m.mu
+// and have already claimed the instance.
+func (m *Manager) restoreSessionLocked(ctx context.Context, instanceID string) (*whatsmeow.Client, error) {
 	if client, exists := m.clients[instanceID]; exists {
 		return client, nil
 	}
@@ -619,7 +651,7 @@ func (m *Manager) SaveSessionBlob(instanceID string) ([]byte, error) {
 
 	data := []byte(fmt.Sprintf("session:%s", instanceID))
 
-	encrypted, err := crypto.Encrypt(data, m.encKey)
+	encrypted, err := m.keys.Seal(data)
 	if err != nil {
 		return nil, fmt.Errorf("whatsmeow: criptografar: %w", err)
 	}
@@ -2,9 +2,11 @@ package whatsmeow
 
 import (
 	"sync"
+	"time"
 
 	"go.mau.fi/whatsmeow/proto/waE2E"
 	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
 )
 
 // InMemMessageStore implements the whatsmeow MessageStore interface.
@@ -59,3 +61,30 @@ func (s *InMemMessageStore) GetMessage(chat types.JID, id string) (*waE2E.Messag
 	}
 	return msg, nil
 }
+
+// receivedMsgLimit is how many inbound messages per instance stay available to be forwarded.
+// Older ones can still be forwarded when they are media (media_refs) or persisted text.
+const receivedMsgLimit = 1000
+
+func (m *Manager) storeReceivedMessage(instanceID string, evt *events.Message) {
+	if evt.Message == nil || evt.Info.ID == "" {
+		return
+	}
+	store, _ := m.receivedMsgs.LoadOrStore(instanceID, NewInMemMessageStore(receivedMsgLimit))
+	_ = store.(*InMemMessageStore).PutMessage(evt.Info.Chat, evt.Info.ID, evt.Message)
+}
+
+// StoredMessage returns the protobuf of a recent message of the instance: one sent by the API
+// (the retry cache) or one received since the session started. Nil when it isn't in memory.
+func (m *Manager) StoredMessage(instanceID, id string) *waE2E.Message {
+	if val, ok := m.outgoingMsgCache.Load(id); ok {
+		if entry, ok := val.(outgoingMsgEntry); ok && entry.instanceID == instanceID && entry.msg != nil && time.Since(entry.at) <= outgoingMsgCacheTTL {
+			return entry.msg
+		}
+	}
+	if store, ok := m.receivedMsgs.Load(instanceID); ok {
+		msg, _ := store.(*InMemMessageStore).GetMessage(types.EmptyJID, id)
+		return msg
+	}
+	return nil
+}
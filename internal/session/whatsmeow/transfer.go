@@ -0,0 +1,235 @@
+package whatsmeow
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go.mau.fi/whatsmeow/store/sqlstore"
+	"go.mau.fi/whatsmeow/types"
+	"go.uber.org/zap"
+
+	"github.com/open-apime/apime/internal/pkg/sessionbundle"
+)
+
+// errHandoverPending keeps an exported device from connecting again while the transfer is not
+// confirmed: the destination may already be using it.
+var errHandoverPending = errors.New("sessão em transferência: confirme ou cancele a exportação")
+
+func (m *Manager) sqlitePath(instanceID string) string {
+	return filepath.Join(m.baseDir, instanceID+".db")
+}
+
+// openStoreDB opens the whatsmeow store of the instance with database/sql, for the raw table
+// copy done by sessionbundle. The sqlstore container hides its connection.
+func (m *Manager) openStoreDB(instanceID string) (*sql.DB, string, error) {
+	if m.storageDriver == "postgres" {
+		if m.pgConnString == "" {
+			return nil, "", fmt.Errorf("whatsmeow: conexão PostgreSQL não configurada")
+		}
+		db, err := sql.Open("postgres", m.pgConnString)
+		if err != nil {
+			return nil, "", fmt.Errorf("whatsmeow: abrir store PostgreSQL: %w", err)
+		}
+		return db, sessionbundle.DialectPostgres, nil
+	}
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?_foreign_keys=on", m.sqlitePath(instanceID)))
+	if err != nil {
+		return nil, "", fmt.Errorf("whatsmeow: abrir store SQLite: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+	return db, sessionbundle.DialectSQLite, nil
+}
+
+// storedDeviceJID finds the device of the instance: the SQLite file holds a single device, the
+// shared PostgreSQL store is looked up by the JID saved on the instance.
+func (m *Manager) storedDeviceJID(ctx context.Context, db *sql.DB, dialect, instanceID string) (string, error) {
+	if dialect == sessionbundle.DialectPostgres {
+		if m.instanceRepo == nil {
+			return "", fmt.Errorf("whatsmeow: instanceRepo não configurado")
+		}
+		inst, err := m.instanceRepo.GetByID(ctx, instanceID)
+		if err != nil {
+			return "", err
+		}
+		if inst.WhatsAppJID == "" {
+			return "", fmt.Errorf("sessão não encontrada")
+		}
+		return inst.WhatsAppJID, nil
+	}
+	var jid string
+	if err := db.QueryRowContext(ctx, `SELECT jid FROM whatsmeow_device LIMIT 1`).Scan(&jid); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("sessão não encontrada")
+		}
+		return "", fmt.Errorf("whatsmeow: ler device: %w", err)
+	}
+	return jid, nil
+}
+
+// ExportDevice dumps the paired device of the instance from the store. With handover the local
+// socket is closed first (without logout: the device must stay valid for the destination), so
+// nothing is written to the store while it is copied; the device is only removed, with
+// RemoveExportedDevice, once the caller confirms the transfer. Without handover the store is
+// copied while the session keeps running.
+func (m *Manager) ExportDevice(ctx context.Context, instanceID string, handover bool) (string, []sessionbundle.Table, error) {
+	if m.storageDriver != "postgres" {
+		if _, err := os.Stat(m.sqlitePath(instanceID)); err != nil {
+			return "", nil, fmt.Errorf("sessão não encontrada")
+		}
+	}
+	db, dialect, err := m.openStoreDB(instanceID)
+	if err != nil {
+		return "", nil, err
+	}
+	defer db.Close()
+
+	jid, err := m.storedDeviceJID(ctx, db, dialect, instanceID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if handover {
+		m.closeLocalSession(instanceID)
+	}
+	tables, err := sessionbundle.Dump(ctx, db, dialect, jid)
+	if err != nil {
+		return "", nil, err
+	}
+
+	m.log.Info("dispositivo exportado",
+		zap.String("instance_id", instanceID),
+		zap.String("jid", jid),
+		zap.Bool("handover", handover),
+		zap.Int("tables", len(tables)))
+	return jid, tables, nil
+}
+
+// RemoveExportedDevice deletes a handed-over device from the local store and releases the
+// instance lease. Unlike DeleteSession it doesn't log out: the device now lives elsewhere.
+func (m *Manager) RemoveExportedDevice(ctx context.Context, instanceID, deviceJID string) error {
+	m.closeLocalSession(instanceID)
+	defer m.releaseInstance(instanceID)
+
+	if m.storageDriver == "postgres" {
+		if m.sharedContainer == nil {
+			return fmt.Errorf("whatsmeow: container PostgreSQL não inicializado")
+		}
+		jid, err := types.ParseJID(deviceJID)
+		if err != nil {
+			return fmt.Errorf("whatsmeow: parse device JID: %w", err)
+		}
+		device, err := m.sharedContainer.GetDevice(ctx, jid)
+		if err != nil {
+			return fmt.Errorf("whatsmeow: obter device PostgreSQL: %w", err)
+		}
+		if device == nil {
+			return nil
+		}
+		if err := device.Delete(ctx); err != nil {
+			return fmt.Errorf("whatsmeow: remover device exportado: %w", err)
+		}
+		return nil
+	}
+
+	path := m.sqlitePath(instanceID)
+	for _, p := range []string{path, path + "-wal", path + "-shm"} {
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("whatsmeow: remover store SQLite exportado: %w", err)
+		}
+	}
+	return nil
+}
+
+// ImportDevice writes an exported device into the local store of the instance. It refuses when
+// the instance already has a connected client or the store already holds a paired device, so an
+// import never overwrites a live session.
+func (m *Manager) ImportDevice(ctx context.Context, instanceID, deviceJID string, tables []sessionbundle.Table) error {
+	jid, err := types.ParseJID(deviceJID)
+	if err != nil {
+		return fmt.Errorf("whatsmeow: parse device JID: %w", err)
+	}
+
+	m.mu.RLock()
+	client, exists := m.clients[instanceID]
+	m.mu.RUnlock()
+	if exists && client.IsLoggedIn() {
+		return sessionbundle.ErrDeviceExists
+	}
+	if err := m.claimInstance(ctx, instanceID); err != nil {
+		return err
+	}
+
+	clientLog := &zapLogger{log: m.log, module: "whatsmeow-import"}
+	if m.storageDriver == "postgres" {
+		if m.sharedContainer == nil {
+			return fmt.Errorf("whatsmeow: container PostgreSQL não inicializado")
+		}
+		existing, err := m.sharedContainer.GetDevice(ctx, jid)
+		if err != nil {
+			return fmt.Errorf("whatsmeow: verificar device PostgreSQL: %w", err)
+		}
+		if existing != nil {
+			return sessionbundle.ErrDeviceExists
+		}
+	} else {
+		path := m.sqlitePath(instanceID)
+		if _, err := os.Stat(path); err == nil {
+			container, err := sqlstore.New(ctx, "sqlite3", fmt.Sprintf("file:%s?_foreign_keys=on", path), clientLog)
+			if err != nil {
+				return fmt.Errorf("whatsmeow: abrir store SQLite existente: %w", err)
+			}
+			device, err := container.GetFirstDevice(ctx)
+			_ = container.Close()
+			if err != nil {
+				return fmt.Errorf("whatsmeow: ler store SQLite existente: %w", err)
+			}
+			if device.ID != nil && !device.ID.IsEmpty() {
+				return sessionbundle.ErrDeviceExists
+			}
+			// Leftover of a pairing that never completed: safe to replace.
+			if err := os.Remove(path); err != nil {
+				return fmt.Errorf("whatsmeow: remover store SQLite sem device: %w", err)
+			}
+		}
+	}
+
+	db, dialect, err := m.openStoreDB(instanceID)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// A new SQLite file has no schema yet; on PostgreSQL this is a no-op.
+	if err := sqlstore.NewWithDB(db, dialect, clientLog).Upgrade(ctx); err != nil {
+		return fmt.Errorf("whatsmeow: preparar schema do store: %w", err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("whatsmeow: iniciar transação de importação: %w", err)
+	}
+	if err := sessionbundle.Load(ctx, tx, dialect, deviceJID, tables); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("whatsmeow: gravar importação: %w", err)
+	}
+
+	m.log.Info("dispositivo importado",
+		zap.String("instance_id", instanceID),
+		zap.String("jid", deviceJID),
+		zap.Int("tables", len(tables)))
+	return nil
+}
+
+// ConnectSession connects an instance whose device is already in the store (e.g. right after
+// ImportDevice).
+func (m *Manager) ConnectSession(ctx context.Context, instanceID string) error {
+	_, err := m.restoreSessionIfExists(ctx, instanceID)
+	return err
+}
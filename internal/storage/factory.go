@@ -1,9 +1,18 @@
 package storage
 
 import (
+	"context"
+	"time"
+
 	"go.uber.org/zap"
 
 	"github.com/open-apime/apime/internal/config"
+	"github.com/open-apime/apime/internal/pkg/cache"
+	cache_memory "github.com/open-apime/apime/internal/pkg/cache/memory"
+	cache_redis "github.com/open-apime/apime/internal/pkg/cache/redis"
+	"github.com/open-apime/apime/internal/pkg/crypto"
+	"github.com/open-apime/apime/internal/pkg/instancelock"
+	lock_redis "github.com/open-apime/apime/internal/pkg/instancelock/redis"
 	"github.com/open-apime/apime/internal/pkg/queue"
 	queue_memory "github.com/open-apime/apime/internal/pkg/queue/memory"
 	queue_redis "github.com/open-apime/apime/internal/pkg/queue/redis"
@@ -15,18 +24,32 @@ import (
 	"github.com/open-apime/apime/internal/storage/sqlite"
 )
 
+// Pinger is the cheap connectivity probe used by the readiness check.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
 type Repositories struct {
-	Instance     InstanceRepository
-	Message      MessageRepository
-	EventLog     EventLogRepository
-	User         UserRepository
-	APIToken     APITokenRepository
-	HistorySync  HistorySyncRepository
-	Contact      ContactRepository
-	RedisClient  *storage_redis.Client
-	WebhookQueue queue.Queue
-	OutboxQueue  queue.Queue
-	RateLimiter  ratelimiter.Limiter
+	DB       Pinger
+	Instance InstanceRepository
+	// SealedInstance is Instance without the secret sealing: it reads and writes the webhook
+	// secrets as stored. Only rotate-keys uses it.
+	SealedInstance InstanceRepository
+	Message        MessageRepository
+	EventLog       EventLogRepository
+	User           UserRepository
+	APIToken       APITokenRepository
+	HistorySync    HistorySyncRepository
+	Contact        ContactRepository
+	MediaRef       MediaRefRepository
+	RedisClient    *storage_redis.Client
+	WebhookQueue   queue.Queue
+	OutboxQueue    queue.Queue
+	RateLimiter    ratelimiter.Limiter
+	InstanceLock   instancelock.Locker
+	Cache          cache.Cache
+	// SessionKeys seals the session blobs and, through Instance, the webhook secrets.
+	SessionKeys *crypto.KeyRing
 }
 
 func NewRepositories(cfg config.Config, log *zap.Logger) (*Repositories, error) {
@@ -38,10 +61,18 @@ func NewRepositories(cfg config.Config, log *zap.Logger) (*Repositories, error)
 		webhookQueue queue.Queue
 		outboxQueue  queue.Queue
 		rateLimiter  ratelimiter.Limiter
+		instanceLock instancelock.Locker
+		sharedCache  cache.Cache
 		storeRedis   *storage_redis.Client
 		err          error
 	)
 
+	sessionKeys, err := crypto.LoadKeyRing(cfg.WhatsApp.SessionKeyFile, cfg.WhatsApp.SessionKeys, cfg.WhatsApp.SessionKeyEnc)
+	if err != nil {
+		log.Error("erro ao carregar chaves de sessão", zap.Error(err))
+		return nil, err
+	}
+
 	// Initialize Redis only when explicitly enabled
 	useRedis := cfg.Redis.Enabled
 
@@ -54,15 +85,19 @@ func NewRepositories(cfg config.Config, log *zap.Logger) (*Repositories, error)
 		}
 
 		redisClient := storeRedis.RDB()
-		webhookQueue = queue_redis.NewQueue(redisClient, "webhook:events")
+		webhookQueue = queue.Sequenced(queue_redis.NewQueue(redisClient, "webhook:events"), queue_redis.NewSequencer(redisClient, "webhook:seq:"))
 		outboxQueue = queue_redis.NewQueue(redisClient, "message:outbox")
 		rateLimiter = limiter_redis.NewLimiter(redisClient)
-		log.Info("Redis conectado, filas e limiter configurados")
+		instanceLock = lock_redis.NewLocker(storeRedis, time.Duration(cfg.Redis.LockTTLSeconds)*time.Second, log)
+		sharedCache = cache_redis.New(redisClient, "cache:")
+		log.Info("Redis conectado, filas, limiter, lock de instância e cache configurados")
 	} else {
 		log.Info("usando implementações em memória")
-		webhookQueue = queue_memory.NewQueue(10000)
+		webhookQueue = queue.Sequenced(queue_memory.NewQueue(10000), queue.NewMemorySequencer())
 		outboxQueue = queue_memory.NewQueue(10000)
 		rateLimiter = limiter_memory.NewLimiter()
+		instanceLock = instancelock.NewMemory(log)
+		sharedCache = cache_memory.New()
 		storeRedis = nil
 	}
 
@@ -76,18 +111,25 @@ func NewRepositories(cfg config.Config, log *zap.Logger) (*Repositories, error)
 		}
 
 		log.Info("repositórios SQLite criados com sucesso", zap.String("data_dir", cfg.Storage.DataDir))
+		instances := sqlite.NewInstanceRepository(db)
 		return &Repositories{
-			Instance:     sqlite.NewInstanceRepository(db),
-			Message:      sqlite.NewMessageRepository(db),
-			EventLog:     sqlite.NewEventLogRepository(db),
-			User:         sqlite.NewUserRepository(db),
-			APIToken:     sqlite.NewAPITokenRepository(db),
-			HistorySync:  sqlite.NewHistorySyncRepository(db),
-			Contact:      sqlite.NewContactRepository(db),
-			RedisClient:  storeRedis,
-			WebhookQueue: webhookQueue,
-			OutboxQueue:  outboxQueue,
-			RateLimiter:  rateLimiter,
+			DB:             db,
+			Instance:       SealInstanceSecrets(instances, sessionKeys, log),
+			SealedInstance: instances,
+			Message:        sqlite.NewMessageRepository(db),
+			EventLog:       sqlite.NewEventLogRepository(db),
+			User:           sqlite.NewUserRepository(db),
+			APIToken:       sqlite.NewAPITokenRepository(db),
+			HistorySync:    sqlite.NewHistorySyncRepository(db),
+			Contact:        sqlite.NewContactRepository(db),
+			MediaRef:       sqlite.NewMediaRefRepository(db),
+			RedisClient:    storeRedis,
+			WebhookQueue:   webhookQueue,
+			OutboxQueue:    outboxQueue,
+			RateLimiter:    rateLimiter,
+			InstanceLock:   instanceLock,
+			Cache:          sharedCache,
+			SessionKeys:    sessionKeys,
 		}, nil
 
 	case "postgres":
@@ -99,18 +141,25 @@ func NewRepositories(cfg config.Config, log *zap.Logger) (*Repositories, error)
 		}
 
 		log.Info("repositórios PostgreSQL criados com sucesso")
+		instances := postgres.NewInstanceRepository(db)
 		return &Repositories{
-			Instance:     postgres.NewInstanceRepository(db),
-			Message:      postgres.NewMessageRepository(db),
-			EventLog:     postgres.NewEventLogRepository(db),
-			User:         postgres.NewUserRepository(db),
-			APIToken:     postgres.NewAPITokenRepository(db),
-			HistorySync:  postgres.NewHistorySyncRepository(db),
-			Contact:      postgres.NewContactRepository(db),
-			RedisClient:  storeRedis,
-			WebhookQueue: webhookQueue,
-			OutboxQueue:  outboxQueue,
-			RateLimiter:  rateLimiter,
+			DB:             db,
+			Instance:       SealInstanceSecrets(instances, sessionKeys, log),
+			SealedInstance: instances,
+			Message:        postgres.NewMessageRepository(db),
+			EventLog:       postgres.NewEventLogRepository(db),
+			User:           postgres.NewUserRepository(db),
+			APIToken:       postgres.NewAPITokenRepository(db),
+			HistorySync:    postgres.NewHistorySyncRepository(db),
+			Contact:        postgres.NewContactRepository(db),
+			MediaRef:       postgres.NewMediaRefRepository(db),
+			RedisClient:    storeRedis,
+			WebhookQueue:   webhookQueue,
+			OutboxQueue:    outboxQueue,
+			RateLimiter:    rateLimiter,
+			InstanceLock:   instanceLock,
+			Cache:          sharedCache,
+			SessionKeys:    sessionKeys,
 		}, nil
 
 	default:
@@ -0,0 +1,50 @@
+package media
+
+// Limits is the largest file accepted per media kind, in bytes, both for uploads on the send
+// endpoints and for inbound media downloaded into the storage. Zero means no limit.
+type Limits struct {
+	Image    int64
+	Video    int64
+	Audio    int64
+	Document int64
+	Sticker  int64
+}
+
+// Max is the limit for a kind ("image", "video", "ptv", "audio", "document", "sticker").
+func (l Limits) Max(kind string) int64 {
+	switch kind {
+	case "image":
+		return l.Image
+	case "video", "ptv":
+		return l.Video
+	case "audio":
+		return l.Audio
+	case "document":
+		return l.Document
+	case "sticker":
+		return l.Sticker
+	default:
+		return l.Largest()
+	}
+}
+
+// Allows reports whether size fits the limit of kind.
+func (l Limits) Allows(kind string, size int64) bool {
+	limit := l.Max(kind)
+	return limit <= 0 || size <= limit
+}
+
+// Largest is the highest limit, used to cap a request body before its kind is known. Zero means
+// at least one kind is unlimited.
+func (l Limits) Largest() int64 {
+	var largest int64
+	for _, limit := range []int64{l.Image, l.Video, l.Audio, l.Document, l.Sticker} {
+		if limit <= 0 {
+			return 0
+		}
+		if limit > largest {
+			largest = limit
+		}
+	}
+	return largest
+}
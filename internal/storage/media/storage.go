@@ -3,7 +3,9 @@ package media
 import (
 	"context"
 	"crypto/md5"
+	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
@@ -13,6 +15,10 @@ import (
 	"go.uber.org/zap"
 )
 
+// ErrNotFound is returned by Open for a media ID with no file, including one removed by the TTL
+// cleanup.
+var ErrNotFound = errors.New("mídia não encontrada")
+
 type Storage struct {
 	baseDir string
 	ttl     time.Duration
@@ -36,57 +42,113 @@ func NewStorage(baseDir string, ttl time.Duration, log *zap.Logger) (*Storage, e
 	return s, nil
 }
 
-func (s *Storage) Save(ctx context.Context, instanceID string, messageID string, data []byte, mimetype string) (string, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// Save streams r into the media dir and returns the media ID. The data goes through a spool
+// file, so memory use doesn't grow with the size of the media.
+func (s *Storage) Save(ctx context.Context, instanceID string, messageID string, r io.Reader, mimetype string) (string, error) {
+	f, err := s.Spool(instanceID)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		s.Discard(f)
+		return "", fmt.Errorf("salvar arquivo: %w", err)
+	}
+	return s.Keep(f, instanceID, messageID, mimetype)
+}
 
+// Spool creates a temporary file in the instance dir for a download in progress. It must end in
+// Keep or Discard; a spool left behind by a crash is removed by the TTL cleanup.
+func (s *Storage) Spool(instanceID string) (*os.File, error) {
+	if !isPlainName(instanceID) {
+		return nil, fmt.Errorf("instância inválida")
+	}
 	instanceDir := filepath.Join(s.baseDir, instanceID)
 	if err := os.MkdirAll(instanceDir, 0755); err != nil {
-		return "", fmt.Errorf("criar diretório da instância: %w", err)
+		return nil, fmt.Errorf("criar diretório da instância: %w", err)
+	}
+	f, err := os.CreateTemp(instanceDir, ".spool-*")
+	if err != nil {
+		return nil, fmt.Errorf("criar arquivo temporário: %w", err)
+	}
+	return f, nil
+}
+
+// Keep names a completed spool after its message and content hash, closes it and publishes it
+// under the returned media ID.
+func (s *Storage) Keep(f *os.File, instanceID string, messageID string, mimetype string) (string, error) {
+	hasher := md5.New()
+	size, err := f.Seek(0, io.SeekStart)
+	if err == nil {
+		size, err = io.Copy(hasher, f)
+	}
+	if err != nil {
+		s.Discard(f)
+		return "", fmt.Errorf("ler arquivo temporário: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+		return "", fmt.Errorf("salvar arquivo: %w", err)
 	}
 
-	hash := md5.Sum(data)
 	ext := getExtensionFromMimetype(mimetype)
-	mediaID := fmt.Sprintf("%s_%x%s", messageID, hash[:4], ext)
+	mediaID := fmt.Sprintf("%s_%x%s", messageID, hasher.Sum(nil)[:4], ext)
 
-	filePath := filepath.Join(instanceDir, mediaID)
-	if err := os.WriteFile(filePath, data, 0644); err != nil {
+	// The rename is atomic, so readers never see a partial file; the lock keeps it from racing
+	// with the cleanup walk.
+	s.mu.Lock()
+	err = os.Rename(f.Name(), filepath.Join(s.baseDir, instanceID, mediaID))
+	s.mu.Unlock()
+	if err != nil {
+		_ = os.Remove(f.Name())
 		return "", fmt.Errorf("salvar arquivo: %w", err)
 	}
 
 	s.log.Info("mídia salva",
 		zap.String("instance_id", instanceID),
 		zap.String("media_id", mediaID),
-		zap.Int("size", len(data)),
+		zap.Int64("size", size),
 		zap.String("mimetype", mimetype),
 	)
 
 	return mediaID, nil
 }
 
-func (s *Storage) Get(ctx context.Context, instanceID string, mediaID string) ([]byte, error) {
+// Discard drops a spool that won't be kept.
+func (s *Storage) Discard(f *os.File) {
+	_ = f.Close()
+	_ = os.Remove(f.Name())
+}
+
+func (s *Storage) Exists(instanceID string, mediaID string) bool {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	filePath := filepath.Join(s.baseDir, instanceID, mediaID)
-	data, err := os.ReadFile(filePath)
+	_, err := os.Stat(filePath)
+	return err == nil
+}
+
+// Open returns the media file for streaming. IDs come from the URL, so anything that isn't a
+// plain file name is refused instead of being joined into a path.
+func (s *Storage) Open(instanceID string, mediaID string) (*os.File, error) {
+	if !isPlainName(instanceID) || !isPlainName(mediaID) {
+		return nil, ErrNotFound
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	f, err := os.Open(filepath.Join(s.baseDir, instanceID, mediaID))
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("mídia não encontrada")
+			return nil, ErrNotFound
 		}
-		return nil, fmt.Errorf("ler arquivo: %w", err)
+		return nil, fmt.Errorf("abrir arquivo: %w", err)
 	}
-
-	return data, nil
+	return f, nil
 }
 
-func (s *Storage) Exists(instanceID string, mediaID string) bool {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	filePath := filepath.Join(s.baseDir, instanceID, mediaID)
-	_, err := os.Stat(filePath)
-	return err == nil
+func isPlainName(name string) bool {
+	return name != "" && name != "." && name != ".." && filepath.Base(name) == name
 }
 
 // GetPath returns the full file path.
@@ -0,0 +1,8 @@
+package model
+
+import "errors"
+
+// ErrNotFound is the one "no such row" error of every repository. It lives here, the package all
+// backends share, so storage.ErrNotFound and the sqlite/postgres ones are the same value and
+// errors.Is works whichever the caller names.
+var ErrNotFound = errors.New("not found")
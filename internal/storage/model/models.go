@@ -9,25 +9,49 @@ const (
 	InstanceStatusActive       InstanceStatus = "active"
 	InstanceStatusError        InstanceStatus = "error"
 	InstanceStatusDisconnected InstanceStatus = "disconnected"
+	// InstanceStatusHandover marks an instance exported for a transfer: the device is still in the
+	// store but must not connect until the export is confirmed (device removed) or cancelled.
+	InstanceStatusHandover InstanceStatus = "handover"
+)
+
+// WebhookSecrets are the current and previous webhook secrets of an instance as stored, i.e.
+// sealed with the session key ring (or in clear, for rows older than encryption at rest).
+type WebhookSecrets struct {
+	Secret   string
+	Previous string
+}
+
+// MediaPolicy decides what happens to inbound media: eager downloads it when the message
+// arrives, lazy only keeps what is needed to download it on request, none keeps nothing.
+type MediaPolicy string
+
+const (
+	MediaPolicyEager MediaPolicy = "eager"
+	MediaPolicyLazy  MediaPolicy = "lazy"
+	MediaPolicyNone  MediaPolicy = "none"
 )
 
 type Instance struct {
-	ID                   string            `json:"id"`
-	Name                 string            `json:"name"`
-	OwnerUserID          string            `json:"ownerUserId"`
-	OwnerEmail           string            `json:"ownerEmail,omitempty"`
-	WhatsAppJID          string            `json:"whatsappJid,omitempty"`
-	WebhookURL           string            `json:"webhookUrl,omitempty"`
-	WebhookSecret        string            `json:"-"`
-	TokenHash            string            `json:"-"`
-	TokenUpdatedAt       *time.Time        `json:"tokenUpdatedAt,omitempty"`
-	Status               InstanceStatus    `json:"status"`
-	SessionBlob          []byte            `json:"-"`
-	HistorySyncStatus    HistorySyncStatus `json:"historySyncStatus"`
-	HistorySyncCycleID   string            `json:"historySyncCycleId"`
-	HistorySyncUpdatedAt *time.Time        `json:"historySyncUpdatedAt,omitempty"`
-	CreatedAt            time.Time         `json:"createdAt"`
-	UpdatedAt            time.Time         `json:"updatedAt"`
+	ID                             string            `json:"id"`
+	Name                           string            `json:"name"`
+	OwnerUserID                    string            `json:"ownerUserId"`
+	OwnerEmail                     string            `json:"ownerEmail,omitempty"`
+	WhatsAppJID                    string            `json:"whatsappJid,omitempty"`
+	WebhookURL                     string            `json:"webhookUrl,omitempty"`
+	WebhookSecret                  string            `json:"-"`
+	WebhookPreviousSecret          string            `json:"-"`
+	WebhookPreviousSecretExpiresAt *time.Time        `json:"webhookPreviousSecretExpiresAt,omitempty"`
+	MediaPolicy                    MediaPolicy       `json:"mediaPolicy"`
+	WebhookOmitRaw                 bool              `json:"webhookOmitRaw"`
+	TokenHash                      string            `json:"-"`
+	TokenUpdatedAt                 *time.Time        `json:"tokenUpdatedAt,omitempty"`
+	Status                         InstanceStatus    `json:"status"`
+	SessionBlob                    []byte            `json:"-"`
+	HistorySyncStatus              HistorySyncStatus `json:"historySyncStatus"`
+	HistorySyncCycleID             string            `json:"historySyncCycleId"`
+	HistorySyncUpdatedAt           *time.Time        `json:"historySyncUpdatedAt,omitempty"`
+	CreatedAt                      time.Time         `json:"createdAt"`
+	UpdatedAt                      time.Time         `json:"updatedAt"`
 }
 
 type Message struct {
@@ -42,6 +66,23 @@ type Message struct {
 	CreatedAt   time.Time  `json:"createdAt"`
 }
 
+// MediaRef keeps an inbound media downloadable after the event: Message is the serialized media
+// message (keys, hashes and direct path) and the message info is what a media retry receipt
+// needs when the CDN copy has expired. MediaID is the cached file, when there is one.
+type MediaRef struct {
+	InstanceID string
+	MessageID  string
+	ChatJID    string
+	SenderJID  string
+	FromMe     bool
+	Kind       string
+	Mimetype   string
+	Message    []byte
+	MediaID    string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
 type EventLog struct {
 	ID          string     `json:"id"`
 	InstanceID  string     `json:"instanceId"`
@@ -115,3 +156,65 @@ type Contact struct {
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
 }
+
+// ReachoutLock is an active 463 (reach-out) block of an instance towards a contact.
+type ReachoutLock struct {
+	InstanceID string     `json:"instanceId"`
+	Contact    string     `json:"contact"`
+	BlockedAt  *time.Time `json:"blockedAt,omitempty"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+}
+
+// JIDCacheEntry is a cached phone → JID resolution. Negative entries (number
+// not on WhatsApp) have an empty JID.
+type JIDCacheEntry struct {
+	Phone     string    `json:"phone"`
+	JID       string    `json:"jid,omitempty"`
+	Negative  bool      `json:"negative"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// JIDLookup combines the cache entry with the persisted Contact row.
+type JIDLookup struct {
+	Phone   string         `json:"phone"`
+	Cache   *JIDCacheEntry `json:"cache"`
+	Contact *Contact       `json:"contact"`
+}
+
+// HealthSignals counts the risk signals seen for an instance over a period.
+type HealthSignals struct {
+	TemporaryBans         int `json:"temporaryBans"`
+	ReachoutTimelocks     int `json:"reachoutTimelocks"`
+	ReachoutErrors        int `json:"reachoutErrors"`
+	RetryReceipts         int `json:"retryReceipts"`
+	UndecryptableMessages int `json:"undecryptableMessages"`
+	OutboundConversations int `json:"outboundConversations"`
+	InboundConversations  int `json:"inboundConversations"`
+	Reconnects            int `json:"reconnects"`
+}
+
+// HealthDay is one point of the health trend: the signals of that day and the score of the
+// window ending on it.
+type HealthDay struct {
+	Date    string        `json:"date"`
+	Score   int           `json:"score"`
+	Signals HealthSignals `json:"signals"`
+}
+
+// InstanceHealth is the computed risk report of an instance's number.
+type InstanceHealth struct {
+	InstanceID           string        `json:"instanceId"`
+	Score                int           `json:"score"`
+	Risk                 string        `json:"risk"`
+	PreviousScore        int           `json:"previousScore"`
+	Trend                string        `json:"trend"`
+	WindowDays           int           `json:"windowDays"`
+	Signals              HealthSignals `json:"signals"`
+	PreviousSignals      HealthSignals `json:"previousSignals"`
+	OutboundInboundRatio float64       `json:"outboundInboundRatio"`
+	Reasons              []string      `json:"reasons"`
+	TemporaryBanReasons  []string      `json:"temporaryBanReasons,omitempty"`
+	SlowedDown           string        `json:"slowedDown,omitempty"`
+	Daily                []HealthDay   `json:"daily"`
+	GeneratedAt          time.Time     `json:"generatedAt"`
+}
@@ -1,7 +1,11 @@
 package postgres
 
-import "errors"
+import (
+	"errors"
 
-var ErrNotFound = errors.New("not found")
+	"github.com/open-apime/apime/internal/storage/model"
+)
+
+var ErrNotFound = model.ErrNotFound
 
 var ErrLastAdmin = errors.New("cannot delete the last admin user")
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 
 	"github.com/open-apime/apime/internal/storage/model"
 )
@@ -75,6 +76,27 @@ func (r *eventLogRepo) ListByInstance(ctx context.Context, instanceID string) ([
 	}
 	defer rows.Close()
 
+	return scanEventLogs(rows)
+}
+
+func (r *eventLogRepo) ListByTypeSince(ctx context.Context, instanceID, eventType string, since time.Time) ([]model.EventLog, error) {
+	query := `
+		SELECT id, instance_id, type, payload, delivered_at, created_at
+		FROM event_logs
+		WHERE instance_id = $1 AND type = $2 AND created_at >= $3
+		ORDER BY created_at ASC
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query, instanceID, eventType, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanEventLogs(rows)
+}
+
+func scanEventLogs(rows pgx.Rows) ([]model.EventLog, error) {
 	var eventLogs []model.EventLog
 	for rows.Next() {
 		var eventLog model.EventLog
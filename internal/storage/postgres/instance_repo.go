@@ -29,22 +29,26 @@ func (r *instanceRepo) Create(ctx context.Context, inst model.Instance) (model.I
 	if inst.HistorySyncStatus == "" {
 		inst.HistorySyncStatus = model.HistorySyncStatusPending
 	}
+	if inst.MediaPolicy == "" {
+		inst.MediaPolicy = model.MediaPolicyEager
+	}
 
 	query := `
-		INSERT INTO instances (id, name, owner_user_id, whatsapp_jid, status, session_blob, webhook_url, webhook_secret, instance_token_hash, instance_token_updated_at,
+		INSERT INTO instances (id, name, owner_user_id, whatsapp_jid, status, session_blob, webhook_url, webhook_secret, webhook_previous_secret, webhook_previous_secret_expires_at, media_policy, webhook_omit_raw, instance_token_hash, instance_token_updated_at,
 		                       history_sync_status, history_sync_cycle_id, history_sync_updated_at, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
-		RETURNING id, name, owner_user_id, COALESCE(whatsapp_jid, ''), status, COALESCE(webhook_url, ''), COALESCE(webhook_secret, ''), COALESCE(instance_token_hash, ''), instance_token_updated_at,
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
+		RETURNING id, name, owner_user_id, COALESCE(whatsapp_jid, ''), status, COALESCE(webhook_url, ''), COALESCE(webhook_secret, ''), COALESCE(webhook_previous_secret, ''), webhook_previous_secret_expires_at, media_policy, webhook_omit_raw, COALESCE(instance_token_hash, ''), instance_token_updated_at,
 		          history_sync_status, COALESCE(history_sync_cycle_id::text, ''), history_sync_updated_at, created_at, updated_at
 	`
 
 	err := r.db.Pool.QueryRow(ctx, query,
 		inst.ID, inst.Name, inst.OwnerUserID, nullIfEmpty(inst.WhatsAppJID), string(inst.Status), inst.SessionBlob,
-		nullIfEmpty(inst.WebhookURL), nullIfEmpty(inst.WebhookSecret), nullIfEmpty(inst.TokenHash), inst.TokenUpdatedAt,
+		nullIfEmpty(inst.WebhookURL), nullIfEmpty(inst.WebhookSecret), nullIfEmpty(inst.WebhookPreviousSecret), inst.WebhookPreviousSecretExpiresAt,
+		string(inst.MediaPolicy), inst.WebhookOmitRaw, nullIfEmpty(inst.TokenHash), inst.TokenUpdatedAt,
 		string(inst.HistorySyncStatus), nullIfEmpty(inst.HistorySyncCycleID), inst.HistorySyncUpdatedAt,
 		inst.CreatedAt, inst.UpdatedAt,
 	).Scan(
-		&inst.ID, &inst.Name, &inst.OwnerUserID, &inst.WhatsAppJID, &inst.Status, &inst.WebhookURL, &inst.WebhookSecret, &inst.TokenHash, &inst.TokenUpdatedAt,
+		&inst.ID, &inst.Name, &inst.OwnerUserID, &inst.WhatsAppJID, &inst.Status, &inst.WebhookURL, &inst.WebhookSecret, &inst.WebhookPreviousSecret, &inst.WebhookPreviousSecretExpiresAt, &inst.MediaPolicy, &inst.WebhookOmitRaw, &inst.TokenHash, &inst.TokenUpdatedAt,
 		&inst.HistorySyncStatus, &inst.HistorySyncCycleID, &inst.HistorySyncUpdatedAt,
 		&inst.CreatedAt, &inst.UpdatedAt,
 	)
@@ -58,7 +62,7 @@ func (r *instanceRepo) Create(ctx context.Context, inst model.Instance) (model.I
 
 func (r *instanceRepo) GetByTokenHash(ctx context.Context, tokenHash string) (model.Instance, error) {
 	query := `
-		SELECT id, name, owner_user_id, COALESCE(whatsapp_jid, ''), status, session_blob, COALESCE(webhook_url, ''), COALESCE(webhook_secret, ''), COALESCE(instance_token_hash, ''), instance_token_updated_at,
+		SELECT id, name, owner_user_id, COALESCE(whatsapp_jid, ''), status, session_blob, COALESCE(webhook_url, ''), COALESCE(webhook_secret, ''), COALESCE(webhook_previous_secret, ''), webhook_previous_secret_expires_at, media_policy, webhook_omit_raw, COALESCE(instance_token_hash, ''), instance_token_updated_at,
 		       history_sync_status, COALESCE(history_sync_cycle_id::text, ''), history_sync_updated_at, created_at, updated_at
 		FROM instances
 		WHERE instance_token_hash = $1
@@ -67,7 +71,7 @@ func (r *instanceRepo) GetByTokenHash(ctx context.Context, tokenHash string) (mo
 	var inst model.Instance
 	err := r.db.Pool.QueryRow(ctx, query, tokenHash).Scan(
 		&inst.ID, &inst.Name, &inst.OwnerUserID, &inst.WhatsAppJID, &inst.Status, &inst.SessionBlob,
-		&inst.WebhookURL, &inst.WebhookSecret, &inst.TokenHash, &inst.TokenUpdatedAt,
+		&inst.WebhookURL, &inst.WebhookSecret, &inst.WebhookPreviousSecret, &inst.WebhookPreviousSecretExpiresAt, &inst.MediaPolicy, &inst.WebhookOmitRaw, &inst.TokenHash, &inst.TokenUpdatedAt,
 		&inst.HistorySyncStatus, &inst.HistorySyncCycleID, &inst.HistorySyncUpdatedAt,
 		&inst.CreatedAt, &inst.UpdatedAt,
 	)
@@ -82,7 +86,7 @@ func (r *instanceRepo) GetByTokenHash(ctx context.Context, tokenHash string) (mo
 
 func (r *instanceRepo) GetByID(ctx context.Context, id string) (model.Instance, error) {
 	query := `
-		SELECT id, name, owner_user_id, COALESCE(whatsapp_jid, ''), status, session_blob, COALESCE(webhook_url, ''), COALESCE(webhook_secret, ''), COALESCE(instance_token_hash, ''), instance_token_updated_at,
+		SELECT id, name, owner_user_id, COALESCE(whatsapp_jid, ''), status, session_blob, COALESCE(webhook_url, ''), COALESCE(webhook_secret, ''), COALESCE(webhook_previous_secret, ''), webhook_previous_secret_expires_at, media_policy, webhook_omit_raw, COALESCE(instance_token_hash, ''), instance_token_updated_at,
 		       history_sync_status, COALESCE(history_sync_cycle_id::text, ''), history_sync_updated_at, created_at, updated_at
 		FROM instances
 		WHERE id = $1
@@ -92,7 +96,7 @@ func (r *instanceRepo) GetByID(ctx context.Context, id string) (model.Instance,
 
 	err := r.db.Pool.QueryRow(ctx, query, id).Scan(
 		&inst.ID, &inst.Name, &inst.OwnerUserID, &inst.WhatsAppJID, &inst.Status, &inst.SessionBlob,
-		&inst.WebhookURL, &inst.WebhookSecret, &inst.TokenHash, &inst.TokenUpdatedAt,
+		&inst.WebhookURL, &inst.WebhookSecret, &inst.WebhookPreviousSecret, &inst.WebhookPreviousSecretExpiresAt, &inst.MediaPolicy, &inst.WebhookOmitRaw, &inst.TokenHash, &inst.TokenUpdatedAt,
 		&inst.HistorySyncStatus, &inst.HistorySyncCycleID, &inst.HistorySyncUpdatedAt,
 		&inst.CreatedAt, &inst.UpdatedAt,
 	)
@@ -123,7 +127,7 @@ func (r *instanceRepo) List(ctx context.Context, searchQuery string, limit, offs
 	}
 
 	query := `
-		SELECT i.id, i.name, i.owner_user_id, COALESCE(u.email, ''), COALESCE(i.whatsapp_jid, ''), i.status, COALESCE(i.webhook_url, ''), COALESCE(i.webhook_secret, ''), COALESCE(i.instance_token_hash, ''), i.instance_token_updated_at,
+		SELECT i.id, i.name, i.owner_user_id, COALESCE(u.email, ''), COALESCE(i.whatsapp_jid, ''), i.status, COALESCE(i.webhook_url, ''), COALESCE(i.webhook_secret, ''), COALESCE(i.webhook_previous_secret, ''), i.webhook_previous_secret_expires_at, i.media_policy, i.webhook_omit_raw, COALESCE(i.instance_token_hash, ''), i.instance_token_updated_at,
 		       i.history_sync_status, COALESCE(i.history_sync_cycle_id::text, ''), i.history_sync_updated_at, i.created_at, i.updated_at
 		FROM instances i
 		LEFT JOIN users u ON i.owner_user_id = u.id
@@ -145,7 +149,7 @@ func (r *instanceRepo) List(ctx context.Context, searchQuery string, limit, offs
 		var inst model.Instance
 		if err := rows.Scan(
 			&inst.ID, &inst.Name, &inst.OwnerUserID, &inst.OwnerEmail, &inst.WhatsAppJID, &inst.Status,
-			&inst.WebhookURL, &inst.WebhookSecret, &inst.TokenHash, &inst.TokenUpdatedAt,
+			&inst.WebhookURL, &inst.WebhookSecret, &inst.WebhookPreviousSecret, &inst.WebhookPreviousSecretExpiresAt, &inst.MediaPolicy, &inst.WebhookOmitRaw, &inst.TokenHash, &inst.TokenUpdatedAt,
 			&inst.HistorySyncStatus, &inst.HistorySyncCycleID, &inst.HistorySyncUpdatedAt,
 			&inst.CreatedAt, &inst.UpdatedAt,
 		); err != nil {
@@ -175,7 +179,7 @@ func (r *instanceRepo) ListByOwner(ctx context.Context, ownerUserID string, sear
 	}
 
 	query := `
-		SELECT i.id, i.name, i.owner_user_id, COALESCE(u.email, ''), COALESCE(i.whatsapp_jid, ''), i.status, COALESCE(i.webhook_url, ''), COALESCE(i.webhook_secret, ''), COALESCE(i.instance_token_hash, ''), i.instance_token_updated_at,
+		SELECT i.id, i.name, i.owner_user_id, COALESCE(u.email, ''), COALESCE(i.whatsapp_jid, ''), i.status, COALESCE(i.webhook_url, ''), COALESCE(i.webhook_secret, ''), COALESCE(i.webhook_previous_secret, ''), i.webhook_previous_secret_expires_at, i.media_policy, i.webhook_omit_raw, COALESCE(i.instance_token_hash, ''), i.instance_token_updated_at,
 		       i.history_sync_status, COALESCE(i.history_sync_cycle_id::text, ''), i.history_sync_updated_at, i.created_at, i.updated_at
 		FROM instances i
 		LEFT JOIN users u ON i.owner_user_id = u.id
@@ -197,7 +201,7 @@ func (r *instanceRepo) ListByOwner(ctx context.Context, ownerUserID string, sear
 		var inst model.Instance
 		if err := rows.Scan(
 			&inst.ID, &inst.Name, &inst.OwnerUserID, &inst.OwnerEmail, &inst.WhatsAppJID, &inst.Status,
-			&inst.WebhookURL, &inst.WebhookSecret, &inst.TokenHash, &inst.TokenUpdatedAt,
+			&inst.WebhookURL, &inst.WebhookSecret, &inst.WebhookPreviousSecret, &inst.WebhookPreviousSecretExpiresAt, &inst.MediaPolicy, &inst.WebhookOmitRaw, &inst.TokenHash, &inst.TokenUpdatedAt,
 			&inst.HistorySyncStatus, &inst.HistorySyncCycleID, &inst.HistorySyncUpdatedAt,
 			&inst.CreatedAt, &inst.UpdatedAt,
 		); err != nil {
@@ -212,13 +216,17 @@ func (r *instanceRepo) ListByOwner(ctx context.Context, ownerUserID string, sear
 
 func (r *instanceRepo) Update(ctx context.Context, inst model.Instance) (model.Instance, error) {
 	inst.UpdatedAt = time.Now()
+	if inst.MediaPolicy == "" {
+		inst.MediaPolicy = model.MediaPolicyEager
+	}
 
 	query := `
 		UPDATE instances
 		SET name = $2, owner_user_id = $3, whatsapp_jid = $4, status = $5, session_blob = $6, webhook_url = $7, webhook_secret = $8, instance_token_hash = $9, instance_token_updated_at = $10,
-		    history_sync_status = $11, history_sync_cycle_id = $12, history_sync_updated_at = $13, updated_at = $14
+		    history_sync_status = $11, history_sync_cycle_id = $12, history_sync_updated_at = $13, updated_at = $14,
+		    webhook_previous_secret = $15, webhook_previous_secret_expires_at = $16, media_policy = $17, webhook_omit_raw = $18
 		WHERE id = $1
-		RETURNING id, name, owner_user_id, COALESCE(whatsapp_jid, ''), status, COALESCE(webhook_url, ''), COALESCE(webhook_secret, ''), COALESCE(instance_token_hash, ''), instance_token_updated_at,
+		RETURNING id, name, owner_user_id, COALESCE(whatsapp_jid, ''), status, COALESCE(webhook_url, ''), COALESCE(webhook_secret, ''), COALESCE(webhook_previous_secret, ''), webhook_previous_secret_expires_at, media_policy, webhook_omit_raw, COALESCE(instance_token_hash, ''), instance_token_updated_at,
 		          history_sync_status, COALESCE(history_sync_cycle_id::text, ''), history_sync_updated_at, created_at, updated_at
 	`
 
@@ -226,9 +234,9 @@ func (r *instanceRepo) Update(ctx context.Context, inst model.Instance) (model.I
 		inst.ID, inst.Name, inst.OwnerUserID, nullIfEmpty(inst.WhatsAppJID), string(inst.Status), inst.SessionBlob,
 		nullIfEmpty(inst.WebhookURL), nullIfEmpty(inst.WebhookSecret), nullIfEmpty(inst.TokenHash), inst.TokenUpdatedAt,
 		string(inst.HistorySyncStatus), nullIfEmpty(inst.HistorySyncCycleID), inst.HistorySyncUpdatedAt,
-		inst.UpdatedAt,
+		inst.UpdatedAt, nullIfEmpty(inst.WebhookPreviousSecret), inst.WebhookPreviousSecretExpiresAt, string(inst.MediaPolicy), inst.WebhookOmitRaw,
 	).Scan(
-		&inst.ID, &inst.Name, &inst.OwnerUserID, &inst.WhatsAppJID, &inst.Status, &inst.WebhookURL, &inst.WebhookSecret, &inst.TokenHash, &inst.TokenUpdatedAt,
+		&inst.ID, &inst.Name, &inst.OwnerUserID, &inst.WhatsAppJID, &inst.Status, &inst.WebhookURL, &inst.WebhookSecret, &inst.WebhookPreviousSecret, &inst.WebhookPreviousSecretExpiresAt, &inst.MediaPolicy, &inst.WebhookOmitRaw, &inst.TokenHash, &inst.TokenUpdatedAt,
 		&inst.HistorySyncStatus, &inst.HistorySyncCycleID, &inst.HistorySyncUpdatedAt,
 		&inst.CreatedAt, &inst.UpdatedAt,
 	)
@@ -250,6 +258,44 @@ func nullIfEmpty(v string) *string {
 	return &v
 }
 
+func (r *instanceRepo) UpdateWebhookSecret(ctx context.Context, id string, old, sealed model.WebhookSecrets) (bool, error) {
+	query := `
+		UPDATE instances
+		SET webhook_secret = NULLIF($1, ''), webhook_previous_secret = NULLIF($2, '')
+		WHERE id = $3 AND COALESCE(webhook_secret, '') = $4 AND COALESCE(webhook_previous_secret, '') = $5
+	`
+	result, err := r.db.Pool.Exec(ctx, query, sealed.Secret, sealed.Previous, id, old.Secret, old.Previous)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+func (r *instanceRepo) UpdateSessionBlob(ctx context.Context, id string, old, sealed []byte) (bool, error) {
+	result, err := r.db.Pool.Exec(ctx, `UPDATE instances SET session_blob = $1 WHERE id = $2 AND session_blob = $3`, sealed, id, old)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+func (r *instanceRepo) IDsByWhatsAppJID(ctx context.Context, jid string) ([]string, error) {
+	rows, err := r.db.Pool.Query(ctx, `SELECT id FROM instances WHERE whatsapp_jid = $1`, jid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
 func (r *instanceRepo) Delete(ctx context.Context, id string) error {
 	query := `DELETE FROM instances WHERE id = $1`
 	result, err := r.db.Pool.Exec(ctx, query, id)
@@ -0,0 +1,62 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/open-apime/apime/internal/storage/model"
+)
+
+type mediaRefRepo struct {
+	db *DB
+}
+
+func NewMediaRefRepository(db *DB) *mediaRefRepo {
+	return &mediaRefRepo{db: db}
+}
+
+func (r *mediaRefRepo) Upsert(ctx context.Context, ref model.MediaRef) error {
+	now := time.Now()
+	query := `
+		INSERT INTO media_refs (instance_id, message_id, chat_jid, sender_jid, from_me, kind, mimetype, message, media_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (instance_id, message_id) DO UPDATE SET
+			message = EXCLUDED.message,
+			media_id = EXCLUDED.media_id,
+			updated_at = EXCLUDED.updated_at
+	`
+	_, err := r.db.Pool.Exec(ctx, query,
+		ref.InstanceID, ref.MessageID, ref.ChatJID, ref.SenderJID, ref.FromMe, ref.Kind, ref.Mimetype, ref.Message, ref.MediaID, now, now,
+	)
+	return err
+}
+
+func (r *mediaRefRepo) Get(ctx context.Context, instanceID, messageID string) (model.MediaRef, error) {
+	query := `
+		SELECT instance_id, message_id, chat_jid, sender_jid, from_me, kind, mimetype, message, media_id, created_at, updated_at
+		FROM media_refs
+		WHERE instance_id = $1 AND message_id = $2
+	`
+	var ref model.MediaRef
+	err := r.db.Pool.QueryRow(ctx, query, instanceID, messageID).Scan(
+		&ref.InstanceID, &ref.MessageID, &ref.ChatJID, &ref.SenderJID, &ref.FromMe, &ref.Kind, &ref.Mimetype, &ref.Message, &ref.MediaID,
+		&ref.CreatedAt, &ref.UpdatedAt,
+	)
+	if err == pgx.ErrNoRows {
+		return model.MediaRef{}, ErrNotFound
+	}
+	if err != nil {
+		return model.MediaRef{}, err
+	}
+	return ref, nil
+}
+
+func (r *mediaRefRepo) DeleteOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.db.Pool.Exec(ctx, `DELETE FROM media_refs WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
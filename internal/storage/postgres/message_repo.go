@@ -125,6 +125,14 @@ func (r *messageRepo) Update(ctx context.Context, msg model.Message) error {
 	return err
 }
 
+func (r *messageRepo) ClaimQueued(ctx context.Context, id string) (bool, error) {
+	tag, err := r.db.Pool.Exec(ctx, `UPDATE message_queue SET status = 'sending' WHERE id = $1 AND status = 'queued'`, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
 func (r *messageRepo) UpdateStatusByWhatsAppID(ctx context.Context, whatsappID string, status string) error {
 	query := `
 		UPDATE message_queue
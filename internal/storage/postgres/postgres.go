@@ -42,3 +42,7 @@ func (db *DB) Close() {
 		db.Pool.Close()
 	}
 }
+
+func (db *DB) Ping(ctx context.Context) error {
+	return db.Pool.Ping(ctx)
+}
@@ -39,3 +39,7 @@ func (c *Client) Close() error {
 func (c *Client) RDB() *redis.Client {
 	return c.rdb
 }
+
+func (c *Client) Ping(ctx context.Context) error {
+	return c.rdb.Ping(ctx).Err()
+}
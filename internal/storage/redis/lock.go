@@ -40,6 +40,23 @@ func (l *Lock) Release(ctx context.Context) error {
 	return nil
 }
 
+// Extend pushes the expiry back to ttl, only while the lock is still ours.
+// It returns false when the lock expired and may now belong to someone else.
+func (l *Lock) Extend(ctx context.Context) (bool, error) {
+	script := `
+		if redis.call("get", KEYS[1]) == ARGV[1] then
+			return redis.call("pexpire", KEYS[1], ARGV[2])
+		else
+			return 0
+		end
+	`
+	res, err := l.client.rdb.Eval(ctx, script, []string{l.key}, l.value, l.ttl.Milliseconds()).Int()
+	if err != nil && err != redis.Nil {
+		return false, fmt.Errorf("lock extend: %w", err)
+	}
+	return res == 1, nil
+}
+
 func NewLock(client *Client, key string, ttl time.Duration) *Lock {
 	return &Lock{
 		client: client,
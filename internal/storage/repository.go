@@ -2,13 +2,12 @@ package storage
 
 import (
 	"context"
-	"errors"
 	"time"
 
 	"github.com/open-apime/apime/internal/storage/model"
 )
 
-var ErrNotFound = errors.New("not found")
+var ErrNotFound = model.ErrNotFound
 
 type InstanceRepository interface {
 	Create(ctx context.Context, instance model.Instance) (model.Instance, error)
@@ -18,18 +17,38 @@ type InstanceRepository interface {
 	ListByOwner(ctx context.Context, ownerUserID string, query string, limit, offset int) ([]model.Instance, int, error)
 	Update(ctx context.Context, instance model.Instance) (model.Instance, error)
 	Delete(ctx context.Context, id string) error
+	// UpdateWebhookSecret writes the stored webhook secrets only while the row still holds old,
+	// and reports whether it did. Values go in as stored (already sealed); nothing else of the
+	// row is touched, so a concurrent edit is never overwritten.
+	UpdateWebhookSecret(ctx context.Context, id string, old, sealed model.WebhookSecrets) (bool, error)
+	// UpdateSessionBlob is UpdateWebhookSecret for the sealed session blob.
+	UpdateSessionBlob(ctx context.Context, id string, old, sealed []byte) (bool, error)
+	// IDsByWhatsAppJID lists the instances linked to the device. It reads no secret, so no row is
+	// left out for one that doesn't open.
+	IDsByWhatsAppJID(ctx context.Context, jid string) ([]string, error)
 }
 
 type MessageRepository interface {
 	Create(ctx context.Context, message model.Message) (model.Message, error)
 	ListByInstance(ctx context.Context, instanceID string) ([]model.Message, error)
 	Update(ctx context.Context, msg model.Message) error
+	// ClaimQueued moves a queued message to sending and reports whether this
+	// caller did it. A redelivered outbox entry whose message another worker
+	// already took (or sent) gets false and must not be sent again.
+	ClaimQueued(ctx context.Context, id string) (bool, error)
 	UpdateStatusByWhatsAppID(ctx context.Context, whatsappID string, status string) error
 	GetByWhatsAppID(ctx context.Context, whatsappID string) (model.Message, error)
 	GetPendingMessages(ctx context.Context, limit int) ([]model.Message, error)
 	DeleteByInstanceID(ctx context.Context, instanceID string) error
 }
 
+type MediaRefRepository interface {
+	Upsert(ctx context.Context, ref model.MediaRef) error
+	// Get returns ErrNotFound when the message has no media reference.
+	Get(ctx context.Context, instanceID, messageID string) (model.MediaRef, error)
+	DeleteOlderThan(ctx context.Context, before time.Time) (int64, error)
+}
+
 type UserRepository interface {
 	Create(ctx context.Context, user model.User) (model.User, error)
 	GetByID(ctx context.Context, id string) (model.User, error)
@@ -42,6 +61,9 @@ type UserRepository interface {
 type EventLogRepository interface {
 	Create(ctx context.Context, eventLog model.EventLog) (model.EventLog, error)
 	ListByInstance(ctx context.Context, instanceID string) ([]model.EventLog, error)
+	// ListByTypeSince returns every event of one type logged since the given time, oldest
+	// first, without the page limit of ListByInstance.
+	ListByTypeSince(ctx context.Context, instanceID, eventType string, since time.Time) ([]model.EventLog, error)
 	DeleteByInstanceID(ctx context.Context, instanceID string) error
 }
 
@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/open-apime/apime/internal/pkg/crypto"
+	"github.com/open-apime/apime/internal/storage/model"
+)
+
+// sealedInstanceRepo keeps the webhook secrets encrypted at rest with the session key ring.
+// Callers always see them in clear: values are sealed on write and opened on read, so the
+// webhook workers, the dashboard and the API don't need to know about the encryption. Secrets
+// stored before this existed are read as they are and sealed on the next write, always with
+// the primary key.
+//
+// A row whose secret no key of the ring opens fails GetByID, but is only left out of List and
+// ListByOwner (and logged), so one bad row doesn't take every listing down.
+type sealedInstanceRepo struct {
+	InstanceRepository
+	keys *crypto.KeyRing
+	log  *zap.Logger
+}
+
+// SealInstanceSecrets wraps repo so the webhook secrets are encrypted at rest.
+func SealInstanceSecrets(repo InstanceRepository, keys *crypto.KeyRing, log *zap.Logger) InstanceRepository {
+	return &sealedInstanceRepo{InstanceRepository: repo, keys: keys, log: log}
+}
+
+func (r *sealedInstanceRepo) Create(ctx context.Context, inst model.Instance) (model.Instance, error) {
+	if err := r.seal(&inst); err != nil {
+		return model.Instance{}, err
+	}
+	created, err := r.InstanceRepository.Create(ctx, inst)
+	if err != nil {
+		return model.Instance{}, err
+	}
+	return created, r.open(&created)
+}
+
+func (r *sealedInstanceRepo) Update(ctx context.Context, inst model.Instance) (model.Instance, error) {
+	if err := r.seal(&inst); err != nil {
+		return model.Instance{}, err
+	}
+	updated, err := r.InstanceRepository.Update(ctx, inst)
+	if err != nil {
+		return model.Instance{}, err
+	}
+	return updated, r.open(&updated)
+}
+
+func (r *sealedInstanceRepo) GetByID(ctx context.Context, id string) (model.Instance, error) {
+	inst, err := r.InstanceRepository.GetByID(ctx, id)
+	if err != nil {
+		return model.Instance{}, err
+	}
+	return inst, r.open(&inst)
+}
+
+func (r *sealedInstanceRepo) GetByTokenHash(ctx context.Context, tokenHash string) (model.Instance, error) {
+	inst, err := r.InstanceRepository.GetByTokenHash(ctx, tokenHash)
+	if err != nil {
+		return model.Instance{}, err
+	}
+	return inst, r.open(&inst)
+}
+
+func (r *sealedInstanceRepo) List(ctx context.Context, query string, limit, offset int) ([]model.Instance, int, error) {
+	list, total, err := r.InstanceRepository.List(ctx, query, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	opened := r.openAll(list)
+	return opened, total - (len(list) - len(opened)), nil
+}
+
+func (r *sealedInstanceRepo) ListByOwner(ctx context.Context, ownerUserID string, query string, limit, offset int) ([]model.Instance, int, error) {
+	list, total, err := r.InstanceRepository.ListByOwner(ctx, ownerUserID, query, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	opened := r.openAll(list)
+	return opened, total - (len(list) - len(opened)), nil
+}
+
+func (r *sealedInstanceRepo) seal(inst *model.Instance) error {
+	for _, secret := range []*string{&inst.WebhookSecret, &inst.WebhookPreviousSecret} {
+		if *secret == "" {
+			continue
+		}
+		sealed, err := r.keys.SealString(*secret)
+		if err != nil {
+			return fmt.Errorf("storage: cifrar segredo do webhook: %w", err)
+		}
+		*secret = sealed
+	}
+	return nil
+}
+
+func (r *sealedInstanceRepo) open(inst *model.Instance) error {
+	for _, secret := range []*string{&inst.WebhookSecret, &inst.WebhookPreviousSecret} {
+		plain, err := r.keys.OpenString(*secret)
+		if err != nil {
+			return fmt.Errorf("storage: decifrar segredo do webhook da instância %s: %w", inst.ID, err)
+		}
+		*secret = plain
+	}
+	return nil
+}
+
+// openAll opens each row on its own and drops the ones that fail, logging them.
+func (r *sealedInstanceRepo) openAll(list []model.Instance) []model.Instance {
+	opened := list[:0]
+	for i := range list {
+		if err := r.open(&list[i]); err != nil {
+			if r.log != nil {
+				r.log.Error("instância omitida da listagem: segredo do webhook não abre com o chaveiro",
+					zap.String("instance_id", list[i].ID), zap.Error(err))
+			}
+			continue
+		}
+		opened = append(opened, list[i])
+	}
+	return opened
+}
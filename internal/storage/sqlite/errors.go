@@ -3,9 +3,11 @@ package sqlite
 import (
 	"database/sql"
 	"errors"
+
+	"github.com/open-apime/apime/internal/storage/model"
 )
 
-var ErrNotFound = errors.New("not found")
+var ErrNotFound = model.ErrNotFound
 
 var ErrLastAdmin = errors.New("cannot delete the last admin user")
 
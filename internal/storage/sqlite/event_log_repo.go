@@ -67,6 +67,27 @@ func (r *eventLogRepo) ListByInstance(ctx context.Context, instanceID string) ([
 	}
 	defer rows.Close()
 
+	return scanEventLogs(rows)
+}
+
+func (r *eventLogRepo) ListByTypeSince(ctx context.Context, instanceID, eventType string, since time.Time) ([]model.EventLog, error) {
+	query := `
+		SELECT id, instance_id, type, payload, delivered_at, created_at
+		FROM event_logs
+		WHERE instance_id = ? AND type = ? AND datetime(created_at) >= datetime(?)
+		ORDER BY created_at ASC
+	`
+
+	rows, err := r.db.Conn.QueryContext(ctx, query, instanceID, eventType, since.Format(time.RFC3339))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanEventLogs(rows)
+}
+
+func scanEventLogs(rows *sql.Rows) ([]model.EventLog, error) {
 	var eventLogs []model.EventLog
 	for rows.Next() {
 		var eventLog model.EventLog
@@ -28,15 +28,18 @@ func (r *instanceRepo) Create(ctx context.Context, inst model.Instance) (model.I
 	if inst.HistorySyncStatus == "" {
 		inst.HistorySyncStatus = model.HistorySyncStatusPending
 	}
+	if inst.MediaPolicy == "" {
+		inst.MediaPolicy = model.MediaPolicyEager
+	}
 
 	query := `
-		INSERT INTO instances (id, name, owner_user_id, whatsapp_jid, status, session_blob, webhook_url, webhook_secret, instance_token_hash, instance_token_updated_at, history_sync_status, history_sync_cycle_id, history_sync_updated_at, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO instances (id, name, owner_user_id, whatsapp_jid, status, session_blob, webhook_url, webhook_secret, webhook_previous_secret, webhook_previous_secret_expires_at, media_policy, webhook_omit_raw, instance_token_hash, instance_token_updated_at, history_sync_status, history_sync_cycle_id, history_sync_updated_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	_, err := r.db.Conn.ExecContext(ctx, query,
 		inst.ID, inst.Name, inst.OwnerUserID, nullIfEmpty(inst.WhatsAppJID), string(inst.Status), inst.SessionBlob,
-		nullIfEmpty(inst.WebhookURL), nullIfEmpty(inst.WebhookSecret), nullIfEmpty(inst.TokenHash),
+		nullIfEmpty(inst.WebhookURL), nullIfEmpty(inst.WebhookSecret), nullIfEmpty(inst.WebhookPreviousSecret), formatTimePtr(inst.WebhookPreviousSecretExpiresAt), string(inst.MediaPolicy), inst.WebhookOmitRaw, nullIfEmpty(inst.TokenHash),
 		formatTimePtr(inst.TokenUpdatedAt), string(inst.HistorySyncStatus), nullIfEmpty(inst.HistorySyncCycleID), formatTimePtr(inst.HistorySyncUpdatedAt),
 		inst.CreatedAt.Format(time.RFC3339), inst.UpdatedAt.Format(time.RFC3339),
 	)
@@ -50,7 +53,7 @@ func (r *instanceRepo) Create(ctx context.Context, inst model.Instance) (model.I
 
 func (r *instanceRepo) GetByTokenHash(ctx context.Context, tokenHash string) (model.Instance, error) {
 	query := `
-		SELECT id, name, owner_user_id, COALESCE(whatsapp_jid, ''), status, session_blob, COALESCE(webhook_url, ''), COALESCE(webhook_secret, ''), COALESCE(instance_token_hash, ''), instance_token_updated_at,
+		SELECT id, name, owner_user_id, COALESCE(whatsapp_jid, ''), status, session_blob, COALESCE(webhook_url, ''), COALESCE(webhook_secret, ''), COALESCE(webhook_previous_secret, ''), webhook_previous_secret_expires_at, media_policy, webhook_omit_raw, COALESCE(instance_token_hash, ''), instance_token_updated_at,
 		       history_sync_status, COALESCE(history_sync_cycle_id, ''), history_sync_updated_at, created_at, updated_at
 		FROM instances
 		WHERE instance_token_hash = ?
@@ -58,11 +61,11 @@ func (r *instanceRepo) GetByTokenHash(ctx context.Context, tokenHash string) (mo
 
 	var inst model.Instance
 	var createdAt, updatedAt string
-	var tokenUpdatedAt, historySyncUpdatedAt sql.NullString
+	var tokenUpdatedAt, historySyncUpdatedAt, previousSecretExpiresAt sql.NullString
 
 	err := r.db.Conn.QueryRowContext(ctx, query, tokenHash).Scan(
 		&inst.ID, &inst.Name, &inst.OwnerUserID, &inst.WhatsAppJID, &inst.Status, &inst.SessionBlob,
-		&inst.WebhookURL, &inst.WebhookSecret, &inst.TokenHash, &tokenUpdatedAt,
+		&inst.WebhookURL, &inst.WebhookSecret, &inst.WebhookPreviousSecret, &previousSecretExpiresAt, &inst.MediaPolicy, &inst.WebhookOmitRaw, &inst.TokenHash, &tokenUpdatedAt,
 		&inst.HistorySyncStatus, &inst.HistorySyncCycleID, &historySyncUpdatedAt,
 		&createdAt, &updatedAt,
 	)
@@ -74,13 +77,14 @@ func (r *instanceRepo) GetByTokenHash(ctx context.Context, tokenHash string) (mo
 	inst.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
 	inst.TokenUpdatedAt = parseTimePtr(tokenUpdatedAt.String)
 	inst.HistorySyncUpdatedAt = parseTimePtr(historySyncUpdatedAt.String)
+	inst.WebhookPreviousSecretExpiresAt = parseTimePtr(previousSecretExpiresAt.String)
 
 	return inst, nil
 }
 
 func (r *instanceRepo) GetByID(ctx context.Context, id string) (model.Instance, error) {
 	query := `
-		SELECT id, name, owner_user_id, COALESCE(whatsapp_jid, ''), status, session_blob, COALESCE(webhook_url, ''), COALESCE(webhook_secret, ''), COALESCE(instance_token_hash, ''), instance_token_updated_at,
+		SELECT id, name, owner_user_id, COALESCE(whatsapp_jid, ''), status, session_blob, COALESCE(webhook_url, ''), COALESCE(webhook_secret, ''), COALESCE(webhook_previous_secret, ''), webhook_previous_secret_expires_at, media_policy, webhook_omit_raw, COALESCE(instance_token_hash, ''), instance_token_updated_at,
 		       history_sync_status, COALESCE(history_sync_cycle_id, ''), history_sync_updated_at, created_at, updated_at
 		FROM instances
 		WHERE id = ?
@@ -88,11 +92,11 @@ func (r *instanceRepo) GetByID(ctx context.Context, id string) (model.Instance,
 
 	var inst model.Instance
 	var createdAt, updatedAt string
-	var tokenUpdatedAt, historySyncUpdatedAt sql.NullString
+	var tokenUpdatedAt, historySyncUpdatedAt, previousSecretExpiresAt sql.NullString
 
 	err := r.db.Conn.QueryRowContext(ctx, query, id).Scan(
 		&inst.ID, &inst.Name, &inst.OwnerUserID, &inst.WhatsAppJID, &inst.Status, &inst.SessionBlob,
-		&inst.WebhookURL, &inst.WebhookSecret, &inst.TokenHash, &tokenUpdatedAt,
+		&inst.WebhookURL, &inst.WebhookSecret, &inst.WebhookPreviousSecret, &previousSecretExpiresAt, &inst.MediaPolicy, &inst.WebhookOmitRaw, &inst.TokenHash, &tokenUpdatedAt,
 		&inst.HistorySyncStatus, &inst.HistorySyncCycleID, &historySyncUpdatedAt,
 		&createdAt, &updatedAt,
 	)
@@ -104,6 +108,7 @@ func (r *instanceRepo) GetByID(ctx context.Context, id string) (model.Instance,
 	inst.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
 	inst.TokenUpdatedAt = parseTimePtr(tokenUpdatedAt.String)
 	inst.HistorySyncUpdatedAt = parseTimePtr(historySyncUpdatedAt.String)
+	inst.WebhookPreviousSecretExpiresAt = parseTimePtr(previousSecretExpiresAt.String)
 
 	return inst, nil
 }
@@ -124,7 +129,7 @@ func (r *instanceRepo) List(ctx context.Context, searchQuery string, limit, offs
 	}
 
 	query := `
-		SELECT i.id, i.name, i.owner_user_id, COALESCE(u.email, ''), COALESCE(i.whatsapp_jid, ''), i.status, COALESCE(i.webhook_url, ''), COALESCE(i.webhook_secret, ''), COALESCE(i.instance_token_hash, ''), i.instance_token_updated_at,
+		SELECT i.id, i.name, i.owner_user_id, COALESCE(u.email, ''), COALESCE(i.whatsapp_jid, ''), i.status, COALESCE(i.webhook_url, ''), COALESCE(i.webhook_secret, ''), COALESCE(i.webhook_previous_secret, ''), i.webhook_previous_secret_expires_at, i.media_policy, i.webhook_omit_raw, COALESCE(i.instance_token_hash, ''), i.instance_token_updated_at,
 		       i.history_sync_status, COALESCE(i.history_sync_cycle_id, ''), i.history_sync_updated_at, i.created_at, i.updated_at
 		FROM instances i
 		LEFT JOIN users u ON i.owner_user_id = u.id
@@ -145,11 +150,11 @@ func (r *instanceRepo) List(ctx context.Context, searchQuery string, limit, offs
 	for rows.Next() {
 		var inst model.Instance
 		var createdAt, updatedAt string
-		var tokenUpdatedAt, historySyncUpdatedAt sql.NullString
+		var tokenUpdatedAt, historySyncUpdatedAt, previousSecretExpiresAt sql.NullString
 
 		if err := rows.Scan(
 			&inst.ID, &inst.Name, &inst.OwnerUserID, &inst.OwnerEmail, &inst.WhatsAppJID, &inst.Status,
-			&inst.WebhookURL, &inst.WebhookSecret, &inst.TokenHash, &tokenUpdatedAt,
+			&inst.WebhookURL, &inst.WebhookSecret, &inst.WebhookPreviousSecret, &previousSecretExpiresAt, &inst.MediaPolicy, &inst.WebhookOmitRaw, &inst.TokenHash, &tokenUpdatedAt,
 			&inst.HistorySyncStatus, &inst.HistorySyncCycleID, &historySyncUpdatedAt,
 			&createdAt, &updatedAt,
 		); err != nil {
@@ -160,6 +165,7 @@ func (r *instanceRepo) List(ctx context.Context, searchQuery string, limit, offs
 		inst.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
 		inst.TokenUpdatedAt = parseTimePtr(tokenUpdatedAt.String)
 		inst.HistorySyncUpdatedAt = parseTimePtr(historySyncUpdatedAt.String)
+		inst.WebhookPreviousSecretExpiresAt = parseTimePtr(previousSecretExpiresAt.String)
 
 		instances = append(instances, inst)
 	}
@@ -184,7 +190,7 @@ func (r *instanceRepo) ListByOwner(ctx context.Context, ownerUserID string, sear
 	}
 
 	query := `
-		SELECT i.id, i.name, i.owner_user_id, COALESCE(u.email, ''), COALESCE(i.whatsapp_jid, ''), i.status, COALESCE(i.webhook_url, ''), COALESCE(i.webhook_secret, ''), COALESCE(i.instance_token_hash, ''), i.instance_token_updated_at,
+		SELECT i.id, i.name, i.owner_user_id, COALESCE(u.email, ''), COALESCE(i.whatsapp_jid, ''), i.status, COALESCE(i.webhook_url, ''), COALESCE(i.webhook_secret, ''), COALESCE(i.webhook_previous_secret, ''), i.webhook_previous_secret_expires_at, i.media_policy, i.webhook_omit_raw, COALESCE(i.instance_token_hash, ''), i.instance_token_updated_at,
 		       i.history_sync_status, COALESCE(i.history_sync_cycle_id, ''), i.history_sync_updated_at, i.created_at, i.updated_at
 		FROM instances i
 		LEFT JOIN users u ON i.owner_user_id = u.id
@@ -205,11 +211,11 @@ func (r *instanceRepo) ListByOwner(ctx context.Context, ownerUserID string, sear
 	for rows.Next() {
 		var inst model.Instance
 		var createdAt, updatedAt string
-		var tokenUpdatedAt, historySyncUpdatedAt sql.NullString
+		var tokenUpdatedAt, historySyncUpdatedAt, previousSecretExpiresAt sql.NullString
 
 		if err := rows.Scan(
 			&inst.ID, &inst.Name, &inst.OwnerUserID, &inst.OwnerEmail, &inst.WhatsAppJID, &inst.Status,
-			&inst.WebhookURL, &inst.WebhookSecret, &inst.TokenHash, &tokenUpdatedAt,
+			&inst.WebhookURL, &inst.WebhookSecret, &inst.WebhookPreviousSecret, &previousSecretExpiresAt, &inst.MediaPolicy, &inst.WebhookOmitRaw, &inst.TokenHash, &tokenUpdatedAt,
 			&inst.HistorySyncStatus, &inst.HistorySyncCycleID, &historySyncUpdatedAt,
 			&createdAt, &updatedAt,
 		); err != nil {
@@ -220,6 +226,7 @@ func (r *instanceRepo) ListByOwner(ctx context.Context, ownerUserID string, sear
 		inst.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
 		inst.TokenUpdatedAt = parseTimePtr(tokenUpdatedAt.String)
 		inst.HistorySyncUpdatedAt = parseTimePtr(historySyncUpdatedAt.String)
+		inst.WebhookPreviousSecretExpiresAt = parseTimePtr(previousSecretExpiresAt.String)
 
 		instances = append(instances, inst)
 	}
@@ -229,17 +236,21 @@ func (r *instanceRepo) ListByOwner(ctx context.Context, ownerUserID string, sear
 
 func (r *instanceRepo) Update(ctx context.Context, inst model.Instance) (model.Instance, error) {
 	inst.UpdatedAt = time.Now()
+	if inst.MediaPolicy == "" {
+		inst.MediaPolicy = model.MediaPolicyEager
+	}
 
 	query := `
 		UPDATE instances
-		SET name = ?, owner_user_id = ?, whatsapp_jid = ?, status = ?, session_blob = ?, webhook_url = ?, webhook_secret = ?, instance_token_hash = ?, instance_token_updated_at = ?,
+		SET name = ?, owner_user_id = ?, whatsapp_jid = ?, status = ?, session_blob = ?, webhook_url = ?, webhook_secret = ?, webhook_previous_secret = ?, webhook_previous_secret_expires_at = ?, media_policy = ?, webhook_omit_raw = ?,
+		    instance_token_hash = ?, instance_token_updated_at = ?,
 		    history_sync_status = ?, history_sync_cycle_id = ?, history_sync_updated_at = ?, updated_at = ?
 		WHERE id = ?
 	`
 
 	result, err := r.db.Conn.ExecContext(ctx, query,
 		inst.Name, inst.OwnerUserID, nullIfEmpty(inst.WhatsAppJID), string(inst.Status), inst.SessionBlob,
-		nullIfEmpty(inst.WebhookURL), nullIfEmpty(inst.WebhookSecret), nullIfEmpty(inst.TokenHash),
+		nullIfEmpty(inst.WebhookURL), nullIfEmpty(inst.WebhookSecret), nullIfEmpty(inst.WebhookPreviousSecret), formatTimePtr(inst.WebhookPreviousSecretExpiresAt), string(inst.MediaPolicy), inst.WebhookOmitRaw, nullIfEmpty(inst.TokenHash),
 		formatTimePtr(inst.TokenUpdatedAt), string(inst.HistorySyncStatus), nullIfEmpty(inst.HistorySyncCycleID), formatTimePtr(inst.HistorySyncUpdatedAt),
 		inst.UpdatedAt.Format(time.RFC3339), inst.ID,
 	)
@@ -278,6 +289,48 @@ func formatTimePtr(t *time.Time) *string {
 	return &s
 }
 
+func (r *instanceRepo) UpdateWebhookSecret(ctx context.Context, id string, old, sealed model.WebhookSecrets) (bool, error) {
+	query := `
+		UPDATE instances
+		SET webhook_secret = ?, webhook_previous_secret = ?
+		WHERE id = ? AND COALESCE(webhook_secret, '') = ? AND COALESCE(webhook_previous_secret, '') = ?
+	`
+	result, err := r.db.Conn.ExecContext(ctx, query,
+		nullIfEmpty(sealed.Secret), nullIfEmpty(sealed.Previous), id, old.Secret, old.Previous,
+	)
+	if err != nil {
+		return false, err
+	}
+	rows, _ := result.RowsAffected()
+	return rows == 1, nil
+}
+
+func (r *instanceRepo) UpdateSessionBlob(ctx context.Context, id string, old, sealed []byte) (bool, error) {
+	result, err := r.db.Conn.ExecContext(ctx, `UPDATE instances SET session_blob = ? WHERE id = ? AND session_blob = ?`, sealed, id, old)
+	if err != nil {
+		return false, err
+	}
+	rows, _ := result.RowsAffected()
+	return rows == 1, nil
+}
+
+func (r *instanceRepo) IDsByWhatsAppJID(ctx context.Context, jid string) ([]string, error) {
+	rows, err := r.db.Conn.QueryContext(ctx, `SELECT id FROM instances WHERE whatsapp_jid = ?`, jid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
 func (r *instanceRepo) Delete(ctx context.Context, id string) error {
 	query := `DELETE FROM instances WHERE id = ?`
 	result, err := r.db.Conn.ExecContext(ctx, query, id)
@@ -0,0 +1,61 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"github.com/open-apime/apime/internal/storage/model"
+)
+
+type mediaRefRepo struct {
+	db *DB
+}
+
+func NewMediaRefRepository(db *DB) *mediaRefRepo {
+	return &mediaRefRepo{db: db}
+}
+
+func (r *mediaRefRepo) Upsert(ctx context.Context, ref model.MediaRef) error {
+	now := time.Now().UTC()
+	query := `
+		INSERT INTO media_refs (instance_id, message_id, chat_jid, sender_jid, from_me, kind, mimetype, message, media_id, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(instance_id, message_id) DO UPDATE SET
+			message = excluded.message,
+			media_id = excluded.media_id,
+			updated_at = excluded.updated_at
+	`
+	_, err := r.db.Conn.ExecContext(ctx, query,
+		ref.InstanceID, ref.MessageID, ref.ChatJID, ref.SenderJID, ref.FromMe, ref.Kind, ref.Mimetype, ref.Message, ref.MediaID,
+		now.Format(time.RFC3339), now.Format(time.RFC3339),
+	)
+	return err
+}
+
+func (r *mediaRefRepo) Get(ctx context.Context, instanceID, messageID string) (model.MediaRef, error) {
+	query := `
+		SELECT instance_id, message_id, chat_jid, sender_jid, from_me, kind, mimetype, message, media_id, created_at, updated_at
+		FROM media_refs
+		WHERE instance_id = ? AND message_id = ?
+	`
+	var ref model.MediaRef
+	var createdAt, updatedAt string
+	err := r.db.Conn.QueryRowContext(ctx, query, instanceID, messageID).Scan(
+		&ref.InstanceID, &ref.MessageID, &ref.ChatJID, &ref.SenderJID, &ref.FromMe, &ref.Kind, &ref.Mimetype, &ref.Message, &ref.MediaID,
+		&createdAt, &updatedAt,
+	)
+	if err != nil {
+		return model.MediaRef{}, mapError(err)
+	}
+	ref.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+	ref.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
+	return ref, nil
+}
+
+func (r *mediaRefRepo) DeleteOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.Conn.ExecContext(ctx, `DELETE FROM media_refs WHERE created_at < ?`, before.UTC().Format(time.RFC3339))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
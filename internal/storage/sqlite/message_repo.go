@@ -114,6 +114,18 @@ func (r *messageRepo) Update(ctx context.Context, msg model.Message) error {
 	return err
 }
 
+func (r *messageRepo) ClaimQueued(ctx context.Context, id string) (bool, error) {
+	res, err := r.db.Conn.ExecContext(ctx, `UPDATE message_queue SET status = 'sending' WHERE id = ? AND status = 'queued'`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
 func (r *messageRepo) UpdateStatusByWhatsAppID(ctx context.Context, whatsappID string, status string) error {
 	deliveredAt := time.Now().Format(time.RFC3339)
 	query := `
@@ -49,3 +49,7 @@ func (db *DB) Close() error {
 	}
 	return nil
 }
+
+func (db *DB) Ping(ctx context.Context) error {
+	return db.Conn.PingContext(ctx)
+}
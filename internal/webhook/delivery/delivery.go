@@ -9,11 +9,24 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"go.uber.org/zap"
 )
 
+const (
+	// SignatureHeader carries "t=<unix>,v1=<hex>[,v1=<hex>]": the HMAC-SHA256 of "<t>.<body>" with
+	// each valid secret, so a receiver can reject replays and keep working during a rotation.
+	SignatureHeader = "X-ApiMe-Webhook-Signature"
+	// LegacySignatureHeader is the original "sha256=<hex>" of the body. It carries a single
+	// signature, so during a rotation it keeps the previous secret until the grace period ends.
+	LegacySignatureHeader = "X-ApiMe-Signature"
+	// DefaultTolerance is how old a timestamp VerifySignature accepts.
+	DefaultTolerance = 5 * time.Minute
+)
+
 type Delivery struct {
 	client     *http.Client
 	log        *zap.Logger
@@ -30,25 +43,15 @@ func NewDelivery(log *zap.Logger, maxRetries int) *Delivery {
 	}
 }
 
-func (d *Delivery) Deliver(ctx context.Context, url string, secret string, event map[string]interface{}) error {
+// Deliver posts event to url. secrets[0] is the current webhook secret; the others are previous
+// secrets still inside their grace period: they add signatures to SignatureHeader and the last
+// one signs LegacySignatureHeader.
+func (d *Delivery) Deliver(ctx context.Context, url string, secrets []string, event map[string]interface{}) error {
 	payload, err := json.Marshal(event)
 	if err != nil {
 		return fmt.Errorf("delivery: marshal: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(payload))
-	if err != nil {
-		return fmt.Errorf("delivery: new request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("User-Agent", "ApiMe/1.0")
-
-	if secret != "" {
-		signature := d.generateSignature(payload, secret)
-		req.Header.Set("X-ApiMe-Signature", signature)
-	}
-
 	var lastErr error
 	for attempt := 0; attempt <= d.maxRetries; attempt++ {
 		if attempt > 0 {
@@ -57,6 +60,18 @@ func (d *Delivery) Deliver(ctx context.Context, url string, secret string, event
 			time.Sleep(backoff)
 		}
 
+		// Built per attempt: the body reader is consumed by each send and the timestamp must be fresh.
+		req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(payload))
+		if err != nil {
+			return fmt.Errorf("delivery: new request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "ApiMe/1.0")
+		if len(secrets) > 0 && secrets[0] != "" {
+			req.Header.Set(LegacySignatureHeader, legacySignature(payload, legacySecret(secrets)))
+			req.Header.Set(SignatureHeader, Sign(payload, secrets, time.Now()))
+		}
+
 		resp, err := d.client.Do(req)
 		if err != nil {
 			lastErr = fmt.Errorf("delivery: request: %w", err)
@@ -76,13 +91,73 @@ func (d *Delivery) Deliver(ctx context.Context, url string, secret string, event
 	return fmt.Errorf("delivery: falhou após %d tentativas: %w", d.maxRetries+1, lastErr)
 }
 
-func (d *Delivery) generateSignature(payload []byte, secret string) string {
+// Sign builds the SignatureHeader value for payload at time ts, with one v1 entry per secret.
+func Sign(payload []byte, secrets []string, ts time.Time) string {
+	t := strconv.FormatInt(ts.Unix(), 10)
+	parts := []string{"t=" + t}
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+		parts = append(parts, "v1="+signedPayloadMAC(t, payload, secret))
+	}
+	return strings.Join(parts, ",")
+}
+
+// VerifySignature checks a SignatureHeader value against secret: one of its v1 entries must match
+// and its timestamp must be within tolerance of now. It is what a receiver is expected to do.
+func VerifySignature(payload []byte, header, secret string, tolerance time.Duration, now time.Time) bool {
+	var t string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			t = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	unix, err := strconv.ParseInt(t, 10, 64)
+	if err != nil {
+		return false
+	}
+	if age := now.Sub(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+		return false
+	}
+	expected := []byte(signedPayloadMAC(t, payload, secret))
+	for _, sig := range signatures {
+		if hmac.Equal([]byte(sig), expected) {
+			return true
+		}
+	}
+	return false
+}
+
+func signedPayloadMAC(t string, payload []byte, secret string) string {
 	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
 	mac.Write(payload)
-	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	return hex.EncodeToString(mac.Sum(nil))
 }
 
-func (d *Delivery) VerifySignature(payload []byte, signature, secret string) bool {
-	expected := d.generateSignature(payload, secret)
-	return hmac.Equal([]byte(signature), []byte(expected))
+// legacySecret picks the oldest secret still valid: a receiver of the legacy header can't be
+// handed two signatures, and it only switches to the new secret when it is told to.
+func legacySecret(secrets []string) string {
+	for i := len(secrets) - 1; i >= 0; i-- {
+		if secrets[i] != "" {
+			return secrets[i]
+		}
+	}
+	return ""
+}
+
+func legacySignature(payload []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
 }
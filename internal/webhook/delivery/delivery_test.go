@@ -0,0 +1,38 @@
+package delivery
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSignatureDuringRotation(t *testing.T) {
+	payload := []byte(`{"type":"message"}`)
+	now := time.Unix(1_700_000_000, 0)
+	header := Sign(payload, []string{"novo", "antigo"}, now)
+
+	for _, secret := range []string{"novo", "antigo"} {
+		if !VerifySignature(payload, header, secret, DefaultTolerance, now.Add(time.Minute)) {
+			t.Fatalf("assinatura com o segredo %q deveria ser válida: %s", secret, header)
+		}
+	}
+	if VerifySignature(payload, header, "outro", DefaultTolerance, now) {
+		t.Fatalf("segredo desconhecido não deveria validar")
+	}
+	if VerifySignature([]byte(`{"type":"other"}`), header, "novo", DefaultTolerance, now) {
+		t.Fatalf("payload alterado não deveria validar")
+	}
+	if VerifySignature(payload, header, "novo", DefaultTolerance, now.Add(10*time.Minute)) {
+		t.Fatalf("timestamp fora da tolerância não deveria validar")
+	}
+}
+
+func TestLegacySignatureKeepsPreviousSecretDuringGrace(t *testing.T) {
+	payload := []byte(`{"type":"message"}`)
+
+	if got, want := legacySignature(payload, legacySecret([]string{"novo", "antigo"})), legacySignature(payload, "antigo"); got != want {
+		t.Fatalf("durante a transição o header antigo deveria usar o segredo anterior: %s != %s", got, want)
+	}
+	if got, want := legacySignature(payload, legacySecret([]string{"novo"})), legacySignature(payload, "novo"); got != want {
+		t.Fatalf("sem segredo anterior o header antigo deveria usar o atual: %s != %s", got, want)
+	}
+}
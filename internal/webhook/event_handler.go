@@ -11,15 +11,33 @@ import (
 	"go.mau.fi/whatsmeow/types/events"
 	"go.uber.org/zap"
 
+	"github.com/open-apime/apime/internal/pkg/mediaurl"
 	"github.com/open-apime/apime/internal/pkg/queue"
 	"github.com/open-apime/apime/internal/storage"
 	"github.com/open-apime/apime/internal/storage/media"
+	"github.com/open-apime/apime/internal/storage/model"
+	"github.com/open-apime/apime/internal/webhook/payload"
 )
 
 type InstanceChecker interface {
 	HasWebhook(ctx context.Context, instanceID string) bool
 }
 
+// MediaPolicyResolver tells how an instance handles the media it receives.
+type MediaPolicyResolver interface {
+	MediaPolicy(ctx context.Context, instanceID string) model.MediaPolicy
+}
+
+// MediaRetryListener receives the phone's answers to media re-upload requests.
+type MediaRetryListener interface {
+	HandleMediaRetry(instanceID string, evt *events.MediaRetry)
+}
+
+// BlocklistListener is told about blocks and unblocks, so the send guard sees them.
+type BlocklistListener interface {
+	HandleBlocklist(instanceID string, evt *events.Blocklist)
+}
+
 type JIDConfirmer interface {
 	ConfirmJID(ctx context.Context, jid types.JID)
 }
@@ -32,6 +50,12 @@ type EventHandler struct {
 	apiBaseURL      string
 	instanceChecker InstanceChecker
 	jidConfirmer    JIDConfirmer
+	mediaSigner     *mediaurl.Signer
+	mediaLimits     media.Limits
+	mediaRefs       storage.MediaRefRepository
+	mediaPolicies   MediaPolicyResolver
+	mediaRetries    MediaRetryListener
+	blocklists      BlocklistListener
 }
 
 func NewEventHandler(q queue.Queue, log *zap.Logger, mediaStorage *media.Storage, messageRepo storage.MessageRepository, apiBaseURL string, instanceChecker InstanceChecker, jidConfirmer JIDConfirmer) *EventHandler {
@@ -46,7 +70,48 @@ func NewEventHandler(q queue.Queue, log *zap.Logger, mediaStorage *media.Storage
 	}
 }
 
+// SetMediaSigner makes the mediaUrl of payloads a signed, expiring link. Without it the URL
+// only opens with the instance token.
+func (h *EventHandler) SetMediaSigner(signer *mediaurl.Signer) {
+	h.mediaSigner = signer
+}
+
+// SetMediaLimits skips downloading inbound media above the size limit of its kind; the webhook
+// still carries the metadata, without mediaUrl.
+func (h *EventHandler) SetMediaLimits(limits media.Limits) {
+	h.mediaLimits = limits
+}
+
+// SetMediaRefs records, for each received media, what is needed to download it later, and applies
+// the instance media policy: eager downloads right away, lazy only on request, none neither.
+// Without it every media is downloaded right away.
+func (h *EventHandler) SetMediaRefs(refs storage.MediaRefRepository, policies MediaPolicyResolver) {
+	h.mediaRefs = refs
+	h.mediaPolicies = policies
+}
+
+// SetMediaRetryListener forwards media retry events, the answers to re-upload requests.
+func (h *EventHandler) SetMediaRetryListener(l MediaRetryListener) {
+	h.mediaRetries = l
+}
+
+// SetBlocklistListener forwards blocklist changes, with or without a webhook configured.
+func (h *EventHandler) SetBlocklistListener(l BlocklistListener) {
+	h.blocklists = l
+}
+
 func (h *EventHandler) Handle(ctx context.Context, instanceID string, instanceJID string, client *whatsmeow.Client, evt any) {
+	// Answers to re-upload requests are needed whether or not the instance has a webhook.
+	if retry, ok := evt.(*events.MediaRetry); ok {
+		if h.mediaRetries != nil {
+			h.mediaRetries.HandleMediaRetry(instanceID, retry)
+		}
+		return
+	}
+	if blocklist, ok := evt.(*events.Blocklist); ok && h.blocklists != nil {
+		h.blocklists.HandleBlocklist(instanceID, blocklist)
+	}
+
 	if h.instanceChecker != nil && !h.instanceChecker.HasWebhook(ctx, instanceID) {
 		h.log.Info("[dispatcher] evento ignorado: instância sem webhook configurado", zap.String("instance", instanceID))
 		return
@@ -80,21 +145,27 @@ func (h *EventHandler) Handle(ctx context.Context, instanceID string, instanceJI
 
 	normalized := h.normalizeEvent(ctx, instanceID, client, evt)
 
-	// Events marked as "ignore" don't generate a webhook (e.g. an undecryptable
+	// nil means the event doesn't generate a webhook (e.g. an undecryptable
 	// message coming from myself or from a group).
-	if t, _ := normalized["type"].(string); t == "ignore" {
+	if normalized == nil {
 		return
 	}
 
 	if instanceJID != "" {
-		normalized["instanceJID"] = instanceJID
+		normalized.Header().InstanceJID = instanceJID
+	}
+
+	data, err := payload.Map(normalized)
+	if err != nil {
+		h.log.Error("[dispatcher] event handler: erro ao serializar payload", zap.String("type", normalized.Header().Type), zap.Error(err))
+		return
 	}
 
 	event := queue.Event{
 		ID:         h.generateEventID(),
 		InstanceID: instanceID,
-		Type:       normalized["type"].(string),
-		Payload:    normalized,
+		Type:       normalized.Header().Type,
+		Payload:    data,
 		CreatedAt:  time.Now(),
 	}
 
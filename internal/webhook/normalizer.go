@@ -14,7 +14,11 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/google/uuid"
+	"google.golang.org/protobuf/proto"
+
 	messageSvc "github.com/open-apime/apime/internal/service/message"
+	"github.com/open-apime/apime/internal/storage/model"
+	"github.com/open-apime/apime/internal/webhook/payload"
 )
 
 // confirmJIDFromEvent extracts the s.whatsapp.net JID from the event (resolving @lid via Alt)
@@ -108,24 +112,30 @@ func nfString(m map[string]interface{}, keys ...string) string {
 	return ""
 }
 
-func (h *EventHandler) normalizeEvent(ctx context.Context, instanceID string, client *whatsmeow.Client, evt any) map[string]interface{} {
-	result := make(map[string]interface{})
+// normalizeEvent turns a whatsmeow event into its webhook payload. nil means the event isn't
+// delivered (the "ignore" type: undecryptable message from myself or from a group, contact sync
+// without username).
+func (h *EventHandler) normalizeEvent(ctx context.Context, instanceID string, client *whatsmeow.Client, evt any) payload.Event {
+	var result payload.Event
 
 	switch evt := evt.(type) {
 	case *events.Message:
+		if evt.Info.Chat.Server == types.NewsletterServer {
+			result = h.normalizeNewsletterMessage(ctx, instanceID, client, evt)
+			break
+		}
 		if reaction := evt.Message.GetReactionMessage(); reaction != nil {
-			result["type"] = "reaction"
-
-			result["reactionEmoji"] = reaction.GetText()
+			p := &payload.Reaction{Base: payload.NewBase(payload.TypeReaction)}
+			p.ReactionEmoji = reaction.GetText()
 			if key := reaction.GetKey(); key != nil {
-				result["reactionMessageId"] = key.GetID()
+				p.ReactionMessageID = key.GetID()
 			}
 
 			senderJID := evt.Info.Sender.String()
 			if strings.Contains(senderJID, "@lid") && !evt.Info.SenderAlt.IsEmpty() {
 				senderJID = evt.Info.SenderAlt.String()
 			}
-			result["from"] = senderJID
+			p.From = senderJID
 
 			chatJID := evt.Info.Chat.String()
 			if strings.Contains(chatJID, "@lid") {
@@ -135,494 +145,93 @@ func (h *EventHandler) normalizeEvent(ctx context.Context, instanceID string, cl
 					chatJID = evt.Info.SenderAlt.String()
 				}
 			}
-			result["chatJID"] = chatJID
+			p.ChatJID = chatJID
+			p.To = chatJID
 			if evt.Info.IsGroup {
-				result["participant"] = senderJID
-				result["author"] = senderJID
-			}
-
-			result["isFromMe"] = evt.Info.IsFromMe
-			result["isGroup"] = evt.Info.IsGroup
-			result["timestamp"] = evt.Info.Timestamp
-			result["pushName"] = evt.Info.PushName
-			return result
-		}
-
-		result["type"] = "message"
-
-		senderJID := evt.Info.Sender.String()
-		if strings.Contains(senderJID, "@lid") && !evt.Info.SenderAlt.IsEmpty() {
-			senderJID = evt.Info.SenderAlt.String()
-		}
-		result["from"] = senderJID
-
-		chatJID := evt.Info.Chat.String()
-		if strings.Contains(chatJID, "@lid") {
-			if evt.Info.IsFromMe && !evt.Info.RecipientAlt.IsEmpty() && strings.Contains(evt.Info.RecipientAlt.String(), "@s.whatsapp.net") {
-				chatJID = evt.Info.RecipientAlt.String()
-			} else if !evt.Info.IsFromMe && !evt.Info.SenderAlt.IsEmpty() && strings.Contains(evt.Info.SenderAlt.String(), "@s.whatsapp.net") {
-				chatJID = evt.Info.SenderAlt.String()
-			}
-			// Event without Alt (the undecryptable retry redelivers only the LID). The local LID→PN
-			// map already knows the contact if they ever spoke by phone.
-			if strings.Contains(chatJID, "@lid") {
-				if pn := h.resolvePNFromStore(ctx, client, evt.Info.Chat); !pn.IsEmpty() {
-					chatJID = pn.String()
-					if strings.Contains(senderJID, "@lid") {
-						senderJID = pn.String()
-						result["from"] = senderJID
-					}
-					h.log.Info("LID resolvido para PN via store local (Alt ausente)",
-						zap.String("lid", evt.Info.Chat.String()),
-						zap.String("pn", chatJID))
-				}
-			}
-			if strings.Contains(chatJID, "@lid") {
-				h.log.Warn("Chat ainda é LID após resolução",
-					zap.String("original_chat", evt.Info.Chat.String()),
-					zap.String("resolved_chat", chatJID),
-					zap.String("recipientAlt", evt.Info.RecipientAlt.String()),
-					zap.String("senderAlt", evt.Info.SenderAlt.String()),
-					zap.Bool("isFromMe", evt.Info.IsFromMe))
-			}
-		}
-		result["chatJID"] = chatJID
-		result["to"] = chatJID
-		result["addressingMode"] = string(evt.Info.AddressingMode)
-		// The LID is the contact's stable identity, so it goes out even when the PN was resolved:
-		// the consumer stores it and a later LID-addressed redelivery matches the same contact,
-		// which is the guard against duplicated conversations. For username-only contacts it is the
-		// only key. The @username is enriched elsewhere, never via usync per message.
-		if lid := lidForEvent(&evt.Info); !lid.IsEmpty() {
-			result["lid"] = lid.String()
-		}
-		result["isFromMe"] = evt.Info.IsFromMe
-		result["isGroup"] = evt.Info.IsGroup
-		result["messageId"] = evt.Info.ID
-		result["timestamp"] = evt.Info.Timestamp
-		result["pushName"] = evt.Info.PushName
-		if vn := evt.Info.VerifiedName; vn != nil && vn.Details != nil {
-			result["verifiedName"] = vn.Details.GetVerifiedName()
-			result["issuer"] = vn.Details.GetIssuer()
-		}
-		// Track inbound messages for auto MarkRead before sending
-		if !evt.Info.IsFromMe {
-			messageSvc.TrackInbound(instanceID, chatJID, evt.Info.ID, senderJID)
-			// Contact replied on this connection → release its reach-out (463) block.
-			messageSvc.ReleaseReachoutOnInbound(instanceID, chatJID)
-			h.log.Info("[markread] inbound tracked",
-				zap.String("key", instanceID+":"+chatJID),
-				zap.String("msg_id", evt.Info.ID),
-				zap.String("sender", senderJID))
-		}
-
-		if evt.Message.GetConversation() != "" {
-			result["text"] = evt.Message.GetConversation()
-		}
-
-		if extText := evt.Message.GetExtendedTextMessage(); extText != nil {
-			result["text"] = extText.GetText()
-		}
-
-		if img := evt.Message.GetImageMessage(); img != nil {
-			h.log.Info("detectada imagem, iniciando processamento", zap.String("msg_id", evt.Info.ID))
-			result["mediaType"] = "image"
-			if img.GetCaption() != "" {
-				result["caption"] = img.GetCaption()
-			}
-			result["mimetype"] = img.GetMimetype()
-			result["fileSize"] = img.GetFileLength()
-
-			if client != nil && h.mediaStorage != nil {
-				if mediaURL := h.downloadAndSaveMedia(ctx, instanceID, evt.Info.ID, client, img, img.GetMimetype()); mediaURL != "" {
-					result["mediaUrl"] = mediaURL
-				} else {
-					h.log.Warn("falha ao baixar imagem, enviando webhook sem URL", zap.String("msg_id", evt.Info.ID))
-				}
-			}
-		} else if vid := evt.Message.GetVideoMessage(); vid != nil {
-			h.log.Info("detectado vídeo, iniciando processamento", zap.String("msg_id", evt.Info.ID))
-			result["mediaType"] = "video"
-			if vid.GetCaption() != "" {
-				result["caption"] = vid.GetCaption()
-			}
-			result["mimetype"] = vid.GetMimetype()
-			result["fileSize"] = vid.GetFileLength()
-			result["duration"] = vid.GetSeconds()
-
-			if client != nil && h.mediaStorage != nil {
-				if mediaURL := h.downloadAndSaveMedia(ctx, instanceID, evt.Info.ID, client, vid, vid.GetMimetype()); mediaURL != "" {
-					result["mediaUrl"] = mediaURL
-				} else {
-					h.log.Warn("falha ao baixar vídeo, enviando webhook sem URL", zap.String("msg_id", evt.Info.ID))
-				}
-			}
-		} else if ptv := evt.Message.GetPtvMessage(); ptv != nil {
-			// Video note (round video message). In the protocol it's a VideoMessage in the
-			// ptvMessage field → downloaded like a video. We flag isPtv so the consumer can tell them apart in the UI.
-			h.log.Info("detectado vídeo em recado (PTV), iniciando processamento", zap.String("msg_id", evt.Info.ID))
-			result["mediaType"] = "video"
-			result["isPtv"] = true
-			if ptv.GetCaption() != "" {
-				result["caption"] = ptv.GetCaption()
-			}
-			result["mimetype"] = ptv.GetMimetype()
-			result["fileSize"] = ptv.GetFileLength()
-			result["duration"] = ptv.GetSeconds()
-
-			if client != nil && h.mediaStorage != nil {
-				if mediaURL := h.downloadAndSaveMedia(ctx, instanceID, evt.Info.ID, client, ptv, ptv.GetMimetype()); mediaURL != "" {
-					result["mediaUrl"] = mediaURL
-				} else {
-					h.log.Warn("falha ao baixar vídeo em recado (PTV), enviando webhook sem URL", zap.String("msg_id", evt.Info.ID))
-				}
-			}
-		} else if doc := evt.Message.GetDocumentMessage(); doc != nil {
-			result["mediaType"] = "document"
-			if doc.GetTitle() != "" {
-				result["fileName"] = doc.GetTitle()
-			}
-			result["mimetype"] = doc.GetMimetype()
-			result["fileSize"] = doc.GetFileLength()
-
-			if client != nil && h.mediaStorage != nil {
-				if mediaURL := h.downloadAndSaveMedia(ctx, instanceID, evt.Info.ID, client, doc, doc.GetMimetype()); mediaURL != "" {
-					result["mediaUrl"] = mediaURL
-				}
-			}
-		} else if aud := evt.Message.GetAudioMessage(); aud != nil {
-			result["mediaType"] = "audio"
-			result["mimetype"] = aud.GetMimetype()
-			result["fileSize"] = aud.GetFileLength()
-			result["duration"] = aud.GetSeconds()
-			result["ptt"] = aud.GetPTT() // Push-to-Talk
-
-			if client != nil && h.mediaStorage != nil {
-				if mediaURL := h.downloadAndSaveMedia(ctx, instanceID, evt.Info.ID, client, aud, aud.GetMimetype()); mediaURL != "" {
-					result["mediaUrl"] = mediaURL
-				}
-			}
-		} else if loc := evt.Message.GetLocationMessage(); loc != nil {
-			result["mediaType"] = "location"
-			result["latitude"] = loc.GetDegreesLatitude()
-			result["longitude"] = loc.GetDegreesLongitude()
-			if loc.GetAddress() != "" {
-				result["address"] = loc.GetAddress()
+				p.Participant = senderJID
+				p.Author = senderJID
 			}
-		} else if con := evt.Message.GetContactMessage(); con != nil {
-			result["mediaType"] = "contact"
-			result["contactName"] = con.GetDisplayName()
-			result["contactNumber"] = con.GetVcard()
-		} else if stk := evt.Message.GetStickerMessage(); stk != nil {
-			result["mediaType"] = "sticker"
-			if client != nil && h.mediaStorage != nil {
-				if mediaURL := h.downloadAndSaveMedia(ctx, instanceID, evt.Info.ID, client, stk, stk.GetMimetype()); mediaURL != "" {
-					result["mediaUrl"] = mediaURL
-				}
-			}
-		}
 
-		if tmpl := evt.Message.GetTemplateMessage(); tmpl != nil {
-			if ht := tmpl.GetHydratedTemplate(); ht != nil {
-				inter := map[string]interface{}{"kind": "buttons"}
-				if ht.GetHydratedContentText() != "" {
-					inter["text"] = ht.GetHydratedContentText()
-					result["text"] = ht.GetHydratedContentText()
-				}
-				if ht.GetHydratedFooterText() != "" {
-					inter["footer"] = ht.GetHydratedFooterText()
-				}
-				btns := []map[string]interface{}{}
-				for _, b := range ht.GetHydratedButtons() {
-					if q := b.GetQuickReplyButton(); q != nil {
-						btns = append(btns, map[string]interface{}{"id": q.GetID(), "label": q.GetDisplayText(), "type": "reply"})
-					} else if u := b.GetUrlButton(); u != nil {
-						btns = append(btns, map[string]interface{}{"id": u.GetURL(), "label": u.GetDisplayText(), "type": "url", "url": u.GetURL()})
-					} else if cb := b.GetCallButton(); cb != nil {
-						btns = append(btns, map[string]interface{}{"id": cb.GetPhoneNumber(), "label": cb.GetDisplayText(), "type": "call", "phone": cb.GetPhoneNumber()})
-					}
-				}
-				inter["buttons"] = btns
-				result["interactive"] = inter
-			}
-		} else if bm := evt.Message.GetButtonsMessage(); bm != nil {
-			inter := map[string]interface{}{"kind": "buttons"}
-			if bm.GetContentText() != "" {
-				inter["text"] = bm.GetContentText()
-				result["text"] = bm.GetContentText()
-			}
-			if bm.GetFooterText() != "" {
-				inter["footer"] = bm.GetFooterText()
-			}
-			btns := []map[string]interface{}{}
-			for _, b := range bm.GetButtons() {
-				btns = append(btns, map[string]interface{}{"id": b.GetButtonID(), "label": b.GetButtonText().GetDisplayText(), "type": "reply"})
-			}
-			inter["buttons"] = btns
-			result["interactive"] = inter
-		} else if lm := evt.Message.GetListMessage(); lm != nil {
-			inter := map[string]interface{}{"kind": "list"}
-			if lm.GetDescription() != "" {
-				inter["text"] = lm.GetDescription()
-				result["text"] = lm.GetDescription()
-			}
-			if lm.GetFooterText() != "" {
-				inter["footer"] = lm.GetFooterText()
-			}
-			secs := []map[string]interface{}{}
-			for _, s := range lm.GetSections() {
-				rows := []map[string]interface{}{}
-				for _, r := range s.GetRows() {
-					rows = append(rows, map[string]interface{}{"id": r.GetRowID(), "label": r.GetTitle(), "description": r.GetDescription()})
-				}
-				secs = append(secs, map[string]interface{}{"title": s.GetTitle(), "rows": rows})
-			}
-			inter["sections"] = secs
-			result["interactive"] = inter
-		} else if im := evt.Message.GetInteractiveMessage(); im != nil {
-			// Current button format (NativeFlow); the branches above only cover the legacy ones.
-			// Without this the message goes out with no text/interactive and the consumer drops it.
-			inter := map[string]interface{}{"kind": "buttons"}
-			if body := im.GetBody(); body != nil && body.GetText() != "" {
-				inter["text"] = body.GetText()
-				result["text"] = body.GetText()
-			}
-			if footer := im.GetFooter(); footer != nil && footer.GetText() != "" {
-				inter["footer"] = footer.GetText()
-			}
-			btns := []map[string]interface{}{}
-			secs := []map[string]interface{}{}
-			for _, b := range im.GetNativeFlowMessage().GetButtons() {
-				var params map[string]interface{}
-				if raw := b.GetButtonParamsJSON(); raw != "" {
-					if err := json.Unmarshal([]byte(raw), &params); err != nil {
-						h.log.Warn("falha ao ler buttonParamsJson do NativeFlow",
-							zap.String("msg_id", evt.Info.ID),
-							zap.String("button", b.GetName()),
-							zap.Error(err))
-						continue
-					}
-				}
-				label := nfString(params, "display_text", "title", "text")
-				switch b.GetName() {
-				case "quick_reply":
-					id := nfString(params, "id")
-					if id == "" {
-						id = label
-					}
-					btns = append(btns, map[string]interface{}{"id": id, "label": label, "type": "reply"})
-				case "cta_url":
-					url := nfString(params, "url")
-					btns = append(btns, map[string]interface{}{"id": url, "label": label, "type": "url", "url": url})
-				case "cta_call":
-					phone := nfString(params, "phone_number")
-					btns = append(btns, map[string]interface{}{"id": phone, "label": label, "type": "call", "phone": phone})
-				case "cta_copy":
-					code := nfString(params, "copy_code")
-					btns = append(btns, map[string]interface{}{"id": nfString(params, "id"), "label": label, "type": "copy", "code": code})
-				case "single_select":
-					inter["kind"] = "list"
-					rawSecs, _ := params["sections"].([]interface{})
-					for _, rs := range rawSecs {
-						sec, ok := rs.(map[string]interface{})
-						if !ok {
-							continue
-						}
-						rows := []map[string]interface{}{}
-						rawRows, _ := sec["rows"].([]interface{})
-						for _, rr := range rawRows {
-							row, ok := rr.(map[string]interface{})
-							if !ok {
-								continue
-							}
-							rows = append(rows, map[string]interface{}{
-								"id":          nfString(row, "id", "row_id"),
-								"label":       nfString(row, "title"),
-								"description": nfString(row, "description"),
-							})
-						}
-						secs = append(secs, map[string]interface{}{"title": nfString(sec, "title"), "rows": rows})
-					}
-				default:
-					if label != "" {
-						btns = append(btns, map[string]interface{}{"id": nfString(params, "id"), "label": label, "type": b.GetName()})
-					}
-				}
-			}
-			if len(secs) > 0 {
-				inter["sections"] = secs
-			}
-			if len(btns) > 0 || len(secs) == 0 {
-				inter["buttons"] = btns
-			}
-			result["interactive"] = inter
-		}
-
-		if br := evt.Message.GetButtonsResponseMessage(); br != nil {
-			if br.GetSelectedDisplayText() != "" {
-				result["text"] = br.GetSelectedDisplayText()
-			}
-			result["interactiveReply"] = map[string]interface{}{"selectedId": br.GetSelectedButtonID(), "selectedLabel": br.GetSelectedDisplayText()}
-		} else if lr := evt.Message.GetListResponseMessage(); lr != nil {
-			if sr := lr.GetSingleSelectReply(); sr != nil {
-				if lr.GetTitle() != "" {
-					result["text"] = lr.GetTitle()
-				}
-				result["interactiveReply"] = map[string]interface{}{"selectedId": sr.GetSelectedRowID(), "selectedLabel": lr.GetTitle()}
-			}
-		} else if tr := evt.Message.GetTemplateButtonReplyMessage(); tr != nil {
-			if tr.GetSelectedDisplayText() != "" {
-				result["text"] = tr.GetSelectedDisplayText()
-			}
-			result["interactiveReply"] = map[string]interface{}{"selectedId": tr.GetSelectedID(), "selectedLabel": tr.GetSelectedDisplayText()}
-		} else if ir := evt.Message.GetInteractiveResponseMessage(); ir != nil {
-			if body := ir.GetBody(); body != nil && body.GetText() != "" {
-				result["text"] = body.GetText()
-			}
-			if nf := ir.GetNativeFlowResponseMessage(); nf != nil {
-				reply := map[string]interface{}{"name": nf.GetName(), "paramsJson": nf.GetParamsJSON()}
-				// On NativeFlow the Body usually arrives empty: the clicked label only exists inside
-				// paramsJson, so without this the user's click is lost.
-				var params map[string]interface{}
-				if raw := nf.GetParamsJSON(); raw != "" {
-					if err := json.Unmarshal([]byte(raw), &params); err != nil {
-						h.log.Warn("falha ao ler paramsJson da resposta NativeFlow",
-							zap.String("msg_id", evt.Info.ID), zap.Error(err))
-					}
-				}
-				label := nfString(params, "display_text", "title", "selected_display_text", "text")
-				id := nfString(params, "id", "selected_id", "selected_row_id", "row_id")
-				// Body.text is the canonical label fallback, valid even with unreadable paramsJson.
-				if label == "" {
-					if body := ir.GetBody(); body != nil {
-						label = body.GetText()
-					}
-				}
-				if label != "" {
-					reply["selectedLabel"] = label
-				}
-				if id != "" {
-					reply["selectedId"] = id
-				}
-				if result["text"] == nil {
-					if label != "" {
-						result["text"] = label
-					} else if id != "" {
-						result["text"] = id
-					}
-				}
-				result["interactiveReply"] = reply
-			}
-		}
-
-		var mentionedJids []string
-		if extText := evt.Message.GetExtendedTextMessage(); extText != nil && extText.GetContextInfo() != nil {
-			mentionedJids = extText.GetContextInfo().GetMentionedJID()
-		} else if img := evt.Message.GetImageMessage(); img != nil && img.GetContextInfo() != nil {
-			mentionedJids = img.GetContextInfo().GetMentionedJID()
-		} else if vid := evt.Message.GetVideoMessage(); vid != nil && vid.GetContextInfo() != nil {
-			mentionedJids = vid.GetContextInfo().GetMentionedJID()
-		} else if aud := evt.Message.GetAudioMessage(); aud != nil && aud.GetContextInfo() != nil {
-			mentionedJids = aud.GetContextInfo().GetMentionedJID()
-		} else if doc := evt.Message.GetDocumentMessage(); doc != nil && doc.GetContextInfo() != nil {
-			mentionedJids = doc.GetContextInfo().GetMentionedJID()
-		}
-		if len(mentionedJids) > 0 {
-			result["mentionedJids"] = mentionedJids
-		}
-
-		// Message edit in the new protocol: it arrives encrypted as a secretEncryptedMessage
-		// (SecretEncType=MESSAGE_EDIT), no longer as protocolMessage type 14. We decrypt it with the
-		// stored message secret and expose the new content + the original message id so the
-		// consumer can apply the edit. Without this the edit is lost (the consumer treats it as a sync).
-		if sec := evt.Message.GetSecretEncryptedMessage(); sec != nil &&
-			sec.GetSecretEncType() == waE2E.SecretEncryptedMessage_MESSAGE_EDIT {
-			if client != nil {
-				if decrypted, err := client.DecryptSecretEncryptedMessage(ctx, evt); err == nil {
-					newText := decrypted.GetConversation()
-					if newText == "" {
-						newText = decrypted.GetExtendedTextMessage().GetText()
-					}
-					if targetKey := sec.GetTargetMessageKey(); targetKey != nil {
-						result["editedMessageId"] = targetKey.GetID()
-					}
-					if newText != "" {
-						result["editedText"] = newText
-						h.log.Info("edição de mensagem decriptada",
-							zap.String("msg_id", evt.Info.ID),
-							zap.String("target", result["editedMessageId"].(string)))
-					}
-				} else {
-					h.log.Warn("falha ao decriptar edição (secretEncryptedMessage)",
-						zap.String("msg_id", evt.Info.ID), zap.Error(err))
-				}
-			}
+			p.IsFromMe = evt.Info.IsFromMe
+			p.IsGroup = evt.Info.IsGroup
+			p.MessageID = evt.Info.ID
+			p.Timestamp = evt.Info.Timestamp
+			p.PushName = evt.Info.PushName
+			result = p
+			break
 		}
+		result = h.normalizeMessage(ctx, instanceID, client, evt)
 	case *events.Receipt:
-		result["type"] = "receipt"
-		result["messageIds"] = evt.MessageIDs
-		result["timestamp"] = evt.Timestamp
-		result["chat"] = evt.Chat.String()
-		result["isGroup"] = evt.IsGroup
+		p := &payload.Receipt{Base: payload.NewBase(payload.TypeReceipt)}
+		p.MessageIDs = evt.MessageIDs
+		if p.MessageIDs == nil {
+			p.MessageIDs = []string{}
+		}
+		p.Timestamp = evt.Timestamp
+		p.Chat = evt.Chat.String()
+		p.IsGroup = evt.IsGroup
 		if !evt.Sender.IsEmpty() {
-			result["from"] = evt.Sender.String()
+			p.From = evt.Sender.String()
 		}
 		if !evt.MessageSender.IsEmpty() {
-			result["messageSender"] = evt.MessageSender.String()
+			p.MessageSender = evt.MessageSender.String()
 		}
-		result["status"] = string(evt.Type)
+		p.Status = string(evt.Type)
+		result = p
 	case *events.Presence:
-		result["type"] = "presence"
-		result["from"] = evt.From.String()
-		result["unavailable"] = evt.Unavailable
+		p := &payload.Presence{Base: payload.NewBase(payload.TypePresence)}
+		p.From = evt.From.String()
+		p.Unavailable = evt.Unavailable
 		if !evt.LastSeen.IsZero() {
-			result["lastSeen"] = evt.LastSeen
+			lastSeen := evt.LastSeen
+			p.LastSeen = &lastSeen
 		}
+		result = p
 	case *events.UndecryptableMessage:
 		// Inbound message that WhatsApp could not decrypt (desynchronized Signal
 		// session, common after re-pairing). The content never arrives; we emit a
 		// marker so the backend records it in the conversation instead of the
 		// inbound silently disappearing. whatsmeow has already requested a resend.
 		if evt.Info.IsFromMe || evt.Info.IsGroup {
-			result["type"] = "ignore"
-			break
+			return nil
 		}
-		result["type"] = "message"
-		result["undecryptable"] = true
-		result["messageType"] = "unsupported"
+		p := &payload.Message{Base: payload.NewBase(payload.TypeMessage)}
+		p.Undecryptable = true
+		p.MessageType = "unsupported"
 
 		senderJID := evt.Info.Sender.String()
 		if strings.Contains(senderJID, "@lid") && !evt.Info.SenderAlt.IsEmpty() {
 			senderJID = evt.Info.SenderAlt.String()
 		}
-		result["from"] = senderJID
+		p.From = senderJID
 
 		chatJID := evt.Info.Chat.String()
 		if strings.Contains(chatJID, "@lid") && !evt.Info.SenderAlt.IsEmpty() && strings.Contains(evt.Info.SenderAlt.String(), "@s.whatsapp.net") {
 			chatJID = evt.Info.SenderAlt.String()
 		}
-		result["chatJID"] = chatJID
-		result["to"] = chatJID
-		result["isFromMe"] = false
-		result["isGroup"] = false
-		result["messageId"] = evt.Info.ID
-		result["timestamp"] = evt.Info.Timestamp
-		result["pushName"] = evt.Info.PushName
+		p.ChatJID = chatJID
+		p.To = chatJID
+		p.IsFromMe = false
+		p.IsGroup = false
+		p.MessageID = evt.Info.ID
+		p.Timestamp = evt.Info.Timestamp
+		p.PushName = evt.Info.PushName
 		// View once: the server delivers only a stub (the media isn't sent to linked
 		// devices, for privacy — same as WhatsApp Web's "view on your phone"). We tell it
 		// apart by UnavailableType so the consumer shows the right notice instead of "unavailable".
 		if evt.UnavailableType == events.UnavailableTypeViewOnce {
-			result["unavailableType"] = "view_once"
-			result["text"] = "Mensagem de visualização única, disponível apenas no celular."
+			p.UnavailableType = "view_once"
+			p.Text = "Mensagem de visualização única, disponível apenas no celular."
 		} else {
-			result["text"] = "Mensagem indisponível"
+			p.Text = "Mensagem indisponível"
 		}
+		result = p
 	case *events.ChatPresence:
 		// "Typing…" / "recording audio" indicator. Emitted by WhatsApp while the contact
 		// is composing. Ephemeral — the consumer decides whether and how long to show it.
 		// Chat/Sender usually comes as @lid; we resolve it to the phone number (PN) so the
 		// consumer can match it with the contact/conversation.
-		result["type"] = "chat_presence"
+		p := &payload.ChatPresence{Base: payload.NewBase(payload.TypeChatPresence)}
 
 		chatJID := evt.Chat
 		if chatJID.Server == types.HiddenUserServer && client != nil && client.Store != nil && client.Store.LIDs != nil {
@@ -637,55 +246,54 @@ func (h *EventHandler) normalizeEvent(ctx context.Context, instanceID string, cl
 			}
 		}
 
-		result["from"] = senderJID.String()
-		result["chatJID"] = chatJID.String()
-		result["state"] = string(evt.State) // "composing" | "paused"
-		if evt.Media != "" {
-			result["media"] = string(evt.Media) // "" (text) | "audio"
-		}
+		p.From = senderJID.String()
+		p.ChatJID = chatJID.String()
+		p.State = string(evt.State) // "composing" | "paused"
+		p.Media = string(evt.Media) // "" (text) | "audio"
+		result = p
 	case *events.Connected:
-		result["type"] = "connected"
+		result = &payload.Connection{Base: payload.NewBase(payload.TypeConnected)}
 	case *events.Disconnected:
-		result["type"] = "disconnected"
+		result = &payload.Connection{Base: payload.NewBase(payload.TypeDisconnected)}
 	case *events.LoggedOut:
-		result["type"] = "disconnected"
-		result["reason"] = evt.Reason.String()
+		result = &payload.Connection{Base: payload.NewBase(payload.TypeDisconnected), Reason: evt.Reason.String()}
 	case *events.TemporaryBan:
 		// The account itself was temporarily banned (whatsmeow "temporary-ban" from the server).
 		// Same webhook type as the reach-out timelock, so a consumer that already handles
 		// temporary_ban gets this one for free; consumers that don't fall into their default
 		// branch. Without this the ban was only visible in the apime log, and the connection
 		// stayed "connected" on the consumer side while every send failed.
-		result["type"] = "temporary_ban"
-		result["reason"] = evt.Code.String()
-		result["code"] = int(evt.Code)
-		result["active"] = true
+		p := &payload.Restriction{Base: payload.NewBase(payload.TypeTemporaryBan)}
+		p.Reason = evt.Code.String()
+		p.Code = int(evt.Code)
+		p.Active = true
 		if evt.Expire > 0 {
 			// RFC3339 in the webhook JSON → the consumer does new Date(restrictedUntil).
 			// The server sends a duration, not a date, so it is anchored here.
-			result["restrictedUntil"] = time.Now().Add(evt.Expire)
+			until := time.Now().Add(evt.Expire)
+			p.RestrictedUntil = &until
 		}
+		result = p
 	case *events.NotifyAccountReachoutTimelock:
 		// AUTHORITATIVE server notification about the reach-out timelock (the cause of error 463).
 		// It's the exact source of the restriction state — no need to guess the duration (it's not "always 7 days"):
 		//   IsActive=true  → restriction in effect; TimeEnforcementEnds = when it expires (exact date).
 		//   IsActive=false → restriction lifted → the consumer moves the connection back to "connected".
 		// Complements the synchronous 463 from the send path (message/service.go), which is only the immediate trigger.
+		p := &payload.Restriction{Base: payload.NewBase(payload.TypeRestrictionLifted)}
 		if evt.IsActive {
-			result["type"] = "temporary_ban"
-			result["reason"] = "account reachout timelock"
-			result["code"] = 463
-		} else {
-			result["type"] = "restriction_lifted"
-		}
-		result["active"] = evt.IsActive
-		if evt.EnforcementType != "" {
-			result["enforcementType"] = evt.EnforcementType
+			p.Base = payload.NewBase(payload.TypeTemporaryBan)
+			p.Reason = "account reachout timelock"
+			p.Code = 463
 		}
+		p.Active = evt.IsActive
+		p.EnforcementType = string(evt.EnforcementType)
 		if !evt.TimeEnforcementEnds.IsZero() {
 			// RFC3339 in the webhook JSON → the consumer does new Date(restrictedUntil).
-			result["restrictedUntil"] = evt.TimeEnforcementEnds.Time
+			until := evt.TimeEnforcementEnds.Time
+			p.RestrictedUntil = &until
 		}
+		result = p
 	case *events.Contact:
 		// Appstate contact sync. Carries the WhatsApp @username (2026) for saved/synced
 		// contacts — FREE (no server query, zero ban surface). We only emit when a username
@@ -693,63 +301,557 @@ func (h *EventHandler) normalizeEvent(ctx context.Context, instanceID string, cl
 		// not flood the webhook. The consumer matches by the LID/jid and stores the username.
 		username := evt.Action.GetUsername()
 		if username == "" {
-			result["type"] = "ignore"
-			return result
+			return nil
 		}
-		result["type"] = "contact_update"
-		result["jid"] = evt.JID.String()
+		p := &payload.ContactUpdate{Base: payload.NewBase(payload.TypeContactUpdate)}
+		p.JID = evt.JID.String()
 		if evt.JID.Server == types.HiddenUserServer {
-			result["lid"] = evt.JID.String()
-		}
-		result["username"] = username
-		result["fromFullSync"] = evt.FromFullSync
-		result["timestamp"] = evt.Timestamp
+			p.LID = evt.JID.String()
+		}
+		p.Username = username
+		p.FromFullSync = evt.FromFullSync
+		p.Timestamp = evt.Timestamp
+		result = p
+	case *events.Archive, *events.Pin, *events.Mute, *events.MarkChatAsRead, *events.ClearChat,
+		*events.DeleteChat, *events.Star, *events.DeleteForMe:
+		p := normalizeChatUpdate(evt)
+		if p == nil {
+			return nil
+		}
+		result = p
+	case *events.Blocklist:
+		p := &payload.BlocklistUpdate{Base: payload.NewBase(payload.TypeBlocklistUpdate)}
+		p.FullRefresh = evt.Action == events.BlocklistActionModify
+		p.Changes = make([]payload.BlocklistChange, 0, len(evt.Changes))
+		for _, change := range evt.Changes {
+			p.Changes = append(p.Changes, payload.BlocklistChange{JID: change.JID.String(), Action: string(change.Action)})
+		}
+		result = p
 	default:
-		result["type"] = "unknown"
-		result["eventType"] = fmt.Sprintf("%T", evt)
+		result = &payload.Unknown{Base: payload.NewBase(payload.TypeUnknown), EventType: fmt.Sprintf("%T", evt)}
 	}
 
 	if data, err := json.Marshal(evt); err == nil {
-		result["raw"] = json.RawMessage(data)
+		result.Header().Raw = data
 	}
 
 	return result
 }
 
-func (h *EventHandler) downloadAndSaveMedia(ctx context.Context, instanceID string, messageID string, client *whatsmeow.Client, downloadable whatsmeow.DownloadableMessage, mimetype string) string {
+func (h *EventHandler) normalizeMessage(ctx context.Context, instanceID string, client *whatsmeow.Client, evt *events.Message) *payload.Message {
+	result := &payload.Message{Base: payload.NewBase(payload.TypeMessage)}
+
+	senderJID := evt.Info.Sender.String()
+	if strings.Contains(senderJID, "@lid") && !evt.Info.SenderAlt.IsEmpty() {
+		senderJID = evt.Info.SenderAlt.String()
+	}
+	result.From = senderJID
+
+	chatJID := evt.Info.Chat.String()
+	if strings.Contains(chatJID, "@lid") {
+		if evt.Info.IsFromMe && !evt.Info.RecipientAlt.IsEmpty() && strings.Contains(evt.Info.RecipientAlt.String(), "@s.whatsapp.net") {
+			chatJID = evt.Info.RecipientAlt.String()
+		} else if !evt.Info.IsFromMe && !evt.Info.SenderAlt.IsEmpty() && strings.Contains(evt.Info.SenderAlt.String(), "@s.whatsapp.net") {
+			chatJID = evt.Info.SenderAlt.String()
+		}
+		// Event without Alt (the undecryptable retry redelivers only the LID). The local LID→PN
+		// map already knows the contact if they ever spoke by phone.
+		if strings.Contains(chatJID, "@lid") {
+			if pn := h.resolvePNFromStore(ctx, client, evt.Info.Chat); !pn.IsEmpty() {
+				chatJID = pn.String()
+				if strings.Contains(senderJID, "@lid") {
+					senderJID = pn.String()
+					result.From = senderJID
+				}
+				h.log.Info("LID resolvido para PN via store local (Alt ausente)",
+					zap.String("lid", evt.Info.Chat.String()),
+					zap.String("pn", chatJID))
+			}
+		}
+		if strings.Contains(chatJID, "@lid") {
+			h.log.Warn("Chat ainda é LID após resolução",
+				zap.String("original_chat", evt.Info.Chat.String()),
+				zap.String("resolved_chat", chatJID),
+				zap.String("recipientAlt", evt.Info.RecipientAlt.String()),
+				zap.String("senderAlt", evt.Info.SenderAlt.String()),
+				zap.Bool("isFromMe", evt.Info.IsFromMe))
+		}
+	}
+	result.ChatJID = chatJID
+	result.To = chatJID
+	result.AddressingMode = string(evt.Info.AddressingMode)
+	// The LID is the contact's stable identity, so it goes out even when the PN was resolved:
+	// the consumer stores it and a later LID-addressed redelivery matches the same contact,
+	// which is the guard against duplicated conversations. For username-only contacts it is the
+	// only key. The @username is enriched elsewhere, never via usync per message.
+	if lid := lidForEvent(&evt.Info); !lid.IsEmpty() {
+		result.LID = lid.String()
+	}
+	result.IsFromMe = evt.Info.IsFromMe
+	result.IsGroup = evt.Info.IsGroup
+	result.MessageID = evt.Info.ID
+	result.Timestamp = evt.Info.Timestamp
+	result.PushName = evt.Info.PushName
+	if vn := evt.Info.VerifiedName; vn != nil && vn.Details != nil {
+		result.VerifiedName = vn.Details.GetVerifiedName()
+		result.Issuer = vn.Details.GetIssuer()
+	}
+	// Track inbound messages for auto MarkRead before sending
+	if !evt.Info.IsFromMe {
+		messageSvc.TrackInbound(instanceID, chatJID, evt.Info.ID, senderJID)
+		// Contact replied on this connection → release its reach-out (463) block.
+		messageSvc.ReleaseReachoutOnInbound(instanceID, chatJID)
+		h.log.Info("[markread] inbound tracked",
+			zap.String("key", instanceID+":"+chatJID),
+			zap.String("msg_id", evt.Info.ID),
+			zap.String("sender", senderJID))
+	}
+
+	if evt.Message.GetConversation() != "" {
+		result.Text = evt.Message.GetConversation()
+	}
+
+	if extText := evt.Message.GetExtendedTextMessage(); extText != nil {
+		result.Text = extText.GetText()
+	}
+
+	if img := evt.Message.GetImageMessage(); img != nil {
+		h.log.Info("detectada imagem, iniciando processamento", zap.String("msg_id", evt.Info.ID))
+		result.MediaType = "image"
+		result.Caption = img.GetCaption()
+		result.Mimetype = img.GetMimetype()
+		result.FileSize = img.GetFileLength()
+
+		if client != nil && h.mediaStorage != nil {
+			if !h.attachMedia(ctx, result, instanceID, client, evt, "image", img, img.GetMimetype()) {
+				h.log.Warn("falha ao baixar imagem, enviando webhook sem URL", zap.String("msg_id", evt.Info.ID))
+			}
+		}
+	} else if vid := evt.Message.GetVideoMessage(); vid != nil {
+		h.log.Info("detectado vídeo, iniciando processamento", zap.String("msg_id", evt.Info.ID))
+		result.MediaType = "video"
+		result.Caption = vid.GetCaption()
+		result.Mimetype = vid.GetMimetype()
+		result.FileSize = vid.GetFileLength()
+		result.Duration = vid.GetSeconds()
+
+		if client != nil && h.mediaStorage != nil {
+			if !h.attachMedia(ctx, result, instanceID, client, evt, "video", vid, vid.GetMimetype()) {
+				h.log.Warn("falha ao baixar vídeo, enviando webhook sem URL", zap.String("msg_id", evt.Info.ID))
+			}
+		}
+	} else if ptv := evt.Message.GetPtvMessage(); ptv != nil {
+		// Video note (round video message). In the protocol it's a VideoMessage in the
+		// ptvMessage field → downloaded like a video. We flag isPtv so the consumer can tell them apart in the UI.
+		h.log.Info("detectado vídeo em recado (PTV), iniciando processamento", zap.String("msg_id", evt.Info.ID))
+		result.MediaType = "video"
+		result.IsPtv = true
+		result.Caption = ptv.GetCaption()
+		result.Mimetype = ptv.GetMimetype()
+		result.FileSize = ptv.GetFileLength()
+		result.Duration = ptv.GetSeconds()
+
+		if client != nil && h.mediaStorage != nil {
+			if !h.attachMedia(ctx, result, instanceID, client, evt, "ptv", ptv, ptv.GetMimetype()) {
+				h.log.Warn("falha ao baixar vídeo em recado (PTV), enviando webhook sem URL", zap.String("msg_id", evt.Info.ID))
+			}
+		}
+	} else if doc := evt.Message.GetDocumentMessage(); doc != nil {
+		result.MediaType = "document"
+		result.FileName = doc.GetTitle()
+		result.Mimetype = doc.GetMimetype()
+		result.FileSize = doc.GetFileLength()
+
+		if client != nil && h.mediaStorage != nil {
+			h.attachMedia(ctx, result, instanceID, client, evt, "document", doc, doc.GetMimetype())
+		}
+	} else if aud := evt.Message.GetAudioMessage(); aud != nil {
+		result.MediaType = "audio"
+		result.Mimetype = aud.GetMimetype()
+		result.FileSize = aud.GetFileLength()
+		result.Duration = aud.GetSeconds()
+		ptt := aud.GetPTT() // Push-to-Talk
+		result.PTT = &ptt
+
+		if client != nil && h.mediaStorage != nil {
+			h.attachMedia(ctx, result, instanceID, client, evt, "audio", aud, aud.GetMimetype())
+		}
+	} else if loc := evt.Message.GetLocationMessage(); loc != nil {
+		result.MediaType = "location"
+		lat, lng := loc.GetDegreesLatitude(), loc.GetDegreesLongitude()
+		result.Latitude = &lat
+		result.Longitude = &lng
+		result.Address = loc.GetAddress()
+	} else if con := evt.Message.GetContactMessage(); con != nil {
+		result.MediaType = "contact"
+		result.ContactName = con.GetDisplayName()
+		result.ContactNumber = con.GetVcard()
+	} else if stk := evt.Message.GetStickerMessage(); stk != nil {
+		result.MediaType = "sticker"
+		if client != nil && h.mediaStorage != nil {
+			h.attachMedia(ctx, result, instanceID, client, evt, "sticker", stk, stk.GetMimetype())
+		}
+	}
+
+	if tmpl := evt.Message.GetTemplateMessage(); tmpl != nil {
+		if ht := tmpl.GetHydratedTemplate(); ht != nil {
+			inter := &payload.Interactive{Kind: "buttons"}
+			if ht.GetHydratedContentText() != "" {
+				inter.Text = ht.GetHydratedContentText()
+				result.Text = ht.GetHydratedContentText()
+			}
+			inter.Footer = ht.GetHydratedFooterText()
+			for _, b := range ht.GetHydratedButtons() {
+				if q := b.GetQuickReplyButton(); q != nil {
+					inter.Buttons = append(inter.Buttons, payload.Button{ID: q.GetID(), Label: q.GetDisplayText(), Type: "reply"})
+				} else if u := b.GetUrlButton(); u != nil {
+					inter.Buttons = append(inter.Buttons, payload.Button{ID: u.GetURL(), Label: u.GetDisplayText(), Type: "url", URL: u.GetURL()})
+				} else if cb := b.GetCallButton(); cb != nil {
+					inter.Buttons = append(inter.Buttons, payload.Button{ID: cb.GetPhoneNumber(), Label: cb.GetDisplayText(), Type: "call", Phone: cb.GetPhoneNumber()})
+				}
+			}
+			result.Interactive = inter
+		}
+	} else if bm := evt.Message.GetButtonsMessage(); bm != nil {
+		inter := &payload.Interactive{Kind: "buttons"}
+		if bm.GetContentText() != "" {
+			inter.Text = bm.GetContentText()
+			result.Text = bm.GetContentText()
+		}
+		inter.Footer = bm.GetFooterText()
+		for _, b := range bm.GetButtons() {
+			inter.Buttons = append(inter.Buttons, payload.Button{ID: b.GetButtonID(), Label: b.GetButtonText().GetDisplayText(), Type: "reply"})
+		}
+		result.Interactive = inter
+	} else if lm := evt.Message.GetListMessage(); lm != nil {
+		inter := &payload.Interactive{Kind: "list"}
+		if lm.GetDescription() != "" {
+			inter.Text = lm.GetDescription()
+			result.Text = lm.GetDescription()
+		}
+		inter.Footer = lm.GetFooterText()
+		for _, s := range lm.GetSections() {
+			rows := []payload.Row{}
+			for _, r := range s.GetRows() {
+				rows = append(rows, payload.Row{ID: r.GetRowID(), Label: r.GetTitle(), Description: r.GetDescription()})
+			}
+			inter.Sections = append(inter.Sections, payload.Section{Title: s.GetTitle(), Rows: rows})
+		}
+		result.Interactive = inter
+	} else if im := evt.Message.GetInteractiveMessage(); im != nil {
+		// Current button format (NativeFlow); the branches above only cover the legacy ones.
+		// Without this the message goes out with no text/interactive and the consumer drops it.
+		inter := &payload.Interactive{Kind: "buttons"}
+		if body := im.GetBody(); body != nil && body.GetText() != "" {
+			inter.Text = body.GetText()
+			result.Text = body.GetText()
+		}
+		if footer := im.GetFooter(); footer != nil {
+			inter.Footer = footer.GetText()
+		}
+		for _, b := range im.GetNativeFlowMessage().GetButtons() {
+			var params map[string]interface{}
+			if raw := b.GetButtonParamsJSON(); raw != "" {
+				if err := json.Unmarshal([]byte(raw), &params); err != nil {
+					h.log.Warn("falha ao ler buttonParamsJson do NativeFlow",
+						zap.String("msg_id", evt.Info.ID),
+						zap.String("button", b.GetName()),
+						zap.Error(err))
+					continue
+				}
+			}
+			label := nfString(params, "display_text", "title", "text")
+			switch b.GetName() {
+			case "quick_reply":
+				id := nfString(params, "id")
+				if id == "" {
+					id = label
+				}
+				inter.Buttons = append(inter.Buttons, payload.Button{ID: id, Label: label, Type: "reply"})
+			case "cta_url":
+				url := nfString(params, "url")
+				inter.Buttons = append(inter.Buttons, payload.Button{ID: url, Label: label, Type: "url", URL: url})
+			case "cta_call":
+				phone := nfString(params, "phone_number")
+				inter.Buttons = append(inter.Buttons, payload.Button{ID: phone, Label: label, Type: "call", Phone: phone})
+			case "cta_copy":
+				code := nfString(params, "copy_code")
+				inter.Buttons = append(inter.Buttons, payload.Button{ID: nfString(params, "id"), Label: label, Type: "copy", Code: code})
+			case "single_select":
+				inter.Kind = "list"
+				rawSecs, _ := params["sections"].([]interface{})
+				for _, rs := range rawSecs {
+					sec, ok := rs.(map[string]interface{})
+					if !ok {
+						continue
+					}
+					rows := []payload.Row{}
+					rawRows, _ := sec["rows"].([]interface{})
+					for _, rr := range rawRows {
+						row, ok := rr.(map[string]interface{})
+						if !ok {
+							continue
+						}
+						rows = append(rows, payload.Row{
+							ID:          nfString(row, "id", "row_id"),
+							Label:       nfString(row, "title"),
+							Description: nfString(row, "description"),
+						})
+					}
+					inter.Sections = append(inter.Sections, payload.Section{Title: nfString(sec, "title"), Rows: rows})
+				}
+			default:
+				if label != "" {
+					inter.Buttons = append(inter.Buttons, payload.Button{ID: nfString(params, "id"), Label: label, Type: b.GetName()})
+				}
+			}
+		}
+		result.Interactive = inter
+	}
+
+	if br := evt.Message.GetButtonsResponseMessage(); br != nil {
+		if br.GetSelectedDisplayText() != "" {
+			result.Text = br.GetSelectedDisplayText()
+		}
+		result.InteractiveReply = &payload.InteractiveReply{SelectedID: br.GetSelectedButtonID(), SelectedLabel: br.GetSelectedDisplayText()}
+	} else if lr := evt.Message.GetListResponseMessage(); lr != nil {
+		if sr := lr.GetSingleSelectReply(); sr != nil {
+			if lr.GetTitle() != "" {
+				result.Text = lr.GetTitle()
+			}
+			result.InteractiveReply = &payload.InteractiveReply{SelectedID: sr.GetSelectedRowID(), SelectedLabel: lr.GetTitle()}
+		}
+	} else if tr := evt.Message.GetTemplateButtonReplyMessage(); tr != nil {
+		if tr.GetSelectedDisplayText() != "" {
+			result.Text = tr.GetSelectedDisplayText()
+		}
+		result.InteractiveReply = &payload.InteractiveReply{SelectedID: tr.GetSelectedID(), SelectedLabel: tr.GetSelectedDisplayText()}
+	} else if ir := evt.Message.GetInteractiveResponseMessage(); ir != nil {
+		if body := ir.GetBody(); body != nil && body.GetText() != "" {
+			result.Text = body.GetText()
+		}
+		if nf := ir.GetNativeFlowResponseMessage(); nf != nil {
+			reply := &payload.InteractiveReply{Name: nf.GetName(), ParamsJSON: nf.GetParamsJSON()}
+			// On NativeFlow the Body usually arrives empty: the clicked label only exists inside
+			// paramsJson, so without this the user's click is lost.
+			var params map[string]interface{}
+			if raw := nf.GetParamsJSON(); raw != "" {
+				if err := json.Unmarshal([]byte(raw), &params); err != nil {
+					h.log.Warn("falha ao ler paramsJson da resposta NativeFlow",
+						zap.String("msg_id", evt.Info.ID), zap.Error(err))
+				}
+			}
+			label := nfString(params, "display_text", "title", "selected_display_text", "text")
+			id := nfString(params, "id", "selected_id", "selected_row_id", "row_id")
+			// Body.text is the canonical label fallback, valid even with unreadable paramsJson.
+			if label == "" {
+				if body := ir.GetBody(); body != nil {
+					label = body.GetText()
+				}
+			}
+			reply.SelectedLabel = label
+			reply.SelectedID = id
+			if result.Text == "" {
+				if label != "" {
+					result.Text = label
+				} else if id != "" {
+					result.Text = id
+				}
+			}
+			result.InteractiveReply = reply
+		}
+	}
+
+	if extText := evt.Message.GetExtendedTextMessage(); extText != nil && extText.GetContextInfo() != nil {
+		result.MentionedJIDs = extText.GetContextInfo().GetMentionedJID()
+	} else if img := evt.Message.GetImageMessage(); img != nil && img.GetContextInfo() != nil {
+		result.MentionedJIDs = img.GetContextInfo().GetMentionedJID()
+	} else if vid := evt.Message.GetVideoMessage(); vid != nil && vid.GetContextInfo() != nil {
+		result.MentionedJIDs = vid.GetContextInfo().GetMentionedJID()
+	} else if aud := evt.Message.GetAudioMessage(); aud != nil && aud.GetContextInfo() != nil {
+		result.MentionedJIDs = aud.GetContextInfo().GetMentionedJID()
+	} else if doc := evt.Message.GetDocumentMessage(); doc != nil && doc.GetContextInfo() != nil {
+		result.MentionedJIDs = doc.GetContextInfo().GetMentionedJID()
+	}
+
+	// Message edit in the new protocol: it arrives encrypted as a secretEncryptedMessage
+	// (SecretEncType=MESSAGE_EDIT), no longer as protocolMessage type 14. We decrypt it with the
+	// stored message secret and expose the new content + the original message id so the
+	// consumer can apply the edit. Without this the edit is lost (the consumer treats it as a sync).
+	if sec := evt.Message.GetSecretEncryptedMessage(); sec != nil &&
+		sec.GetSecretEncType() == waE2E.SecretEncryptedMessage_MESSAGE_EDIT {
+		if client != nil {
+			if decrypted, err := client.DecryptSecretEncryptedMessage(ctx, evt); err == nil {
+				newText := decrypted.GetConversation()
+				if newText == "" {
+					newText = decrypted.GetExtendedTextMessage().GetText()
+				}
+				if targetKey := sec.GetTargetMessageKey(); targetKey != nil {
+					result.EditedMessageID = targetKey.GetID()
+				}
+				if newText != "" {
+					result.EditedText = newText
+					h.log.Info("edição de mensagem decriptada",
+						zap.String("msg_id", evt.Info.ID),
+						zap.String("target", result.EditedMessageID))
+				}
+			} else {
+				h.log.Warn("falha ao decriptar edição (secretEncryptedMessage)",
+					zap.String("msg_id", evt.Info.ID), zap.Error(err))
+			}
+		}
+	}
+
+	return result
+}
+
+// attachMedia applies the instance media policy to a received media. eager downloads it now and
+// sets mediaUrl; lazy only records the reference behind mediaDownloadUrl; none does neither. It
+// returns false when the media should have been made available and wasn't.
+func (h *EventHandler) attachMedia(ctx context.Context, result *payload.Message, instanceID string, client *whatsmeow.Client, evt *events.Message, kind string, downloadable whatsmeow.DownloadableMessage, mimetype string) bool {
+	policy := model.MediaPolicyEager
+	if h.mediaRefs != nil && h.mediaPolicies != nil {
+		policy = h.mediaPolicies.MediaPolicy(ctx, instanceID)
+	}
+	if policy == model.MediaPolicyNone {
+		return true
+	}
+
+	ref, hasRef := h.mediaRef(instanceID, evt, kind, downloadable, mimetype)
+	if policy == model.MediaPolicyLazy {
+		if !hasRef || !h.saveMediaRef(ctx, ref) {
+			return false
+		}
+		result.MediaDownloadURL = h.mediaDownloadURL(instanceID, evt.Info.ID)
+		return true
+	}
+
+	mediaID, mediaURL := h.downloadAndSaveMedia(ctx, instanceID, evt.Info.ID, client, kind, downloadable, mimetype)
+	result.MediaURL = mediaURL
+	// Also recorded in eager mode, so the media can still be downloaded after the TTL removes
+	// the file or when the download above failed.
+	if hasRef {
+		ref.MediaID = mediaID
+		if h.saveMediaRef(ctx, ref) {
+			result.MediaDownloadURL = h.mediaDownloadURL(instanceID, evt.Info.ID)
+		}
+	}
+	return mediaURL != ""
+}
+
+// mediaRef builds the reference of a received media: the media sub-message alone, which holds
+// the keys and CDN path, plus what a re-upload request needs.
+func (h *EventHandler) mediaRef(instanceID string, evt *events.Message, kind string, downloadable whatsmeow.DownloadableMessage, mimetype string) (model.MediaRef, bool) {
+	if h.mediaRefs == nil {
+		return model.MediaRef{}, false
+	}
+	var msg *waE2E.Message
+	switch m := downloadable.(type) {
+	case *waE2E.ImageMessage:
+		msg = &waE2E.Message{ImageMessage: m}
+	case *waE2E.VideoMessage:
+		if kind == "ptv" {
+			msg = &waE2E.Message{PtvMessage: m}
+		} else {
+			msg = &waE2E.Message{VideoMessage: m}
+		}
+	case *waE2E.DocumentMessage:
+		msg = &waE2E.Message{DocumentMessage: m}
+	case *waE2E.AudioMessage:
+		msg = &waE2E.Message{AudioMessage: m}
+	case *waE2E.StickerMessage:
+		msg = &waE2E.Message{StickerMessage: m}
+	default:
+		return model.MediaRef{}, false
+	}
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		h.log.Warn("erro ao serializar referência de mídia", zap.String("msg_id", evt.Info.ID), zap.Error(err))
+		return model.MediaRef{}, false
+	}
+	return model.MediaRef{
+		InstanceID: instanceID,
+		MessageID:  evt.Info.ID,
+		ChatJID:    evt.Info.Chat.String(),
+		SenderJID:  evt.Info.Sender.String(),
+		FromMe:     evt.Info.IsFromMe,
+		Kind:       kind,
+		Mimetype:   mimetype,
+		Message:    data,
+	}, true
+}
+
+func (h *EventHandler) saveMediaRef(ctx context.Context, ref model.MediaRef) bool {
+	if err := h.mediaRefs.Upsert(ctx, ref); err != nil {
+		h.log.Error("erro ao salvar referência de mídia",
+			zap.String("instance_id", ref.InstanceID),
+			zap.String("message_id", ref.MessageID),
+			zap.Error(err),
+		)
+		return false
+	}
+	return true
+}
+
+func (h *EventHandler) mediaDownloadURL(instanceID, messageID string) string {
+	return fmt.Sprintf("%s/api/instances/%s/messages/%s/media", h.apiBaseURL, instanceID, messageID)
+}
+
+// downloadAndSaveMedia downloads a received media into the storage and returns its media ID and
+// download URL, both empty when it wasn't saved.
+func (h *EventHandler) downloadAndSaveMedia(ctx context.Context, instanceID string, messageID string, client *whatsmeow.Client, kind string, downloadable whatsmeow.DownloadableMessage, mimetype string) (string, string) {
+	// Checked against the size the sender declared, before anything is fetched.
+	if sized, ok := downloadable.(interface{ GetFileLength() uint64 }); ok && !h.mediaLimits.Allows(kind, int64(sized.GetFileLength())) {
+		h.log.Warn("mídia acima do limite, não será baixada",
+			zap.String("instance_id", instanceID),
+			zap.String("message_id", messageID),
+			zap.String("kind", kind),
+			zap.Uint64("size", sized.GetFileLength()),
+			zap.Int64("limit", h.mediaLimits.Max(kind)),
+		)
+		return "", ""
+	}
+
 	h.log.Info("baixando mídia",
 		zap.String("instance_id", instanceID),
 		zap.String("message_id", messageID),
 		zap.String("mimetype", mimetype),
 	)
 
-	data, err := client.Download(ctx, downloadable)
+	// Decrypted straight into a spool file: a large video never sits whole in memory.
+	spool, err := h.mediaStorage.Spool(instanceID)
 	if err != nil {
+		h.log.Error("erro ao preparar arquivo de mídia",
+			zap.String("instance_id", instanceID),
+			zap.String("message_id", messageID),
+			zap.Error(err),
+		)
+		return "", ""
+	}
+	if err := client.DownloadToFile(ctx, downloadable, spool); err != nil {
+		h.mediaStorage.Discard(spool)
 		h.log.Error("erro ao baixar mídia",
 			zap.String("instance_id", instanceID),
 			zap.String("message_id", messageID),
 			zap.Error(err),
 		)
-		return ""
+		return "", ""
 	}
 
-	h.log.Info("mídia baixada com sucesso",
-		zap.String("instance_id", instanceID),
-		zap.String("message_id", messageID),
-		zap.Int("size", len(data)),
-	)
-
-	mediaID, err := h.mediaStorage.Save(ctx, instanceID, messageID, data, mimetype)
+	mediaID, err := h.mediaStorage.Keep(spool, instanceID, messageID, mimetype)
 	if err != nil {
 		h.log.Error("erro ao salvar mídia",
 			zap.String("instance_id", instanceID),
 			zap.String("message_id", messageID),
 			zap.Error(err),
 		)
-		return ""
+		return "", ""
 	}
 
 	mediaURL := fmt.Sprintf("%s/api/media/%s/%s", h.apiBaseURL, instanceID, mediaID)
+	if h.mediaSigner != nil {
+		mediaURL = h.mediaSigner.URL(h.apiBaseURL, instanceID, mediaID, time.Now())
+	}
 
 	h.log.Info("mídia salva e URL gerada",
 		zap.String("instance_id", instanceID),
@@ -757,9 +859,139 @@ func (h *EventHandler) downloadAndSaveMedia(ctx context.Context, instanceID stri
 		zap.String("media_url", mediaURL),
 	)
 
-	return mediaURL
+	return mediaID, mediaURL
 }
 
 func (h *EventHandler) generateEventID() string {
 	return uuid.New().String()
 }
+
+// normalizeNewsletterMessage maps a channel post. It doesn't go through normalizeMessage: a post
+// has no sender to resolve, and it must not feed the inbound tracking (mark-read, reach-out
+// release), which is about 1:1 conversations.
+func (h *EventHandler) normalizeNewsletterMessage(ctx context.Context, instanceID string, client *whatsmeow.Client, evt *events.Message) *payload.NewsletterMessage {
+	p := &payload.NewsletterMessage{Base: payload.NewBase(payload.TypeNewsletterMessage)}
+	p.NewsletterJID = evt.Info.Chat.String()
+	p.MessageID = evt.Info.ID
+	p.ServerID = int(evt.Info.ServerID)
+	p.IsFromMe = evt.Info.IsFromMe
+	p.Timestamp = evt.Info.Timestamp
+	if meta := evt.NewsletterMeta; meta != nil && !meta.EditTS.IsZero() {
+		edited := meta.EditTS
+		p.EditedAt = &edited
+	}
+
+	var (
+		kind         string
+		downloadable whatsmeow.DownloadableMessage
+	)
+	msg := evt.Message
+	switch {
+	case msg.GetConversation() != "":
+		p.Text = msg.GetConversation()
+	case msg.GetExtendedTextMessage() != nil:
+		p.Text = msg.GetExtendedTextMessage().GetText()
+	case msg.GetImageMessage() != nil:
+		img := msg.GetImageMessage()
+		p.MediaType, p.Caption, p.Mimetype, p.FileSize = "image", img.GetCaption(), img.GetMimetype(), img.GetFileLength()
+		kind, downloadable = "image", img
+	case msg.GetVideoMessage() != nil:
+		vid := msg.GetVideoMessage()
+		p.MediaType, p.Caption, p.Mimetype, p.FileSize = "video", vid.GetCaption(), vid.GetMimetype(), vid.GetFileLength()
+		kind, downloadable = "video", vid
+	case msg.GetAudioMessage() != nil:
+		aud := msg.GetAudioMessage()
+		p.MediaType, p.Mimetype, p.FileSize = "audio", aud.GetMimetype(), aud.GetFileLength()
+		kind, downloadable = "audio", aud
+	case msg.GetDocumentMessage() != nil:
+		doc := msg.GetDocumentMessage()
+		p.MediaType, p.Caption, p.Mimetype, p.FileSize = "document", doc.GetCaption(), doc.GetMimetype(), doc.GetFileLength()
+		p.FileName = doc.GetFileName()
+		kind, downloadable = "document", doc
+	case msg.GetStickerMessage() != nil:
+		stk := msg.GetStickerMessage()
+		p.MediaType, p.Mimetype = "sticker", stk.GetMimetype()
+		kind, downloadable = "sticker", stk
+	}
+
+	// Channel media is stored and served like any received media, under the same policy.
+	if downloadable != nil && client != nil && h.mediaStorage != nil {
+		var media payload.Message
+		if !h.attachMedia(ctx, &media, instanceID, client, evt, kind, downloadable, p.Mimetype) {
+			h.log.Warn("falha ao baixar mídia do canal, enviando webhook sem URL", zap.String("msg_id", evt.Info.ID))
+		}
+		p.MediaURL, p.MediaDownloadURL = media.MediaURL, media.MediaDownloadURL
+	}
+	return p
+}
+
+// normalizeChatUpdate maps the app state chat actions. The full sync replays the state of every
+// chat at pairing; those aren't changes, so they aren't delivered (nil).
+func normalizeChatUpdate(evt any) *payload.ChatUpdate {
+	p := &payload.ChatUpdate{Base: payload.NewBase(payload.TypeChatUpdate)}
+	pick := func(on bool, yes, no string) string {
+		if on {
+			return yes
+		}
+		return no
+	}
+	switch evt := evt.(type) {
+	case *events.Archive:
+		if evt.FromFullSync {
+			return nil
+		}
+		p.ChatJID, p.Timestamp = evt.JID.String(), evt.Timestamp
+		p.Action = pick(evt.Action.GetArchived(), payload.ChatArchive, payload.ChatUnarchive)
+	case *events.Pin:
+		if evt.FromFullSync {
+			return nil
+		}
+		p.ChatJID, p.Timestamp = evt.JID.String(), evt.Timestamp
+		p.Action = pick(evt.Action.GetPinned(), payload.ChatPin, payload.ChatUnpin)
+	case *events.Mute:
+		if evt.FromFullSync {
+			return nil
+		}
+		p.ChatJID, p.Timestamp = evt.JID.String(), evt.Timestamp
+		p.Action = pick(evt.Action.GetMuted(), payload.ChatMute, payload.ChatUnmute)
+		// Milliseconds; -1 (or nothing) is a mute without end.
+		if end := evt.Action.GetMuteEndTimestamp(); evt.Action.GetMuted() && end > 0 {
+			until := time.UnixMilli(end)
+			p.MutedUntil = &until
+		}
+	case *events.MarkChatAsRead:
+		if evt.FromFullSync {
+			return nil
+		}
+		p.ChatJID, p.Timestamp = evt.JID.String(), evt.Timestamp
+		p.Action = pick(evt.Action.GetRead(), payload.ChatMarkRead, payload.ChatMarkUnread)
+	case *events.ClearChat:
+		if evt.FromFullSync {
+			return nil
+		}
+		p.ChatJID, p.Timestamp, p.Action = evt.JID.String(), evt.Timestamp, payload.ChatClear
+	case *events.DeleteChat:
+		if evt.FromFullSync {
+			return nil
+		}
+		p.ChatJID, p.Timestamp, p.Action = evt.JID.String(), evt.Timestamp, payload.ChatDelete
+	case *events.Star:
+		if evt.FromFullSync {
+			return nil
+		}
+		p.ChatJID, p.Timestamp = evt.ChatJID.String(), evt.Timestamp
+		p.Action = pick(evt.Action.GetStarred(), payload.ChatStar, payload.ChatUnstar)
+		p.MessageID = evt.MessageID
+		p.IsFromMe = proto.Bool(evt.IsFromMe)
+	case *events.DeleteForMe:
+		if evt.FromFullSync {
+			return nil
+		}
+		p.ChatJID, p.Timestamp, p.Action = evt.ChatJID.String(), evt.Timestamp, payload.ChatDeleteForMe
+		p.MessageID = evt.MessageID
+		p.IsFromMe = proto.Bool(evt.IsFromMe)
+	default:
+		return nil
+	}
+	return p
+}
@@ -0,0 +1,113 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow/proto/waSyncAction"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/open-apime/apime/internal/webhook/payload"
+)
+
+func TestNormalizeChatUpdate(t *testing.T) {
+	chat := types.NewJID("5511999999999", types.DefaultUserServer)
+	ts := time.Unix(1_700_000_000, 0)
+
+	cases := []struct {
+		name      string
+		evt       any
+		action    string
+		messageID string
+	}{
+		{"archive", &events.Archive{JID: chat, Timestamp: ts, Action: &waSyncAction.ArchiveChatAction{Archived: proto.Bool(true)}}, payload.ChatArchive, ""},
+		{"unarchive", &events.Archive{JID: chat, Timestamp: ts, Action: &waSyncAction.ArchiveChatAction{Archived: proto.Bool(false)}}, payload.ChatUnarchive, ""},
+		{"pin", &events.Pin{JID: chat, Timestamp: ts, Action: &waSyncAction.PinAction{Pinned: proto.Bool(true)}}, payload.ChatPin, ""},
+		{"unpin", &events.Pin{JID: chat, Timestamp: ts, Action: &waSyncAction.PinAction{Pinned: proto.Bool(false)}}, payload.ChatUnpin, ""},
+		{"mute", &events.Mute{JID: chat, Timestamp: ts, Action: &waSyncAction.MuteAction{Muted: proto.Bool(true)}}, payload.ChatMute, ""},
+		{"unmute", &events.Mute{JID: chat, Timestamp: ts, Action: &waSyncAction.MuteAction{Muted: proto.Bool(false)}}, payload.ChatUnmute, ""},
+		{"mark_read", &events.MarkChatAsRead{JID: chat, Timestamp: ts, Action: &waSyncAction.MarkChatAsReadAction{Read: proto.Bool(true)}}, payload.ChatMarkRead, ""},
+		{"mark_unread", &events.MarkChatAsRead{JID: chat, Timestamp: ts, Action: &waSyncAction.MarkChatAsReadAction{Read: proto.Bool(false)}}, payload.ChatMarkUnread, ""},
+		{"clear", &events.ClearChat{JID: chat, Timestamp: ts, Action: &waSyncAction.ClearChatAction{}}, payload.ChatClear, ""},
+		{"delete", &events.DeleteChat{JID: chat, Timestamp: ts, Action: &waSyncAction.DeleteChatAction{}}, payload.ChatDelete, ""},
+		{"star", &events.Star{ChatJID: chat, MessageID: "ABC", IsFromMe: true, Timestamp: ts, Action: &waSyncAction.StarAction{Starred: proto.Bool(true)}}, payload.ChatStar, "ABC"},
+		{"unstar", &events.Star{ChatJID: chat, MessageID: "ABC", IsFromMe: true, Timestamp: ts, Action: &waSyncAction.StarAction{Starred: proto.Bool(false)}}, payload.ChatUnstar, "ABC"},
+		{"delete_for_me", &events.DeleteForMe{ChatJID: chat, MessageID: "ABC", IsFromMe: true, Timestamp: ts, Action: &waSyncAction.DeleteMessageForMeAction{}}, payload.ChatDeleteForMe, "ABC"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := normalizeChatUpdate(tc.evt)
+			if p == nil {
+				t.Fatalf("evento descartado")
+			}
+			if p.Type != payload.TypeChatUpdate || p.Action != tc.action {
+				t.Fatalf("type %q, action %q; queria %q", p.Type, p.Action, tc.action)
+			}
+			if p.ChatJID != chat.String() || !p.Timestamp.Equal(ts) {
+				t.Fatalf("chatJID %q, timestamp %s", p.ChatJID, p.Timestamp)
+			}
+			if p.MessageID != tc.messageID {
+				t.Fatalf("messageId %q; queria %q", p.MessageID, tc.messageID)
+			}
+			if (tc.messageID != "") != (p.IsFromMe != nil) {
+				t.Fatalf("isFromMe só vem com messageId, veio %v", p.IsFromMe)
+			}
+			if p.MutedUntil != nil {
+				t.Fatalf("mutedUntil sem fim de silêncio: %v", p.MutedUntil)
+			}
+		})
+	}
+}
+
+// TestNormalizeChatUpdateFullSync: the full sync after pairing replays the whole app state, which
+// would send one webhook per chat for changes made long ago.
+func TestNormalizeChatUpdateFullSync(t *testing.T) {
+	chat := types.NewJID("5511999999999", types.DefaultUserServer)
+	for _, evt := range []any{
+		&events.Archive{JID: chat, FromFullSync: true, Action: &waSyncAction.ArchiveChatAction{Archived: proto.Bool(true)}},
+		&events.Pin{JID: chat, FromFullSync: true, Action: &waSyncAction.PinAction{Pinned: proto.Bool(true)}},
+		&events.Mute{JID: chat, FromFullSync: true, Action: &waSyncAction.MuteAction{Muted: proto.Bool(true)}},
+		&events.MarkChatAsRead{JID: chat, FromFullSync: true, Action: &waSyncAction.MarkChatAsReadAction{Read: proto.Bool(true)}},
+		&events.ClearChat{JID: chat, FromFullSync: true},
+		&events.DeleteChat{JID: chat, FromFullSync: true},
+		&events.Star{ChatJID: chat, MessageID: "ABC", FromFullSync: true, Action: &waSyncAction.StarAction{Starred: proto.Bool(true)}},
+		&events.DeleteForMe{ChatJID: chat, MessageID: "ABC", FromFullSync: true},
+	} {
+		if p := normalizeChatUpdate(evt); p != nil {
+			t.Errorf("%T do full sync deveria ser descartado, veio %+v", evt, p)
+		}
+	}
+	if p := normalizeChatUpdate(&events.Receipt{}); p != nil {
+		t.Errorf("evento sem relação com chat deveria ser ignorado, veio %+v", p)
+	}
+}
+
+func TestNormalizeChatUpdateMutedUntil(t *testing.T) {
+	chat := types.NewJID("5511999999999", types.DefaultUserServer)
+	end := time.UnixMilli(1_700_000_123_456)
+
+	cases := []struct {
+		name  string
+		muted bool
+		end   *int64
+		want  *time.Time
+	}{
+		{"com fim, em milissegundos", true, proto.Int64(end.UnixMilli()), &end},
+		{"sem fim (-1)", true, proto.Int64(-1), nil},
+		{"sem fim (ausente)", true, nil, nil},
+		{"unmute ignora o fim", false, proto.Int64(end.UnixMilli()), nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := normalizeChatUpdate(&events.Mute{JID: chat, Action: &waSyncAction.MuteAction{Muted: proto.Bool(tc.muted), MuteEndTimestamp: tc.end}})
+			switch {
+			case tc.want == nil && p.MutedUntil != nil:
+				t.Fatalf("mutedUntil = %s; queria ausente", p.MutedUntil)
+			case tc.want != nil && (p.MutedUntil == nil || !p.MutedUntil.Equal(*tc.want)):
+				t.Fatalf("mutedUntil = %v; queria %s", p.MutedUntil, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,327 @@
+// Package payload defines the "payload" object of each webhook event type. These structs are
+// the contract with consumers: the JSON Schemas served at /api/webhook-schemas are generated
+// from them, so a field only exists if it is declared here.
+package payload
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
+
+// SchemaVersion goes up when a field is removed or changes meaning. Adding an optional field
+// doesn't change it.
+const SchemaVersion = 1
+
+const (
+	TypeMessage               = "message"
+	TypeReceipt               = "receipt"
+	TypePresence              = "presence"
+	TypeChatPresence          = "chat_presence"
+	TypeReaction              = "reaction"
+	TypeContactUpdate         = "contact_update"
+	TypeChatUpdate            = "chat_update"
+	TypeBlocklistUpdate       = "blocklist_update"
+	TypeNewsletterMessage     = "newsletter_message"
+	TypeConnected             = "connected"
+	TypeDisconnected          = "disconnected"
+	TypeTemporaryBan          = "temporary_ban"
+	TypeRestrictionLifted     = "restriction_lifted"
+	TypeContactReachoutLocked = "contact_reachout_locked"
+	TypeUnknown               = "unknown"
+)
+
+// Event is implemented by every payload through its embedded Base.
+type Event interface {
+	Header() *Base
+}
+
+// Base holds the fields shared by every payload.
+type Base struct {
+	Type          string `json:"type"`
+	SchemaVersion int    `json:"schemaVersion"`
+	// InstanceJID is the WhatsApp account of the instance, once paired.
+	InstanceJID string `json:"instanceJID,omitempty"`
+	// Raw is the whatsmeow event as received, for fields not mapped here. Its shape follows
+	// whatsmeow and is not covered by the schema version; the webhook can opt out of it.
+	Raw json.RawMessage `json:"raw,omitempty"`
+}
+
+// NewBase starts the payload of an event type.
+func NewBase(eventType string) Base {
+	return Base{Type: eventType, SchemaVersion: SchemaVersion}
+}
+
+func (b *Base) Header() *Base {
+	return b
+}
+
+// Message is a message received or sent by the instance, including the undecryptable ones.
+type Message struct {
+	Base
+	From           string    `json:"from"`
+	ChatJID        string    `json:"chatJID"`
+	To             string    `json:"to"`
+	AddressingMode string    `json:"addressingMode,omitempty"`
+	LID            string    `json:"lid,omitempty"`
+	IsFromMe       bool      `json:"isFromMe"`
+	IsGroup        bool      `json:"isGroup"`
+	MessageID      string    `json:"messageId"`
+	Timestamp      time.Time `json:"timestamp"`
+	PushName       string    `json:"pushName"`
+	VerifiedName   string    `json:"verifiedName,omitempty"`
+	Issuer         string    `json:"issuer,omitempty"`
+	Text           string    `json:"text,omitempty"`
+
+	MediaType        string   `json:"mediaType,omitempty"`
+	IsPtv            bool     `json:"isPtv,omitempty"`
+	Caption          string   `json:"caption,omitempty"`
+	Mimetype         string   `json:"mimetype,omitempty"`
+	FileSize         uint64   `json:"fileSize,omitempty"`
+	FileName         string   `json:"fileName,omitempty"`
+	Duration         uint32   `json:"duration,omitempty"`
+	PTT              *bool    `json:"ptt,omitempty"`
+	MediaURL         string   `json:"mediaUrl,omitempty"`
+	MediaDownloadURL string   `json:"mediaDownloadUrl,omitempty"`
+	Latitude         *float64 `json:"latitude,omitempty"`
+	Longitude        *float64 `json:"longitude,omitempty"`
+	Address          string   `json:"address,omitempty"`
+	ContactName      string   `json:"contactName,omitempty"`
+	ContactNumber    string   `json:"contactNumber,omitempty"`
+
+	Interactive      *Interactive      `json:"interactive,omitempty"`
+	InteractiveReply *InteractiveReply `json:"interactiveReply,omitempty"`
+	MentionedJIDs    []string          `json:"mentionedJids,omitempty"`
+	EditedMessageID  string            `json:"editedMessageId,omitempty"`
+	EditedText       string            `json:"editedText,omitempty"`
+
+	Undecryptable   bool   `json:"undecryptable,omitempty"`
+	MessageType     string `json:"messageType,omitempty"`
+	UnavailableType string `json:"unavailableType,omitempty"`
+}
+
+// NewsletterMessage is a post on a channel (newsletter) the instance follows or administers,
+// including the ones it published. Posts aren't encrypted and have no sender: ServerID is the
+// sequential id the channel endpoints (reactions, mark-viewed, pagination) take.
+type NewsletterMessage struct {
+	Base
+	NewsletterJID string     `json:"newsletterJID"`
+	MessageID     string     `json:"messageId"`
+	ServerID      int        `json:"serverId"`
+	IsFromMe      bool       `json:"isFromMe"`
+	Timestamp     time.Time  `json:"timestamp"`
+	EditedAt      *time.Time `json:"editedAt,omitempty"`
+	Text          string     `json:"text,omitempty"`
+
+	MediaType        string `json:"mediaType,omitempty"`
+	Caption          string `json:"caption,omitempty"`
+	Mimetype         string `json:"mimetype,omitempty"`
+	FileSize         uint64 `json:"fileSize,omitempty"`
+	FileName         string `json:"fileName,omitempty"`
+	MediaURL         string `json:"mediaUrl,omitempty"`
+	MediaDownloadURL string `json:"mediaDownloadUrl,omitempty"`
+}
+
+// Interactive describes the buttons or list of an interactive message.
+type Interactive struct {
+	Kind     string    `json:"kind"`
+	Text     string    `json:"text,omitempty"`
+	Footer   string    `json:"footer,omitempty"`
+	Buttons  []Button  `json:"buttons,omitempty"`
+	Sections []Section `json:"sections,omitempty"`
+}
+
+type Button struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+	Type  string `json:"type"`
+	URL   string `json:"url,omitempty"`
+	Phone string `json:"phone,omitempty"`
+	Code  string `json:"code,omitempty"`
+}
+
+// Rows, like every list field without omitempty, is never null: an empty list goes out as [].
+type Section struct {
+	Title string `json:"title"`
+	Rows  []Row  `json:"rows"`
+}
+
+type Row struct {
+	ID          string `json:"id"`
+	Label       string `json:"label"`
+	Description string `json:"description"`
+}
+
+// InteractiveReply is the choice made on a button or list.
+type InteractiveReply struct {
+	SelectedID    string `json:"selectedId,omitempty"`
+	SelectedLabel string `json:"selectedLabel,omitempty"`
+	Name          string `json:"name,omitempty"`
+	ParamsJSON    string `json:"paramsJson,omitempty"`
+}
+
+// Reaction is a reaction added to (or, with an empty emoji, removed from) a message.
+type Reaction struct {
+	Base
+	From              string    `json:"from"`
+	ChatJID           string    `json:"chatJID"`
+	To                string    `json:"to"`
+	Participant       string    `json:"participant,omitempty"`
+	Author            string    `json:"author,omitempty"`
+	IsFromMe          bool      `json:"isFromMe"`
+	IsGroup           bool      `json:"isGroup"`
+	MessageID         string    `json:"messageId"`
+	Timestamp         time.Time `json:"timestamp"`
+	PushName          string    `json:"pushName"`
+	ReactionEmoji     string    `json:"reactionEmoji"`
+	ReactionMessageID string    `json:"reactionMessageId,omitempty"`
+}
+
+type Receipt struct {
+	Base
+	// MessageIDs is never null (see Section).
+	MessageIDs    []string  `json:"messageIds"`
+	Timestamp     time.Time `json:"timestamp"`
+	Chat          string    `json:"chat"`
+	IsGroup       bool      `json:"isGroup"`
+	From          string    `json:"from,omitempty"`
+	MessageSender string    `json:"messageSender,omitempty"`
+	Status        string    `json:"status"`
+}
+
+type Presence struct {
+	Base
+	From        string     `json:"from"`
+	Unavailable bool       `json:"unavailable"`
+	LastSeen    *time.Time `json:"lastSeen,omitempty"`
+}
+
+type ChatPresence struct {
+	Base
+	From    string `json:"from"`
+	ChatJID string `json:"chatJID"`
+	State   string `json:"state"`
+	Media   string `json:"media,omitempty"`
+}
+
+type ContactUpdate struct {
+	Base
+	JID          string    `json:"jid"`
+	LID          string    `json:"lid,omitempty"`
+	Username     string    `json:"username"`
+	FromFullSync bool      `json:"fromFullSync"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+// ChatUpdate is a change to a chat made on any device of the account (the phone, or the API
+// itself): archived, pinned, muted, read state, cleared or deleted, or a message starred or
+// deleted for me. Action says which; MessageID is set for star, unstar and delete_for_me.
+type ChatUpdate struct {
+	Base
+	ChatJID string `json:"chatJID"`
+	Action  string `json:"action"`
+	// MutedUntil is set for mute with an end; a mute without it is forever.
+	MutedUntil *time.Time `json:"mutedUntil,omitempty"`
+	MessageID  string     `json:"messageId,omitempty"`
+	IsFromMe   *bool      `json:"isFromMe,omitempty"`
+	Timestamp  time.Time  `json:"timestamp"`
+}
+
+// Actions of chat_update.
+const (
+	ChatArchive     = "archive"
+	ChatUnarchive   = "unarchive"
+	ChatPin         = "pin"
+	ChatUnpin       = "unpin"
+	ChatMute        = "mute"
+	ChatUnmute      = "unmute"
+	ChatMarkRead    = "mark_read"
+	ChatMarkUnread  = "mark_unread"
+	ChatClear       = "clear"
+	ChatDelete      = "delete"
+	ChatStar        = "star"
+	ChatUnstar      = "unstar"
+	ChatDeleteForMe = "delete_for_me"
+)
+
+// BlocklistUpdate is a change to the contacts blocked by the account, made on any device. When
+// FullRefresh is set WhatsApp only said the list changed, without the changes: fetch it again.
+type BlocklistUpdate struct {
+	Base
+	FullRefresh bool `json:"fullRefresh"`
+	// Changes is empty, not null, on a full refresh.
+	Changes []BlocklistChange `json:"changes"`
+}
+
+// BlocklistChange is one contact blocked or unblocked. Action is "block" or "unblock".
+type BlocklistChange struct {
+	JID    string `json:"jid"`
+	Action string `json:"action"`
+}
+
+// Connection is the payload of connected and disconnected.
+type Connection struct {
+	Base
+	Reason string `json:"reason,omitempty"`
+}
+
+// Restriction is the payload of temporary_ban and restriction_lifted.
+type Restriction struct {
+	Base
+	Reason          string     `json:"reason,omitempty"`
+	Code            int        `json:"code,omitempty"`
+	Active          bool       `json:"active"`
+	RestrictedUntil *time.Time `json:"restrictedUntil,omitempty"`
+	EnforcementType string     `json:"enforcementType,omitempty"`
+}
+
+type ContactReachoutLocked struct {
+	Base
+	To     string `json:"to"`
+	Reason string `json:"reason"`
+	Detail string `json:"detail"`
+	Code   int    `json:"code"`
+}
+
+type Unknown struct {
+	Base
+	EventType string `json:"eventType"`
+}
+
+// Types lists the payload of each delivered event type, in the order of the docs.
+var Types = []struct {
+	Type    string
+	Payload Event
+}{
+	{TypeMessage, &Message{}},
+	{TypeReceipt, &Receipt{}},
+	{TypePresence, &Presence{}},
+	{TypeChatPresence, &ChatPresence{}},
+	{TypeReaction, &Reaction{}},
+	{TypeContactUpdate, &ContactUpdate{}},
+	{TypeChatUpdate, &ChatUpdate{}},
+	{TypeBlocklistUpdate, &BlocklistUpdate{}},
+	{TypeNewsletterMessage, &NewsletterMessage{}},
+	{TypeConnected, &Connection{}},
+	{TypeDisconnected, &Connection{}},
+	{TypeTemporaryBan, &Restriction{}},
+	{TypeRestrictionLifted, &Restriction{}},
+	{TypeContactReachoutLocked, &ContactReachoutLocked{}},
+	{TypeUnknown, &Unknown{}},
+}
+
+// Map converts a payload to the map carried by queue.Event. Numbers are kept as json.Number, so
+// they come out of the queue exactly as they went in.
+func Map(e Event) (map[string]interface{}, error) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var m map[string]interface{}
+	if err := dec.Decode(&m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,108 @@
+package payload
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSchemasMatchDocs keeps docs/webhook-payloads.md in step with the structs: every field of
+// every event type has to be in the section of that type.
+func TestSchemasMatchDocs(t *testing.T) {
+	data, err := os.ReadFile("../../../docs/webhook-payloads.md")
+	if err != nil {
+		t.Fatalf("ler documentação: %v", err)
+	}
+	doc := string(data)
+
+	for name := range typeSchema(reflect.TypeOf(Base{}))["properties"].(map[string]interface{}) {
+		if !strings.Contains(doc, "`"+name+"`") {
+			t.Errorf("campo comum %q não documentado", name)
+		}
+	}
+
+	for _, typ := range Types {
+		start := strings.Index(doc, "### `"+typ.Type+"`")
+		if start < 0 {
+			t.Errorf("tipo %q sem seção na documentação", typ.Type)
+			continue
+		}
+		section := doc[start:]
+		if end := strings.Index(section, "\n---"); end >= 0 {
+			section = section[:end]
+		}
+		schema, _ := Schema(typ.Type)
+		for name := range schema["properties"].(map[string]interface{}) {
+			if isBaseField(name) {
+				continue
+			}
+			if !strings.Contains(section, "`"+name+"`") {
+				t.Errorf("campo %q de %q não documentado na seção do tipo", name, typ.Type)
+			}
+		}
+	}
+}
+
+func TestSchemaRequiredFields(t *testing.T) {
+	schema, ok := Schema(TypeMessage)
+	if !ok {
+		t.Fatalf("schema de message ausente")
+	}
+	required := map[string]bool{}
+	for _, name := range schema["required"].([]string) {
+		required[name] = true
+	}
+	for _, name := range []string{"type", "schemaVersion", "from", "to", "messageId", "timestamp"} {
+		if !required[name] {
+			t.Errorf("%q deveria ser obrigatório", name)
+		}
+	}
+	for _, name := range []string{"raw", "mediaUrl", "instanceJID", "interactive"} {
+		if required[name] {
+			t.Errorf("%q deveria ser opcional", name)
+		}
+	}
+	props := schema["properties"].(map[string]interface{})
+	if got := props["type"].(map[string]interface{})["const"]; got != TypeMessage {
+		t.Errorf("const de type = %v", got)
+	}
+	if got := props["timestamp"].(map[string]interface{})["format"]; got != "date-time" {
+		t.Errorf("timestamp deveria ser date-time, veio %v", got)
+	}
+	buttons := props["interactive"].(map[string]interface{})["properties"].(map[string]interface{})["buttons"].(map[string]interface{})
+	if buttons["type"] != "array" {
+		t.Errorf("buttons deveria ser array, veio %v", buttons["type"])
+	}
+
+	if _, ok := Schema("ignore"); ok {
+		t.Errorf("ignore não é entregue e não deveria ter schema")
+	}
+}
+
+func TestMapKeepsFields(t *testing.T) {
+	size := uint64(1<<53 + 1)
+	msg := &Message{Base: NewBase(TypeMessage), MessageID: "ABC", FileSize: size, Timestamp: time.Unix(1_700_000_000, 0).UTC()}
+	m, err := Map(msg)
+	if err != nil {
+		t.Fatalf("Map: %v", err)
+	}
+	if m["type"] != TypeMessage || m["messageId"] != "ABC" {
+		t.Fatalf("campos perdidos: %v", m)
+	}
+	if got := m["fileSize"]; got == nil || got.(interface{ String() string }).String() != "9007199254740993" {
+		t.Fatalf("fileSize alterado: %v", got)
+	}
+	if _, ok := m["mediaUrl"]; ok {
+		t.Fatalf("campo vazio com omitempty não deveria sair")
+	}
+}
+
+func isBaseField(name string) bool {
+	switch name {
+	case "type", "schemaVersion", "instanceJID", "raw":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package payload
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const schemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	rawJSONType = reflect.TypeOf(json.RawMessage{})
+)
+
+// Schema returns the JSON Schema of the payload of eventType, generated from its struct. A field
+// without omitempty is required; extra properties are allowed, so a consumer validating against
+// it keeps working when optional fields are added.
+func Schema(eventType string) (map[string]interface{}, bool) {
+	for _, t := range Types {
+		if t.Type != eventType {
+			continue
+		}
+		schema := typeSchema(reflect.TypeOf(t.Payload))
+		schema["$schema"] = schemaDialect
+		schema["$id"] = "/api/webhook-schemas/" + eventType
+		schema["title"] = eventType
+		props := schema["properties"].(map[string]interface{})
+		props["type"] = map[string]interface{}{"type": "string", "const": eventType}
+		props["schemaVersion"] = map[string]interface{}{"type": "integer", "const": SchemaVersion}
+		return schema, true
+	}
+	return nil, false
+}
+
+// Schemas returns the schema of every delivered event type, keyed by type.
+func Schemas() map[string]interface{} {
+	schemas := make(map[string]interface{}, len(Types))
+	for _, t := range Types {
+		schemas[t.Type], _ = Schema(t.Type)
+	}
+	return schemas
+}
+
+func typeSchema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t == rawJSONType:
+		// Any JSON value.
+		return map[string]interface{}{}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Struct:
+		props := map[string]interface{}{}
+		required := []string{}
+		addFields(t, props, &required)
+		schema := map[string]interface{}{"type": "object", "properties": props}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// addFields follows encoding/json: embedded structs are flattened and "-" fields skipped.
+func addFields(t reflect.Type, props map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			addFields(f.Type, props, required)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = typeSchema(f.Type)
+		if !strings.Contains(opts, "omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
@@ -20,15 +20,76 @@ type Pool struct {
 	log          *zap.Logger
 
 	numWorkers int
+	ordering   string
 	workers    []*poolWorker
 	taskChan   chan *queue.Event
 	wg         sync.WaitGroup
 	ctx        context.Context
 	cancel     context.CancelFunc
+
+	// Ordered modes: one FIFO per key, drained by at most one worker at a time.
+	partMu      sync.Mutex
+	partitions  map[string]*partition
+	ready       chan *partition
+	buffered    int
+	maxBuffered int
+	spins       int
+}
+
+// partition holds the events of one ordering key waiting for a worker.
+type partition struct {
+	key     string
+	events  []*queue.Event
+	running bool // handed to ready or being drained by a worker
+	// deferred are the events requeued while the partition was full, oldest
+	// first. Only the head is admitted when it comes back; any other event of
+	// the key, deferred or new, goes back to the queue behind it, so none
+	// overtakes an older one.
+	deferred []deferral
+}
+
+// deferral is one requeued event of a partition and when it was last requeued.
+type deferral struct {
+	id string
+	at time.Time
+}
+
+func (part *partition) deferredIndex(id string) int {
+	for i, d := range part.deferred {
+		if d.id == id {
+			return i
+		}
+	}
+	return -1
 }
 
+// Ordering modes. With instance or chat, events sharing the key always go to
+// the same worker, which delivers them one at a time in queue order.
+const (
+	OrderingNone     = "none"
+	OrderingInstance = "instance"
+	OrderingChat     = "chat"
+)
+
+// maxDeliveryAttempts bounds the Nack cycles of an event; each cycle already
+// includes the retries of delivery.Deliver.
+const maxDeliveryAttempts = 5
+
+const (
+	// maxPartitionDepth bounds the events buffered per key. Past it, and past
+	// workers*maxPartitionDepth in total, the dispatcher requeues the event
+	// instead of waiting, so a slow endpoint never holds up the other instances.
+	maxPartitionDepth = 16
+	// deferralWindow forgets a requeued event that has not come back for this
+	// long (another replica took it), so the key is not requeued forever. Events
+	// still cycling through the pool are requeued far more often than that.
+	deferralWindow = time.Minute
+)
+
 type poolWorker struct {
 	id       int
+	ordered  bool
+	queue    queue.Queue
 	taskChan chan *queue.Event
 	log      *zap.Logger
 	delivery *delivery.Delivery
@@ -52,19 +113,42 @@ func NewPool(
 		delivery:     delivery,
 		log:          log,
 		numWorkers:   numWorkers,
+		ordering:     OrderingNone,
 		workers:      make([]*poolWorker, numWorkers),
 		taskChan:     make(chan *queue.Event, numWorkers*2),
 	}
 }
 
+// SetOrdering must be called before Start. Unknown values fall back to none.
+func (p *Pool) SetOrdering(mode string) {
+	switch mode {
+	case OrderingInstance, OrderingChat:
+		p.ordering = mode
+	default:
+		p.ordering = OrderingNone
+	}
+}
+
 func (p *Pool) Start(ctx context.Context) {
 	p.ctx, p.cancel = context.WithCancel(ctx)
 
-	p.log.Info("webhook pool: iniciando", zap.Int("workers", p.numWorkers))
+	p.log.Info("webhook pool: iniciando", zap.Int("workers", p.numWorkers), zap.String("ordering", p.ordering))
 
+	ordered := p.ordering != OrderingNone
+	if ordered {
+		if p.partitions == nil {
+			p.partitions = make(map[string]*partition)
+		}
+		p.maxBuffered = p.numWorkers * maxPartitionDepth
+		// Every partition in ready holds at least one buffered event, so this
+		// capacity keeps the send in dispatchOrdered from ever blocking.
+		p.ready = make(chan *partition, p.maxBuffered)
+	}
 	for i := 0; i < p.numWorkers; i++ {
 		worker := &poolWorker{
 			id:       i,
+			ordered:  ordered,
+			queue:    p.queue,
 			taskChan: p.taskChan,
 			log:      p.log,
 			delivery: p.delivery,
@@ -87,6 +171,7 @@ func (p *Pool) Stop() {
 	p.cancel()
 	p.wg.Wait()
 	close(p.taskChan)
+	p.releaseBuffered()
 	p.log.Info("webhook pool: encerrada")
 }
 
@@ -108,22 +193,137 @@ func (p *Pool) runDispatcher() {
 				continue
 			}
 
+			if p.ordering != OrderingNone {
+				p.dispatchOrdered(event)
+				continue
+			}
+
+			// Block instead of dropping: busy workers just apply backpressure,
+			// and the event stays safe in the queue until a worker acks it.
 			select {
 			case p.taskChan <- event:
 			case <-p.ctx.Done():
+				p.nack(event)
 				return
-			case <-time.After(5 * time.Second):
-				p.log.Warn("webhook pool: taskChan cheio, descartando evento", zap.String("eventId", event.ID))
 			}
 		}
 	}
 }
 
+// dispatchOrdered appends the event to the partition of its key, or requeues
+// it when the partition (or the pool) is full. It never blocks.
+func (p *Pool) dispatchOrdered(event *queue.Event) {
+	key := p.partitionKey(event)
+
+	p.partMu.Lock()
+	part := p.partitions[key]
+	if part == nil {
+		part = &partition{key: key}
+		p.partitions[key] = part
+	}
+	now := time.Now()
+	for len(part.deferred) > 0 && now.Sub(part.deferred[0].at) > deferralWindow {
+		part.deferred = part.deferred[1:]
+	}
+	pos := part.deferredIndex(event.ID)
+	full := len(part.events) >= maxPartitionDepth || p.buffered >= p.maxBuffered
+
+	if pos > 0 || (pos < 0 && len(part.deferred) > 0) || full {
+		if pos < 0 {
+			part.deferred = append(part.deferred, deferral{id: event.ID, at: now})
+		} else {
+			part.deferred[pos].at = now
+		}
+		p.spins++
+		spins := p.spins
+		p.partMu.Unlock()
+
+		p.requeue(event)
+		if spins >= maxPartitionDepth {
+			// Only requeued events in a row: the queue holds nothing else, so
+			// pause instead of cycling the same events through Redis.
+			p.partMu.Lock()
+			p.spins = 0
+			p.partMu.Unlock()
+			select {
+			case <-p.ctx.Done():
+			case <-time.After(200 * time.Millisecond):
+			}
+		}
+		return
+	}
+
+	if pos == 0 {
+		part.deferred = part.deferred[1:]
+	}
+	part.events = append(part.events, event)
+	p.buffered++
+	p.spins = 0
+	if !part.running {
+		part.running = true
+		p.ready <- part
+	}
+	p.partMu.Unlock()
+}
+
+// next pops the head of the partition, or marks it idle when it is empty.
+func (p *Pool) next(part *partition) *queue.Event {
+	p.partMu.Lock()
+	defer p.partMu.Unlock()
+	if len(part.events) == 0 {
+		part.running = false
+		if len(part.deferred) == 0 {
+			delete(p.partitions, part.key)
+		}
+		return nil
+	}
+	event := part.events[0]
+	part.events[0] = nil
+	part.events = part.events[1:]
+	p.buffered--
+	return event
+}
+
+// releaseBuffered hands back the events still waiting in partitions on
+// shutdown, so another replica takes them right away. They go to the front of
+// the deferred list: if this pool starts again it still admits them first.
+func (p *Pool) releaseBuffered() {
+	p.partMu.Lock()
+	var pending []*queue.Event
+	now := time.Now()
+	for key, part := range p.partitions {
+		if len(part.events) > 0 {
+			head := make([]deferral, 0, len(part.events)+len(part.deferred))
+			for _, event := range part.events {
+				head = append(head, deferral{id: event.ID, at: now})
+			}
+			part.deferred = append(head, part.deferred...)
+			pending = append(pending, part.events...)
+		}
+		part.events = nil
+		part.running = false
+		if len(part.deferred) == 0 {
+			delete(p.partitions, key)
+		}
+	}
+	p.buffered = 0
+	p.partMu.Unlock()
+
+	for _, event := range pending {
+		p.requeue(event)
+	}
+}
+
 func (p *Pool) runWorker(worker *poolWorker) {
 	defer p.wg.Done()
 
 	p.log.Info(fmt.Sprintf("[worker %d] webhook pool: worker iniciado", worker.id+1))
 
+	if worker.ordered {
+		p.runOrderedWorker(worker)
+		return
+	}
+
 	for {
 		select {
 		case <-p.ctx.Done():
@@ -138,8 +338,128 @@ func (p *Pool) runWorker(worker *poolWorker) {
 	}
 }
 
+// runOrderedWorker drains one partition at a time; the partition is never
+// handed to two workers at once, which is what keeps its order.
+func (p *Pool) runOrderedWorker(worker *poolWorker) {
+	for {
+		select {
+		case <-p.ctx.Done():
+			p.log.Info(fmt.Sprintf("[worker %d] webhook pool: worker encerrando", worker.id+1))
+			return
+		case part := <-p.ready:
+			for p.ctx.Err() == nil {
+				event := p.next(part)
+				if event == nil {
+					break
+				}
+				worker.processEvent(p.ctx, event)
+			}
+		}
+	}
+}
+
+func (p *Pool) partitionKey(event *queue.Event) string {
+	if p.ordering == OrderingChat {
+		return event.InstanceID + "\x00" + chatKey(event.Payload)
+	}
+	return event.InstanceID
+}
+
+// chatKey picks the conversation of an event from the normalized payload.
+// Events without a chat (connection, ban) share the instance-wide key "".
+func chatKey(payload map[string]interface{}) string {
+	for _, k := range []string{"chatJID", "chat", "newsletterJID", "from"} {
+		if v, ok := payload[k].(string); ok && v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// requeue hands back an event the pool cannot buffer now, without counting
+// a delivery attempt.
+func (p *Pool) requeue(event *queue.Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := p.queue.Requeue(ctx, event); err != nil {
+		p.log.Warn("webhook pool: erro ao devolver evento à fila", zap.String("eventId", event.ID), zap.Error(err))
+	}
+}
+
+// nack hands an event back on shutdown so another replica delivers it right
+// away instead of waiting for the visibility timeout.
+func (p *Pool) nack(event *queue.Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := p.queue.Nack(ctx, event); err != nil {
+		p.log.Warn("webhook pool: erro ao devolver evento à fila", zap.String("eventId", event.ID), zap.Error(err))
+	}
+}
+
 func (w *poolWorker) processEvent(ctx context.Context, event *queue.Event) {
 	prefix := fmt.Sprintf("[worker %d]", w.id+1)
+	// Deliver with its retries, plus the inline retries of ordered mode, can outlast the
+	// visibility timeout; the entry must not be reclaimed and delivered twice meanwhile.
+	stopKeepAlive := queue.KeepAlive(w.queue, event)
+	defer stopKeepAlive()
+	if w.deliver(ctx, prefix, event) {
+		if err := w.queue.Ack(context.Background(), event); err != nil {
+			w.log.Warn(fmt.Sprintf("%s webhook pool: erro ao confirmar evento", prefix), zap.String("eventId", event.ID), zap.Error(err))
+		}
+		return
+	}
+
+	if w.ordered {
+		w.retryInline(ctx, prefix, event)
+		return
+	}
+
+	if ctx.Err() == nil && event.Attempts+1 >= maxDeliveryAttempts {
+		w.log.Error(fmt.Sprintf("%s webhook pool: evento descartado após esgotar tentativas", prefix),
+			zap.String("eventId", event.ID),
+			zap.Int("attempts", event.Attempts+1),
+		)
+		_ = w.queue.Ack(context.Background(), event)
+		return
+	}
+	if err := w.queue.Nack(context.Background(), event); err != nil {
+		w.log.Warn(fmt.Sprintf("%s webhook pool: erro ao devolver evento à fila", prefix), zap.String("eventId", event.ID), zap.Error(err))
+	}
+}
+
+// retryInline keeps the partition blocked while the event is retried: a Nack
+// would put it behind newer events of the same key and break the order.
+func (w *poolWorker) retryInline(ctx context.Context, prefix string, event *queue.Event) {
+	backoff := time.Second
+	for attempt := event.Attempts + 2; attempt <= maxDeliveryAttempts; attempt++ {
+		select {
+		case <-ctx.Done():
+			_ = w.queue.Nack(context.Background(), event)
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+
+		if w.deliver(ctx, prefix, event) {
+			_ = w.queue.Ack(context.Background(), event)
+			return
+		}
+	}
+
+	if ctx.Err() != nil {
+		_ = w.queue.Nack(context.Background(), event)
+		return
+	}
+	w.log.Error(fmt.Sprintf("%s webhook pool: evento descartado após esgotar tentativas", prefix),
+		zap.String("eventId", event.ID),
+		zap.Int64("sequence", event.Sequence),
+	)
+	_ = w.queue.Ack(context.Background(), event)
+}
+
+// deliver reports whether the event is done with: delivered, or pointless to
+// retry (instance gone, no webhook configured).
+func (w *poolWorker) deliver(ctx context.Context, prefix string, event *queue.Event) bool {
 	w.log.Debug(fmt.Sprintf("%s webhook pool: processando evento", prefix), zap.String("eventId", event.ID))
 
 	inst, err := w.instRepo.GetByID(ctx, event.InstanceID)
@@ -148,33 +468,29 @@ func (w *poolWorker) processEvent(ctx context.Context, event *queue.Event) {
 			zap.String("eventId", event.ID),
 			zap.Error(err),
 		)
-		return
+		return true
 	}
 
 	if inst.WebhookURL == "" {
 		w.log.Warn(fmt.Sprintf("%s webhook pool: instância sem webhook configurado", prefix),
 			zap.String("instanceId", event.InstanceID),
 		)
-		return
+		return true
 	}
 
-	payload := map[string]interface{}{
-		"id":         event.ID,
-		"instanceId": event.InstanceID,
-		"type":       event.Type,
-		"payload":    event.Payload,
-		"createdAt":  event.CreatedAt,
-	}
+	payload := envelope(inst, event)
 
-	if err := w.delivery.Deliver(ctx, inst.WebhookURL, inst.WebhookSecret, payload); err != nil {
+	if err := w.delivery.Deliver(ctx, inst.WebhookURL, signingSecrets(inst, time.Now()), payload); err != nil {
 		w.log.Error(fmt.Sprintf("%s webhook pool: falha na entrega", prefix),
 			zap.String("eventId", event.ID),
+			zap.Int("attempt", event.Attempts+1),
 			zap.Error(err),
 		)
-		return
+		return false
 	}
 
 	w.log.Info(fmt.Sprintf("%s webhook pool: evento entregue com sucesso", prefix),
 		zap.String("eventId", event.ID),
 	)
+	return true
 }
@@ -0,0 +1,104 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/open-apime/apime/internal/pkg/queue"
+	"github.com/open-apime/apime/internal/pkg/queue/memory"
+	"github.com/open-apime/apime/internal/storage"
+	"github.com/open-apime/apime/internal/storage/model"
+	"github.com/open-apime/apime/internal/webhook/delivery"
+)
+
+type webhookInstances struct {
+	storage.InstanceRepository
+	url string
+}
+
+func (f webhookInstances) GetByID(_ context.Context, id string) (model.Instance, error) {
+	return model.Instance{ID: id, WebhookURL: f.url}, nil
+}
+
+// TestPoolOrderUnderPressure sends one key well past maxPartitionDepth while its endpoint is
+// stuck, so most events are deferred (some more than once); they must still arrive in order.
+func TestPoolOrderUnderPressure(t *testing.T) {
+	const total = 4 * maxPartitionDepth
+
+	release := make(chan struct{})
+	first := make(chan struct{})
+	var (
+		mu  sync.Mutex
+		got []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			ID string `json:"id"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		got = append(got, body.ID)
+		n := len(got)
+		mu.Unlock()
+		if n == 1 {
+			close(first)
+			<-release
+		}
+	}))
+	defer srv.Close()
+
+	q := memory.NewQueue(total)
+	pool := NewPool(q, webhookInstances{url: srv.URL}, delivery.NewDelivery(zap.NewNop(), 0), zap.NewNop(), 1)
+	pool.SetOrdering(OrderingInstance)
+	pool.Start(context.Background())
+	defer pool.Stop()
+
+	ctx := context.Background()
+	enqueue := func(i int) {
+		if err := q.Enqueue(ctx, queue.Event{ID: fmt.Sprintf("e%02d", i), InstanceID: "inst", Type: "message"}); err != nil {
+			t.Fatalf("enqueue: %v", err)
+		}
+	}
+	enqueue(0)
+	select {
+	case <-first:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("primeiro evento não foi entregue")
+	}
+	for i := 1; i < total; i++ {
+		enqueue(i)
+	}
+	// Let the dispatcher fill the partition and cycle the rest through the queue a few times.
+	time.Sleep(500 * time.Millisecond)
+	close(release)
+
+	deadline := time.Now().Add(20 * time.Second)
+	for {
+		mu.Lock()
+		n := len(got)
+		mu.Unlock()
+		if n >= total {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("só %d de %d eventos entregues", n, total)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for i, id := range got {
+		if want := fmt.Sprintf("e%02d", i); id != want {
+			t.Fatalf("posição %d: %s; queria %s (ordem %v)", i, id, want, got)
+		}
+	}
+}
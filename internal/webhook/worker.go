@@ -8,6 +8,7 @@ import (
 
 	"github.com/open-apime/apime/internal/pkg/queue"
 	"github.com/open-apime/apime/internal/storage"
+	"github.com/open-apime/apime/internal/storage/model"
 	"github.com/open-apime/apime/internal/webhook/delivery"
 )
 
@@ -58,6 +59,15 @@ func (w *Worker) processNext(ctx context.Context) {
 	}
 
 	w.log.Info("webhook worker: processando evento", zap.String("id", event.ID))
+	// Acked unless delivery fails; a failed event goes back for another try.
+	done := true
+	defer func() {
+		if done {
+			_ = w.queue.Ack(context.Background(), event)
+		} else {
+			_ = w.queue.Nack(context.Background(), event)
+		}
+	}()
 
 	inst, err := w.instanceRepo.GetByID(ctx, event.InstanceID)
 	if err != nil {
@@ -70,18 +80,50 @@ func (w *Worker) processNext(ctx context.Context) {
 		return
 	}
 
-	payload := map[string]interface{}{
+	payload := envelope(inst, event)
+
+	if err := w.delivery.Deliver(ctx, inst.WebhookURL, signingSecrets(inst, time.Now()), payload); err != nil {
+		w.log.Error("webhook worker: falha na entrega", zap.Error(err))
+		done = event.Attempts+1 >= maxDeliveryAttempts
+		return
+	}
+
+	w.log.Info("webhook worker: evento entregue com sucesso", zap.String("eventId", event.ID))
+}
+
+// envelope wraps the event payload for delivery. The omit-raw option is applied here rather than
+// when the event is queued, so it also covers events already waiting in the queue.
+func envelope(inst model.Instance, event *queue.Event) map[string]interface{} {
+	data := event.Payload
+	if inst.WebhookOmitRaw {
+		if _, ok := data["raw"]; ok {
+			data = make(map[string]interface{}, len(event.Payload))
+			for k, v := range event.Payload {
+				if k != "raw" {
+					data[k] = v
+				}
+			}
+		}
+	}
+	return map[string]interface{}{
 		"id":         event.ID,
 		"instanceId": event.InstanceID,
 		"type":       event.Type,
-		"payload":    event.Payload,
+		"sequence":   event.Sequence,
+		"payload":    data,
 		"createdAt":  event.CreatedAt,
 	}
+}
 
-	if err := w.delivery.Deliver(ctx, inst.WebhookURL, inst.WebhookSecret, payload); err != nil {
-		w.log.Error("webhook worker: falha na entrega", zap.Error(err))
-		return
+// signingSecrets is the current webhook secret followed by the previous one while its grace
+// period after a rotation lasts, so receivers still holding it keep validating deliveries.
+func signingSecrets(inst model.Instance, now time.Time) []string {
+	if inst.WebhookSecret == "" {
+		return nil
 	}
-
-	w.log.Info("webhook worker: evento entregue com sucesso", zap.String("eventId", event.ID))
+	secrets := []string{inst.WebhookSecret}
+	if inst.WebhookPreviousSecret != "" && inst.WebhookPreviousSecretExpiresAt != nil && now.Before(*inst.WebhookPreviousSecretExpiresAt) {
+		secrets = append(secrets, inst.WebhookPreviousSecret)
+	}
+	return secrets
 }